@@ -573,6 +573,13 @@ const (
 	AnnotationDataHash = "reconcile.external-secrets.io/data-hash"
 	// AnnotationForceSync all ExternalSecrets managed by a ClusterExternalSecret mirror the state and value of this annotation.
 	AnnotationForceSync = "external-secrets.io/force-sync"
+	// AnnotationAuthOverrideSecret names a Secret in the namespace of the ExternalSecret holding
+	// a one-off token under the key AuthOverrideSecretKey. Providers that support it use the token
+	// instead of the store's configured auth, e.g. for break-glass access. It is rejected unless
+	// the controller runs with --enable-auth-override and the store allows overrides.
+	AnnotationAuthOverrideSecret = "external-secrets.io/auth-override-secret"
+	// AuthOverrideSecretKey is the key of the token in the Secret named by AnnotationAuthOverrideSecret.
+	AuthOverrideSecretKey = "token"
 
 	// LabelManaged all secrets managed by an ExternalSecret will have this label equal to "true".
 	LabelManaged      = "reconcile.external-secrets.io/managed"
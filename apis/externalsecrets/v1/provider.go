@@ -16,10 +16,14 @@ package v1
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
 )
 
 const (
@@ -96,6 +100,24 @@ type SecretsClient interface {
 	Close(ctx context.Context) error
 }
 
+// WarningReporter is implemented by clients that collect warnings which
+// don't fail the validation of the store, e.g. warnings returned by the
+// provider on login. The warnings are reported in a store condition.
+// +kubebuilder:object:generate=false
+type WarningReporter interface {
+	Warnings() []string
+}
+
+// AuthMethodReporter is implemented by clients that can tell which of the
+// configured auth methods they logged in with. The method is reported in
+// the store status.
+// +kubebuilder:object:generate=false
+type AuthMethodReporter interface {
+	// AuthMethod returns the auth method of the last login, or an empty
+	// string if the client re-used an existing token.
+	AuthMethod() string
+}
+
 var NoSecretErr = NoSecretError{}
 
 // NoSecretError shall be returned when a GetSecret can not find the
@@ -106,6 +128,24 @@ func (NoSecretError) Error() string {
 	return "Secret does not exist"
 }
 
+// PendingError shall be returned by NewClient when the client can't be
+// created yet but is expected to be soon, e.g. while a credential Secret
+// created by a later sync wave doesn't exist yet. The store is then reported
+// as pending and reconciled again after RequeueAfter, instead of failing.
+// +kubebuilder:object:generate=false
+type PendingError struct {
+	Err          error
+	RequeueAfter time.Duration
+}
+
+func (e *PendingError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PendingError) Unwrap() error {
+	return e.Err
+}
+
 var NotModifiedErr = NotModifiedError{}
 
 // NotModifiedError to signal that the webhook received no changes,
@@ -115,3 +155,62 @@ type NotModifiedError struct{}
 func (NotModifiedError) Error() string {
 	return "not modified"
 }
+
+// authOverrideKey is the context key of the auth override secret reference.
+// +kubebuilder:object:generate=false
+type authOverrideKey struct{}
+
+// ContextWithAuthOverride returns a context carrying a reference to a Secret
+// with a token that should be used instead of the store's configured auth.
+// Providers that don't support auth overrides ignore it.
+func ContextWithAuthOverride(ctx context.Context, ref esmeta.SecretKeySelector) context.Context {
+	return context.WithValue(ctx, authOverrideKey{}, ref)
+}
+
+// AuthOverrideFromContext returns the auth override secret reference of the context, if any.
+func AuthOverrideFromContext(ctx context.Context) (esmeta.SecretKeySelector, bool) {
+	ref, ok := ctx.Value(authOverrideKey{}).(esmeta.SecretKeySelector)
+	return ref, ok
+}
+
+// requeueHintKey is the context key of the requeue hint of a reconcile.
+// +kubebuilder:object:generate=false
+type requeueHintKey struct{}
+
+// RequeueHint collects the earliest time providers asked for the resource
+// of a reconcile to be reconciled again.
+// +kubebuilder:object:generate=false
+type RequeueHint struct {
+	mu    sync.Mutex
+	after time.Duration
+}
+
+// After returns the earliest requested requeue, if any.
+func (h *RequeueHint) After() (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.after, h.after > 0
+}
+
+// ContextWithRequeueHint returns a context in which providers can request
+// an earlier reconcile with RequestRequeue.
+func ContextWithRequeueHint(ctx context.Context) (context.Context, *RequeueHint) {
+	hint := &RequeueHint{}
+	return context.WithValue(ctx, requeueHintKey{}, hint), hint
+}
+
+// RequestRequeue asks for the resource of the current reconcile to be
+// reconciled again after the given duration, e.g. to refresh credentials
+// before they expire rather than inline in a later request. It does nothing
+// if the reconciler doesn't support requeue hints.
+func RequestRequeue(ctx context.Context, after time.Duration) {
+	hint, ok := ctx.Value(requeueHintKey{}).(*RequeueHint)
+	if !ok || after <= 0 {
+		return
+	}
+	hint.mu.Lock()
+	defer hint.mu.Unlock()
+	if hint.after == 0 || after < hint.after {
+		hint.after = after
+	}
+}
@@ -256,12 +256,17 @@ type SecretStoreConditionType string
 
 const (
 	SecretStoreReady SecretStoreConditionType = "Ready"
+	// SecretStoreWarnings is set if the provider reported warnings while
+	// the store was validated.
+	SecretStoreWarnings SecretStoreConditionType = "Warnings"
 
 	ReasonInvalidStore          = "InvalidStoreConfiguration"
 	ReasonInvalidProviderConfig = "InvalidProviderConfig"
 	ReasonValidationFailed      = "ValidationFailed"
 	ReasonValidationUnknown     = "ValidationUnknown"
+	ReasonPending               = "Pending"
 	ReasonStoreValid            = "Valid"
+	ReasonProviderWarnings      = "ProviderWarnings"
 	StoreUnmaintained           = "StoreUnmaintained"
 )
 
@@ -294,6 +299,10 @@ type SecretStoreStatus struct {
 	Conditions []SecretStoreStatusCondition `json:"conditions,omitempty"`
 	// +optional
 	Capabilities SecretStoreCapabilities `json:"capabilities,omitempty"`
+	// AuthMethod is the auth method the provider last logged in with,
+	// for providers that report it.
+	// +optional
+	AuthMethod string `json:"authMethod,omitempty"`
 }
 
 // +kubebuilder:object:root=true
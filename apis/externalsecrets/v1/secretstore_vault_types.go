@@ -15,6 +15,8 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
 )
 
@@ -31,9 +33,45 @@ type VaultProvider struct {
 	// Auth configures how secret-manager authenticates with the Vault server.
 	Auth *VaultAuth `json:"auth,omitempty"`
 
+	// MountAuth configures separate logins for reads below specific paths,
+	// e.g. for mounts whose secrets are protected by a different role.
+	// Reads below none of the paths use Auth.
+	// +optional
+	MountAuth []VaultMountAuth `json:"mountAuth,omitempty"`
+
+	// WriteAuth configures a separate login for the writes and deletes of
+	// PushSecret, e.g. with a role allowed to write, so that Auth can use a
+	// read-only role. The reads of PushSecret checking the current secret
+	// use it as well. Writes use Auth if unset.
+	// +optional
+	WriteAuth *VaultAuth `json:"writeAuth,omitempty"`
+
 	// Server is the connection address for the Vault server, e.g: "https://vault.example.com:8200".
+	// A unix domain socket, e.g. of a Vault Agent sidecar, is addressed as
+	// "unix:///path/to/socket".
 	Server string `json:"server"`
 
+	// DialAddress is the address to connect to instead of the host of Server,
+	// e.g: "127.0.0.1:8200" for the local end of a tunnel. Server is still used
+	// for the Host header and as the TLS server name.
+	// +optional
+	DialAddress string `json:"dialAddress,omitempty"`
+
+	// BasePath is the path prefix Vault is served under, e.g: "/vault" if a
+	// reverse proxy exposes it as "https://proxy.example.com/vault/". It is
+	// prepended to the path of all requests to Vault, including logins and
+	// token lookups, and must not include the "/v1" API prefix.
+	// +optional
+	BasePath string `json:"basePath,omitempty"`
+
+	// HTTPVersion restricts the HTTP version of the requests to Vault,
+	// including logins. "HTTP1" forces HTTP/1.1, e.g. for gateways that
+	// misbehave with HTTP/2, and "HTTP2" requires HTTP/2, which is only
+	// negotiated over HTTPS. By default, HTTP/2 is used if the server
+	// supports it, and HTTP/1.1 otherwise.
+	// +optional
+	HTTPVersion VaultHTTPVersion `json:"httpVersion,omitempty"`
+
 	// Path is the mount path of the Vault KV backend endpoint, e.g:
 	// "secret". The v2 KV secret engine version specific "/data" path suffix
 	// for fetching secrets from Vault is optional and will be appended
@@ -54,6 +92,13 @@ type VaultProvider struct {
 	// +optional
 	Namespace *string `json:"namespace,omitempty"`
 
+	// LowercaseNamespaces lowercases the namespace and the auth namespaces
+	// before they are used, for Vault setups whose namespaces are lowercase
+	// but referenced with mixed case, e.g. when derived from other resource
+	// names. Leading and trailing slashes are trimmed regardless.
+	// +optional
+	LowercaseNamespaces bool `json:"lowercaseNamespaces,omitempty"`
+
 	// PEM encoded CA bundle used to validate Vault server certificate. Only used
 	// if the Server URL is using HTTPS protocol. This parameter is ignored for
 	// plain HTTP protocol connection. If not set the system root certificates
@@ -73,6 +118,14 @@ type VaultProvider struct {
 	// +optional
 	CAProvider *CAProvider `json:"caProvider,omitempty"`
 
+	// RevocationCheck checks the revocation status of the Vault server
+	// certificate on each TLS handshake, including those of logins, and
+	// fails the connection if it is revoked. It is opt-in as every new
+	// connection waits for an OCSP or CRL request, unless the status is
+	// stapled or still cached. Only used if the Server URL is using HTTPS.
+	// +optional
+	RevocationCheck *VaultRevocationCheck `json:"revocationCheck,omitempty"`
+
 	// ReadYourWrites ensures isolated read-after-write semantics by
 	// providing discovered cluster replication states in each request.
 	// More information about eventual consistency in Vault can be found here
@@ -98,6 +151,17 @@ type VaultProvider struct {
 	CheckAndSet *VaultCheckAndSet `json:"checkAndSet,omitempty"`
 }
 
+// VaultMountAuth authenticates reads below a path with a login of its own.
+type VaultMountAuth struct {
+	// Path is the prefix of the Vault paths read with this auth, e.g. the
+	// mount "team-a" or "secret/data/team-a". It is matched on whole path
+	// segments, the longest matching path wins.
+	Path string `json:"path"`
+
+	// Auth configures the login used for reads below Path.
+	Auth VaultAuth `json:"auth"`
+}
+
 // VaultClientTLS is the configuration used for client side related TLS communication,
 // when the Vault server requires mutual authentication.
 type VaultClientTLS struct {
@@ -126,10 +190,68 @@ type VaultAuth struct {
 	// +optional
 	Namespace *string `json:"namespace,omitempty"`
 
+	// RootNamespace logs in at the root namespace, regardless of the
+	// namespace of the store, e.g. if the auth method is only mounted there.
+	// The token is then used in the namespace of the store.
+	// Mutually exclusive with Namespace.
+	// +optional
+	RootNamespace bool `json:"rootNamespace,omitempty"`
+
+	// DiscoverNamespace uses the namespace Vault reports in the auth metadata
+	// of the login response, "namespace_path", for the requests of the store
+	// if it differs from the requested namespace, e.g. if Vault Enterprise
+	// issued the token in a child namespace.
+	// +optional
+	DiscoverNamespace bool `json:"discoverNamespace,omitempty"`
+
+	// LocalMount marks the auth method as mounted locally to the Vault
+	// cluster, so that logins are handled by the node receiving them rather
+	// than forwarded to the active node, even on a performance standby.
+	// Cannot be used with ForwardInconsistent.
+	// +optional
+	LocalMount bool `json:"localMount,omitempty"`
+
 	// TokenSecretRef authenticates with Vault by presenting a token.
 	// +optional
 	TokenSecretRef *esmeta.SecretKeySelector `json:"tokenSecretRef,omitempty"`
 
+	// WrappedToken marks the value of TokenSecretRef as a response-wrapping
+	// token wrapping the Vault token, e.g. one created with a wrap TTL, which
+	// is unwrapped before the token is used. As wrapping tokens can be
+	// unwrapped only once, the token is reused until the Secret holds
+	// another wrapping token.
+	// +optional
+	WrappedToken bool `json:"wrappedToken,omitempty"`
+
+	// TokenRole creates the token the store uses with a named token role,
+	// as a child of the token obtained by the login, e.g. of a parent token
+	// read from TokenSecretRef.
+	// +optional
+	TokenRole *VaultTokenRole `json:"tokenRole,omitempty"`
+
+	// TokenType is the type of token logins have to issue. With "service",
+	// a login issuing a batch token fails, instead of the batch token being
+	// replaced by a new login whenever it would be reused, as batch tokens
+	// can't be renewed. Any type is accepted if unset.
+	// +optional
+	TokenType VaultTokenType `json:"tokenType,omitempty"`
+
+	// TokenFilePath authenticates with Vault by presenting the token in a
+	// file on the filesystem of the controller, e.g. one written by a Vault
+	// Agent sidecar. The file is read again on each login, so that rotated
+	// tokens are picked up, and the next auth method is tried while it is
+	// missing or empty. Only files within the directories allowed with
+	// --vault-token-file-dirs can be read.
+	// +optional
+	TokenFilePath string `json:"tokenFilePath,omitempty"`
+
+	// FallbackTokenRef is a static token, e.g. from a break-glass Secret,
+	// that is used as a last resort once the configured auth method failed
+	// or none of them could log in. It is only used while Vault accepts it,
+	// and is never renewed or revoked by the controller.
+	// +optional
+	FallbackTokenRef *esmeta.SecretKeySelector `json:"fallbackTokenRef,omitempty"`
+
 	// AppRole authenticates with Vault using the App Role auth mechanism,
 	// with the role and secret stored in a Kubernetes Secret resource.
 	// +optional
@@ -160,9 +282,402 @@ type VaultAuth struct {
 	// +optional
 	Iam *VaultIamAuth `json:"iam,omitempty"`
 
+	// Gcp authenticates with Vault by passing a JWT signed by a Google
+	// service account using the GCP IAM authentication method
+	// +optional
+	Gcp *VaultGcpAuth `json:"gcp,omitempty"`
+
 	// UserPass authenticates with Vault by passing username/password pair
 	// +optional
 	UserPass *VaultUserPassAuth `json:"userPass,omitempty"`
+
+	// Azure authenticates with Vault by passing an Azure AD access token of
+	// a managed identity or workload identity using the Azure auth method
+	// +optional
+	Azure *VaultAzureAuth `json:"azure,omitempty"`
+
+	// Plugin authenticates with Vault by posting parameters to the login
+	// endpoint of an auth method that has no dedicated configuration, e.g. a
+	// custom plugin
+	// +optional
+	Plugin *VaultPluginAuth `json:"plugin,omitempty"`
+
+	// TokenNumUses is the number of uses the tokens issued by the auth method
+	// are limited to, e.g. as set with the `token_num_uses` role parameter.
+	// Vault does not return this value on login, so it has to be configured here.
+	// Tokens limited to 2 or fewer uses are not validated with a token lookup
+	// before they are used, as the lookup would consume one of the uses.
+	// Their validity is derived from the lease returned at login instead.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	TokenNumUses *int `json:"tokenNumUses,omitempty"`
+
+	// TokenExpirationLeewaySeconds is how long before their expiry tokens are
+	// treated as expired and replaced, so that they don't expire while they
+	// are in use, e.g. with slow Vault round-trips or long reconciles.
+	// Defaults to 60 if unset or zero.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	TokenExpirationLeewaySeconds int `json:"tokenExpirationLeewaySeconds,omitempty"`
+
+	// TokenUsesCheck checks before a batch of reads, like those of a find,
+	// whether the token has enough uses left for it, with a token lookup.
+	// Then "relogin" replaces a token with too few uses by a new login,
+	// while "fail" fails the batch before its first read, instead of the
+	// token running out midway. Tokens limited to 2 or fewer uses are not
+	// looked up and are assumed to be used up. Disabled if unset.
+	// +optional
+	TokenUsesCheck VaultTokenUsesCheck `json:"tokenUsesCheck,omitempty"`
+
+	// TokenRenewIncrement is the TTL renewals of the token ask for. Vault
+	// caps the increment at the max TTL of the token and of its auth method
+	// without failing the renewal, so the TTL it grants may be shorter.
+	// Renewals ask for the default TTL of the token if unset.
+	// +optional
+	TokenRenewIncrement *metav1.Duration `json:"tokenRenewIncrement,omitempty"`
+
+	// TokenRenewIncrementCheck is how a renewal granting a shorter TTL than
+	// TokenRenewIncrement is handled: "warn" logs the capped renewal and
+	// keeps the token, while "fail" treats the renewal as failed, so that the
+	// token is replaced by a new login instead of being renewed any further.
+	// The granted TTL is used as is if unset.
+	// +optional
+	TokenRenewIncrementCheck VaultTokenRenewIncrementCheck `json:"tokenRenewIncrementCheck,omitempty"`
+
+	// RevokeScope controls how the token is revoked when the client is closed:
+	// "self" revokes it with the revoke-self endpoint, "tree" revokes it and
+	// all of its child tokens with the revoke endpoint, and "orphan" revokes
+	// only the token itself, leaving its child tokens alive as orphans.
+	// Revoking orphans requires a policy with sudo capability on
+	// auth/token/revoke-orphan. Defaults to "self".
+	// +optional
+	// +kubebuilder:default=self
+	RevokeScope VaultTokenRevokeScope `json:"revokeScope,omitempty"`
+
+	// RevokeStaticToken revokes the token read from TokenSecretRef when the
+	// client is closed, like tokens obtained through a login. Static tokens
+	// are usually managed outside of ESO and shared, so they are not revoked
+	// by default. Tokens with the root policy are never revoked.
+	// +optional
+	RevokeStaticToken bool `json:"revokeStaticToken,omitempty"`
+
+	// RevokeOnRelogin revokes a token that is about to expire before logging
+	// in again to replace it, so that its lease doesn't linger in Vault until
+	// it runs out. This costs one revocation for each login that replaces a
+	// token. A failed revocation doesn't prevent the login.
+	// +optional
+	RevokeOnRelogin bool `json:"revokeOnRelogin,omitempty"`
+
+	// Selection chooses the auth method depending on the environment the
+	// controller runs in, e.g. Kubernetes auth on-prem and IAM auth in the cloud.
+	// Rules are evaluated in order and the method of the first matching rule is used.
+	// The selected method must be configured in this auth block.
+	// If not set, the first configured method is used.
+	// +optional
+	Selection []VaultAuthSelectionRule `json:"selection,omitempty"`
+
+	// AuthMethodPriority lists the configured auth methods in the order they
+	// are tried, e.g. Kubernetes auth with AppRole auth as fallback. If the
+	// login of a method fails, the next one is tried. Methods that aren't
+	// listed aren't used. It cannot be combined with Selection.
+	// If not set, the first configured method is used.
+	// +optional
+	AuthMethodPriority []VaultAuthMethodName `json:"authMethodPriority,omitempty"`
+
+	// CredentialsGracePeriod is how long after the creation of the store a
+	// login whose credential Secret or service account doesn't exist yet
+	// reports the store as pending, and checks again shortly, rather than
+	// failing, e.g. while a later GitOps sync wave creates the Secret.
+	// +optional
+	CredentialsGracePeriod *metav1.Duration `json:"credentialsGracePeriod,omitempty"`
+
+	// CanaryPath is a Vault path that is read with the token after each
+	// login, e.g. "secret/data/canary". The login fails if the read fails,
+	// so that a policy lacking access to the expected secrets is caught
+	// at login rather than on the first request.
+	// +optional
+	CanaryPath string `json:"canaryPath,omitempty"`
+
+	// RequiredCapabilities maps Vault paths to the capabilities the token
+	// needs on them, e.g. "secret/data/app": ["read"]. They are checked
+	// with sys/capabilities-self after each login, and the login fails if
+	// any of them is missing.
+	// +optional
+	RequiredCapabilities map[string][]string `json:"requiredCapabilities,omitempty"`
+
+	// ResolveCheckPaths resolves CanaryPath and the paths of
+	// RequiredCapabilities like the keys of remote refs, against the path and
+	// KV version of the store, e.g. "app" is checked at "secret/data/app" with
+	// KV v2 and at "secret/app" with KV v1. Otherwise they are Vault API paths.
+	// +optional
+	ResolveCheckPaths bool `json:"resolveCheckPaths,omitempty"`
+
+	// ExpectedMountAccessor is the accessor of the auth mount the token is
+	// expected to be issued by, e.g. "auth_approle_1a2b3c4d". After each login,
+	// the mount is looked up by the creation path of the token in sys/auth,
+	// and the login fails if its accessor differs, e.g. because the mount was
+	// replaced by another one at the same path. The token needs read access
+	// on sys/auth.
+	// +optional
+	ExpectedMountAccessor string `json:"expectedMountAccessor,omitempty"`
+
+	// DeniedPolicies are policies the token must not carry, e.g. "root", to
+	// detect a role or identity unexpectedly granting broad access. After
+	// each login, the token and identity policies of the token are checked
+	// against them, and a denied policy is handled by DeniedPoliciesAction.
+	// +optional
+	DeniedPolicies []string `json:"deniedPolicies,omitempty"`
+
+	// DeniedPoliciesAction is how a token carrying a denied policy is
+	// handled: "fail" revokes the token and fails the login, while "warn"
+	// logs the denied policies and keeps the token. Either way, each denied
+	// policy is counted in the provider_auth_denied_policy_count metric.
+	// Defaults to "fail".
+	// +optional
+	// +kubebuilder:default=fail
+	DeniedPoliciesAction VaultDeniedPoliciesAction `json:"deniedPoliciesAction,omitempty"`
+
+	// PolicySource is where the policies of the token are expected to come
+	// from, e.g. a ConfigMap managed by GitOps. Once they change, the token
+	// is replaced by a new login instead of being reused until it expires,
+	// so that it carries the new policies.
+	// +optional
+	PolicySource *VaultPolicySource `json:"policySource,omitempty"`
+
+	// LoginWarnings configures the handling of warnings returned by Vault
+	// on login, e.g. about deprecated policies. Warnings are always logged.
+	// +optional
+	LoginWarnings *VaultLoginWarnings `json:"loginWarnings,omitempty"`
+
+	// StatusMapping classifies failed logins by the HTTP status code of the
+	// response, e.g. for non-standard status codes returned by a gateway in
+	// front of Vault. Mappings are evaluated in order and take precedence
+	// over the default classification.
+	// +optional
+	StatusMapping []VaultAuthStatusMapping `json:"statusMapping,omitempty"`
+
+	// LoginRetry retries the login request of the auth method with an
+	// exponential backoff while it fails with a transient error, e.g. a 5xx
+	// response or a network error. Rejected logins are never retried.
+	// The loginRetrySettings of Kubernetes auth take precedence over it.
+	// +optional
+	LoginRetry *VaultLoginRetry `json:"loginRetry,omitempty"`
+
+	// LoginTimeout bounds each login, including its retries and the requests
+	// for the credentials it is made with, e.g. "30s", so that a hung Vault
+	// endpoint doesn't block the reconcile. It takes precedence over the
+	// --vault-auth-timeout and --vault-auth-method-timeouts flags, which
+	// default to the timeout of the Vault client.
+	// +optional
+	LoginTimeout *metav1.Duration `json:"loginTimeout,omitempty"`
+
+	// AuthOverride allows ExternalSecrets to use a token of their own
+	// instead of the auth of the store, with the
+	// external-secrets.io/auth-override-secret annotation. The controller
+	// must also be started with --enable-auth-override.
+	// If not set, auth overrides are rejected.
+	// +optional
+	AuthOverride *VaultAuthOverride `json:"authOverride,omitempty"`
+}
+
+// VaultAuthOverride configures which ExternalSecrets may override the auth
+// of the store.
+type VaultAuthOverride struct {
+	// Namespaces of the ExternalSecrets allowed to override the auth of a
+	// ClusterSecretStore. Overrides of a ClusterSecretStore are rejected in
+	// any other namespace. Ignored for SecretStores, whose auth can be
+	// overridden by any ExternalSecret in their namespace.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// VaultTokenRole creates tokens with the auth/token/create endpoint of a
+// token role, which bounds the policies, TTLs and other properties of the
+// tokens it creates.
+type VaultTokenRole struct {
+	// Name of the token role.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Policies of the created token, which the role has to allow. Vault
+	// picks the policies according to the role if empty.
+	// +optional
+	Policies []string `json:"policies,omitempty"`
+
+	// TTL of the created token, which the max TTL of the role caps. The TTL
+	// of the role is used if unset.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+// VaultPolicySource is the source of the policies a token is expected to
+// carry. Exactly one of configMapRef or rolePath must be specified.
+type VaultPolicySource struct {
+	// ConfigMapRef references a key of a ConfigMap listing the expected
+	// policies, separated by commas or newlines.
+	// +optional
+	ConfigMapRef *VaultConfigMapKeySelector `json:"configMapRef,omitempty"`
+
+	// RolePath is the Vault path of the role the token is issued for, e.g.
+	// "auth/kubernetes/role/app". Its token_policies are read with the token,
+	// in the namespace the token was issued in.
+	// +optional
+	RolePath string `json:"rolePath,omitempty"`
+}
+
+// VaultConfigMapKeySelector references a key of a ConfigMap.
+type VaultConfigMapKeySelector struct {
+	// The name of the ConfigMap.
+	Name string `json:"name"`
+
+	// The namespace of the ConfigMap.
+	// Ignored if referent is not cluster-scoped, otherwise defaults to the namespace of the referent.
+	// +optional
+	Namespace *string `json:"namespace,omitempty"`
+
+	// The key in the ConfigMap.
+	Key string `json:"key"`
+}
+
+// VaultHTTPVersion is the HTTP version of the requests to Vault.
+// +kubebuilder:validation:Enum=HTTP1;HTTP2
+type VaultHTTPVersion string
+
+const (
+	VaultHTTPVersion1 VaultHTTPVersion = "HTTP1"
+	VaultHTTPVersion2 VaultHTTPVersion = "HTTP2"
+)
+
+// VaultTokenRevokeScope selects the endpoint used to revoke a token.
+// +kubebuilder:validation:Enum=self;tree;orphan
+type VaultTokenRevokeScope string
+
+const (
+	VaultTokenRevokeScopeSelf   VaultTokenRevokeScope = "self"
+	VaultTokenRevokeScopeTree   VaultTokenRevokeScope = "tree"
+	VaultTokenRevokeScopeOrphan VaultTokenRevokeScope = "orphan"
+)
+
+// VaultDeniedPoliciesAction is how a token carrying a denied policy is handled.
+// +kubebuilder:validation:Enum=fail;warn
+type VaultDeniedPoliciesAction string
+
+const (
+	VaultDeniedPoliciesActionFail VaultDeniedPoliciesAction = "fail"
+	VaultDeniedPoliciesActionWarn VaultDeniedPoliciesAction = "warn"
+)
+
+// VaultTokenType is the type of a Vault token.
+// +kubebuilder:validation:Enum=service
+type VaultTokenType string
+
+const (
+	VaultTokenTypeService VaultTokenType = "service"
+)
+
+// VaultTokenRenewIncrementCheck is how a renewal granting a shorter TTL than
+// requested is handled.
+// +kubebuilder:validation:Enum=warn;fail
+type VaultTokenRenewIncrementCheck string
+
+const (
+	VaultTokenRenewIncrementCheckWarn VaultTokenRenewIncrementCheck = "warn"
+	VaultTokenRenewIncrementCheckFail VaultTokenRenewIncrementCheck = "fail"
+)
+
+// VaultTokenUsesCheck is how a token with too few uses left for a batch of
+// reads is handled.
+// +kubebuilder:validation:Enum=relogin;fail
+type VaultTokenUsesCheck string
+
+const (
+	VaultTokenUsesCheckRelogin VaultTokenUsesCheck = "relogin"
+	VaultTokenUsesCheckFail    VaultTokenUsesCheck = "fail"
+)
+
+// VaultAuthMethodName is the name of an auth method as configured in VaultAuth.
+// +kubebuilder:validation:Enum=tokenSecretRef;tokenFile;appRole;kubernetes;ldap;userPass;jwt;cert;iam;gcp;azure;plugin
+type VaultAuthMethodName string
+
+const (
+	VaultAuthMethodTokenSecretRef VaultAuthMethodName = "tokenSecretRef"
+	VaultAuthMethodTokenFile      VaultAuthMethodName = "tokenFile"
+	VaultAuthMethodAppRole        VaultAuthMethodName = "appRole"
+	VaultAuthMethodKubernetes     VaultAuthMethodName = "kubernetes"
+	VaultAuthMethodLdap           VaultAuthMethodName = "ldap"
+	VaultAuthMethodUserPass       VaultAuthMethodName = "userPass"
+	VaultAuthMethodJwt            VaultAuthMethodName = "jwt"
+	VaultAuthMethodCert           VaultAuthMethodName = "cert"
+	VaultAuthMethodIam            VaultAuthMethodName = "iam"
+	VaultAuthMethodGcp            VaultAuthMethodName = "gcp"
+	VaultAuthMethodAzure          VaultAuthMethodName = "azure"
+	VaultAuthMethodPlugin         VaultAuthMethodName = "plugin"
+)
+
+// VaultAuthSelectionRule selects an auth method if all of its conditions
+// match the environment of the controller. A rule without conditions
+// always matches and can be used as a fallback.
+type VaultAuthSelectionRule struct {
+	// Method is the auth method to use when the rule matches.
+	Method VaultAuthMethodName `json:"method"`
+
+	// EnvVar matches if the named environment variable is set to a
+	// non-empty value in the controller, e.g. AWS_WEB_IDENTITY_TOKEN_FILE.
+	// +optional
+	EnvVar string `json:"envVar,omitempty"`
+
+	// EnvValue additionally requires EnvVar to be set to this value.
+	// +optional
+	EnvValue *string `json:"envValue,omitempty"`
+
+	// FileExists matches if the given path exists in the controller's
+	// filesystem, e.g. a projected cloud identity token.
+	// +optional
+	FileExists string `json:"fileExists,omitempty"`
+}
+
+// VaultAuthErrorClass is how a failed login is treated.
+// +kubebuilder:validation:Enum=authRejected;transient;sealed
+type VaultAuthErrorClass string
+
+const (
+	// VaultAuthErrorClassAuthRejected is a login rejected by Vault, which
+	// isn't retried.
+	VaultAuthErrorClassAuthRejected VaultAuthErrorClass = "authRejected"
+	// VaultAuthErrorClassTransient is a login that failed temporarily and
+	// is retried.
+	VaultAuthErrorClassTransient VaultAuthErrorClass = "transient"
+	// VaultAuthErrorClassSealed is a login that failed because Vault is
+	// sealed, which is retried.
+	VaultAuthErrorClassSealed VaultAuthErrorClass = "sealed"
+)
+
+// VaultAuthStatusMapping classifies failed logins with a given HTTP status code.
+type VaultAuthStatusMapping struct {
+	// Method limits the mapping to logins with the given auth method.
+	// If not set, the mapping applies to all methods.
+	// +optional
+	Method VaultAuthMethodName `json:"method,omitempty"`
+
+	// StatusCode is the HTTP status code of the failed login, e.g. 418.
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	StatusCode int `json:"statusCode"`
+
+	// Class is how failed logins with the status code are treated.
+	Class VaultAuthErrorClass `json:"class"`
+}
+
+// VaultLoginRetry configures the retries of a failed login request.
+type VaultLoginRetry struct {
+	// MaxAttempts is the number of login requests made, including the first.
+	// +kubebuilder:validation:Minimum=1
+	MaxAttempts int32 `json:"maxAttempts"`
+
+	// InitialInterval is the wait before the first retry, e.g. "500ms". It
+	// doubles with each further retry, up to a minute. Defaults to 1s.
+	// +optional
+	InitialInterval *string `json:"initialInterval,omitempty"`
 }
 
 // VaultAppRole authenticates with Vault using the App Role auth mechanism,
@@ -190,6 +705,40 @@ type VaultAppRole struct {
 	// The `key` field must be specified and denotes which entry within the Secret
 	// resource is used as the app role secret.
 	SecretRef esmeta.SecretKeySelector `json:"secretRef"`
+
+	// WrappedSecretID marks the value of SecretRef as a response-wrapping
+	// token wrapping the secret ID, e.g. one issued with a wrap TTL, which is
+	// unwrapped before the login. As wrapping tokens can be unwrapped only
+	// once, the secret ID is reused until the Secret holds another token.
+	// +optional
+	WrappedSecretID bool `json:"wrappedSecretId,omitempty"`
+
+	// FallbackCredentials are credentials of further roles of the same
+	// backend, tried in order when Vault rejects the login with the previous
+	// credentials, e.g. those of the old role while migrating to a new one.
+	// Logins failing for other reasons, like Vault being unavailable, are not
+	// retried with them.
+	// +optional
+	FallbackCredentials []VaultAppRoleCredentials `json:"fallbackCredentials,omitempty"`
+}
+
+// VaultAppRoleCredentials are the credentials of an App Role.
+type VaultAppRoleCredentials struct {
+	// RoleID configured in the App Role authentication backend.
+	//+optional
+	RoleID string `json:"roleId,omitempty"`
+
+	// Reference to a key in a Secret that contains the App Role ID.
+	//+optional
+	RoleRef *esmeta.SecretKeySelector `json:"roleRef,omitempty"`
+
+	// Reference to a key in a Secret that contains the App Role secret.
+	SecretRef esmeta.SecretKeySelector `json:"secretRef"`
+
+	// WrappedSecretID marks the value of SecretRef as a response-wrapping
+	// token wrapping the secret ID.
+	// +optional
+	WrappedSecretID bool `json:"wrappedSecretId,omitempty"`
 }
 
 // Authenticate against Vault using a Kubernetes ServiceAccount token stored in
@@ -217,6 +766,38 @@ type VaultKubernetesAuth struct {
 	// A required field containing the Vault Role to assume. A Role binds a
 	// Kubernetes ServiceAccount with a set of Vault policies.
 	Role string `json:"role"`
+
+	// Optional issuer that the `iss` claim of the ServiceAccount token must match.
+	// When set, the token is checked before logging in so that a mismatch with the
+	// issuer configured on the Vault Kubernetes auth backend fails with a clear error
+	// instead of a permission denied response from Vault.
+	// +optional
+	ExpectedIssuer string `json:"expectedIssuer,omitempty"`
+
+	// Optional audiences of the token requested for the serviceAccountRef.
+	// They are added to the audiences of the serviceAccountRef, each audience
+	// being requested once, so that stores authenticating to roles with
+	// different `bound_audiences` can use the same ServiceAccount.
+	// +optional
+	Audiences []string `json:"audiences,omitempty"`
+
+	// Optional KubernetesTokenRequest configures the token requested for the
+	// serviceAccountRef. Cannot be used with Audiences.
+	// +optional
+	KubernetesTokenRequest *VaultKubernetesTokenRequest `json:"kubernetesTokenRequest,omitempty"`
+
+	// Optional retry settings for requesting the token of the serviceAccountRef
+	// from the Kubernetes TokenRequest API. By default, a failed request is only
+	// retried with the next reconcile.
+	// +optional
+	TokenRequestRetrySettings *SecretStoreRetrySettings `json:"tokenRequestRetrySettings,omitempty"`
+
+	// Optional retry settings for the Vault login with the ServiceAccount token.
+	// They are independent of the token request, which isn't repeated when the
+	// login is retried. Permission errors are never retried. By default, a failed
+	// login is only retried with the next reconcile.
+	// +optional
+	LoginRetrySettings *SecretStoreRetrySettings `json:"loginRetrySettings,omitempty"`
 }
 
 // VaultLdapAuth authenticates with Vault using the LDAP authentication method,
@@ -292,6 +873,55 @@ type VaultKubernetesServiceAccountTokenAuth struct {
 	// Defaults to 10 minutes.
 	// +optional
 	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
+
+	// Optional KubernetesTokenRequest configures the token requested for the
+	// serviceAccountRef. Cannot be used with Audiences or ExpirationSeconds.
+	// +optional
+	KubernetesTokenRequest *VaultKubernetesTokenRequest `json:"kubernetesTokenRequest,omitempty"`
+}
+
+// VaultKubernetesTokenRequest configures the request of a temporary Kubernetes
+// service account token with the `TokenRequest` API.
+type VaultKubernetesTokenRequest struct {
+	// Optional audiences of the token. When set, they are used instead of
+	// the audiences of the serviceAccountRef. Defaults to the audiences of
+	// the serviceAccountRef, or to those of the Kubernetes API server if
+	// there are none.
+	// +optional
+	Audiences []string `json:"audiences,omitempty"`
+
+	// Optional expiration time of the token in seconds.
+	// Defaults to 10 minutes, which is also the minimum.
+	// +kubebuilder:validation:Minimum=600
+	// +optional
+	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
+
+	// Optional BoundObjectRef binds the token to a Kubernetes object in the
+	// namespace of the ServiceAccount, e.g. a Pod. The token is then
+	// invalidated once the object is deleted, and carries its name and UID
+	// as claims that Vault roles can be bound to.
+	// +optional
+	BoundObjectRef *VaultKubernetesBoundObjectRef `json:"boundObjectRef,omitempty"`
+}
+
+// VaultKubernetesBoundObjectRef references the object a service account token
+// is bound to.
+type VaultKubernetesBoundObjectRef struct {
+	// Kind of the object, either Pod or Secret.
+	// +kubebuilder:validation:Enum=Pod;Secret
+	Kind string `json:"kind"`
+
+	// Optional API version of the object. Defaults to "v1".
+	// +optional
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Name of the object.
+	Name string `json:"name"`
+
+	// Optional UID of the object. When set, the token is only issued if it
+	// matches the UID of the object.
+	// +optional
+	UID string `json:"uid,omitempty"`
 }
 
 // VaultJwtAuth authenticates with Vault using the JWT/OIDC authentication
@@ -300,6 +930,10 @@ type VaultKubernetesServiceAccountTokenAuth struct {
 type VaultJwtAuth struct {
 	// Path where the JWT authentication backend is mounted
 	// in Vault, e.g: "jwt"
+	// The path may be a Go template rendered with the namespace the secrets
+	// are requested from as .Namespace and the kind and name of the store as
+	// .StoreKind and .StoreName, so that each namespace can log in through
+	// its own mount.
 	// +kubebuilder:default=jwt
 	Path string `json:"path"`
 
@@ -317,6 +951,91 @@ type VaultJwtAuth struct {
 	// a token for with the `TokenRequest` API.
 	// +optional
 	KubernetesServiceAccountToken *VaultKubernetesServiceAccountTokenAuth `json:"kubernetesServiceAccountToken,omitempty"`
+
+	// Optional HTTPSource fetches the JWT token from an HTTP endpoint
+	// vending tokens for the workload.
+	// +optional
+	HTTPSource *VaultJwtHTTPSource `json:"httpSource,omitempty"`
+
+	// Optional TokenExchange exchanges the token for the JWT used to
+	// authenticate with Vault, using OAuth 2.0 Token Exchange (RFC 8693).
+	// +optional
+	TokenExchange *VaultJwtTokenExchange `json:"tokenExchange,omitempty"`
+}
+
+// VaultLoginWarnings configures the handling of login warnings.
+type VaultLoginWarnings struct {
+	// Condition reports the warnings of the login done while validating
+	// the store in a Warnings condition of the store.
+	// +optional
+	Condition bool `json:"condition,omitempty"`
+
+	// Escalate fails the login if a warning contains any of these strings.
+	// +optional
+	Escalate []string `json:"escalate,omitempty"`
+}
+
+// VaultJwtTokenExchange exchanges the token of the JWT authentication
+// method at an OAuth 2.0 Token Exchange (RFC 8693) endpoint. The token read
+// from SecretRef or requested for KubernetesServiceAccountToken is the
+// subject token, the issued JWT is used to log in.
+type VaultJwtTokenExchange struct {
+	// TokenEndpoint is the URL of the token exchange endpoint,
+	// e.g: "https://sts.example.com/oauth2/token"
+	TokenEndpoint string `json:"tokenEndpoint"`
+
+	// Audience of the exchanged token, e.g. the audience bound by the
+	// Vault JWT role.
+	// +optional
+	Audience string `json:"audience,omitempty"`
+
+	// Scope requested for the exchanged token.
+	// +optional
+	Scope string `json:"scope,omitempty"`
+
+	// PEM encoded CA bundle used to validate the token endpoint's
+	// certificate. Defaults to the system roots.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+}
+
+// VaultJwtHTTPSource fetches the JWT token of the JWT authentication method
+// with a GET request to an HTTP endpoint. The token is fetched again once
+// it expires.
+type VaultJwtHTTPSource struct {
+	// URL of the endpoint returning the JWT token,
+	// e.g: "https://identity.internal/v1/token"
+	URL string `json:"url"`
+
+	// Headers sent with the request.
+	// +optional
+	Headers []VaultJwtHTTPHeader `json:"headers,omitempty"`
+
+	// Field of the JSON response holding the JWT token. The whole response
+	// body is used as the token if empty.
+	// +optional
+	Field string `json:"field,omitempty"`
+
+	// PEM encoded CA bundle used to validate the endpoint's certificate.
+	// Defaults to the system roots.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+}
+
+// VaultJwtHTTPHeader is a header sent to the JWT HTTP source, with its
+// value either given inline or read from a Secret.
+type VaultJwtHTTPHeader struct {
+	// Name of the header.
+	Name string `json:"name"`
+
+	// Value of the header.
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// SecretRef to a key in a Secret resource holding the value of the
+	// header.
+	// +optional
+	SecretRef *esmeta.SecretKeySelector `json:"secretRef,omitempty"`
 }
 
 // VaultCertAuth authenticates with Vault using the JWT/OIDC authentication
@@ -359,6 +1078,28 @@ type VaultIamAuth struct {
 	JWTAuth *VaultAwsJWTAuth `json:"jwt,omitempty"`
 }
 
+// VaultGcpAuth authenticates with Vault using the GCP authentication method
+// of type iam, with a JWT signed by a Google service account through the IAM
+// signJwt API. Refer: https://developer.hashicorp.com/vault/docs/auth/gcp
+type VaultGcpAuth struct {
+	// Path where the GCP authentication backend is mounted
+	// in Vault, e.g: "gcp"
+	// +kubebuilder:default=gcp
+	Path string `json:"path"`
+
+	// Role of the GCP authentication method in Vault, it must be of type iam
+	Role string `json:"role"`
+
+	// ServiceAccountRef of a service account bound to a Google service
+	// account with GKE workload identity, as set in its
+	// `iam.gke.io/gcp-service-account` annotation. The JWT is signed by that
+	// Google service account, which needs the iam.serviceAccounts.signJwt
+	// permission on itself. If not set, the Google service account of the
+	// controller is used, as returned by the GCP metadata server.
+	// +optional
+	ServiceAccountRef *esmeta.ServiceAccountSelector `json:"serviceAccountRef,omitempty"`
+}
+
 // VaultUserPassAuth authenticates with Vault using UserPass authentication method,
 // with the username and password stored in a Kubernetes Secret resource.
 type VaultUserPassAuth struct {
@@ -368,7 +1109,9 @@ type VaultUserPassAuth struct {
 	Path string `json:"path"`
 
 	// Username is a username used to authenticate using the UserPass Vault
-	// authentication method
+	// authentication method. It is required unless the username is read from
+	// the Secret with JSONPaths.
+	// +optional
 	Username string `json:"username"`
 
 	// SecretRef to a key in a Secret resource containing password for the
@@ -376,8 +1119,95 @@ type VaultUserPassAuth struct {
 	// method
 	// +optional
 	SecretRef esmeta.SecretKeySelector `json:"secretRef,omitempty"`
+
+	// JSONPaths read the credentials from a JSON value in the key referenced
+	// by SecretRef, e.g. {"username":"...","password":"..."}. If unset, the
+	// value of the key is the password.
+	// +optional
+	JSONPaths *VaultUserPassJSONPaths `json:"jsonPaths,omitempty"`
+}
+
+// VaultUserPassJSONPaths are the JSON paths of the credentials in the JSON
+// value of a Secret key, e.g. "$.password".
+type VaultUserPassJSONPaths struct {
+	// Username is the JSON path of the username. If unset, the username of
+	// the UserPass authentication is used.
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// Password is the JSON path of the password.
+	// +kubebuilder:validation:MinLength=1
+	Password string `json:"password"`
+}
+
+// VaultAzureAuth authenticates with Vault using the Azure authentication
+// method, with an Azure AD access token of the controller's managed identity
+// or of a workload identity. Refer: https://developer.hashicorp.com/vault/docs/auth/azure
+type VaultAzureAuth struct {
+	// Path where the Azure authentication backend is mounted
+	// in Vault, e.g: "azure"
+	// +kubebuilder:default=azure
+	Path string `json:"path"`
+
+	// Role of the Azure authentication method in Vault
+	Role string `json:"role"`
+
+	// Resource the Azure AD access token is requested for. It must match the
+	// resource configured in the Azure authentication method.
+	// Defaults to "https://management.azure.com/".
+	// +optional
+	Resource string `json:"resource,omitempty"`
+
+	// ServiceAccountRef of a service account set up for Azure workload
+	// identity. A token of the service account is exchanged for the access
+	// token of the identity with the client and tenant ids set in its
+	// `azure.workload.identity/client-id` and `azure.workload.identity/tenant-id`
+	// annotations. If not set, the workload identity of the controller is used
+	// if available, its managed identity otherwise.
+	// +optional
+	ServiceAccountRef *esmeta.ServiceAccountSelector `json:"serviceAccountRef,omitempty"`
 }
 
+// VaultPluginAuth authenticates with Vault by posting parameters to the
+// login endpoint of an auth method, for auth methods without a dedicated
+// configuration such as custom plugins.
+type VaultPluginAuth struct {
+	// Path where the auth method is mounted in Vault, e.g: "my-plugin".
+	// The login is posted to auth/<path>/login.
+	Path string `json:"path"`
+
+	// Parameters of the login, e.g. the role to log in with.
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// SecretParameters of the login read from Secrets, e.g. a password.
+	// They take precedence over Parameters of the same name.
+	// +optional
+	SecretParameters map[string]esmeta.SecretKeySelector `json:"secretParameters,omitempty"`
+
+	// Encoding of the body of the login request. "json" sends the parameters
+	// as a JSON object, "form" sends them form-encoded, as some gateways in
+	// front of Vault require. Defaults to "json".
+	// +optional
+	// +kubebuilder:default=json
+	Encoding VaultLoginEncoding `json:"encoding,omitempty"`
+
+	// ContentType of the login request, replacing the content type of the
+	// encoding, "application/json" or "application/x-www-form-urlencoded",
+	// e.g. to add a charset.
+	// +optional
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// VaultLoginEncoding is the encoding of the body of a login request.
+// +kubebuilder:validation:Enum=json;form
+type VaultLoginEncoding string
+
+const (
+	VaultLoginEncodingJSON VaultLoginEncoding = "json"
+	VaultLoginEncodingForm VaultLoginEncoding = "form"
+)
+
 // VaultCheckAndSet defines the Check-And-Set (CAS) settings for Vault KV v2 PushSecret operations.
 type VaultCheckAndSet struct {
 	// Required when true, all write operations must include a check-and-set parameter.
@@ -385,3 +1215,31 @@ type VaultCheckAndSet struct {
 	// +optional
 	Required bool `json:"required,omitempty"`
 }
+
+// VaultRevocationCheckMethod is a way of checking the revocation status of
+// the Vault server certificate.
+// +kubebuilder:validation:Enum=OCSP;CRL
+type VaultRevocationCheckMethod string
+
+const (
+	// VaultRevocationCheckOCSP verifies the OCSP response stapled by the
+	// server, or queries the OCSP responder of the certificate if there is
+	// none.
+	VaultRevocationCheckOCSP VaultRevocationCheckMethod = "OCSP"
+	// VaultRevocationCheckCRL looks the certificate up in the CRL of its
+	// distribution points.
+	VaultRevocationCheckCRL VaultRevocationCheckMethod = "CRL"
+)
+
+// VaultRevocationCheck configures the revocation check of the Vault server
+// certificate.
+type VaultRevocationCheck struct {
+	// Method is how the revocation status is checked, either OCSP or CRL.
+	Method VaultRevocationCheckMethod `json:"method"`
+
+	// SoftFail accepts the certificate if its revocation status can't be
+	// determined, e.g. because the OCSP responder is unreachable. Revoked
+	// certificates are rejected regardless.
+	// +optional
+	SoftFail bool `json:"softFail,omitempty"`
+}
@@ -3470,6 +3470,13 @@ func (in *VaultAppRole) DeepCopyInto(out *VaultAppRole) {
 		(*in).DeepCopyInto(*out)
 	}
 	in.SecretRef.DeepCopyInto(&out.SecretRef)
+	if in.FallbackCredentials != nil {
+		in, out := &in.FallbackCredentials, &out.FallbackCredentials
+		*out = make([]VaultAppRoleCredentials, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultAppRole.
@@ -3482,6 +3489,27 @@ func (in *VaultAppRole) DeepCopy() *VaultAppRole {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultAppRoleCredentials) DeepCopyInto(out *VaultAppRoleCredentials) {
+	*out = *in
+	if in.RoleRef != nil {
+		in, out := &in.RoleRef, &out.RoleRef
+		*out = new(apismetav1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.SecretRef.DeepCopyInto(&out.SecretRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultAppRoleCredentials.
+func (in *VaultAppRoleCredentials) DeepCopy() *VaultAppRoleCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultAppRoleCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VaultAuth) DeepCopyInto(out *VaultAuth) {
 	*out = *in
@@ -3495,6 +3523,16 @@ func (in *VaultAuth) DeepCopyInto(out *VaultAuth) {
 		*out = new(apismetav1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TokenRole != nil {
+		in, out := &in.TokenRole, &out.TokenRole
+		*out = new(VaultTokenRole)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FallbackTokenRef != nil {
+		in, out := &in.FallbackTokenRef, &out.FallbackTokenRef
+		*out = new(apismetav1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.AppRole != nil {
 		in, out := &in.AppRole, &out.AppRole
 		*out = new(VaultAppRole)
@@ -3525,11 +3563,104 @@ func (in *VaultAuth) DeepCopyInto(out *VaultAuth) {
 		*out = new(VaultIamAuth)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Gcp != nil {
+		in, out := &in.Gcp, &out.Gcp
+		*out = new(VaultGcpAuth)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.UserPass != nil {
 		in, out := &in.UserPass, &out.UserPass
 		*out = new(VaultUserPassAuth)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Azure != nil {
+		in, out := &in.Azure, &out.Azure
+		*out = new(VaultAzureAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Plugin != nil {
+		in, out := &in.Plugin, &out.Plugin
+		*out = new(VaultPluginAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TokenNumUses != nil {
+		in, out := &in.TokenNumUses, &out.TokenNumUses
+		*out = new(int)
+		**out = **in
+	}
+	if in.TokenRenewIncrement != nil {
+		in, out := &in.TokenRenewIncrement, &out.TokenRenewIncrement
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Selection != nil {
+		in, out := &in.Selection, &out.Selection
+		*out = make([]VaultAuthSelectionRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AuthMethodPriority != nil {
+		in, out := &in.AuthMethodPriority, &out.AuthMethodPriority
+		*out = make([]VaultAuthMethodName, len(*in))
+		copy(*out, *in)
+	}
+	if in.CredentialsGracePeriod != nil {
+		in, out := &in.CredentialsGracePeriod, &out.CredentialsGracePeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RequiredCapabilities != nil {
+		in, out := &in.RequiredCapabilities, &out.RequiredCapabilities
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.DeniedPolicies != nil {
+		in, out := &in.DeniedPolicies, &out.DeniedPolicies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PolicySource != nil {
+		in, out := &in.PolicySource, &out.PolicySource
+		*out = new(VaultPolicySource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LoginWarnings != nil {
+		in, out := &in.LoginWarnings, &out.LoginWarnings
+		*out = new(VaultLoginWarnings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StatusMapping != nil {
+		in, out := &in.StatusMapping, &out.StatusMapping
+		*out = make([]VaultAuthStatusMapping, len(*in))
+		copy(*out, *in)
+	}
+	if in.LoginRetry != nil {
+		in, out := &in.LoginRetry, &out.LoginRetry
+		*out = new(VaultLoginRetry)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LoginTimeout != nil {
+		in, out := &in.LoginTimeout, &out.LoginTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.AuthOverride != nil {
+		in, out := &in.AuthOverride, &out.AuthOverride
+		*out = new(VaultAuthOverride)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultAuth.
@@ -3542,6 +3673,61 @@ func (in *VaultAuth) DeepCopy() *VaultAuth {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultAuthOverride) DeepCopyInto(out *VaultAuthOverride) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultAuthOverride.
+func (in *VaultAuthOverride) DeepCopy() *VaultAuthOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultAuthOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultAuthSelectionRule) DeepCopyInto(out *VaultAuthSelectionRule) {
+	*out = *in
+	if in.EnvValue != nil {
+		in, out := &in.EnvValue, &out.EnvValue
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultAuthSelectionRule.
+func (in *VaultAuthSelectionRule) DeepCopy() *VaultAuthSelectionRule {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultAuthSelectionRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultAuthStatusMapping) DeepCopyInto(out *VaultAuthStatusMapping) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultAuthStatusMapping.
+func (in *VaultAuthStatusMapping) DeepCopy() *VaultAuthStatusMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultAuthStatusMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VaultAwsAuth) DeepCopyInto(out *VaultAwsAuth) {
 	*out = *in
@@ -3609,6 +3795,26 @@ func (in *VaultAwsJWTAuth) DeepCopy() *VaultAwsJWTAuth {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultAzureAuth) DeepCopyInto(out *VaultAzureAuth) {
+	*out = *in
+	if in.ServiceAccountRef != nil {
+		in, out := &in.ServiceAccountRef, &out.ServiceAccountRef
+		*out = new(apismetav1.ServiceAccountSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultAzureAuth.
+func (in *VaultAzureAuth) DeepCopy() *VaultAzureAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultAzureAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VaultCertAuth) DeepCopyInto(out *VaultCertAuth) {
 	*out = *in
@@ -3666,6 +3872,46 @@ func (in *VaultClientTLS) DeepCopy() *VaultClientTLS {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultConfigMapKeySelector) DeepCopyInto(out *VaultConfigMapKeySelector) {
+	*out = *in
+	if in.Namespace != nil {
+		in, out := &in.Namespace, &out.Namespace
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultConfigMapKeySelector.
+func (in *VaultConfigMapKeySelector) DeepCopy() *VaultConfigMapKeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultConfigMapKeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultGcpAuth) DeepCopyInto(out *VaultGcpAuth) {
+	*out = *in
+	if in.ServiceAccountRef != nil {
+		in, out := &in.ServiceAccountRef, &out.ServiceAccountRef
+		*out = new(apismetav1.ServiceAccountSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultGcpAuth.
+func (in *VaultGcpAuth) DeepCopy() *VaultGcpAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultGcpAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VaultIamAuth) DeepCopyInto(out *VaultIamAuth) {
 	*out = *in
@@ -3704,6 +3950,16 @@ func (in *VaultJwtAuth) DeepCopyInto(out *VaultJwtAuth) {
 		*out = new(VaultKubernetesServiceAccountTokenAuth)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.HTTPSource != nil {
+		in, out := &in.HTTPSource, &out.HTTPSource
+		*out = new(VaultJwtHTTPSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TokenExchange != nil {
+		in, out := &in.TokenExchange, &out.TokenExchange
+		*out = new(VaultJwtTokenExchange)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultJwtAuth.
@@ -3716,6 +3972,73 @@ func (in *VaultJwtAuth) DeepCopy() *VaultJwtAuth {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultJwtHTTPHeader) DeepCopyInto(out *VaultJwtHTTPHeader) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(apismetav1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultJwtHTTPHeader.
+func (in *VaultJwtHTTPHeader) DeepCopy() *VaultJwtHTTPHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultJwtHTTPHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultJwtHTTPSource) DeepCopyInto(out *VaultJwtHTTPSource) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]VaultJwtHTTPHeader, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultJwtHTTPSource.
+func (in *VaultJwtHTTPSource) DeepCopy() *VaultJwtHTTPSource {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultJwtHTTPSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultJwtTokenExchange) DeepCopyInto(out *VaultJwtTokenExchange) {
+	*out = *in
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultJwtTokenExchange.
+func (in *VaultJwtTokenExchange) DeepCopy() *VaultJwtTokenExchange {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultJwtTokenExchange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VaultKubernetesAuth) DeepCopyInto(out *VaultKubernetesAuth) {
 	*out = *in
@@ -3729,6 +4052,26 @@ func (in *VaultKubernetesAuth) DeepCopyInto(out *VaultKubernetesAuth) {
 		*out = new(apismetav1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Audiences != nil {
+		in, out := &in.Audiences, &out.Audiences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.KubernetesTokenRequest != nil {
+		in, out := &in.KubernetesTokenRequest, &out.KubernetesTokenRequest
+		*out = new(VaultKubernetesTokenRequest)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TokenRequestRetrySettings != nil {
+		in, out := &in.TokenRequestRetrySettings, &out.TokenRequestRetrySettings
+		*out = new(SecretStoreRetrySettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LoginRetrySettings != nil {
+		in, out := &in.LoginRetrySettings, &out.LoginRetrySettings
+		*out = new(SecretStoreRetrySettings)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultKubernetesAuth.
@@ -3741,6 +4084,21 @@ func (in *VaultKubernetesAuth) DeepCopy() *VaultKubernetesAuth {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultKubernetesBoundObjectRef) DeepCopyInto(out *VaultKubernetesBoundObjectRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultKubernetesBoundObjectRef.
+func (in *VaultKubernetesBoundObjectRef) DeepCopy() *VaultKubernetesBoundObjectRef {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultKubernetesBoundObjectRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VaultKubernetesServiceAccountTokenAuth) DeepCopyInto(out *VaultKubernetesServiceAccountTokenAuth) {
 	*out = *in
@@ -3759,6 +4117,11 @@ func (in *VaultKubernetesServiceAccountTokenAuth) DeepCopyInto(out *VaultKuberne
 		*out = new(int64)
 		**out = **in
 	}
+	if in.KubernetesTokenRequest != nil {
+		in, out := &in.KubernetesTokenRequest, &out.KubernetesTokenRequest
+		*out = new(VaultKubernetesTokenRequest)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultKubernetesServiceAccountTokenAuth.
@@ -3771,6 +4134,36 @@ func (in *VaultKubernetesServiceAccountTokenAuth) DeepCopy() *VaultKubernetesSer
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultKubernetesTokenRequest) DeepCopyInto(out *VaultKubernetesTokenRequest) {
+	*out = *in
+	if in.Audiences != nil {
+		in, out := &in.Audiences, &out.Audiences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExpirationSeconds != nil {
+		in, out := &in.ExpirationSeconds, &out.ExpirationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.BoundObjectRef != nil {
+		in, out := &in.BoundObjectRef, &out.BoundObjectRef
+		*out = new(VaultKubernetesBoundObjectRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultKubernetesTokenRequest.
+func (in *VaultKubernetesTokenRequest) DeepCopy() *VaultKubernetesTokenRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultKubernetesTokenRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VaultLdapAuth) DeepCopyInto(out *VaultLdapAuth) {
 	*out = *in
@@ -3787,6 +4180,111 @@ func (in *VaultLdapAuth) DeepCopy() *VaultLdapAuth {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultLoginRetry) DeepCopyInto(out *VaultLoginRetry) {
+	*out = *in
+	if in.InitialInterval != nil {
+		in, out := &in.InitialInterval, &out.InitialInterval
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultLoginRetry.
+func (in *VaultLoginRetry) DeepCopy() *VaultLoginRetry {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultLoginRetry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultLoginWarnings) DeepCopyInto(out *VaultLoginWarnings) {
+	*out = *in
+	if in.Escalate != nil {
+		in, out := &in.Escalate, &out.Escalate
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultLoginWarnings.
+func (in *VaultLoginWarnings) DeepCopy() *VaultLoginWarnings {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultLoginWarnings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultMountAuth) DeepCopyInto(out *VaultMountAuth) {
+	*out = *in
+	in.Auth.DeepCopyInto(&out.Auth)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultMountAuth.
+func (in *VaultMountAuth) DeepCopy() *VaultMountAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultMountAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultPluginAuth) DeepCopyInto(out *VaultPluginAuth) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SecretParameters != nil {
+		in, out := &in.SecretParameters, &out.SecretParameters
+		*out = make(map[string]apismetav1.SecretKeySelector, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultPluginAuth.
+func (in *VaultPluginAuth) DeepCopy() *VaultPluginAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultPluginAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultPolicySource) DeepCopyInto(out *VaultPolicySource) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(VaultConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultPolicySource.
+func (in *VaultPolicySource) DeepCopy() *VaultPolicySource {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultPolicySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VaultProvider) DeepCopyInto(out *VaultProvider) {
 	*out = *in
@@ -3795,6 +4293,18 @@ func (in *VaultProvider) DeepCopyInto(out *VaultProvider) {
 		*out = new(VaultAuth)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MountAuth != nil {
+		in, out := &in.MountAuth, &out.MountAuth
+		*out = make([]VaultMountAuth, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WriteAuth != nil {
+		in, out := &in.WriteAuth, &out.WriteAuth
+		*out = new(VaultAuth)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Path != nil {
 		in, out := &in.Path, &out.Path
 		*out = new(string)
@@ -3816,6 +4326,11 @@ func (in *VaultProvider) DeepCopyInto(out *VaultProvider) {
 		*out = new(CAProvider)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RevocationCheck != nil {
+		in, out := &in.RevocationCheck, &out.RevocationCheck
+		*out = new(VaultRevocationCheck)
+		**out = **in
+	}
 	if in.Headers != nil {
 		in, out := &in.Headers, &out.Headers
 		*out = make(map[string]string, len(*in))
@@ -3840,10 +4355,55 @@ func (in *VaultProvider) DeepCopy() *VaultProvider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultRevocationCheck) DeepCopyInto(out *VaultRevocationCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultRevocationCheck.
+func (in *VaultRevocationCheck) DeepCopy() *VaultRevocationCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultRevocationCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultTokenRole) DeepCopyInto(out *VaultTokenRole) {
+	*out = *in
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultTokenRole.
+func (in *VaultTokenRole) DeepCopy() *VaultTokenRole {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultTokenRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VaultUserPassAuth) DeepCopyInto(out *VaultUserPassAuth) {
 	*out = *in
 	in.SecretRef.DeepCopyInto(&out.SecretRef)
+	if in.JSONPaths != nil {
+		in, out := &in.JSONPaths, &out.JSONPaths
+		*out = new(VaultUserPassJSONPaths)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultUserPassAuth.
@@ -3856,6 +4416,21 @@ func (in *VaultUserPassAuth) DeepCopy() *VaultUserPassAuth {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultUserPassJSONPaths) DeepCopyInto(out *VaultUserPassJSONPaths) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultUserPassJSONPaths.
+func (in *VaultUserPassJSONPaths) DeepCopy() *VaultUserPassJSONPaths {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultUserPassJSONPaths)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WebhookCAProvider) DeepCopyInto(out *WebhookCAProvider) {
 	*out = *in
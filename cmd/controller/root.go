@@ -17,7 +17,9 @@ limitations under the License.
 package controller
 
 import (
+	"context"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -31,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
@@ -52,6 +55,7 @@ import (
 	"github.com/external-secrets/external-secrets/pkg/controllers/secretstore/cssmetrics"
 	"github.com/external-secrets/external-secrets/pkg/controllers/secretstore/ssmetrics"
 	"github.com/external-secrets/external-secrets/pkg/feature"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
 
 	// To allow using gcp auth.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -84,7 +88,9 @@ var (
 	enablePushSecretReconciler            bool
 	enableFloodGate                       bool
 	enableGeneratorState                  bool
+	enableAuthOverride                    bool
 	enableExtendedMetricLabels            bool
+	enableOpenMetrics                     bool
 	storeRequeueInterval                  time.Duration
 	serviceName, serviceNamespace         string
 	secretName, secretNamespace           string
@@ -138,11 +144,15 @@ var rootCmd = &cobra.Command{
 			clientCacheDisableFor = append(clientCacheDisableFor, &v1.ConfigMap{})
 		}
 
+		metricsOpts := server.Options{
+			BindAddress: metricsAddr,
+		}
+		if enableOpenMetrics {
+			metricsOpts.FilterProvider = metrics.WithOpenMetrics(metricsOpts.FilterProvider)
+		}
 		mgrOpts := ctrl.Options{
-			Scheme: scheme,
-			Metrics: server.Options{
-				BindAddress: metricsAddr,
-			},
+			Scheme:                 scheme,
+			Metrics:                metricsOpts,
 			HealthProbeBindAddress: liveAddr,
 			WebhookServer: webhook.NewServer(webhook.Options{
 				Port: 9443,
@@ -232,6 +242,7 @@ var rootCmd = &cobra.Command{
 			ClusterSecretStoreEnabled: enableClusterStoreReconciler,
 			EnableFloodGate:           enableFloodGate,
 			EnableGeneratorState:      enableGeneratorState,
+			EnableAuthOverride:        enableAuthOverride,
 		}).SetupWithManager(mgr, controller.Options{
 			MaxConcurrentReconciles: concurrent,
 			RateLimiter:             ctrlcommon.BuildRateLimiter(),
@@ -302,6 +313,26 @@ var rootCmd = &cobra.Command{
 			}
 			f.Initialize()
 		}
+		// runnables that don't opt out of leader election are only started
+		// once this replica has been elected leader.
+		if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			var wg sync.WaitGroup
+			for _, f := range fs {
+				if f.Elected == nil {
+					continue
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					f.Elected(ctx, mgr.GetClient(), mgr.GetConfig())
+				}()
+			}
+			wg.Wait()
+			return nil
+		})); err != nil {
+			setupLog.Error(err, "unable to add leader election hooks")
+			os.Exit(1)
+		}
 		setupLog.Info("starting manager")
 		if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 			setupLog.Error(err, "problem running manager")
@@ -337,7 +368,9 @@ func init() {
 	rootCmd.Flags().DurationVar(&storeRequeueInterval, "store-requeue-interval", time.Minute*5, "Default Time duration between reconciling (Cluster)SecretStores")
 	rootCmd.Flags().BoolVar(&enableFloodGate, "enable-flood-gate", true, "Enable flood gate. External secret will be reconciled only if the ClusterStore or Store have an healthy or unknown state.")
 	rootCmd.Flags().BoolVar(&enableGeneratorState, "enable-generator-state", true, "Whether the Controller should manage GeneratorState")
+	rootCmd.Flags().BoolVar(&enableAuthOverride, "enable-auth-override", false, "Allow ExternalSecrets to override the auth of stores that opt in with the external-secrets.io/auth-override-secret annotation.")
 	rootCmd.Flags().BoolVar(&enableExtendedMetricLabels, "enable-extended-metric-labels", false, "Enable recommended kubernetes annotations as labels in metrics.")
+	rootCmd.Flags().BoolVar(&enableOpenMetrics, "enable-openmetrics", false, "Serve metrics in the OpenMetrics format to scrapers accepting it, which exposes exemplars linking auth metrics to traces.")
 	fs := feature.Features()
 	for _, f := range fs {
 		rootCmd.Flags().AddFlagSet(f.Flags)
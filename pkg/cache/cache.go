@@ -93,6 +93,11 @@ func (c *Cache[T]) Add(version string, key Key, client T) {
 	c.lru.Add(key, value[T]{Version: version, Client: client})
 }
 
+// Purge removes all values, calling the cleanup func for each of them.
+func (c *Cache[T]) Purge() {
+	c.lru.Purge()
+}
+
 // Contains returns true if a value with the given key exists.
 func (c *Cache[T]) Contains(key Key) bool {
 	return c.lru.Contains(key)
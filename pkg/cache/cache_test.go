@@ -98,3 +98,21 @@ func TestCacheEvict(t *testing.T) {
 	c.Add("", Key{Name: "bar"}, client{})
 	assert.True(t, cleanupCalled)
 }
+
+func TestCachePurge(t *testing.T) {
+	var cleanups int
+	c, err := New(2, func(client client) {
+		cleanups++
+	})
+	if err != nil {
+		t.Fail()
+	}
+
+	c.Add("", Key{Name: "foo"}, client{})
+	c.Add("", Key{Name: "bar"}, client{})
+	c.Purge()
+
+	assert.Equal(t, 2, cleanups)
+	assert.False(t, c.Contains(Key{Name: "foo"}))
+	assert.False(t, c.Contains(Key{Name: "bar"}))
+}
@@ -59,14 +59,32 @@ const (
 	CallGCPSMGenerateIDBindToken = "GenerateIDBindToken"
 	CallGCPSMGenerateAccessToken = "GenerateAccessToken"
 
-	ProviderHCVault            = "HashiCorp/Vault"
-	CallHCVaultLogin           = "Login"
-	CallHCVaultRevokeSelf      = "RevokeSelf"
-	CallHCVaultLookupSelf      = "LookupSelf"
-	CallHCVaultReadSecretData  = "ReadSecretData"
-	CallHCVaultWriteSecretData = "WriteSecretData"
-	CallHCVaultDeleteSecret    = "DeleteSecret"
-	CallHCVaultListSecrets     = "ListSecrets"
+	ProviderHCVault             = "HashiCorp/Vault"
+	CallHCVaultLogin            = "Login"
+	CallHCVaultRevokeSelf       = "RevokeSelf"
+	CallHCVaultRevokeTree       = "RevokeTree"
+	CallHCVaultRevokeOrphan     = "RevokeOrphan"
+	CallHCVaultLookupSelf       = "LookupSelf"
+	CallHCVaultRenewSelf        = "RenewSelf"
+	CallHCVaultHealth           = "Health"
+	CallHCVaultPing             = "Ping"
+	CallHCVaultUnwrap           = "Unwrap"
+	CallHCVaultUnwrapToken      = "UnwrapToken"
+	CallHCVaultCreateRoleToken  = "CreateRoleToken"
+	CallHCVaultReadAuthRole     = "ReadAuthRole"
+	CallHCVaultReadCanary       = "ReadCanary"
+	CallHCVaultCapabilitiesSelf = "CapabilitiesSelf"
+	CallHCVaultListAuthMounts   = "ListAuthMounts"
+	CallHCVaultTokenExchange    = "TokenExchange"
+	CallHCVaultFetchJwt         = "FetchJwt"
+	CallHCVaultFetchAzureToken  = "FetchAzureToken"
+	CallHCVaultSignGcpJwt       = "SignGcpJwt"
+	CallHCVaultQueryOCSP        = "QueryOCSP"
+	CallHCVaultFetchCRL         = "FetchCRL"
+	CallHCVaultReadSecretData   = "ReadSecretData"
+	CallHCVaultWriteSecretData  = "WriteSecretData"
+	CallHCVaultDeleteSecret     = "DeleteSecret"
+	CallHCVaultListSecrets      = "ListSecrets"
 
 	ProviderKubernetes                         = "Kubernetes"
 	CallKubernetesGetSecret                    = "GetSecret"
@@ -106,6 +106,7 @@ const (
 	errUpdateNotFound        = "unable to update secret %s: not found"
 	errDeleteCreatePolicy    = "unable to delete secret %s: creationPolicy=%s is not Owner"
 	errSecretCachesNotSynced = "controller caches for secret %s are not in sync"
+	errAuthOverrideDisabled  = "auth overrides are disabled, the controller must be started with --enable-auth-override to use the %s annotation"
 
 	// event messages.
 	eventCreated                  = "secret created"
@@ -138,6 +139,7 @@ type Reconciler struct {
 	ClusterSecretStoreEnabled bool
 	EnableFloodGate           bool
 	EnableGeneratorState      bool
+	EnableAuthOverride        bool
 	recorder                  record.EventRecorder
 }
 
@@ -147,6 +149,15 @@ type Reconciler struct {
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	log := r.Log.WithValues("ExternalSecret", req.NamespacedName)
 
+	// providers may ask for an earlier reconcile, e.g. to re-authenticate
+	// before their credentials expire rather than inline.
+	ctx, requeueHint := esv1.ContextWithRequeueHint(ctx)
+	defer func() {
+		if err == nil {
+			result = requeueAfterHint(result, requeueHint)
+		}
+	}()
+
 	resourceLabels := ctrlmetrics.RefineNonConditionMetricLabels(map[string]string{"name": req.Name, "namespace": req.Namespace})
 	start := time.Now()
 
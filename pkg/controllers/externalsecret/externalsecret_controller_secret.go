@@ -26,6 +26,7 @@ import (
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
 	genv1alpha1 "github.com/external-secrets/external-secrets/apis/generators/v1alpha1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
 	"github.com/external-secrets/external-secrets/pkg/controllers/secretstore"
 	"github.com/external-secrets/external-secrets/pkg/generator/statemanager"
 	"github.com/external-secrets/external-secrets/pkg/utils"
@@ -47,6 +48,19 @@ func (r *Reconciler) GetProviderSecretData(ctx context.Context, externalSecret *
 		_ = mgr.Close(ctx)
 	}()
 
+	// the clients are only used for this ExternalSecret, so an auth override
+	// doesn't affect other ExternalSecrets using the same store.
+	if name := externalSecret.Annotations[esv1.AnnotationAuthOverrideSecret]; name != "" {
+		if !r.EnableAuthOverride {
+			return nil, fmt.Errorf(errAuthOverrideDisabled, esv1.AnnotationAuthOverrideSecret)
+		}
+		r.Log.Info("using auth override secret", "namespace", externalSecret.Namespace, "name", externalSecret.Name, "secret", name)
+		ctx = esv1.ContextWithAuthOverride(ctx, esmeta.SecretKeySelector{
+			Name: name,
+			Key:  esv1.AuthOverrideSecretKey,
+		})
+	}
+
 	// statemanager takes care of managing the state of the generators.
 	// Since ExternalSecrets can have multiple generators, we need to keep track of the state of each generator
 	// and if one fails we need to rollback all generated values from this iteration.
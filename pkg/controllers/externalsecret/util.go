@@ -20,6 +20,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
 	"github.com/external-secrets/external-secrets/pkg/controllers/externalsecret/esmetrics"
@@ -83,6 +84,17 @@ func filterOutCondition(conditions []esv1.ExternalSecretStatusCondition, condTyp
 	return newConditions
 }
 
+// requeueAfterHint schedules the reconcile requested by the providers with the
+// hint, unless the result already requeues sooner. Results without a requeue,
+// e.g. of ExternalSecrets without a refresh interval, are requeued as well.
+func requeueAfterHint(result ctrl.Result, hint *esv1.RequeueHint) ctrl.Result {
+	after, ok := hint.After()
+	if ok && (result.RequeueAfter == 0 || result.RequeueAfter > after) {
+		result.RequeueAfter = after
+	}
+	return result
+}
+
 func fqdnFor(name string) string {
 	fqdn := fmt.Sprintf(fieldOwnerTemplate, name)
 	// If secret name is just too big, use the SHA3 hash of the secret name
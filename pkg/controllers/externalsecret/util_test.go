@@ -15,12 +15,14 @@ limitations under the License.
 package externalsecret
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
 )
@@ -223,3 +225,47 @@ func TestSetExternalSecretCondition(t *testing.T) {
 		})
 	}
 }
+
+func TestRequeueAfterHint(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   ctrl.Result
+		hint     time.Duration
+		expected ctrl.Result
+	}{
+		{
+			name:     "Hint before the refresh",
+			result:   ctrl.Result{RequeueAfter: time.Hour},
+			hint:     time.Minute,
+			expected: ctrl.Result{RequeueAfter: time.Minute},
+		},
+		{
+			name:     "Hint after the refresh",
+			result:   ctrl.Result{RequeueAfter: time.Minute},
+			hint:     time.Hour,
+			expected: ctrl.Result{RequeueAfter: time.Minute},
+		},
+		{
+			name:     "Hint without a refresh",
+			result:   ctrl.Result{},
+			hint:     time.Minute,
+			expected: ctrl.Result{RequeueAfter: time.Minute},
+		},
+		{
+			name:     "No hint",
+			result:   ctrl.Result{},
+			expected: ctrl.Result{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, hint := esv1.ContextWithRequeueHint(context.Background())
+			esv1.RequestRequeue(ctx, tt.hint)
+
+			if diff := cmp.Diff(requeueAfterHint(tt.result, hint), tt.expected); diff != "" {
+				t.Errorf("(-got, +want)\n%s", diff)
+			}
+		})
+	}
+}
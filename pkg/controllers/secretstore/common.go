@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -39,6 +40,7 @@ const (
 	errUnableValidateStore = "unable to validate store: %s"
 
 	msgStoreValidated     = "store validated"
+	msgStorePending       = "store is pending: %s"
 	msgStoreNotMaintained = "store isn't currently maintained. Please plan and prepare accordingly."
 )
 
@@ -76,6 +78,13 @@ func reconcile(ctx context.Context, req ctrl.Request, ss esapi.GenericStore, cl
 	// we have to patch the status
 	log.V(1).Info("validating")
 	err := validateStore(ctx, req.Namespace, opts.ControllerClass, ss, cl, opts.GaugeVecGetter, opts.Recorder)
+	// a pending store is checked again soon, without the backoff of a
+	// failed reconcile.
+	var pending *esapi.PendingError
+	if errors.As(err, &pending) {
+		log.V(1).Info("store is pending", "reason", pending.Error(), "requeueAfter", pending.RequeueAfter)
+		return ctrl.Result{RequeueAfter: pending.RequeueAfter}, nil
+	}
 	if err != nil {
 		log.Error(err, "unable to validate store")
 		// in case of validation status unknown, validateStore will mark
@@ -104,6 +113,7 @@ func reconcile(ctx context.Context, req ctrl.Request, ss esapi.GenericStore, cl
 	capStatus := esapi.SecretStoreStatus{
 		Capabilities: storeProvider.Capabilities(),
 		Conditions:   ss.GetStatus().Conditions,
+		AuthMethod:   ss.GetStatus().AuthMethod,
 	}
 	ss.SetStatus(capStatus)
 
@@ -125,6 +135,13 @@ func validateStore(ctx context.Context, namespace, controllerClass string, store
 		_ = mgr.Close(ctx)
 	}()
 	cl, err := mgr.GetFromStore(ctx, store, namespace)
+	var pending *esapi.PendingError
+	if errors.As(err, &pending) {
+		cond := NewSecretStoreCondition(esapi.SecretStoreReady, v1.ConditionFalse, esapi.ReasonPending, fmt.Sprintf(msgStorePending, pending.Error()))
+		SetExternalSecretCondition(store, *cond, gaugeVecGetter)
+		recorder.Event(store, v1.EventTypeNormal, esapi.ReasonPending, pending.Error())
+		return err
+	}
 	if err != nil {
 		cond := NewSecretStoreCondition(esapi.SecretStoreReady, v1.ConditionFalse, esapi.ReasonInvalidProviderConfig, errUnableCreateClient)
 		SetExternalSecretCondition(store, *cond, gaugeVecGetter)
@@ -144,10 +161,41 @@ func validateStore(ctx context.Context, namespace, controllerClass string, store
 		recorder.Event(store, v1.EventTypeWarning, esapi.ReasonInvalidProviderConfig, err.Error())
 		return fmt.Errorf(errValidationFailed, err)
 	}
+	if reporter, ok := cl.(esapi.WarningReporter); ok {
+		setWarningsCondition(store, reporter.Warnings(), gaugeVecGetter)
+	}
+	if reporter, ok := cl.(esapi.AuthMethodReporter); ok {
+		setAuthMethod(store, reporter.AuthMethod())
+	}
 
 	return nil
 }
 
+// setWarningsCondition reports the warnings of a provider in the store
+// conditions, or drops the condition if there are none.
+func setWarningsCondition(store esapi.GenericStore, warnings []string, gaugeVecGetter metrics.GaugeVevGetter) {
+	if len(warnings) == 0 {
+		status := store.GetStatus()
+		status.Conditions = filterOutCondition(status.Conditions, esapi.SecretStoreWarnings)
+		store.SetStatus(status)
+		return
+	}
+	cond := NewSecretStoreCondition(esapi.SecretStoreWarnings, v1.ConditionTrue, esapi.ReasonProviderWarnings, strings.Join(warnings, "; "))
+	SetExternalSecretCondition(store, *cond, gaugeVecGetter)
+}
+
+// setAuthMethod records the auth method the provider logged in with in the
+// store status. Clients re-using a token don't know the method, so the
+// last reported method is kept.
+func setAuthMethod(store esapi.GenericStore, method string) {
+	if method == "" {
+		return
+	}
+	status := store.GetStatus()
+	status.AuthMethod = method
+	store.SetStatus(status)
+}
+
 // ShouldProcessStore returns true if the store should be processed.
 func ShouldProcessStore(store esapi.GenericStore, class string) bool {
 	if store == nil || store.GetSpec().Controller == "" || store.GetSpec().Controller == class {
@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 
 	esapi "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -195,6 +196,49 @@ var _ = Describe("SecretStore reconcile", func() {
 		}
 	}
 
+	// a credential secret that doesn't exist yet within the grace period
+	// of the auth should be reflected as pending in the store status condition
+	pendingCredentials := func(tc *testCase) {
+		spc := tc.store.GetSpec()
+		spc.Provider.Vault.Server = "https://vault.example.com"
+		spc.Provider.Vault.Auth = &esapi.VaultAuth{
+			CredentialsGracePeriod: &metav1.Duration{Duration: time.Hour},
+			AppRole: &esapi.VaultAppRole{
+				Path:   "approle",
+				RoleID: "role-id",
+				SecretRef: esmeta.SecretKeySelector{
+					Name: "pending-approle-secret",
+					Key:  "secret-id",
+				},
+			},
+		}
+
+		tc.assert = func() {
+			Eventually(func() bool {
+				ss := tc.store.Copy()
+				err := k8sClient.Get(context.Background(), types.NamespacedName{
+					Name:      defaultStoreName,
+					Namespace: ss.GetNamespace(),
+				}, ss)
+				if err != nil {
+					return false
+				}
+
+				if len(ss.GetStatus().Conditions) != 1 {
+					return false
+				}
+
+				return ss.GetStatus().Conditions[0].Reason == esapi.ReasonPending &&
+					ss.GetStatus().Conditions[0].Type == esapi.SecretStoreReady &&
+					ss.GetStatus().Conditions[0].Status == corev1.ConditionFalse &&
+					hasEvent(tc.store.GetTypeMeta().Kind, ss.GetName(), esapi.ReasonPending)
+			}).
+				WithTimeout(time.Second * 10).
+				WithPolling(time.Second).
+				Should(BeTrue())
+		}
+	}
+
 	DescribeTable("Controller Reconcile logic", func(muts ...func(tc *testCase)) {
 		for _, mut := range muts {
 			mut(test)
@@ -209,6 +253,7 @@ var _ = Describe("SecretStore reconcile", func() {
 		Entry("[namespace] valid provider has status=ready", validProvider),
 		Entry("[namespace] valid provider has capabilities=ReadWrite", readWrite),
 		Entry("[namespace] validation unknown status should set ValidationUnknown condition", validationUnknown),
+		Entry("[namespace] missing credentials within the grace period should set Pending condition", pendingCredentials),
 
 		// cluster store
 		Entry("[cluster] invalid provider with secretStore should set InvalidStore condition", invalidProvider, useClusterStore),
@@ -15,15 +15,24 @@ limitations under the License.
 package feature
 
 import (
+	"context"
+
 	"github.com/spf13/pflag"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // Feature contains the CLI flags that a provider exposes to a user.
 // A optional Initialize func is called once the flags have been parsed.
 // A provider can use this to do late-initialization using the defined cli args.
+// A optional Elected func is called with the client and config of the
+// manager once the controller has been elected leader, or on start if leader
+// election is disabled. It may block until the context is done, the manager
+// waits for it to return on shutdown.
 type Feature struct {
 	Flags      *pflag.FlagSet
 	Initialize func()
+	Elected    func(ctx context.Context, kube client.Client, cfg *rest.Config)
 }
 
 var features = make([]Feature, 0)
@@ -94,7 +94,7 @@ spec:
 				kube: clientfake.NewClientBuilder().Build(),
 			},
 			want: want{
-				err: errors.New("unable to setup Vault client: no role name was provided"),
+				err: errors.New("unable to setup Vault client: kubernetes auth matched but login failed: no role name was provided"),
 			},
 		},
 		"EmptyVaultResponse": {
@@ -0,0 +1,225 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/external-secrets/external-secrets/pkg/feature"
+)
+
+const (
+	providerAuthLoginDuration = "provider_auth_login_duration_seconds"
+	providerAuthTokenReuse    = "provider_auth_token_reuse_count"
+	providerAuthTokenTTL      = "provider_auth_token_ttl_seconds"
+	providerAuthFallback      = "provider_auth_fallback_count"
+	providerAuthFailure       = "provider_auth_failure_count"
+	providerAuthMethod        = "provider_auth_method_count"
+	providerAuthDeniedPolicy  = "provider_auth_denied_policy_count"
+)
+
+// Reasons of failed logins reported by ObserveAuthFailure.
+const (
+	AuthFailureSealed            = "sealed"
+	AuthFailurePermissionDenied  = "permission_denied"
+	AuthFailureNetwork           = "network"
+	AuthFailureNamespaceNotFound = "namespace_not_found"
+	AuthFailureCredentialMissing = "credential_missing"
+	AuthFailureRoleNotFound      = "role_not_found"
+	AuthFailureOther             = "other"
+)
+
+// authFailureReasons bounds the values of the reason label.
+var authFailureReasons = map[string]bool{
+	AuthFailureSealed:            true,
+	AuthFailurePermissionDenied:  true,
+	AuthFailureNetwork:           true,
+	AuthFailureNamespaceNotFound: true,
+	AuthFailureCredentialMissing: true,
+	AuthFailureRoleNotFound:      true,
+	AuthFailureOther:             true,
+}
+
+var (
+	authMetricsNamespace string
+
+	// The auth metrics are created once the flags have been parsed,
+	// so that their names can carry the configured namespace.
+	authLoginDuration *prometheus.HistogramVec
+	authTokenReuse    *prometheus.CounterVec
+	authTokenTTL      *prometheus.GaugeVec
+	authFallback      *prometheus.CounterVec
+	authFailure       *prometheus.CounterVec
+	authMethod        *prometheus.CounterVec
+	authDeniedPolicy  *prometheus.CounterVec
+)
+
+// ObserveAuthLogin records the duration and outcome of a login
+// performed with the given auth method. If the context carries a sampled
+// tracing span, the observation links to it with an exemplar.
+func ObserveAuthLogin(ctx context.Context, provider, method string, duration time.Duration, err error) {
+	if authLoginDuration == nil {
+		return
+	}
+	observer := authLoginDuration.WithLabelValues(provider, method, deriveStatus(err))
+	if exemplar := traceExemplar(ctx); exemplar != nil {
+		observer.(prometheus.ExemplarObserver).ObserveWithExemplar(duration.Seconds(), exemplar)
+		return
+	}
+	observer.Observe(duration.Seconds())
+}
+
+// traceExemplar returns the exemplar labels of the span in the context, or
+// nil if there is none or it isn't sampled, as its trace is then likely
+// not stored.
+func traceExemplar(ctx context.Context) prometheus.Labels {
+	span := trace.SpanContextFromContext(ctx)
+	if !span.IsValid() || !span.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{
+		"trace_id": span.TraceID().String(),
+		"span_id":  span.SpanID().String(),
+	}
+}
+
+// ObserveAuthTokenReuse records that an existing token was reused instead of logging in again.
+func ObserveAuthTokenReuse(provider string) {
+	if authTokenReuse == nil {
+		return
+	}
+	authTokenReuse.WithLabelValues(provider).Inc()
+}
+
+// ObserveAuthTokenTTL records the remaining TTL of the most recently validated token.
+func ObserveAuthTokenTTL(provider string, ttl time.Duration) {
+	if authTokenTTL == nil {
+		return
+	}
+	authTokenTTL.WithLabelValues(provider).Set(ttl.Seconds())
+}
+
+// ObserveAuthFallback records that a login succeeded with the fallback
+// method after the primary method failed.
+func ObserveAuthFallback(provider, primary, fallback string) {
+	if authFallback == nil {
+		return
+	}
+	authFallback.WithLabelValues(provider, primary, fallback).Inc()
+}
+
+// ObserveAuthFailure records a failed login for the given reason. Reasons
+// other than the AuthFailure constants are recorded as AuthFailureOther.
+func ObserveAuthFailure(provider, reason string) {
+	if authFailure == nil {
+		return
+	}
+	if !authFailureReasons[reason] {
+		reason = AuthFailureOther
+	}
+	authFailure.WithLabelValues(provider, reason).Inc()
+}
+
+// ObserveAuthMethod records a successful auth of a store with the given
+// auth method. The namespace is empty for cluster-scoped stores.
+func ObserveAuthMethod(provider, method, storeKind, storeName, storeNamespace string) {
+	if authMethod == nil {
+		return
+	}
+	authMethod.WithLabelValues(provider, method, storeKind, storeName, storeNamespace).Inc()
+}
+
+// ObserveAuthDeniedPolicy records a token issued by a login that carries a
+// policy denied by the store.
+func ObserveAuthDeniedPolicy(provider, policy string) {
+	if authDeniedPolicy == nil {
+		return
+	}
+	authDeniedPolicy.WithLabelValues(provider, policy).Inc()
+}
+
+// SetUpAuthMetrics creates the provider auth metrics using the given
+// metric namespace as prefix and registers them.
+func SetUpAuthMetrics(namespace string) {
+	metrics.Registry.MustRegister(newAuthMetrics(namespace)...)
+}
+
+func newAuthMetrics(namespace string) []prometheus.Collector {
+	authLoginDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: ExternalSecretSubsystem,
+		Name:      providerAuthLoginDuration,
+		Help:      "Duration of logins towards the secret provider",
+	}, []string{"provider", "method", "status"})
+
+	authTokenReuse = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: ExternalSecretSubsystem,
+		Name:      providerAuthTokenReuse,
+		Help:      "Number of times an existing token was reused instead of logging in again",
+	}, []string{"provider"})
+
+	authTokenTTL = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: ExternalSecretSubsystem,
+		Name:      providerAuthTokenTTL,
+		Help:      "Remaining TTL of the most recently validated token",
+	}, []string{"provider"})
+
+	authFallback = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: ExternalSecretSubsystem,
+		Name:      providerAuthFallback,
+		Help:      "Number of logins that succeeded with a fallback method after the primary method failed",
+	}, []string{"provider", "primary", "fallback"})
+
+	authFailure = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: ExternalSecretSubsystem,
+		Name:      providerAuthFailure,
+		Help:      "Number of failed logins towards the secret provider by reason",
+	}, []string{"provider", "reason"})
+
+	authMethod = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: ExternalSecretSubsystem,
+		Name:      providerAuthMethod,
+		Help:      "Number of successful auths towards the secret provider by auth method and store",
+	}, []string{"provider", "method", "store_kind", "store_name", "store_namespace"})
+
+	authDeniedPolicy = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: ExternalSecretSubsystem,
+		Name:      providerAuthDeniedPolicy,
+		Help:      "Number of tokens issued by logins that carry a policy denied by the store",
+	}, []string{"provider", "policy"})
+
+	return []prometheus.Collector{authLoginDuration, authTokenReuse, authTokenTTL, authFallback, authFailure, authMethod, authDeniedPolicy}
+}
+
+func init() {
+	fs := pflag.NewFlagSet("metrics", pflag.ExitOnError)
+	fs.StringVar(&authMetricsNamespace, "auth-metrics-namespace", "", "Namespace used as prefix for the provider auth metrics, e.g. to match existing dashboards. The metric labels are not affected.")
+	feature.Register(feature.Feature{
+		Flags:      fs,
+		Initialize: func() { SetUpAuthMetrics(authMetricsNamespace) },
+	})
+}
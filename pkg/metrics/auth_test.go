@@ -0,0 +1,218 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAuthMetricsNamespace(t *testing.T) {
+	cases := map[string]struct {
+		namespace string
+		wantNames []string
+	}{
+		"NoNamespace": {
+			wantNames: []string{
+				"externalsecret_provider_auth_denied_policy_count",
+				"externalsecret_provider_auth_failure_count",
+				"externalsecret_provider_auth_fallback_count",
+				"externalsecret_provider_auth_login_duration_seconds",
+				"externalsecret_provider_auth_method_count",
+				"externalsecret_provider_auth_token_reuse_count",
+				"externalsecret_provider_auth_token_ttl_seconds",
+			},
+		},
+		"CustomNamespace": {
+			namespace: "team",
+			wantNames: []string{
+				"team_externalsecret_provider_auth_denied_policy_count",
+				"team_externalsecret_provider_auth_failure_count",
+				"team_externalsecret_provider_auth_fallback_count",
+				"team_externalsecret_provider_auth_login_duration_seconds",
+				"team_externalsecret_provider_auth_method_count",
+				"team_externalsecret_provider_auth_token_reuse_count",
+				"team_externalsecret_provider_auth_token_ttl_seconds",
+			},
+		},
+	}
+
+	wantLabels := map[string][]string{
+		providerAuthLoginDuration: {"method", "provider", "status"},
+		providerAuthTokenReuse:    {"provider"},
+		providerAuthTokenTTL:      {"provider"},
+		providerAuthFallback:      {"fallback", "primary", "provider"},
+		providerAuthFailure:       {"provider", "reason"},
+		providerAuthMethod:        {"method", "provider", "store_kind", "store_name", "store_namespace"},
+		providerAuthDeniedPolicy:  {"policy", "provider"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(newAuthMetrics(tc.namespace)...)
+
+			ObserveAuthLogin(context.Background(), "provider", "method", time.Second, errors.New("boom"))
+			ObserveAuthTokenReuse("provider")
+			ObserveAuthTokenTTL("provider", time.Minute)
+			ObserveAuthFallback("provider", "primary", "fallback")
+			ObserveAuthFailure("provider", AuthFailureSealed)
+			ObserveAuthMethod("provider", "method", "SecretStore", "store", "default")
+			ObserveAuthDeniedPolicy("provider", "root")
+
+			families, err := reg.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotNames := make([]string, 0, len(families))
+			for _, f := range families {
+				gotNames = append(gotNames, f.GetName())
+
+				gotLabels := make([]string, 0)
+				for _, l := range f.GetMetric()[0].GetLabel() {
+					gotLabels = append(gotLabels, l.GetName())
+				}
+				sort.Strings(gotLabels)
+				var metricName string
+				for n := range wantLabels {
+					if strings.HasSuffix(f.GetName(), n) {
+						metricName = n
+					}
+				}
+				if diff := cmp.Diff(wantLabels[metricName], gotLabels); diff != "" {
+					t.Errorf("unexpected labels for %s: -want +got:\n%s", f.GetName(), diff)
+				}
+			}
+			if diff := cmp.Diff(tc.wantNames, gotNames); diff != "" {
+				t.Errorf("unexpected metric names: -want +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestObserveAuthLoginExemplar(t *testing.T) {
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	spanID := trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+
+	cases := map[string]struct {
+		flags trace.TraceFlags
+		// noSpan leaves the context without a span.
+		noSpan       bool
+		wantExemplar map[string]string
+	}{
+		"SampledSpan": {
+			flags: trace.FlagsSampled,
+			wantExemplar: map[string]string{
+				"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+				"span_id":  "00f067aa0ba902b7",
+			},
+		},
+		"UnsampledSpan": {},
+		"NoSpan": {
+			noSpan: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			newAuthMetrics("")
+			ctx := context.Background()
+			if !tc.noSpan {
+				ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID:    traceID,
+					SpanID:     spanID,
+					TraceFlags: tc.flags,
+				}))
+			}
+
+			ObserveAuthLogin(ctx, "vault", "kubernetes", 300*time.Millisecond, nil)
+
+			m := &dto.Metric{}
+			if err := authLoginDuration.WithLabelValues("vault", "kubernetes", "success").(prometheus.Metric).Write(m); err != nil {
+				t.Fatal(err)
+			}
+			if got := m.GetHistogram().GetSampleCount(); got != 1 {
+				t.Fatalf("expected 1 login, got %d", got)
+			}
+			var gotExemplar map[string]string
+			for _, b := range m.GetHistogram().GetBucket() {
+				if e := b.GetExemplar(); e != nil {
+					gotExemplar = map[string]string{}
+					for _, l := range e.GetLabel() {
+						gotExemplar[l.GetName()] = l.GetValue()
+					}
+					if e.GetValue() != 0.3 {
+						t.Errorf("expected exemplar value 0.3, got %v", e.GetValue())
+					}
+				}
+			}
+			if diff := cmp.Diff(tc.wantExemplar, gotExemplar); diff != "" {
+				t.Errorf("unexpected exemplar: -want +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestObserveAuthFallback(t *testing.T) {
+	newAuthMetrics("")
+
+	ObserveAuthFallback("vault", "kubernetes", "approle")
+	ObserveAuthFallback("vault", "kubernetes", "approle")
+	ObserveAuthFallback("vault", "iam", "jwt")
+
+	if got := testutil.ToFloat64(authFallback.WithLabelValues("vault", "kubernetes", "approle")); got != 2 {
+		t.Errorf("expected 2 fallbacks from kubernetes to approle, got %v", got)
+	}
+	if got := testutil.ToFloat64(authFallback.WithLabelValues("vault", "iam", "jwt")); got != 1 {
+		t.Errorf("expected 1 fallback from iam to jwt, got %v", got)
+	}
+}
+
+func TestObserveAuthFailure(t *testing.T) {
+	newAuthMetrics("")
+
+	ObserveAuthFailure("vault", AuthFailureSealed)
+	ObserveAuthFailure("vault", AuthFailurePermissionDenied)
+	ObserveAuthFailure("vault", AuthFailurePermissionDenied)
+	ObserveAuthFailure("vault", "some unknown reason")
+
+	want := map[string]float64{
+		AuthFailureSealed:            1,
+		AuthFailurePermissionDenied:  2,
+		AuthFailureNetwork:           0,
+		AuthFailureNamespaceNotFound: 0,
+		AuthFailureCredentialMissing: 0,
+		AuthFailureOther:             1,
+	}
+	for reason, count := range want {
+		if got := testutil.ToFloat64(authFailure.WithLabelValues("vault", reason)); got != count {
+			t.Errorf("expected %v failures for reason %q, got %v", count, reason, got)
+		}
+	}
+	// unknown reasons don't create new label values.
+	if got := testutil.CollectAndCount(authFailure); got != len(want) {
+		t.Errorf("expected %d series, got %d", len(want), got)
+	}
+}
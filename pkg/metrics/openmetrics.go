@@ -0,0 +1,70 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// metricsPath is the path the metrics server serves the metrics on.
+const metricsPath = "/metrics"
+
+// FilterProvider provides the filter of the metrics server, see
+// server.Options.
+type FilterProvider func(c *rest.Config, httpClient *http.Client) (server.Filter, error)
+
+// WithOpenMetrics returns a filter provider for the metrics server that
+// serves the metrics in the OpenMetrics format to scrapers accepting it, as
+// exemplars are only exposed in that format. Only the metrics endpoint is
+// served differently, the filter of the given provider, if any, still wraps
+// it and the extra handlers, e.g. the authentication and authorization of
+// secure serving.
+func WithOpenMetrics(provider FilterProvider) FilterProvider {
+	return func(c *rest.Config, httpClient *http.Client) (server.Filter, error) {
+		var next server.Filter
+		if provider != nil {
+			var err error
+			next, err = provider(c, httpClient)
+			if err != nil {
+				return nil, err
+			}
+		}
+		openMetrics := promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+			ErrorHandling:     promhttp.HTTPErrorOnError,
+			EnableOpenMetrics: true,
+		})
+		return func(log logr.Logger, handler http.Handler) (http.Handler, error) {
+			// the filter wraps the extra handlers as well, which are never
+			// registered for the metrics path.
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == metricsPath {
+					openMetrics.ServeHTTP(w, r)
+					return
+				}
+				handler.ServeHTTP(w, r)
+			})
+			if next == nil {
+				return h, nil
+			}
+			return next(log, h)
+		}, nil
+	}
+}
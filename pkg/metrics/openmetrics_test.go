@@ -0,0 +1,161 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// bearerAuth stands in for the authentication and authorization filter of
+// secure serving.
+func bearerAuth(_ *rest.Config, _ *http.Client) (server.Filter, error) {
+	return func(_ logr.Logger, handler http.Handler) (http.Handler, error) {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer scraper" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		}), nil
+	}, nil
+}
+
+func TestWithOpenMetricsSecureServing(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	srv, err := server.NewServer(server.Options{
+		SecureServing:  true,
+		BindAddress:    addr,
+		CertDir:        t.TempDir(),
+		FilterProvider: WithOpenMetrics(bearerAuth),
+		ExtraHandlers: map[string]http.Handler{
+			"/debug": http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("debug"))
+			}),
+		},
+	}, &rest.Config{}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	// the server serves a self-signed certificate.
+	httpClient := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}, //nolint:gosec // test server
+	}
+	get := func(path, accept, token string) (*http.Response, string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+addr+path, http.NoBody)
+		if err != nil {
+			return nil, "", err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return resp, string(body), err
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, _, err := get("/metrics", "", ""); err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("metrics server not ready: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	cases := map[string]struct {
+		path            string
+		accept          string
+		token           string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		"Unauthorized": {
+			path:       "/metrics",
+			accept:     "application/openmetrics-text;version=1.0.0",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"OpenMetrics": {
+			path:            "/metrics",
+			accept:          "application/openmetrics-text;version=1.0.0",
+			token:           "scraper",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/openmetrics-text",
+		},
+		"TextFormat": {
+			path:            "/metrics",
+			token:           "scraper",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/plain",
+		},
+		"ExtraHandler": {
+			path:       "/debug",
+			token:      "scraper",
+			wantStatus: http.StatusOK,
+			wantBody:   "debug",
+		},
+		"ExtraHandlerUnauthorized": {
+			path:       "/debug",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, body, err := get(tc.path, tc.accept, tc.token)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.StatusCode != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, resp.StatusCode)
+			}
+			if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, tc.wantContentType) {
+				t.Errorf("expected content type %q, got %q", tc.wantContentType, got)
+			}
+			if tc.wantBody != "" && body != tc.wantBody {
+				t.Errorf("expected body %q, got %q", tc.wantBody, body)
+			}
+		})
+	}
+}
@@ -30,8 +30,9 @@ func NewCreateTokenMock() *MockK8sV1 {
 type MockK8sV1 struct {
 	k8sv1.CoreV1Interface
 
-	token string
-	err   error
+	token    string
+	err      error
+	requests []*authv1.TokenRequest
 }
 
 func (m *MockK8sV1) WithToken(token string) *MockK8sV1 {
@@ -44,6 +45,11 @@ func (m *MockK8sV1) WithError(err error) *MockK8sV1 {
 	return m
 }
 
+// Requests returns the token requests received so far.
+func (m *MockK8sV1) Requests() []*authv1.TokenRequest {
+	return m.requests
+}
+
 func (m *MockK8sV1) ServiceAccounts(_ string) k8sv1.ServiceAccountInterface {
 	return &MockK8sV1SA{v1mock: m}
 }
@@ -57,9 +63,10 @@ type MockK8sV1SA struct {
 func (ma *MockK8sV1SA) CreateToken(
 	_ context.Context,
 	_ string,
-	_ *authv1.TokenRequest,
+	tokenRequest *authv1.TokenRequest,
 	_ metav1.CreateOptions,
 ) (*authv1.TokenRequest, error) {
+	ma.v1mock.requests = append(ma.v1mock.requests, tokenRequest)
 	if ma.v1mock.err != nil {
 		return nil, ma.v1mock.err
 	}
@@ -19,100 +19,400 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	vault "github.com/hashicorp/vault/api"
 	authv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
 	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
 	"github.com/external-secrets/external-secrets/pkg/constants"
 	"github.com/external-secrets/external-secrets/pkg/metrics"
-	vaultiamauth "github.com/external-secrets/external-secrets/pkg/provider/vault/iamauth"
 	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
 )
 
 const (
 	errAuthFormat            = "cannot initialize Vault client: no valid auth method specified"
+	errAuthNoLogin           = "cannot initialize Vault client: no auth method could log in: %s"
+	errAuthMethodFailed      = "%s auth matched but login failed: %w"
+	errAuthMethodSkipped     = "%s auth skipped: %v"
+	errAuthFallbackFailed    = "%s; %w"
 	errVaultToken            = "cannot parse Vault authentication token: %w"
 	errGetKubeSATokenRequest = "cannot request Kubernetes service account token for service account %q: %w"
 	errVaultRevokeToken      = "error while revoking token: %w"
 )
 
+const (
+	authMethodToken      = "token"
+	authMethodTokenFile  = "tokenfile"
+	authMethodAppRole    = "approle"
+	authMethodKubernetes = "kubernetes"
+	authMethodLdap       = "ldap"
+	authMethodUserPass   = "userpass"
+	authMethodJwt        = "jwt"
+	authMethodCert       = "cert"
+	authMethodIam        = "iam"
+	authMethodGcp        = "gcp"
+	authMethodAzure      = "azure"
+	authMethodPlugin     = "plugin"
+	// authMethodReused is recorded when the client re-used an existing token.
+	authMethodReused = "reused"
+)
+
+// authMethod is a way of obtaining a token. login returns false
+// if the method isn't configured for the store, along with an error
+// explaining why if it is configured but cannot log in yet.
+type authMethod struct {
+	name    string
+	message string
+	login   func(ctx context.Context) (bool, error)
+}
+
+// authMethods returns the supported auth methods in the order they are tried.
+func (c *client) authMethods(cfg *vault.Config) []authMethod {
+	return []authMethod{
+		{
+			name:    authMethodToken,
+			message: "Set token from secret",
+			login:   func(ctx context.Context) (bool, error) { return setSecretKeyToken(ctx, c) },
+		},
+		{
+			name:    authMethodTokenFile,
+			message: "Set token from file",
+			login:   func(ctx context.Context) (bool, error) { return setFileToken(ctx, c) },
+		},
+		{
+			name:    authMethodAppRole,
+			message: "Retrieved new token using AppRole auth",
+			login:   func(ctx context.Context) (bool, error) { return setAppRoleToken(ctx, c) },
+		},
+		{
+			name:    authMethodKubernetes,
+			message: "Retrieved new token using Kubernetes auth",
+			login:   func(ctx context.Context) (bool, error) { return setKubernetesAuthToken(ctx, c) },
+		},
+		{
+			name:    authMethodLdap,
+			message: "Retrieved new token using LDAP auth",
+			login:   func(ctx context.Context) (bool, error) { return setLdapAuthToken(ctx, c) },
+		},
+		{
+			name:    authMethodUserPass,
+			message: "Retrieved new token using userPass auth",
+			login:   func(ctx context.Context) (bool, error) { return setUserPassAuthToken(ctx, c) },
+		},
+		{
+			name:    authMethodJwt,
+			message: "Retrieved new token using JWT auth",
+			login:   func(ctx context.Context) (bool, error) { return setJwtAuthToken(ctx, c) },
+		},
+		{
+			name:    authMethodCert,
+			message: "Retrieved new token using certificate auth",
+			login:   func(ctx context.Context) (bool, error) { return setCertAuthToken(ctx, c, cfg) },
+		},
+		{
+			name:    authMethodIam,
+			message: "Retrieved new token using IAM auth",
+			login: func(ctx context.Context) (bool, error) {
+				return setIamAuthToken(ctx, c, c.iamJWTProvider(), c.iamSTSProvider())
+			},
+		},
+		{
+			name:    authMethodGcp,
+			message: "Retrieved new token using GCP auth",
+			login:   func(ctx context.Context) (bool, error) { return setGcpAuthToken(ctx, c) },
+		},
+		{
+			name:    authMethodAzure,
+			message: "Retrieved new token using Azure auth",
+			login:   func(ctx context.Context) (bool, error) { return setAzureAuthToken(ctx, c) },
+		},
+		{
+			name:    authMethodPlugin,
+			message: "Retrieved new token using plugin auth",
+			login:   func(ctx context.Context) (bool, error) { return setPluginAuthToken(ctx, c) },
+		},
+	}
+}
+
 // setAuth gets a new token using the configured mechanism.
 // If there's already a valid token, does nothing.
 func (c *client) setAuth(ctx context.Context, cfg *vault.Config) error {
+	if c.externalToken != nil {
+		if err := c.externalToken.use(c); err != nil {
+			return err
+		}
+		c.observeAuthMethod()
+		return nil
+	}
 	if c.store.Auth == nil {
 		return nil
 	}
+	if err := checkElected(); err != nil {
+		return err
+	}
 
 	if c.store.Namespace != nil { // set namespace before checking the need for AuthNamespace
-		c.client.SetNamespace(*c.store.Namespace)
+		c.client.SetNamespace(c.normalizeNamespace(*c.store.Namespace))
+	}
+	if method, err := c.cachedAuthFailure(ctx); err != nil {
+		if !c.useFallbackToken(ctx, method, err) {
+			return authFailed(err)
+		}
+		c.acquireToken(ctx)
+		c.observeAuthMethod()
+		return nil
+	}
+	if c.recentlyAuthenticated() {
+		c.useDiscoveredNamespace()
+		c.acquireToken(ctx)
+		c.observeAuthMethod()
+		return nil
 	}
 
-	// Switch to auth namespace if different from the provider namespace
-	restoreNamespace := c.useAuthNamespace(ctx)
-	defer restoreNamespace()
+	// Log in with a client scoped to the auth namespace if it differs from the
+	// provider namespace, or without request forwarding if the auth method
+	// is mounted locally, then hand the token over to this client.
+	login := c.withAuthNamespace().withLocalMount()
+	loggedIn, err := login.authenticate(ctx, cfg)
+	c.tokenNamespace = nil
+	if login != c {
+		ns := login.client.Namespace()
+		c.tokenNamespace = &ns
+		c.client.SetToken(login.client.Token())
+		c.loginWarnings = login.loginWarnings
+		c.authMethod = login.authMethod
+		c.loginAuth = login.loginAuth
+		c.suppliedToken = login.suppliedToken
+	}
+	if err != nil {
+		return authFailed(err)
+	}
+	if loggedIn {
+		c.discoveredNamespace = c.loginNamespace()
+	}
+	c.useDiscoveredNamespace()
+	if loggedIn {
+		if err := c.checkCanaryPath(ctx); err != nil {
+			return authFailed(err)
+		}
+		if err := c.checkRequiredCapabilities(ctx); err != nil {
+			return authFailed(err)
+		}
+		if err := c.checkMountAccessor(ctx); err != nil {
+			return authFailed(err)
+		}
+		if err := c.checkDeniedPolicies(ctx); err != nil {
+			return authFailed(err)
+		}
+		c.recordPolicySource(ctx)
+		c.startTokenRenewal(ctx)
+	}
+	c.acquireToken(ctx)
+	c.recordAuth()
+	c.observeAuthMethod()
+	return nil
+}
+
+// observeAuthMethod records the auth method the store authenticated with.
+func (c *client) observeAuthMethod() {
+	method := c.authMethod
+	if method == "" {
+		method = authMethodReused
+	}
+	namespace := c.namespace
+	if c.storeKind == esv1.ClusterSecretStoreKind {
+		namespace = ""
+	}
+	metrics.ObserveAuthMethod(constants.ProviderHCVault, method, c.storeKind, c.storeName, namespace)
+}
+
+// authFailed records the reason of a failed login, if err is set.
+func authFailed(err error) error {
+	if err != nil {
+		metrics.ObserveAuthFailure(constants.ProviderHCVault, authFailureReason(err))
+	}
+	return err
+}
 
-	tokenExists := false
+// authenticate gets a new token using the configured mechanism, unless
+// there's already a valid token. It reports whether it logged in.
+func (c *client) authenticate(ctx context.Context, cfg *vault.Config) (bool, error) {
+	state := tokenInvalid
 	var err error
+	replaced := c.client.Token()
 	if c.client.Token() != "" {
-		tokenExists, err = checkToken(ctx, c.token)
+		if isFallbackToken(c.client.Token()) {
+			// the primary auth method is tried again before each use of
+			// the fallback token.
+			state = tokenInvalid
+		} else if c.limitedUseToken() {
+			// Looking up a limited-use token consumes one of its uses,
+			// so rely on the lease returned at login instead.
+			if c.checkLimitedUseToken(c.expiryThreshold()) {
+				state = tokenValid
+			}
+		} else if tokenValidityCacheTTL > 0 {
+			state, err = c.checkTokenShared(ctx)
+		} else {
+			state, err = checkToken(ctx, c.tokenAPI(), c.expiryThreshold())
+		}
+	}
+	if state == tokenExpiring {
+		state = c.renewExpiringToken(ctx)
+	}
+	if state == tokenValid && c.policySourceChanged(ctx) {
+		c.dropLoginToken(ctx, "policy change")
+		state = tokenInvalid
 	}
-	if tokenExists {
+	if state == tokenValid {
 		c.log.V(1).Info("Re-using existing token")
-		return err
+		metrics.ObserveAuthTokenReuse(constants.ProviderHCVault)
+		return false, err
 	}
-
-	tokenExists, err = setSecretKeyToken(ctx, c)
-	if tokenExists {
-		c.log.V(1).Info("Set token from secret")
-		return err
+	if c.client.Token() != "" && err == nil && c.store.Auth.RevokeOnRelogin {
+		c.revokeStaleToken(ctx)
 	}
-
-	tokenExists, err = setAppRoleToken(ctx, c)
-	if tokenExists {
-		c.log.V(1).Info("Retrieved new token using AppRole auth")
-		return err
+	if c.useReplicaToken(ctx) {
+		return false, nil
 	}
 
-	tokenExists, err = setKubernetesAuthToken(ctx, c)
-	if tokenExists {
-		c.log.V(1).Info("Retrieved new token using Kubernetes auth")
-		return err
+	if pingBeforeLogin {
+		if err := c.Ping(ctx); err != nil {
+			return false, err
+		}
 	}
-
-	tokenExists, err = setLdapAuthToken(ctx, c)
-	if tokenExists {
-		c.log.V(1).Info("Retrieved new token using LDAP auth")
-		return err
+	if err := c.checkServerVersion(ctx); err != nil {
+		return false, err
 	}
-
-	tokenExists, err = setUserPassAuthToken(ctx, c)
-	if tokenExists {
-		c.log.V(1).Info("Retrieved new token using userPass auth")
-		return err
+	methods, err := c.selectAuthMethods(c.prioritizeAuthMethods(c.authMethods(cfg)))
+	if err != nil {
+		return false, err
 	}
-	tokenExists, err = setJwtAuthToken(ctx, c)
-	if tokenExists {
-		c.log.V(1).Info("Retrieved new token using JWT auth")
-		return err
+	c.loginAuth = nil
+	c.suppliedToken = false
+	var skipped, failed []string
+	for i, method := range methods {
+		start := time.Now()
+		loggedIn, err := loginWithTimeout(ctx, method, c.loginTimeout(cfg, method.name))
+		if !loggedIn && err != nil {
+			skipped = append(skipped, fmt.Sprintf(errAuthMethodSkipped, method.name, err))
+		}
+		if loggedIn {
+			err = c.wrapRoleNotFound(method.name, replaced, err)
+			if err == nil {
+				err = c.createRoleToken(ctx)
+			}
+			if err == nil {
+				err = c.checkTokenType(ctx)
+			}
+			if err != nil {
+				err = fmt.Errorf(errAuthMethodFailed, method.name, err)
+			}
+			metrics.ObserveAuthLogin(ctx, constants.ProviderHCVault, method.name, time.Since(start), err)
+			c.log.V(1).Info(method.message)
+			// with an auth method priority, a failed login falls back to
+			// the next method.
+			if err != nil && len(c.store.Auth.AuthMethodPriority) > 0 && i < len(methods)-1 {
+				c.log.Info("login failed, trying the next auth method", "method", method.name, "error", err.Error())
+				failed = append(failed, err.Error())
+				continue
+			}
+			if err != nil && len(failed) > 0 {
+				err = fmt.Errorf(errAuthFallbackFailed, strings.Join(failed, "; "), err)
+			}
+			if err != nil {
+				c.rememberAuthFailure(ctx, method.name, err)
+				if c.useFallbackToken(ctx, method.name, err) {
+					return true, nil
+				}
+			} else if c.shareReplicaToken(ctx) {
+				return false, nil
+			} else {
+				c.authMethod = method.name
+			}
+			return true, err
+		}
 	}
-
-	tokenExists, err = setCertAuthToken(ctx, c, cfg)
-	if tokenExists {
-		c.log.V(1).Info("Retrieved new token using certificate auth")
-		return err
+	skipped = append(failed, skipped...)
+	if len(skipped) == 0 {
+		err = errors.New(errAuthFormat)
+	} else {
+		err = fmt.Errorf(errAuthNoLogin, strings.Join(skipped, "; "))
 	}
+	if c.useFallbackToken(ctx, "", err) {
+		return true, nil
+	}
+	return false, err
+}
 
-	tokenExists, err = setIamAuthToken(ctx, c, vaultiamauth.DefaultJWTProvider, vaultiamauth.DefaultSTSProvider)
-	if tokenExists {
-		c.log.V(1).Info("Retrieved new token using IAM auth")
-		return err
+// AuthMethod returns the name of the auth method of the last login, it is
+// empty if the client re-used an existing token.
+func (c *client) AuthMethod() string {
+	return c.authMethod
+}
+
+// defaultTokenExpirationSeconds is the expiration of requested service
+// account tokens, unless configured otherwise.
+const defaultTokenExpirationSeconds = 600
+
+// tokenRequestSpec returns the spec of the request of a token for the
+// service account. The audiences of the request take precedence over those
+// of the service account, and are requested once each, as callers may
+// combine both.
+func tokenRequestSpec(serviceAccountRef esmeta.ServiceAccountSelector, request *esv1.VaultKubernetesTokenRequest) authv1.TokenRequestSpec {
+	expirationSeconds := int64(defaultTokenExpirationSeconds)
+	spec := authv1.TokenRequestSpec{
+		Audiences:         uniqueAudiences(serviceAccountRef.Audiences),
+		ExpirationSeconds: &expirationSeconds,
+	}
+	if request == nil {
+		return spec
+	}
+	if len(request.Audiences) > 0 {
+		spec.Audiences = uniqueAudiences(request.Audiences)
+	}
+	if request.ExpirationSeconds != nil {
+		spec.ExpirationSeconds = request.ExpirationSeconds
 	}
+	if ref := request.BoundObjectRef; ref != nil {
+		apiVersion := ref.APIVersion
+		if apiVersion == "" {
+			apiVersion = "v1"
+		}
+		spec.BoundObjectRef = &authv1.BoundObjectReference{
+			Kind:       ref.Kind,
+			APIVersion: apiVersion,
+			Name:       ref.Name,
+			UID:        types.UID(ref.UID),
+		}
+	}
+	return spec
+}
 
-	return errors.New(errAuthFormat)
+// uniqueAudiences returns the audiences without repetitions, in the order
+// they first appear in.
+func uniqueAudiences(audiences []string) []string {
+	seen := make(map[string]struct{}, len(audiences))
+	unique := make([]string, 0, len(audiences))
+	for _, audience := range audiences {
+		if _, ok := seen[audience]; ok {
+			continue
+		}
+		seen[audience] = struct{}{}
+		unique = append(unique, audience)
+	}
+	if len(unique) == len(audiences) {
+		return audiences
+	}
+	return unique
 }
 
 func createServiceAccountToken(
@@ -121,112 +421,354 @@ func createServiceAccountToken(
 	storeKind string,
 	namespace string,
 	serviceAccountRef esmeta.ServiceAccountSelector,
-	additionalAud []string,
-	expirationSeconds int64) (string, error) {
-	audiences := serviceAccountRef.Audiences
-	if len(additionalAud) > 0 {
-		audiences = append(audiences, additionalAud...)
-	}
+	request *esv1.VaultKubernetesTokenRequest) (string, error) {
 	tokenRequest := &authv1.TokenRequest{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: namespace,
 		},
-		Spec: authv1.TokenRequestSpec{
-			Audiences:         audiences,
-			ExpirationSeconds: &expirationSeconds,
-		},
+		Spec: tokenRequestSpec(serviceAccountRef, request),
 	}
 	if (storeKind == esv1.ClusterSecretStoreKind) &&
 		(serviceAccountRef.Namespace != nil) {
 		tokenRequest.Namespace = *serviceAccountRef.Namespace
 	}
+	key := mintedTokenKey(tokenRequest.Namespace, serviceAccountRef.Name, tokenRequest.Spec)
+	if reuseMintedTokens {
+		if token, ok := reusableMintedToken(key); ok {
+			logger.V(1).Info("re-using service account token", "serviceAccount", serviceAccountRef.Name, "namespace", tokenRequest.Namespace)
+			return token, nil
+		}
+	}
 	tokenResponse, err := corev1Client.ServiceAccounts(tokenRequest.Namespace).
 		CreateToken(ctx, serviceAccountRef.Name, tokenRequest, metav1.CreateOptions{})
 	if err != nil {
+		// the last token issued for the request can be used as long as it
+		// is valid, while the Kubernetes API is unavailable.
+		if token, ok := cachedMintedToken(key); ok && isKubeAPIUnavailable(err) {
+			logger.V(1).Info("Kubernetes API unavailable, re-using service account token", "serviceAccount", serviceAccountRef.Name, "namespace", tokenRequest.Namespace, "error", err.Error())
+			return token, nil
+		}
 		return "", fmt.Errorf(errGetKubeSATokenRequest, serviceAccountRef.Name, err)
 	}
+	storeMintedToken(key, tokenResponse.Status.Token, tokenResponse.Status.ExpirationTimestamp.Time)
 	return tokenResponse.Status.Token, nil
 }
 
-// checkToken does a lookup and checks if the provided token exists.
-func checkToken(ctx context.Context, token util.Token) (bool, error) {
+// tokenState is the outcome of a token lookup.
+type tokenState int
+
+const (
+	// tokenInvalid tokens don't exist or are about to expire, a new token
+	// has to be obtained.
+	tokenInvalid tokenState = iota
+	// tokenValid tokens can be used as they are.
+	tokenValid
+	// tokenExpiring tokens are about to expire but can be renewed.
+	tokenExpiring
+)
+
+// checkToken does a lookup and checks if the provided token exists and
+// doesn't expire within the threshold.
+func checkToken(ctx context.Context, token util.Token, threshold time.Duration) (tokenState, error) {
+	state, _, err := lookupToken(ctx, token, threshold)
+	return state, err
+}
+
+// lookupToken does a lookup and checks if the provided token exists.
+// It also returns the lease of valid tokens.
+func lookupToken(ctx context.Context, token util.Token, threshold time.Duration) (tokenState, tokenLease, error) {
 	// https://www.vaultproject.io/api-docs/auth/token#lookup-a-token-self
 	resp, err := token.LookupSelfWithContext(ctx)
 	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLookupSelf, err)
 	if err != nil {
-		return false, err
+		return tokenInvalid, tokenLease{}, err
+	}
+	return lookupState(resp, threshold)
+}
+
+// lookupState returns the state of the token of a lookup, and the lease of
+// valid tokens.
+func lookupState(resp *vault.Secret, threshold time.Duration) (tokenState, tokenLease, error) {
+	info, err := parseTokenLookup(resp)
+	if err != nil {
+		return tokenInvalid, tokenLease{}, err
 	}
+	if info.Type == "batch" {
+		return tokenInvalid, tokenLease{}, nil
+	}
+	metrics.ObserveAuthTokenTTL(constants.ProviderHCVault, info.TTL)
+	if info.TTL < threshold && !info.ExpireTime.IsZero() {
+		// Treat expirable tokens that are about to expire as already expired.
+		// This ensures that the token won't expire in between this check and
+		// performing the actual operation. Renewable tokens may be renewed
+		// instead of being replaced.
+		if info.Renewable {
+			return tokenExpiring, tokenLease{}, nil
+		}
+		return tokenInvalid, tokenLease{}, nil
+	}
+	return tokenValid, tokenLease{expiry: info.ExpireTime, renewable: info.Renewable}, nil
+}
+
+// parseTokenLookup parses the lease and identity of the token of a lookup.
+// The TTL is the sooner of the ttl and expire_time of the lookup, see
+// conservativeTTL.
+func parseTokenLookup(resp *vault.Secret) (*TokenInfo, error) {
 	// LookupSelfWithContext() calls ParseSecret(), which has several places
 	// that return no data and no error, including when a token is expired.
 	if resp == nil {
-		return false, errors.New("no response nor error for token lookup")
+		return nil, errors.New("no response nor error for token lookup")
 	}
 	t, ok := resp.Data["type"]
 	if !ok {
-		return false, errors.New("could not assert token type")
-	}
-	tokenType := t.(string)
-	if tokenType == "batch" {
-		return false, nil
+		return nil, errors.New("could not assert token type")
 	}
+	tokenType, _ := t.(string)
 	ttl, ok := resp.Data["ttl"]
 	if !ok {
-		return false, errors.New("no TTL found in response")
+		return nil, errors.New("no TTL found in response")
 	}
-	ttlInt, err := ttl.(json.Number).Int64()
+	ttlInt, err := parseTTL(ttl)
 	if err != nil {
-		return false, fmt.Errorf("invalid token TTL: %v: %w", ttl, err)
+		return nil, fmt.Errorf("invalid token TTL: %v: %w", ttl, err)
 	}
 	expireTime, ok := resp.Data["expire_time"]
 	if !ok {
-		return false, errors.New("no expiration time found in response")
+		return nil, errors.New("no expiration time found in response")
 	}
-	if ttlInt < 60 && expireTime != nil {
-		// Treat expirable tokens that are about to expire as already expired.
-		// This ensures that the token won't expire in between this check and
-		// performing the actual operation.
+	info := &TokenInfo{
+		Type:      tokenType,
+		Renewable: tokenRenewable(resp),
+	}
+	info.Accessor, _ = resp.Data["accessor"].(string)
+	if expireTime != nil {
+		ttlInt = conservativeTTL(ttlInt, expireTime)
+	}
+	info.TTL = time.Duration(ttlInt) * time.Second
+	if expireTime != nil {
+		info.ExpireTime = time.Now().Add(info.TTL)
+	}
+	return info, nil
+}
+
+// parseTTL returns the TTL of a token lookup in seconds. Depending on the
+// Vault version, the TTL is encoded as a number of seconds or as a duration
+// string like "15m".
+func parseTTL(ttl any) (int64, error) {
+	switch v := ttl.(type) {
+	case json.Number:
+		return v.Int64()
+	case float64:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case string:
+		if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return seconds, nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, err
+		}
+		return int64(d / time.Second), nil
+	}
+	return 0, fmt.Errorf("unexpected type %T", ttl)
+}
+
+// tokenRenewable returns whether the token of a lookup is renewable.
+// Depending on the Vault version and the path it was read from, the
+// renewable flag is encoded as a bool or as a string like "true". Flags that
+// can't be parsed are treated as not renewable, so that the token is
+// replaced by a new login instead of failing to renew.
+func tokenRenewable(resp *vault.Secret) bool {
+	if resp == nil {
+		return false
+	}
+	if resp.Auth != nil && resp.Auth.Renewable {
+		return true
+	}
+	renewable, err := parseRenewable(resp.Data["renewable"])
+	return err == nil && renewable
+}
+
+func parseRenewable(renewable any) (bool, error) {
+	switch v := renewable.(type) {
+	case nil:
 		return false, nil
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	case json.Number:
+		n, err := v.Int64()
+		return n != 0, err
 	}
-	return true, nil
+	return false, fmt.Errorf("unexpected type %T", renewable)
 }
 
-func revokeTokenIfValid(ctx context.Context, client util.Client) error {
-	valid, err := checkToken(ctx, client.AuthToken())
+// conservativeTTL compares the reported TTL with the remaining time until
+// expire_time. Both should describe the same expiry, but they can drift apart
+// (e.g. after a renewal race). If they disagree by more than
+// tokenExpiryTolerance, the sooner of the two is used.
+func conservativeTTL(ttl int64, expireTime any) int64 {
+	s, ok := expireTime.(string)
+	if !ok {
+		return ttl
+	}
+	expiry, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return ttl
+	}
+	remaining := int64(time.Until(expiry).Seconds())
+	drift := time.Duration(ttl-remaining) * time.Second
+	if drift.Abs() <= tokenExpiryTolerance {
+		return ttl
+	}
+	logger.Info("token ttl and expire_time disagree, using the sooner expiry", "ttl", ttl, "expireTime", s, "tolerance", tokenExpiryTolerance.String())
+	return min(ttl, remaining)
+}
+
+// revokeTokenIfValid revokes the token of the client unless it is invalid
+// already. Tokens with the root policy are never revoked, whether they were
+// supplied by the user or issued by a login, even if RevokeStaticToken is
+// set. Tokens supplied through a Secret are skipped by the callers, see
+// providedToken.
+func revokeTokenIfValid(ctx context.Context, client util.Client, scope esv1.VaultTokenRevokeScope) error {
+	stopTokenRenewal(client.Token())
+	forgetPolicySnapshot(client.Token())
+	forgetRecentAuths(client.Token())
+	// https://www.vaultproject.io/api-docs/auth/token#lookup-a-token-self
+	resp, err := client.AuthToken().LookupSelfWithContext(ctx)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLookupSelf, err)
+	if err != nil {
+		return fmt.Errorf(errVaultRevokeToken, err)
+	}
+	state, _, err := lookupState(resp, tokenExpiryThreshold)
 	if err != nil {
 		return fmt.Errorf(errVaultRevokeToken, err)
 	}
-	if valid {
+	if state == tokenInvalid {
+		return nil
+	}
+	// the policies of a lookup include those of the identity.
+	policies, err := resp.TokenPolicies()
+	if err != nil {
+		return fmt.Errorf(errVaultRevokeToken, err)
+	}
+	if slices.Contains(policies, "root") {
+		logger.Info("not revoking token with root policy")
+		return nil
+	}
+	if err := revokeToken(ctx, client, scope); err != nil {
+		return fmt.Errorf(errVaultRevokeToken, err)
+	}
+	client.ClearToken()
+	return nil
+}
+
+// revokeStaleToken revokes the token that a new login replaces. It was
+// just looked up, so it is revoked without checking it again, which would
+// report it as invalid if it is about to expire. A failed revocation is
+// only logged, the token then expires on its own.
+func (c *client) revokeStaleToken(ctx context.Context) {
+	token := c.client.Token()
+	if c.limitedUseToken() || c.providedToken() {
+		return
+	}
+	stopTokenRenewal(token)
+	forgetPolicySnapshot(token)
+	forgetRecentAuths(token)
+	forgetValidity(token)
+	if err := revokeToken(ctx, c.tokenClient(), c.store.Auth.RevokeScope); err != nil {
+		c.log.Error(fmt.Errorf(errVaultRevokeToken, err), "unable to revoke token before logging in again")
+		return
+	}
+	c.client.ClearToken()
+}
+
+// revokeToken revokes the current token using the endpoint for the given scope.
+func revokeToken(ctx context.Context, client util.Client, scope esv1.VaultTokenRevokeScope) error {
+	// tokens shared with other replicas are left to expire, fallback tokens
+	// are managed outside of the controller.
+	if isReplicaToken(client.Token()) || isFallbackToken(client.Token()) {
+		return nil
+	}
+	var err error
+	switch scope {
+	case esv1.VaultTokenRevokeScopeTree:
+		// https://developer.hashicorp.com/vault/api-docs/auth/token#revoke-a-token
+		err = client.AuthToken().RevokeTreeWithContext(ctx, client.Token())
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultRevokeTree, err)
+	case esv1.VaultTokenRevokeScopeOrphan:
+		// https://developer.hashicorp.com/vault/api-docs/auth/token#revoke-token-and-orphan-children
+		err = client.AuthToken().RevokeOrphanWithContext(ctx, client.Token())
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultRevokeOrphan, err)
+	default:
+		// https://developer.hashicorp.com/vault/api-docs/auth/token#revoke-a-token-self
 		err = client.AuthToken().RevokeSelfWithContext(ctx, client.Token())
 		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultRevokeSelf, err)
-		if err != nil {
-			return fmt.Errorf(errVaultRevokeToken, err)
-		}
-		client.ClearToken()
 	}
-	return nil
+	return err
 }
 
-func (c *client) useAuthNamespace(_ context.Context) func() {
+// withAuthNamespace returns the client to log in with. If the auth namespace
+// differs from the provider namespace, this is a copy scoped to the auth
+// namespace, so that the namespace of the shared Vault client is never
+// switched while other operations may be using it.
+func (c *client) withAuthNamespace() *client {
 	ns := ""
 	if c.store != nil && c.store.Namespace != nil {
-		ns = *c.store.Namespace
-	}
-
-	if c.store.Auth != nil && c.store.Auth.Namespace != nil {
-		// Different Auth Vault Namespace than Secret Vault Namespace
-		// Switch namespaces then switch back at the end
-		if c.store.Auth.Namespace != nil && *c.store.Auth.Namespace != ns {
-			c.log.V(1).Info("Using namespace=%s for the vault login", *c.store.Auth.Namespace)
-			c.client.SetNamespace(*c.store.Auth.Namespace)
-			// use this as a defer to reset the namespace
-			return func() {
-				c.log.V(1).Info("Restoring client namespace to namespace=%s", ns)
-				c.client.SetNamespace(ns)
-			}
-		}
+		ns = c.normalizeNamespace(*c.store.Namespace)
 	}
+	if c.store.Auth == nil {
+		return c
+	}
+	authNS := ""
+	switch {
+	case c.store.Auth.RootNamespace:
+	case c.store.Auth.Namespace != nil:
+		authNS = c.normalizeNamespace(*c.store.Auth.Namespace)
+	default:
+		return c
+	}
+	if authNS == ns {
+		return c
+	}
+	c.log.V(1).Info("Using auth namespace for the vault login", "namespace", authNS)
+	return c.withNamespace(authNS)
+}
+
+// withNamespace returns a copy of the client using a clone of the Vault
+// client scoped to the given namespace.
+func (c *client) withNamespace(namespace string) *client {
+	scoped := *c
+	scoped.client = c.client.WithNamespace(namespace)
+	scoped.auth = scoped.client.Auth()
+	scoped.logical = scoped.client.Logical()
+	scoped.token = scoped.client.AuthToken()
+	return &scoped
+}
 
-	return func() {
-		// no-op
+// tokenClient returns the client for requests about the token itself,
+// like lookups and revocations. These have to run in the namespace the
+// token was issued in rather than in the namespace of the store.
+func (c *client) tokenClient() util.Client {
+	if !c.tokenInOtherNamespace() {
+		return c.client
 	}
+	return c.client.WithNamespace(*c.tokenNamespace)
+}
+
+// tokenAPI returns the token API of the namespace the token was issued in.
+func (c *client) tokenAPI() util.Token {
+	if !c.tokenInOtherNamespace() {
+		return c.token
+	}
+	return c.tokenClient().AuthToken()
+}
+
+func (c *client) tokenInOtherNamespace() bool {
+	return c.tokenNamespace != nil && *c.tokenNamespace != c.client.Namespace()
 }
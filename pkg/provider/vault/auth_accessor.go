@@ -0,0 +1,94 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errVaultMountAccessor         = "cannot check the auth mount of the token: %w"
+	errVaultMountAccessorMismatch = "token was issued by %s auth mount %q with accessor %q, expected accessor %q"
+	errVaultAuthMountNotFound     = "no auth mount found for creation path %q"
+)
+
+// authMount is an auth mount as listed by sys/auth.
+type authMount struct {
+	path      string
+	mountType string
+	accessor  string
+}
+
+// checkMountAccessor checks that the token of a fresh login was issued by
+// the auth mount with the expected accessor, not by another mount enabled
+// at the same path. If it wasn't, the token is dropped like after a failed
+// canary read.
+func (c *client) checkMountAccessor(ctx context.Context) error {
+	expected := c.store.Auth.ExpectedMountAccessor
+	if expected == "" {
+		return nil
+	}
+	mount, err := c.tokenAuthMount(ctx)
+	if err != nil {
+		c.dropLoginToken(ctx, "failed mount accessor check")
+		return fmt.Errorf(errVaultMountAccessor, err)
+	}
+	if mount.accessor == expected {
+		return nil
+	}
+	c.dropLoginToken(ctx, "failed mount accessor check")
+	return fmt.Errorf(errVaultMountAccessorMismatch, mount.mountType, mount.path, mount.accessor, expected)
+}
+
+// tokenAuthMount returns the auth mount the token was issued by, which is
+// the mount with the longest path its creation path, e.g.
+// "auth/approle/login", is below. Mounts are listed in the namespace the
+// token was issued in.
+func (c *client) tokenAuthMount(ctx context.Context) (authMount, error) {
+	lookup, err := c.tokenAPI().LookupSelfWithContext(ctx)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLookupSelf, err)
+	if err != nil {
+		return authMount{}, err
+	}
+	creationPath, _ := lookup.Data["path"].(string)
+
+	// https://developer.hashicorp.com/vault/api-docs/system/auth#list-auth-methods
+	mounts, err := c.tokenClient().Logical().ReadWithDataWithContext(ctx, "sys/auth", nil)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultListAuthMounts, err)
+	if err != nil {
+		return authMount{}, err
+	}
+	var found authMount
+	if mounts != nil {
+		for path, raw := range mounts.Data {
+			mount, ok := raw.(map[string]any)
+			if !ok || !strings.HasPrefix(creationPath, "auth/"+path) || len(path) <= len(found.path) {
+				continue
+			}
+			found.path = path
+			found.mountType, _ = mount["type"].(string)
+			found.accessor, _ = mount["accessor"].(string)
+		}
+	}
+	if found.path == "" {
+		return authMount{}, fmt.Errorf(errVaultAuthMountNotFound, creationPath)
+	}
+	return found, nil
+}
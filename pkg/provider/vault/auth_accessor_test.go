@@ -0,0 +1,158 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestExpectedMountAccessor(t *testing.T) {
+	mounts := map[string]any{
+		"kubernetes/":        map[string]any{"type": "kubernetes", "accessor": "auth_kubernetes_1a2b3c4d"},
+		"kubernetes/team-a/": map[string]any{"type": "kubernetes", "accessor": "auth_kubernetes_5e6f7a8b"},
+		"token/":             map[string]any{"type": "token", "accessor": "auth_token_9c0d1e2f"},
+	}
+
+	cases := map[string]struct {
+		expected     string
+		creationPath string
+		listErr      error
+		wantErr      string
+		wantLists    int
+		wantRevoked  int
+		wantToken    string
+	}{
+		"NoExpectedAccessor": {
+			creationPath: "auth/kubernetes/login",
+			wantToken:    "kubernetes-token",
+		},
+		"Matching": {
+			expected:     "auth_kubernetes_1a2b3c4d",
+			creationPath: "auth/kubernetes/login",
+			wantLists:    1,
+			wantToken:    "kubernetes-token",
+		},
+		// the mount enabled at the path since has another accessor.
+		"Mismatching": {
+			expected:     "auth_kubernetes_00000000",
+			creationPath: "auth/kubernetes/login",
+			wantErr:      `token was issued by kubernetes auth mount "kubernetes/" with accessor "auth_kubernetes_1a2b3c4d", expected accessor "auth_kubernetes_00000000"`,
+			wantLists:    1,
+			wantRevoked:  1,
+		},
+		"NestedMount": {
+			expected:     "auth_kubernetes_5e6f7a8b",
+			creationPath: "auth/kubernetes/team-a/login",
+			wantLists:    1,
+			wantToken:    "kubernetes-token",
+		},
+		"MountNotFound": {
+			expected:     "auth_kubernetes_1a2b3c4d",
+			creationPath: "auth/jwt/login",
+			wantErr:      `no auth mount found for creation path "auth/jwt/login"`,
+			wantLists:    1,
+			wantRevoked:  1,
+		},
+		"ListDenied": {
+			expected:     "auth_kubernetes_1a2b3c4d",
+			creationPath: "auth/kubernetes/login",
+			listErr:      &vault.ResponseError{StatusCode: http.StatusForbidden, Errors: []string{"permission denied"}},
+			wantErr:      "cannot check the auth mount of the token",
+			wantLists:    1,
+			wantRevoked:  1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			lists := 0
+			revoked := 0
+			token := ""
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, &logins)
+			c.store.Auth.ExpectedMountAccessor = tc.expected
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					logins++
+					token = "kubernetes-token"
+					return &vault.Secret{}, nil
+				},
+			}
+			authToken := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					lookup := makeTokenLookup(time.Hour, true)
+					lookup.Data["path"] = tc.creationPath
+					return lookup, nil
+				},
+				RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+					revoked++
+					return nil
+				},
+			}
+			c.token = authToken
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				ClearTokenFunc:   func() { token = "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+				AuthTokenField:   authToken,
+				LogicalField: fake.Logical{
+					ReadWithDataWithContextFn: func(ctx context.Context, path string, data map[string][]string) (*vault.Secret, error) {
+						lists++
+						if path != "sys/auth" {
+							t.Errorf("expected the auth mounts to be listed, got a read of %q", path)
+						}
+						if tc.listErr != nil {
+							return nil, tc.listErr
+						}
+						return &vault.Secret{Data: mounts}, nil
+					},
+				},
+			}
+
+			err := c.setAuth(context.Background(), nil)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+			if lists != tc.wantLists {
+				t.Errorf("expected %d auth mount lists, got %d", tc.wantLists, lists)
+			}
+			if revoked != tc.wantRevoked {
+				t.Errorf("expected %d revocations, got %d", tc.wantRevoked, revoked)
+			}
+			if token != tc.wantToken {
+				t.Errorf("expected token %q, got %q", tc.wantToken, token)
+			}
+		})
+	}
+}
@@ -17,18 +17,24 @@ package vault
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"strings"
 
+	vault "github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/api/auth/approle"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
 	"github.com/external-secrets/external-secrets/pkg/constants"
 	"github.com/external-secrets/external-secrets/pkg/metrics"
 	"github.com/external-secrets/external-secrets/pkg/utils/resolvers"
 )
 
 const (
-	errInvalidAppRoleID = "invalid Auth.AppRole: neither `roleId` nor `roleRef` was supplied"
+	errInvalidAppRoleID      = "invalid Auth.AppRole: neither `roleId` nor `roleRef` was supplied"
+	errAppRoleUnwrapSecretID = "cannot unwrap AppRole secret ID: %w"
+	errAppRoleNoSecretID     = "wrapped response contains no secret_id"
 )
 
 func setAppRoleToken(ctx context.Context, v *client) (bool, error) {
@@ -44,6 +50,32 @@ func setAppRoleToken(ctx context.Context, v *client) (bool, error) {
 }
 
 func (c *client) requestTokenWithAppRoleRef(ctx context.Context, appRole *esv1.VaultAppRole) error {
+	err := c.loginWithAppRoleCredentials(ctx, appRole.Path, &esv1.VaultAppRoleCredentials{
+		RoleID:          appRole.RoleID,
+		RoleRef:         appRole.RoleRef,
+		SecretRef:       appRole.SecretRef,
+		WrappedSecretID: appRole.WrappedSecretID,
+	})
+	for i := range appRole.FallbackCredentials {
+		// only credentials rejected by Vault are replaced by the next ones.
+		if err == nil || c.classifyLoginError(authMethodAppRole, err) != esv1.VaultAuthErrorClassAuthRejected {
+			break
+		}
+		c.log.Info("AppRole login was rejected, trying fallback credentials", "fallback", i, "error", err.Error())
+		err = c.loginWithAppRoleCredentials(ctx, appRole.Path, &appRole.FallbackCredentials[i])
+	}
+	return err
+}
+
+func (c *client) loginWithAppRoleCredentials(ctx context.Context, path string, appRole *esv1.VaultAppRoleCredentials) error {
+	refs := []*esmeta.SecretKeySelector{&appRole.SecretRef}
+	if appRole.RoleID == "" && appRole.RoleRef != nil {
+		refs = append(refs, appRole.RoleRef)
+	}
+	if err := c.checkLoginSecrets(ctx, refs...); err != nil {
+		return err
+	}
+
 	var err error
 	var roleID string // becomes the RoleID used to authenticate with HashiCorp Vault
 
@@ -59,19 +91,56 @@ func (c *client) requestTokenWithAppRoleRef(ctx context.Context, appRole *esv1.V
 		return errors.New(errInvalidAppRoleID)
 	}
 
+	// the secret ID is read on every login, so that a rotated one is used
+	// as soon as the Secret is updated.
 	secretID, err := resolvers.SecretKeyRef(ctx, c.kube, c.storeKind, c.namespace, &appRole.SecretRef)
 	if err != nil {
 		return err
 	}
+	if appRole.WrappedSecretID {
+		secretID, err = c.unwrapSecretID(ctx, &appRole.SecretRef, strings.TrimSpace(secretID))
+		if err != nil {
+			return err
+		}
+	}
 	secret := approle.SecretID{FromString: secretID}
-	appRoleClient, err := approle.NewAppRoleAuth(roleID, &secret, approle.WithMountPath(appRole.Path))
+	appRoleClient, err := approle.NewAppRoleAuth(roleID, &secret, approle.WithMountPath(path))
 	if err != nil {
 		return err
 	}
-	_, err = c.auth.Login(ctx, appRoleClient)
-	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, err)
+	var resp *vault.Secret
+	err = c.retryLogin(ctx, authMethodAppRole, func() error {
+		var loginErr error
+		resp, loginErr = c.login(ctx, appRoleClient)
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, loginErr)
+		return loginErr
+	})
+	return c.checkLogin(ctx, resp, err)
+}
+
+// unwrapSecretID returns the secret ID wrapped by the token read from the
+// Secret.
+func (c *client) unwrapSecretID(ctx context.Context, ref *esmeta.SecretKeySelector, wrappingToken string) (string, error) {
+	secretID, err := c.unwrapSecretValue(ctx, ref, wrappingToken, constants.CallHCVaultUnwrap, func(resp *vault.Secret) (string, error) {
+		if resp != nil {
+			if secretID, _ := resp.Data["secret_id"].(string); secretID != "" {
+				return secretID, nil
+			}
+		}
+		return "", errors.New(errAppRoleNoSecretID)
+	})
 	if err != nil {
-		return err
+		return "", fmt.Errorf(errAppRoleUnwrapSecretID, err)
+	}
+	return secretID, nil
+}
+
+// appRoleSecretRefs returns the references to the Secrets holding the
+// credentials of the AppRole auth, including its fallback credentials.
+func appRoleSecretRefs(appRole *esv1.VaultAppRole) []*esmeta.SecretKeySelector {
+	refs := []*esmeta.SecretKeySelector{&appRole.SecretRef, appRole.RoleRef}
+	for i := range appRole.FallbackCredentials {
+		refs = append(refs, &appRole.FallbackCredentials[i].SecretRef, appRole.FallbackCredentials[i].RoleRef)
 	}
-	return nil
+	return slices.DeleteFunc(refs, func(ref *esmeta.SecretKeySelector) bool { return ref == nil })
 }
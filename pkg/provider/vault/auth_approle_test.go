@@ -0,0 +1,262 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+)
+
+func TestAppRoleFallbackCredentials(t *testing.T) {
+	cases := map[string]struct {
+		// responses maps role IDs to the status of their login.
+		responses  map[string]int
+		wantLogins []string
+		wantErr    bool
+	}{
+		"FirstSucceeds": {
+			responses:  map[string]int{"green-role": http.StatusOK, "blue-role": http.StatusOK},
+			wantLogins: []string{"green-role"},
+		},
+		"FirstRejected": {
+			responses:  map[string]int{"green-role": http.StatusBadRequest, "blue-role": http.StatusOK},
+			wantLogins: []string{"green-role", "blue-role"},
+		},
+		"AllRejected": {
+			responses:  map[string]int{"green-role": http.StatusBadRequest, "blue-role": http.StatusForbidden},
+			wantLogins: []string{"green-role", "blue-role"},
+			wantErr:    true,
+		},
+		// the fallback credentials are only used if Vault rejects the login.
+		"FirstUnavailable": {
+			responses:  map[string]int{"green-role": http.StatusServiceUnavailable, "blue-role": http.StatusOK},
+			wantLogins: []string{"green-role"},
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var logins []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/auth/approle/login" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				var body map[string]string
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				logins = append(logins, body["role_id"])
+				if status := tc.responses[body["role_id"]]; status != http.StatusOK {
+					w.WriteHeader(status)
+					_, _ = w.Write([]byte(`{"errors": ["invalid role or secret ID"]}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"auth": {"client_token": "` + body["role_id"] + `-token"}}`))
+			}))
+			defer server.Close()
+
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "approle-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"green-secret-id": []byte("green-secret"),
+					"blue-role-id":    []byte("blue-role"),
+					"blue-secret-id":  []byte("blue-secret"),
+				},
+			}).Build()
+			store := &esv1.SecretStore{
+				TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vault-store",
+					Namespace: "default",
+				},
+				Spec: esv1.SecretStoreSpec{
+					// unavailable logins aren't retried by the Vault client.
+					RetrySettings: &esv1.SecretStoreRetrySettings{MaxRetries: ptr.To(int32(0))},
+					Provider: &esv1.SecretStoreProvider{
+						Vault: &esv1.VaultProvider{
+							Server:  server.URL,
+							Version: esv1.VaultKVStoreV2,
+							Auth: &esv1.VaultAuth{
+								AppRole: &esv1.VaultAppRole{
+									Path:      "approle",
+									RoleID:    "green-role",
+									SecretRef: esmeta.SecretKeySelector{Name: "approle-secret", Key: "green-secret-id"},
+									FallbackCredentials: []esv1.VaultAppRoleCredentials{{
+										RoleRef:   &esmeta.SecretKeySelector{Name: "approle-secret", Key: "blue-role-id"},
+										SecretRef: esmeta.SecretKeySelector{Name: "approle-secret", Key: "blue-secret-id"},
+									}},
+								},
+							},
+						},
+					},
+				},
+			}
+			prov := &Provider{NewVaultClient: NewVaultClient}
+			c, err := prov.newClient(context.Background(), store, kube, nil, "default")
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.wantLogins, logins); diff != "" {
+				t.Errorf("unexpected logins: -want, +got:\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+			wantToken := tc.wantLogins[len(tc.wantLogins)-1] + "-token"
+			if token := c.(*client).client.Token(); token != wantToken {
+				t.Errorf("expected token %q, got %q", wantToken, token)
+			}
+		})
+	}
+}
+
+func TestAppRoleSecretIDRotation(t *testing.T) {
+	defer func(ttl time.Duration) { negativeAuthCacheTTL = ttl }(negativeAuthCacheTTL)
+	negativeAuthCacheTTL = time.Minute
+	defer func() {
+		authFailures = map[string]authFailure{}
+		unwrapped = map[string]unwrappedValue{}
+	}()
+
+	cases := map[string]struct {
+		wrapped bool
+		// values are the values of the Secret before and after the rotation.
+		values      []string
+		wantUnwraps []string
+	}{
+		"Plain": {
+			values: []string{"old-secret", "new-secret"},
+		},
+		// each wrapping token is unwrapped once, the secret ID is reused by
+		// the following logins.
+		"Wrapped": {
+			wrapped:     true,
+			values:      []string{"old-wrapping-token", "new-wrapping-token"},
+			wantUnwraps: []string{"old-wrapping-token", "new-wrapping-token"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			authFailures = map[string]authFailure{}
+			unwrapped = map[string]unwrappedValue{}
+			wrapped := map[string]string{"old-wrapping-token": "old-secret", "new-wrapping-token": "new-secret"}
+			var logins, unwraps []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]string
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				switch r.URL.Path {
+				case "/v1/sys/wrapping/unwrap":
+					token := body["token"]
+					if token == "" {
+						token = r.Header.Get("X-Vault-Token")
+					}
+					unwraps = append(unwraps, token)
+					secretID, ok := wrapped[token]
+					if !ok {
+						w.WriteHeader(http.StatusBadRequest)
+						_, _ = w.Write([]byte(`{"errors": ["wrapping token is not valid or does not exist"]}`))
+						return
+					}
+					// wrapping tokens can be unwrapped only once.
+					delete(wrapped, token)
+					_, _ = w.Write([]byte(`{"data": {"secret_id": "` + secretID + `"}}`))
+				case "/v1/auth/approle/login":
+					logins = append(logins, body["secret_id"])
+					if body["secret_id"] != "new-secret" {
+						w.WriteHeader(http.StatusBadRequest)
+						_, _ = w.Write([]byte(`{"errors": ["invalid role or secret ID"]}`))
+						return
+					}
+					_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token"}}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "approle-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{"secret-id": []byte(tc.values[0])},
+			}
+			kube := clientfake.NewClientBuilder().WithObjects(secret).Build()
+			store := &esv1.SecretStore{
+				TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vault-store",
+					Namespace: "default",
+				},
+				Spec: esv1.SecretStoreSpec{
+					RetrySettings: &esv1.SecretStoreRetrySettings{MaxRetries: ptr.To(int32(0))},
+					Provider: &esv1.SecretStoreProvider{
+						Vault: &esv1.VaultProvider{
+							Server:  server.URL,
+							Version: esv1.VaultKVStoreV2,
+							Auth: &esv1.VaultAuth{
+								AppRole: &esv1.VaultAppRole{
+									Path:            "approle",
+									RoleID:          "role",
+									SecretRef:       esmeta.SecretKeySelector{Name: "approle-secret", Key: "secret-id"},
+									WrappedSecretID: tc.wrapped,
+								},
+							},
+						},
+					},
+				},
+			}
+			prov := &Provider{NewVaultClient: NewVaultClient}
+
+			if _, err := prov.newClient(context.Background(), store, kube, nil, "default"); err == nil {
+				t.Fatal("expected the login with the old secret ID to be rejected")
+			}
+			// the cached failure is dropped once the secret ID is rotated.
+			secret.Data["secret-id"] = []byte(tc.values[1])
+			if err := kube.Update(context.Background(), secret); err != nil {
+				t.Fatal(err)
+			}
+			for range 2 {
+				if _, err := prov.newClient(context.Background(), store, kube, nil, "default"); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if diff := cmp.Diff([]string{"old-secret", "new-secret", "new-secret"}, logins); diff != "" {
+				t.Errorf("unexpected logins (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantUnwraps, unwraps); diff != "" {
+				t.Errorf("unexpected unwraps (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
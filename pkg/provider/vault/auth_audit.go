@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+const (
+	// loginMetadataParameter is the login parameter carrying the audit
+	// fields for auth methods whose login request is built here.
+	loginMetadataParameter = "metadata"
+	auditUserAgentPrefix   = "external-secrets"
+)
+
+// loginAuditFields are added to each login, so that the Vault audit log
+// attributes it to a store. Values may reference the store through
+// ${storeKind}, ${storeNamespace} and ${storeName}.
+var loginAuditFields map[string]string
+
+// auditFields returns the login audit fields with the store references
+// expanded, or nil if none are configured.
+func (c *client) auditFields() map[string]string {
+	if len(loginAuditFields) == 0 {
+		return nil
+	}
+	store := map[string]string{
+		"storeKind":      c.storeKind,
+		"storeNamespace": c.namespace,
+		"storeName":      c.storeName,
+	}
+	fields := make(map[string]string, len(loginAuditFields))
+	for key, value := range loginAuditFields {
+		fields[key] = os.Expand(value, func(name string) string {
+			if v, ok := store[name]; ok {
+				return v
+			}
+			return "${" + name + "}"
+		})
+	}
+	return fields
+}
+
+// withLoginMetadata adds the audit fields to the parameters of a login.
+func (c *client) withLoginMetadata(parameters map[string]any) map[string]any {
+	fields := c.auditFields()
+	if fields == nil {
+		return parameters
+	}
+	if parameters == nil {
+		parameters = make(map[string]any, 1)
+	}
+	parameters[loginMetadataParameter] = fields
+	return parameters
+}
+
+// auditUserAgent returns the User-Agent carrying the audit fields for
+// logins that don't take metadata, e.g. "external-secrets (cluster=prod)".
+func (c *client) auditUserAgent() string {
+	fields := c.auditFields()
+	if fields == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(fields))
+	for key, value := range fields {
+		pairs = append(pairs, key+"="+value)
+	}
+	slices.Sort(pairs)
+	return auditUserAgentPrefix + " (" + strings.Join(pairs, "; ") + ")"
+}
+
+// login logs in with one of the auth methods of the Vault client. With
+// audit fields or a correlation ID, the login is sent by a clone carrying
+// them in its headers, so that other requests of the shared client are
+// unaffected.
+func (c *client) login(ctx context.Context, method vault.AuthMethod) (*vault.Secret, error) {
+	userAgent := c.auditUserAgent()
+	id := correlationID(ctx)
+	if userAgent == "" && id == "" {
+		return c.auth.Login(ctx, method)
+	}
+	audited := c.client.WithNamespace(c.client.Namespace())
+	if userAgent != "" {
+		audited.AddHeader("User-Agent", userAgent)
+	}
+	if id != "" {
+		audited.AddHeader(correlationHeader, id)
+	}
+	resp, err := audited.Auth().Login(ctx, method)
+	if token := audited.Token(); token != "" {
+		c.client.SetToken(token)
+	}
+	return resp, err
+}
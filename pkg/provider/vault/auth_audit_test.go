@@ -0,0 +1,193 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+)
+
+func TestLoginAuditFields(t *testing.T) {
+	defer func(fields map[string]string) {
+		loginAuditFields = fields
+	}(loginAuditFields)
+
+	type login struct {
+		UserAgent string
+		Metadata  map[string]string
+	}
+
+	cases := map[string]struct {
+		fields    map[string]string
+		auth      esv1.VaultAuth
+		loginPath string
+		wantLogin login
+	}{
+		"UserAgentForAppRole": {
+			fields: map[string]string{
+				"cluster": "prod",
+				"store":   "${storeKind}/${storeNamespace}/${storeName}",
+			},
+			auth: esv1.VaultAuth{
+				AppRole: &esv1.VaultAppRole{
+					Path:   "approle",
+					RoleID: "role-id",
+					SecretRef: esmeta.SecretKeySelector{
+						Name: "login-secret",
+						Key:  "secret",
+					},
+				},
+			},
+			loginPath: "/v1/auth/approle/login",
+			wantLogin: login{
+				UserAgent: "external-secrets (cluster=prod; store=SecretStore/default/vault-store)",
+			},
+		},
+		"MetadataForJwt": {
+			fields: map[string]string{
+				"cluster": "prod",
+				"store":   "${storeName}",
+				"other":   "${unknown}",
+			},
+			auth: esv1.VaultAuth{
+				Jwt: &esv1.VaultJwtAuth{
+					Path: "jwt",
+					Role: "role",
+					SecretRef: &esmeta.SecretKeySelector{
+						Name: "login-secret",
+						Key:  "secret",
+					},
+				},
+			},
+			loginPath: "/v1/auth/jwt/login",
+			wantLogin: login{
+				Metadata: map[string]string{
+					"cluster": "prod",
+					"store":   "vault-store",
+					"other":   "${unknown}",
+				},
+			},
+		},
+		"Disabled": {
+			auth: esv1.VaultAuth{
+				AppRole: &esv1.VaultAppRole{
+					Path:   "approle",
+					RoleID: "role-id",
+					SecretRef: esmeta.SecretKeySelector{
+						Name: "login-secret",
+						Key:  "secret",
+					},
+				},
+			},
+			loginPath: "/v1/auth/approle/login",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			loginAuditFields = tc.fields
+
+			var logins []login
+			var readAgents []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case tc.loginPath:
+					var body struct {
+						Metadata map[string]string `json:"metadata"`
+					}
+					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+						t.Errorf("cannot decode login request: %v", err)
+					}
+					ua := r.Header.Get("User-Agent")
+					if !strings.HasPrefix(ua, auditUserAgentPrefix) {
+						// ignore the default agent of the HTTP client.
+						ua = ""
+					}
+					logins = append(logins, login{UserAgent: ua, Metadata: body.Metadata})
+					_, _ = w.Write([]byte(`{"auth": {"client_token": "login-token", "lease_duration": 3600}}`))
+				case "/v1/secret/data/foo":
+					readAgents = append(readAgents, r.Header.Get("User-Agent"))
+					_, _ = w.Write([]byte(`{"data": {"data": {"foo": "bar"}}}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			cfg := vault.DefaultConfig()
+			cfg.Address = server.URL
+			vaultClient, err := NewVaultClient(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vaultClient.ClearToken()
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "login-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"secret": []byte("secret"),
+				},
+			}).Build()
+			auth := tc.auth
+			c := &client{
+				kube:      kube,
+				log:       logger,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				storeName: "vault-store",
+				store:     &esv1.VaultProvider{Auth: &auth},
+				client:    vaultClient,
+				auth:      vaultClient.Auth(),
+				logical:   vaultClient.Logical(),
+				token:     vaultClient.AuthToken(),
+			}
+
+			if _, err := c.authenticate(context.Background(), cfg); err != nil {
+				t.Fatalf("unexpected login error: %v", err)
+			}
+			if diff := cmp.Diff([]login{tc.wantLogin}, logins); diff != "" {
+				t.Errorf("unexpected login requests (-want, +got):\n%s", diff)
+			}
+			if token := c.client.Token(); token != "login-token" {
+				t.Errorf("expected the login token on the client, got %q", token)
+			}
+
+			// the audit fields are only sent with logins.
+			if _, err := c.logical.ReadWithDataWithContext(context.Background(), "secret/data/foo", nil); err != nil {
+				t.Fatalf("unexpected read error: %v", err)
+			}
+			for _, ua := range readAgents {
+				if strings.HasPrefix(ua, auditUserAgentPrefix) {
+					t.Errorf("expected no audit User-Agent on reads, got %q", ua)
+				}
+			}
+		})
+	}
+}
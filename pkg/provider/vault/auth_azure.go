@@ -0,0 +1,176 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+	"github.com/external-secrets/external-secrets/pkg/provider/azure/keyvault"
+)
+
+const (
+	defaultAzureAuthMountPath = "azure"
+	defaultAzureResource      = "https://management.azure.com/"
+	errAzureAccessToken       = "cannot get Azure AD access token for Vault Azure auth: %w"
+	errAzureSAAnnotation      = "service account %q is missing annotation %s"
+	errAzureTokenFile         = "cannot read Azure federated token file %s: %w"
+)
+
+// azureTokenProvider exchanges a federated token for an Azure AD access
+// token, it is replaced in tests.
+var azureTokenProvider = keyvault.NewTokenProvider
+
+func setAzureAuthToken(ctx context.Context, v *client) (bool, error) {
+	azureAuth := v.store.Auth.Azure
+	if azureAuth != nil {
+		err := v.requestTokenWithAzureAuth(ctx, azureAuth)
+		if err != nil {
+			return true, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (c *client) requestTokenWithAzureAuth(ctx context.Context, azureAuth *esv1.VaultAzureAuth) error {
+	accessToken, err := c.azureAccessToken(ctx, azureAuth)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultFetchAzureToken, err)
+	if err != nil {
+		return fmt.Errorf(errAzureAccessToken, err)
+	}
+
+	path := defaultAzureAuthMountPath
+	if azureAuth.Path != "" {
+		path = azureAuth.Path
+	}
+	parameters := map[string]any{
+		"role": azureAuth.Role,
+		"jwt":  accessToken,
+	}
+	// https://developer.hashicorp.com/vault/api-docs/auth/azure#login
+	url := strings.Join([]string{"auth", path, "login"}, "/")
+	var vaultResult *vault.Secret
+	err = c.retryLogin(ctx, authMethodAzure, func() error {
+		var loginErr error
+		vaultResult, loginErr = c.writeLogin(ctx, url, parameters)
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, loginErr)
+		return loginErr
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.setLoginToken(ctx, vaultResult)
+}
+
+// azureAccessToken returns an Azure AD access token for the resource of the
+// auth method, using the workload identity of the service account if set.
+// Otherwise the workload identity the webhook set up for the controller is
+// used, or its managed identity if there is none.
+func (c *client) azureAccessToken(ctx context.Context, azureAuth *esv1.VaultAzureAuth) (string, error) {
+	resource := defaultAzureResource
+	if azureAuth.Resource != "" {
+		resource = azureAuth.Resource
+	}
+	if ref := azureAuth.ServiceAccountRef; ref != nil {
+		namespace := c.namespace
+		if c.storeKind == esv1.ClusterSecretStoreKind && ref.Namespace != nil {
+			namespace = *ref.Namespace
+		}
+		sa := &corev1.ServiceAccount{}
+		if err := c.kube.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, sa); err != nil {
+			return "", fmt.Errorf(errGetKubeSA, ref.Name, err)
+		}
+		clientID, ok := sa.Annotations[keyvault.AnnotationClientID]
+		if !ok {
+			return "", fmt.Errorf(errAzureSAAnnotation, ref.Name, keyvault.AnnotationClientID)
+		}
+		tenantID, ok := sa.Annotations[keyvault.AnnotationTenantID]
+		if !ok {
+			// the webhook sets the tenant of the controller's identity.
+			tenantID = os.Getenv("AZURE_TENANT_ID")
+		}
+		if tenantID == "" {
+			return "", fmt.Errorf(errAzureSAAnnotation, ref.Name, keyvault.AnnotationTenantID)
+		}
+		token, err := createServiceAccountToken(
+			ctx,
+			c.corev1,
+			c.storeKind,
+			c.namespace,
+			*ref,
+			&esv1.VaultKubernetesTokenRequest{Audiences: append([]string{keyvault.AzureDefaultAudience}, ref.Audiences...)})
+		if err != nil {
+			return "", err
+		}
+		return exchangeAzureToken(ctx, token, clientID, tenantID, resource)
+	}
+
+	if tokenFile := os.Getenv("AZURE_FEDERATED_TOKEN_FILE"); tokenFile != "" {
+		token, err := os.ReadFile(filepath.Clean(tokenFile))
+		if err != nil {
+			return "", fmt.Errorf(errAzureTokenFile, tokenFile, err)
+		}
+		return exchangeAzureToken(ctx, string(token), os.Getenv("AZURE_CLIENT_ID"), os.Getenv("AZURE_TENANT_ID"), resource)
+	}
+
+	creds, err := azidentity.NewManagedIdentityCredential(nil)
+	if err != nil {
+		return "", err
+	}
+	accessToken, err := creds.GetToken(ctx, policy.TokenRequestOptions{
+		Scopes: []string{azureScope(resource)},
+	})
+	if err != nil {
+		return "", err
+	}
+	return accessToken.Token, nil
+}
+
+// exchangeAzureToken exchanges a federated token for an Azure AD access
+// token of the identity.
+func exchangeAzureToken(ctx context.Context, token, clientID, tenantID, resource string) (string, error) {
+	aadEndpoint := keyvault.AadEndpointForType(esv1.AzureEnvironmentPublicCloud)
+	// the webhook sets the authority host of the cloud the cluster runs in.
+	if host := os.Getenv("AZURE_AUTHORITY_HOST"); host != "" {
+		aadEndpoint = strings.TrimSuffix(host, "/") + "/"
+	}
+	tp, err := azureTokenProvider(ctx, token, clientID, tenantID, aadEndpoint, azureScope(resource))
+	if err != nil {
+		return "", err
+	}
+	return tp.OAuthToken(), nil
+}
+
+// azureScope returns the scope of an access token for the resource.
+func azureScope(resource string) string {
+	if strings.HasSuffix(resource, "/.default") {
+		return resource
+	}
+	return strings.TrimSuffix(resource, "/") + "/.default"
+}
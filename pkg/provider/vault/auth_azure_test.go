@@ -0,0 +1,198 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	utilfake "github.com/external-secrets/external-secrets/pkg/provider/util/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+// fakeAzureToken is an Azure AD access token returned by a fake exchange.
+type fakeAzureToken string
+
+func (t fakeAzureToken) OAuthToken() string {
+	return string(t)
+}
+
+// azureExchange is a federated token exchanged for an access token.
+type azureExchange struct {
+	token, clientID, tenantID, aadEndpoint, scope string
+}
+
+func TestAzureAuth(t *testing.T) {
+	defer func(provider func(context.Context, string, string, string, string, string) (adal.OAuthTokenProvider, error)) {
+		azureTokenProvider = provider
+	}(azureTokenProvider)
+
+	tokenFile := filepath.Join(t.TempDir(), "azure-identity-token")
+	if err := os.WriteFile(tokenFile, []byte("federated-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		auth         *esv1.VaultAzureAuth
+		annotations  map[string]string
+		env          map[string]string
+		wantExchange azureExchange
+		wantPath     string
+		wantErr      string
+	}{
+		"ServiceAccount": {
+			auth: &esv1.VaultAzureAuth{
+				Path:              "azure-aks",
+				Role:              "demo",
+				ServiceAccountRef: &esmeta.ServiceAccountSelector{Name: "workload"},
+			},
+			annotations: map[string]string{
+				"azure.workload.identity/client-id": "client-id",
+				"azure.workload.identity/tenant-id": "tenant-id",
+			},
+			wantExchange: azureExchange{
+				token:       "service-account-token",
+				clientID:    "client-id",
+				tenantID:    "tenant-id",
+				aadEndpoint: "https://login.microsoftonline.com/",
+				scope:       "https://management.azure.com/.default",
+			},
+			wantPath: "auth/azure-aks/login",
+		},
+		"ServiceAccountTenantFromEnv": {
+			auth: &esv1.VaultAzureAuth{
+				Role:              "demo",
+				Resource:          "api://vault",
+				ServiceAccountRef: &esmeta.ServiceAccountSelector{Name: "workload"},
+			},
+			annotations: map[string]string{
+				"azure.workload.identity/client-id": "client-id",
+			},
+			env: map[string]string{"AZURE_TENANT_ID": "controller-tenant"},
+			wantExchange: azureExchange{
+				token:       "service-account-token",
+				clientID:    "client-id",
+				tenantID:    "controller-tenant",
+				aadEndpoint: "https://login.microsoftonline.com/",
+				scope:       "api://vault/.default",
+			},
+			wantPath: "auth/azure/login",
+		},
+		"ServiceAccountWithoutClientID": {
+			auth: &esv1.VaultAzureAuth{
+				Role:              "demo",
+				ServiceAccountRef: &esmeta.ServiceAccountSelector{Name: "workload"},
+			},
+			wantErr: `service account "workload" is missing annotation azure.workload.identity/client-id`,
+		},
+		"ControllerWorkloadIdentity": {
+			auth: &esv1.VaultAzureAuth{Path: "azure", Role: "demo"},
+			env: map[string]string{
+				"AZURE_FEDERATED_TOKEN_FILE": tokenFile,
+				"AZURE_CLIENT_ID":            "controller-client",
+				"AZURE_TENANT_ID":            "controller-tenant",
+				"AZURE_AUTHORITY_HOST":       "https://login.microsoftonline.us",
+			},
+			wantExchange: azureExchange{
+				token:       "federated-token",
+				clientID:    "controller-client",
+				tenantID:    "controller-tenant",
+				aadEndpoint: "https://login.microsoftonline.us/",
+				scope:       "https://management.azure.com/.default",
+			},
+			wantPath: "auth/azure/login",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for _, env := range []string{"AZURE_FEDERATED_TOKEN_FILE", "AZURE_CLIENT_ID", "AZURE_TENANT_ID", "AZURE_AUTHORITY_HOST"} {
+				t.Setenv(env, tc.env[env])
+			}
+			var exchange azureExchange
+			azureTokenProvider = func(ctx context.Context, token, clientID, tenantID, aadEndpoint, scope string) (adal.OAuthTokenProvider, error) {
+				exchange = azureExchange{token, clientID, tenantID, aadEndpoint, scope}
+				return fakeAzureToken("access-token"), nil
+			}
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "workload",
+					Namespace:   "default",
+					Annotations: tc.annotations,
+				},
+			}).Build()
+			var loginPath string
+			var loginData map[string]any
+			token := ""
+			c := &client{
+				log:       logger,
+				kube:      kube,
+				corev1:    utilfake.NewCreateTokenMock().WithToken("service-account-token"),
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store:     &esv1.VaultProvider{Auth: &esv1.VaultAuth{Azure: tc.auth}},
+				client: &util.VaultClient{
+					SetTokenFunc: func(v string) { token = v },
+				},
+				logical: fake.Logical{
+					WriteWithContextFn: func(ctx context.Context, path string, data map[string]any) (*vault.Secret, error) {
+						loginPath = path
+						loginData = data
+						return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "azure-token"}}, nil
+					},
+				},
+			}
+
+			loggedIn, err := setAzureAuthToken(context.Background(), c)
+			if !loggedIn {
+				t.Fatal("expected the Azure auth method to be used")
+			}
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantExchange, exchange, cmp.AllowUnexported(azureExchange{})); diff != "" {
+				t.Errorf("unexpected token exchange: -want, +got:\n%s", diff)
+			}
+			if loginPath != tc.wantPath {
+				t.Errorf("expected login at %q, got %q", tc.wantPath, loginPath)
+			}
+			if diff := cmp.Diff(map[string]any{"role": "demo", "jwt": "access-token"}, loginData); diff != "" {
+				t.Errorf("unexpected login data: -want, +got:\n%s", diff)
+			}
+			if token != "azure-token" {
+				t.Errorf("expected token %q, got %q", "azure-token", token)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errVaultCanary = "token cannot read canary path %q: %w"
+)
+
+// checkCanaryPath reads the canary path of the store with the token of a
+// fresh login. If that fails, the token is dropped, so that a later
+// reconcile logs in and checks again instead of reusing it.
+func (c *client) checkCanaryPath(ctx context.Context) error {
+	if c.store.Auth.CanaryPath == "" {
+		return nil
+	}
+	path := c.checkPath(c.store.Auth.CanaryPath)
+	_, err := c.logical.ReadWithDataWithContext(ctx, path, nil)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultReadCanary, err)
+	if err == nil {
+		return nil
+	}
+	c.dropLoginToken(ctx, "failed canary read")
+	return fmt.Errorf(errVaultCanary, path, err)
+}
+
+// checkPath returns the Vault path checked for a canary or required
+// capabilities path, which is resolved against the mount and KV version of
+// the store if requested.
+func (c *client) checkPath(path string) string {
+	if !c.store.Auth.ResolveCheckPaths {
+		return path
+	}
+	return c.buildPath(path)
+}
+
+// dropLoginToken revokes the token of a fresh login that failed a check
+// and clears it from the client.
+func (c *client) dropLoginToken(ctx context.Context, reason string) {
+	forgetLease(c.client.Token())
+	forgetRecentAuths(c.client.Token())
+	if !c.limitedUseToken() && !c.providedToken() {
+		if revokeErr := revokeTokenIfValid(ctx, c.tokenClient(), c.store.Auth.RevokeScope); revokeErr != nil {
+			c.log.Error(revokeErr, "unable to revoke token after "+reason)
+		}
+	}
+	c.client.ClearToken()
+}
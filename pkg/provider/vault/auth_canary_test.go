@@ -0,0 +1,167 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	vault "github.com/hashicorp/vault/api"
+	"k8s.io/utils/ptr"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestCanaryPath(t *testing.T) {
+	denied := &vault.ResponseError{StatusCode: http.StatusForbidden, Errors: []string{"permission denied"}}
+
+	cases := map[string]struct {
+		canaryPath string
+		// resolve resolves the canary path against the store of the version.
+		resolve       bool
+		version       esv1.VaultKVStoreVersion
+		wantPath      string
+		existingToken string
+		readErr       error
+		wantErr       bool
+		wantReads     int
+		wantRevoked   int
+		wantToken     string
+	}{
+		"NoCanaryPath": {
+			wantToken: "kubernetes-token",
+		},
+		"CanaryReadable": {
+			canaryPath: "secret/data/canary",
+			wantReads:  1,
+			wantToken:  "kubernetes-token",
+		},
+		"CanaryDenied": {
+			canaryPath:  "secret/data/canary",
+			readErr:     denied,
+			wantErr:     true,
+			wantReads:   1,
+			wantRevoked: 1,
+		},
+		"ResolvedKVv2": {
+			canaryPath: "canary",
+			resolve:    true,
+			version:    esv1.VaultKVStoreV2,
+			wantPath:   "secret/data/canary",
+			wantReads:  1,
+			wantToken:  "kubernetes-token",
+		},
+		"ResolvedKVv1": {
+			canaryPath: "canary",
+			resolve:    true,
+			version:    esv1.VaultKVStoreV1,
+			wantPath:   "secret/canary",
+			wantReads:  1,
+			wantToken:  "kubernetes-token",
+		},
+		// paths including the mount aren't resolved twice.
+		"ResolvedFullPath": {
+			canaryPath: "secret/data/canary",
+			resolve:    true,
+			version:    esv1.VaultKVStoreV2,
+			wantPath:   "secret/data/canary",
+			wantReads:  1,
+			wantToken:  "kubernetes-token",
+		},
+		"ExistingTokenNotChecked": {
+			canaryPath:    "secret/data/canary",
+			existingToken: "existing-token",
+			wantToken:     "existing-token",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			reads := 0
+			revoked := 0
+			token := tc.existingToken
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, &logins)
+			c.store.Auth.CanaryPath = tc.canaryPath
+			c.store.Auth.ResolveCheckPaths = tc.resolve
+			c.store.Path = ptr.To("secret")
+			c.store.Version = tc.version
+			wantPath := tc.canaryPath
+			if tc.wantPath != "" {
+				wantPath = tc.wantPath
+			}
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					logins++
+					token = "kubernetes-token"
+					return &vault.Secret{}, nil
+				},
+			}
+			authToken := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					return makeTokenLookup(time.Hour, true), nil
+				},
+				RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+					revoked++
+					return nil
+				},
+			}
+			c.token = authToken
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				ClearTokenFunc:   func() { token = "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+				AuthTokenField:   authToken,
+			}
+			c.logical = fake.Logical{
+				ReadWithDataWithContextFn: func(ctx context.Context, path string, data map[string][]string) (*vault.Secret, error) {
+					reads++
+					if path != wantPath {
+						t.Errorf("expected canary path %q to be read, got %q", wantPath, path)
+					}
+					return &vault.Secret{}, tc.readErr
+				},
+			}
+
+			err := c.setAuth(context.Background(), nil)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if tc.readErr != nil && !errors.Is(err, tc.readErr) {
+				t.Errorf("expected the canary read error to be returned, got %v", err)
+			}
+			if reads != tc.wantReads {
+				t.Errorf("expected %d canary reads, got %d", tc.wantReads, reads)
+			}
+			if revoked != tc.wantRevoked {
+				t.Errorf("expected %d revocations, got %d", tc.wantRevoked, revoked)
+			}
+			if token != tc.wantToken {
+				t.Errorf("expected token %q, got %q", tc.wantToken, token)
+			}
+		})
+	}
+}
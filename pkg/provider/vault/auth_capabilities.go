@@ -0,0 +1,94 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errVaultCapabilities        = "cannot check token capabilities on %q: %w"
+	errVaultMissingCapabilities = "token lacks required capabilities: %s"
+)
+
+const (
+	capabilityRoot = "root"
+	capabilityDeny = "deny"
+)
+
+// checkRequiredCapabilities checks the capabilities of the token of a fresh
+// login on the required paths of the store. If any are missing, the token is
+// dropped like after a failed canary read.
+func (c *client) checkRequiredCapabilities(ctx context.Context) error {
+	required := c.store.Auth.RequiredCapabilities
+	if len(required) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(required))
+	for path := range required {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	var missing []string
+	for _, configured := range paths {
+		path := c.checkPath(configured)
+		granted, err := c.client.Sys().CapabilitiesSelfWithContext(ctx, path)
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultCapabilitiesSelf, err)
+		if err != nil {
+			c.dropLoginToken(ctx, "failed capabilities check")
+			return fmt.Errorf(errVaultCapabilities, path, err)
+		}
+		if lacking := missingCapabilities(required[configured], granted); len(lacking) > 0 {
+			missing = append(missing, fmt.Sprintf("%q requires %s, has %s", path, formatCapabilities(lacking), formatCapabilities(granted)))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	c.dropLoginToken(ctx, "failed capabilities check")
+	return fmt.Errorf(errVaultMissingCapabilities, strings.Join(missing, "; "))
+}
+
+// missingCapabilities returns the required capabilities that aren't granted.
+// The root capability grants all others, deny revokes them.
+func missingCapabilities(required, granted []string) []string {
+	if slices.Contains(granted, capabilityDeny) {
+		return required
+	}
+	if slices.Contains(granted, capabilityRoot) {
+		return nil
+	}
+	var missing []string
+	for _, capability := range required {
+		if !slices.Contains(granted, capability) {
+			missing = append(missing, capability)
+		}
+	}
+	return missing
+}
+
+func formatCapabilities(capabilities []string) string {
+	if len(capabilities) == 0 {
+		return "none"
+	}
+	return "[" + strings.Join(capabilities, ", ") + "]"
+}
@@ -0,0 +1,191 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	vault "github.com/hashicorp/vault/api"
+	"k8s.io/utils/ptr"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestRequiredCapabilities(t *testing.T) {
+	cases := map[string]struct {
+		required map[string][]string
+		// resolve resolves the required paths against the store of the version.
+		resolve     bool
+		version     esv1.VaultKVStoreVersion
+		granted     map[string][]string
+		checkErr    error
+		wantErr     string
+		wantChecks  int
+		wantRevoked int
+		wantToken   string
+	}{
+		"NoRequiredCapabilities": {
+			wantToken: "kubernetes-token",
+		},
+		"Sufficient": {
+			required: map[string][]string{
+				"secret/data/app":     {"read"},
+				"secret/metadata/app": {"read", "list"},
+			},
+			granted: map[string][]string{
+				"secret/data/app":     {"read", "update"},
+				"secret/metadata/app": {"list", "read"},
+			},
+			wantChecks: 2,
+			wantToken:  "kubernetes-token",
+		},
+		"Root": {
+			required:   map[string][]string{"secret/data/app": {"read"}},
+			granted:    map[string][]string{"secret/data/app": {"root"}},
+			wantChecks: 1,
+			wantToken:  "kubernetes-token",
+		},
+		"Insufficient": {
+			required: map[string][]string{
+				"secret/data/app":     {"read"},
+				"secret/metadata/app": {"read", "list"},
+			},
+			granted: map[string][]string{
+				"secret/data/app":     {"read"},
+				"secret/metadata/app": {"read"},
+			},
+			wantErr:     `token lacks required capabilities: "secret/metadata/app" requires [list], has [read]`,
+			wantChecks:  2,
+			wantRevoked: 1,
+		},
+		"Denied": {
+			required:    map[string][]string{"secret/data/app": {"read"}},
+			granted:     map[string][]string{"secret/data/app": {"deny"}},
+			wantErr:     `token lacks required capabilities: "secret/data/app" requires [read], has [deny]`,
+			wantChecks:  1,
+			wantRevoked: 1,
+		},
+		"NotGranted": {
+			required:    map[string][]string{"secret/data/app": {"read"}},
+			wantErr:     `token lacks required capabilities: "secret/data/app" requires [read], has none`,
+			wantChecks:  1,
+			wantRevoked: 1,
+		},
+		"ResolvedKVv2": {
+			required:   map[string][]string{"app": {"read"}},
+			resolve:    true,
+			version:    esv1.VaultKVStoreV2,
+			granted:    map[string][]string{"secret/data/app": {"read"}},
+			wantChecks: 1,
+			wantToken:  "kubernetes-token",
+		},
+		"ResolvedKVv1": {
+			required:   map[string][]string{"app": {"read"}},
+			resolve:    true,
+			version:    esv1.VaultKVStoreV1,
+			granted:    map[string][]string{"secret/app": {"read"}},
+			wantChecks: 1,
+			wantToken:  "kubernetes-token",
+		},
+		// the data path is checked, not the configured one.
+		"ResolvedInsufficient": {
+			required:    map[string][]string{"app": {"read"}},
+			resolve:     true,
+			version:     esv1.VaultKVStoreV2,
+			granted:     map[string][]string{"app": {"read"}},
+			wantErr:     `token lacks required capabilities: "secret/data/app" requires [read], has none`,
+			wantChecks:  1,
+			wantRevoked: 1,
+		},
+		"CheckFails": {
+			required:    map[string][]string{"secret/data/app": {"read"}},
+			checkErr:    errors.New("connection refused"),
+			wantErr:     `cannot check token capabilities on "secret/data/app": connection refused`,
+			wantChecks:  1,
+			wantRevoked: 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			checks := 0
+			revoked := 0
+			token := ""
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, &logins)
+			c.store.Auth.RequiredCapabilities = tc.required
+			c.store.Auth.ResolveCheckPaths = tc.resolve
+			c.store.Path = ptr.To("secret")
+			c.store.Version = tc.version
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					token = "kubernetes-token"
+					return &vault.Secret{}, nil
+				},
+			}
+			authToken := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					return makeTokenLookup(time.Hour, true), nil
+				},
+				RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+					revoked++
+					return nil
+				},
+			}
+			c.token = authToken
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				ClearTokenFunc:   func() { token = "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+				AuthTokenField:   authToken,
+				SysField: fake.Sys{
+					CapabilitiesSelfWithContextFn: func(ctx context.Context, path string) ([]string, error) {
+						checks++
+						return tc.granted[path], tc.checkErr
+					},
+				},
+			}
+
+			err := c.setAuth(context.Background(), nil)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if checks != tc.wantChecks {
+				t.Errorf("expected %d capability checks, got %d", tc.wantChecks, checks)
+			}
+			if revoked != tc.wantRevoked {
+				t.Errorf("expected %d revocations, got %d", tc.wantRevoked, revoked)
+			}
+			if token != tc.wantToken {
+				t.Errorf("expected token %q, got %q", tc.wantToken, token)
+			}
+		})
+	}
+}
@@ -15,24 +15,47 @@ limitations under the License.
 package vault
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"weak"
 
 	vault "github.com/hashicorp/vault/api"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
 	"github.com/external-secrets/external-secrets/pkg/constants"
 	"github.com/external-secrets/external-secrets/pkg/metrics"
 	"github.com/external-secrets/external-secrets/pkg/utils/resolvers"
 )
 
 const (
-	errVaultRequest = "error from Vault request: %w"
+	errVaultRequest        = "error from Vault request: %w"
+	errCertAuthNotYetValid = "client certificate in secret %q is not valid before %s"
 )
 
+// certAuthCerts holds a *certAuthCert per client certificate of cert auth.
+var certAuthCerts sync.Map
+
+// certAuthCert holds the client certificate read at the last cert auth
+// login, which all transports logging in with it present. Transports set up
+// for an earlier reconcile and kept by the client cache follow a rotated
+// certificate like those set up for the login.
+type certAuthCert struct {
+	mu   sync.Mutex
+	cert atomic.Pointer[tls.Certificate]
+	// transports are those presenting the certificate. They are only
+	// referenced weakly, so that transports no longer used are collected.
+	transports []weak.Pointer[http.Transport]
+}
+
 func setCertAuthToken(ctx context.Context, v *client, cfg *vault.Config) (bool, error) {
 	certAuth := v.store.Auth.Cert
 	if certAuth != nil {
@@ -46,6 +69,9 @@ func setCertAuthToken(ctx context.Context, v *client, cfg *vault.Config) (bool,
 }
 
 func (c *client) requestTokenWithCertAuth(ctx context.Context, certAuth *esv1.VaultCertAuth, cfg *vault.Config) error {
+	if err := c.checkLoginSecrets(ctx, &certAuth.SecretRef, &certAuth.ClientCert); err != nil {
+		return err
+	}
 	clientKey, err := resolvers.SecretKeyRef(ctx, c.kube, c.storeKind, c.namespace, &certAuth.SecretRef)
 	if err != nil {
 		return err
@@ -60,21 +86,105 @@ func (c *client) requestTokenWithCertAuth(ctx context.Context, certAuth *esv1.Va
 	if err != nil {
 		return fmt.Errorf(errClientTLSAuth, err)
 	}
-
-	if transport, ok := cfg.HttpClient.Transport.(*http.Transport); ok {
-		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	// Vault rejects certificates that aren't valid yet with a generic
+	// error, e.g. when a rotated certificate is issued for the future.
+	if notBefore := cert.Leaf.NotBefore; time.Now().Before(notBefore) {
+		return fmt.Errorf(errCertAuthNotYetValid, certAuth.ClientCert.Name, notBefore.Format(time.RFC3339))
 	}
+	current := c.certAuthCert(certAuth)
+	current.present(cfg)
+	current.rotate(c, &cert)
 
 	url := strings.Join([]string{"auth", "cert", "login"}, "/")
-	vaultResult, err := c.logical.WriteWithContext(ctx, url, nil)
-	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultWriteSecretData, err)
+	var vaultResult *vault.Secret
+	err = c.retryLogin(ctx, authMethodCert, func() error {
+		var loginErr error
+		vaultResult, loginErr = c.writeLogin(ctx, url, c.withLoginMetadata(nil))
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultWriteSecretData, loginErr)
+		return loginErr
+	})
 	if err != nil {
 		return fmt.Errorf(errVaultRequest, err)
 	}
-	token, err := vaultResult.TokenID()
-	if err != nil {
-		return fmt.Errorf(errVaultToken, err)
+	return c.setLoginToken(ctx, vaultResult)
+}
+
+// certAuthCert returns the client certificate of the cert auth, which is
+// shared by the stores logging in with the same Secrets.
+func (c *client) certAuthCert(certAuth *esv1.VaultCertAuth) *certAuthCert {
+	key := certAuthKey(c.storeKind, c.namespace, certAuth)
+	cert, _ := certAuthCerts.LoadOrStore(key, &certAuthCert{})
+	return cert.(*certAuthCert)
+}
+
+// certAuthKey identifies the Secrets the client certificate of the cert auth
+// is read from, it is empty without cert auth.
+func certAuthKey(storeKind, namespace string, certAuth *esv1.VaultCertAuth) string {
+	if certAuth == nil {
+		return ""
+	}
+	refNamespace := func(ref *esmeta.SecretKeySelector) string {
+		if storeKind == esv1.ClusterSecretStoreKind && ref.Namespace != nil {
+			return *ref.Namespace
+		}
+		return namespace
+	}
+	return strings.Join([]string{
+		storeKind,
+		refNamespace(&certAuth.ClientCert), certAuth.ClientCert.Name, certAuth.ClientCert.Key,
+		refNamespace(&certAuth.SecretRef), certAuth.SecretRef.Name, certAuth.SecretRef.Key,
+	}, "/")
+}
+
+// present makes the transport of the config present the certificate to
+// Vault, in place of the client certificate of the ClientTLS until the
+// first login.
+func (a *certAuthCert) present(cfg *vault.Config) {
+	if cfg == nil {
+		return
+	}
+	transport, ok := cfg.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.transports = slices.DeleteFunc(a.transports, func(t weak.Pointer[http.Transport]) bool {
+		return t.Value() == nil
+	})
+	if slices.Contains(a.transports, weak.Make(transport)) {
+		return
+	}
+	a.transports = append(a.transports, weak.Make(transport))
+	// connections set up before didn't present the certificate.
+	transport.CloseIdleConnections()
+	tlsConfig := transport.TLSClientConfig
+	clientTLS := tlsConfig.Certificates
+	tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		if cert := a.cert.Load(); cert != nil {
+			return cert, nil
+		}
+		if len(clientTLS) != 0 {
+			return &clientTLS[0], nil
+		}
+		return &tls.Certificate{}, nil
+	}
+}
+
+// rotate replaces the certificate. The idle connections of the transports
+// presenting a replaced certificate are closed, as Vault authenticates a
+// login with the certificate the connection was set up with.
+func (a *certAuthCert) rotate(c *client, cert *tls.Certificate) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	previous := a.cert.Swap(cert)
+	if previous == nil || slices.EqualFunc(previous.Certificate, cert.Certificate, bytes.Equal) {
+		return
+	}
+	c.log.Info("using rotated client certificate for cert auth", "serial", cert.Leaf.SerialNumber.String())
+	for _, t := range a.transports {
+		if transport := t.Value(); transport != nil {
+			transport.CloseIdleConnections()
+		}
 	}
-	c.client.SetToken(token)
-	return nil
 }
@@ -0,0 +1,169 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+)
+
+func TestCertAuthRotation(t *testing.T) {
+	var mu sync.Mutex
+	var presented []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/cert/login" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(r.TLS.PeerCertificates) == 0 {
+			presented = append(presented, "")
+		} else {
+			presented = append(presented, r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+		_, _ = w.Write([]byte(`{"auth": {"client_token": "cert-token", "lease_duration": 3600}}`))
+	}))
+	server.TLS = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAnyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	certSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cert-auth-rotation", Namespace: "default"},
+		Data:       makeSelfSignedClientCert(t, "first", time.Now().Add(-time.Minute)),
+	}
+	kube := clientfake.NewClientBuilder().WithObjects(certSecret).Build()
+	c := &client{
+		kube:      kube,
+		log:       logger,
+		namespace: "default",
+		storeKind: esv1.SecretStoreKind,
+		store: &esv1.VaultProvider{
+			Server:   server.URL,
+			CABundle: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+			Auth: &esv1.VaultAuth{
+				Cert: &esv1.VaultCertAuth{
+					ClientCert: esmeta.SecretKeySelector{Name: "cert-auth-rotation", Key: corev1.TLSCertKey},
+					SecretRef:  esmeta.SecretKeySelector{Name: "cert-auth-rotation", Key: corev1.TLSPrivateKeyKey},
+				},
+			},
+		},
+	}
+	ctx := context.Background()
+	cfg, err := c.newConfig(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.MaxRetries = 0
+	// the client is kept like one of the token cache.
+	vaultClient, err := NewVaultClient(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	login := func(cfg *vault.Config) error {
+		vaultClient.ClearToken()
+		login := *c
+		login.client = vaultClient
+		login.auth = vaultClient.Auth()
+		login.logical = vaultClient.Logical()
+		login.token = vaultClient.AuthToken()
+		return login.setAuth(ctx, cfg)
+	}
+	if err := login(cfg); err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+
+	certSecret.Data = makeSelfSignedClientCert(t, "second", time.Now().Add(-time.Minute))
+	if err := kube.Update(ctx, certSecret); err != nil {
+		t.Fatal(err)
+	}
+	// later reconciles log in the kept client with a config of their own.
+	later, err := c.newConfig(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := login(later); err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+
+	certSecret.Data = makeSelfSignedClientCert(t, "future", time.Now().Add(time.Hour))
+	if err := kube.Update(ctx, certSecret); err != nil {
+		t.Fatal(err)
+	}
+	if err := login(later); err == nil || !strings.Contains(err.Error(), `client certificate in secret "cert-auth-rotation" is not valid before`) {
+		t.Fatalf("expected the certificate not to be valid yet, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if diff := cmp.Diff([]string{"first", "second"}, presented); diff != "" {
+		t.Errorf("unexpected certificates presented to Vault (-want, +got):\n%s", diff)
+	}
+}
+
+// makeSelfSignedClientCert returns the data of a TLS Secret holding a
+// self-signed client certificate valid from notBefore.
+func makeSelfSignedClientCert(t *testing.T, commonName string, notBefore time.Time) map[string][]byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string][]byte{
+		corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+
+	vault "github.com/hashicorp/vault/api"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+)
+
+// correlationHeader is the header carrying the correlation ID of each
+// login, which Vault records in its audit log if the header is audited.
+// Disabled if empty.
+var correlationHeader string
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context whose Vault logins carry the ID in
+// the header configured with --vault-login-correlation-header, e.g. to
+// trace a request of an application embedding the provider to the login.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// correlationID returns the correlation ID of a login, which is the one
+// set on the context or the ID of the reconcile it is part of. It is empty
+// if correlation IDs are disabled.
+func correlationID(ctx context.Context) string {
+	if correlationHeader == "" {
+		return ""
+	}
+	if id, ok := ctx.Value(correlationIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	return string(controller.ReconcileIDFromContext(ctx))
+}
+
+// writeLogin sends a login request built here. With a correlation ID, it
+// is sent by a clone carrying the ID in its headers, so that other requests
+// of the shared client are unaffected.
+func (c *client) writeLogin(ctx context.Context, url string, parameters map[string]any) (*vault.Secret, error) {
+	id := correlationID(ctx)
+	if id == "" {
+		return c.logical.WriteWithContext(ctx, url, parameters)
+	}
+	correlated := c.client.WithNamespace(c.client.Namespace())
+	correlated.AddHeader(correlationHeader, id)
+	return correlated.Logical().WriteWithContext(ctx, url, parameters)
+}
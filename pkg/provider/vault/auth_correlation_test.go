@@ -0,0 +1,161 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+)
+
+func TestLoginCorrelationID(t *testing.T) {
+	defer func(header string) { correlationHeader = header }(correlationHeader)
+
+	appRole := esv1.VaultAuth{
+		AppRole: &esv1.VaultAppRole{
+			Path:   "approle",
+			RoleID: "role-id",
+			SecretRef: esmeta.SecretKeySelector{
+				Name: "login-secret",
+				Key:  "secret",
+			},
+		},
+	}
+	cases := map[string]struct {
+		header    string
+		id        string
+		auth      esv1.VaultAuth
+		loginPath string
+		wantID    string
+	}{
+		"AppRole": {
+			header:    "X-Correlation-ID",
+			id:        "request-1",
+			auth:      appRole,
+			loginPath: "/v1/auth/approle/login",
+			wantID:    "request-1",
+		},
+		// the login request of jwt auth is built by the provider.
+		"Jwt": {
+			header: "X-Correlation-ID",
+			id:     "request-1",
+			auth: esv1.VaultAuth{
+				Jwt: &esv1.VaultJwtAuth{
+					Path: "jwt",
+					Role: "role",
+					SecretRef: &esmeta.SecretKeySelector{
+						Name: "login-secret",
+						Key:  "secret",
+					},
+				},
+			},
+			loginPath: "/v1/auth/jwt/login",
+			wantID:    "request-1",
+		},
+		"NoID": {
+			header:    "X-Correlation-ID",
+			auth:      appRole,
+			loginPath: "/v1/auth/approle/login",
+		},
+		"Disabled": {
+			id:        "request-1",
+			auth:      appRole,
+			loginPath: "/v1/auth/approle/login",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			correlationHeader = tc.header
+
+			var loginIDs, readIDs []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case tc.loginPath:
+					loginIDs = append(loginIDs, r.Header.Get("X-Correlation-ID"))
+					_, _ = w.Write([]byte(`{"auth": {"client_token": "login-token", "lease_duration": 3600}}`))
+				case "/v1/secret/data/foo":
+					readIDs = append(readIDs, r.Header.Get("X-Correlation-ID"))
+					_, _ = w.Write([]byte(`{"data": {"data": {"foo": "bar"}}}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			cfg := vault.DefaultConfig()
+			cfg.Address = server.URL
+			vaultClient, err := NewVaultClient(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vaultClient.ClearToken()
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "login-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"secret": []byte("secret"),
+				},
+			}).Build()
+			auth := tc.auth
+			c := &client{
+				kube:      kube,
+				log:       logger,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				storeName: "vault-store",
+				store:     &esv1.VaultProvider{Auth: &auth},
+				client:    vaultClient,
+				auth:      vaultClient.Auth(),
+				logical:   vaultClient.Logical(),
+				token:     vaultClient.AuthToken(),
+			}
+
+			ctx := context.Background()
+			if tc.id != "" {
+				ctx = WithCorrelationID(ctx, tc.id)
+			}
+			if _, err := c.authenticate(ctx, cfg); err != nil {
+				t.Fatalf("unexpected login error: %v", err)
+			}
+			if diff := cmp.Diff([]string{tc.wantID}, loginIDs); diff != "" {
+				t.Errorf("unexpected correlation IDs of logins (-want, +got):\n%s", diff)
+			}
+			if token := c.client.Token(); token != "login-token" {
+				t.Errorf("expected the login token on the client, got %q", token)
+			}
+
+			// the correlation ID is only sent with logins.
+			if _, err := c.logical.ReadWithDataWithContext(ctx, "secret/data/foo", nil); err != nil {
+				t.Fatalf("unexpected read error: %v", err)
+			}
+			if diff := cmp.Diff([]string{""}, readIDs); diff != "" {
+				t.Errorf("unexpected correlation IDs of reads (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
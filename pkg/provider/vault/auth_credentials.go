@@ -0,0 +1,86 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/utils/resolvers"
+)
+
+// errCredentialsMissing is returned if the secrets referenced by a login
+// can't be read.
+var errCredentialsMissing = errors.New("missing credentials for login")
+
+// credentialsPendingRequeue is how soon a store waiting for its login
+// credentials to be created is checked again.
+const credentialsPendingRequeue = 15 * time.Second
+
+// prevalidateLoginSecrets checks that all secrets referenced by a login are
+// readable before any of them is used.
+var prevalidateLoginSecrets bool
+
+// checkLoginSecrets reads all referenced secret keys and returns a single
+// error naming each one that can't be read, so that a login isn't started
+// with only part of its credentials.
+func (c *client) checkLoginSecrets(ctx context.Context, refs ...*esmeta.SecretKeySelector) error {
+	if !prevalidateLoginSecrets {
+		return nil
+	}
+	var missing []string
+	for _, ref := range refs {
+		if _, err := resolvers.SecretKeyRef(ctx, c.kube, c.storeKind, c.namespace, ref); err != nil {
+			c.log.V(1).Info("cannot read login credential", "secret", ref.Name, "key", ref.Key, "error", err.Error())
+			missing = append(missing, fmt.Sprintf("secret %q key %q", c.secretRefNamespace(ref)+"/"+ref.Name, ref.Key))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", errCredentialsMissing, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// secretRefNamespace returns the namespace a secret reference is read from.
+func (c *client) secretRefNamespace(ref *esmeta.SecretKeySelector) string {
+	if c.storeKind == esv1.ClusterSecretStoreKind && ref.Namespace != nil {
+		return *ref.Namespace
+	}
+	return c.namespace
+}
+
+// pendingCredentials reports a login failing because its credentials don't
+// exist yet as pending, as long as the store is within the credentials
+// grace period of its auth. Once the grace period passed, the login fails.
+func pendingCredentials(store esv1.GenericStore, auth *esv1.VaultAuth, err error) error {
+	if auth == nil || auth.CredentialsGracePeriod == nil {
+		return err
+	}
+	if !errors.Is(err, errCredentialsMissing) && !apierrors.IsNotFound(err) {
+		return err
+	}
+	remaining := time.Until(store.GetObjectMeta().CreationTimestamp.Add(auth.CredentialsGracePeriod.Duration))
+	if remaining <= 0 {
+		return err
+	}
+	return &esv1.PendingError{Err: err, RequeueAfter: min(remaining, credentialsPendingRequeue)}
+}
@@ -0,0 +1,226 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+)
+
+func TestCheckLoginSecrets(t *testing.T) {
+	defer func(prevalidate bool) { prevalidateLoginSecrets = prevalidate }(prevalidateLoginSecrets)
+
+	cases := map[string]struct {
+		prevalidate bool
+		auth        esv1.VaultAuth
+		wantErr     string
+		wantLogins  int
+	}{
+		"AppRoleMissingRoleRef": {
+			prevalidate: true,
+			auth: esv1.VaultAuth{
+				AppRole: &esv1.VaultAppRole{
+					Path:      "approle",
+					RoleRef:   &esmeta.SecretKeySelector{Name: "approle-secret", Key: "role-id"},
+					SecretRef: esmeta.SecretKeySelector{Name: "approle-secret", Key: "secret-id"},
+				},
+			},
+			wantErr: `missing credentials for login: secret "default/approle-secret" key "role-id"`,
+		},
+		"CertMissingSecretAndKey": {
+			prevalidate: true,
+			auth: esv1.VaultAuth{
+				Cert: &esv1.VaultCertAuth{
+					SecretRef:  esmeta.SecretKeySelector{Name: "cert-secret", Key: "tls.key"},
+					ClientCert: esmeta.SecretKeySelector{Name: "approle-secret", Key: "tls.crt"},
+				},
+			},
+			wantErr: `missing credentials for login: secret "default/cert-secret" key "tls.key", secret "default/approle-secret" key "tls.crt"`,
+		},
+		"AllPresent": {
+			prevalidate: true,
+			auth: esv1.VaultAuth{
+				AppRole: &esv1.VaultAppRole{
+					Path:      "approle",
+					RoleID:    "role-id",
+					SecretRef: esmeta.SecretKeySelector{Name: "approle-secret", Key: "secret-id"},
+				},
+			},
+			wantLogins: 1,
+		},
+		// without prevalidation the first missing reference fails the login.
+		"Disabled": {
+			auth: esv1.VaultAuth{
+				AppRole: &esv1.VaultAppRole{
+					Path:      "approle",
+					RoleRef:   &esmeta.SecretKeySelector{Name: "approle-secret", Key: "role-id"},
+					SecretRef: esmeta.SecretKeySelector{Name: "approle-secret", Key: "secret-id"},
+				},
+			},
+			wantErr: `cannot find secret data for key: "role-id"`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			prevalidateLoginSecrets = tc.prevalidate
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "approle-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"secret-id": []byte("secret-id"),
+				},
+			}).Build()
+			logins := 0
+			c := &client{
+				kube:      kube,
+				log:       logger,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store:     &esv1.VaultProvider{Auth: &tc.auth},
+				auth: fake.Auth{
+					LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+						logins++
+						return &vault.Secret{}, nil
+					},
+				},
+			}
+
+			var err error
+			if tc.auth.Cert != nil {
+				err = c.requestTokenWithCertAuth(context.Background(), tc.auth.Cert, nil)
+			} else {
+				err = c.requestTokenWithAppRoleRef(context.Background(), tc.auth.AppRole)
+			}
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || err.Error() != tc.wantErr) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, logins)
+			}
+		})
+	}
+}
+
+func TestCredentialsGracePeriod(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token", "lease_duration": 3600}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cases := map[string]struct {
+		gracePeriod *metav1.Duration
+		age         time.Duration
+		wantPending bool
+		wantRequeue time.Duration
+	}{
+		"NoGracePeriod": {},
+		"WithinGracePeriod": {
+			gracePeriod: &metav1.Duration{Duration: 10 * time.Minute},
+			age:         time.Minute,
+			wantPending: true,
+			wantRequeue: credentialsPendingRequeue,
+		},
+		// the store is checked again when the grace period ends.
+		"EndOfGracePeriod": {
+			gracePeriod: &metav1.Duration{Duration: 10 * time.Minute},
+			age:         10*time.Minute - 5*time.Second,
+			wantPending: true,
+			wantRequeue: 5 * time.Second,
+		},
+		"GracePeriodElapsed": {
+			gracePeriod: &metav1.Duration{Duration: 10 * time.Minute},
+			age:         11 * time.Minute,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kube := clientfake.NewClientBuilder().Build()
+			auth := makeAppRoleAuth("grace-role-id")
+			auth.CredentialsGracePeriod = tc.gracePeriod
+			store := &esv1.SecretStore{
+				TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "vault-store",
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(time.Now().Add(-tc.age)),
+				},
+				Spec: esv1.SecretStoreSpec{
+					Provider: &esv1.SecretStoreProvider{
+						Vault: &esv1.VaultProvider{
+							Server: server.URL,
+							Auth:   &auth,
+						},
+					},
+				},
+			}
+			prov := &Provider{NewVaultClient: NewVaultClient}
+			_, err := prov.newClient(context.Background(), store, kube, nil, "default")
+			if err == nil {
+				t.Fatal("expected the login to fail without its secret")
+			}
+			var pending *esv1.PendingError
+			if errors.As(err, &pending) != tc.wantPending {
+				t.Fatalf("expected pending: %t, got %v", tc.wantPending, err)
+			}
+			if !tc.wantPending {
+				return
+			}
+			// the requeue is rounded, as time passes during the login.
+			if got := pending.RequeueAfter.Round(time.Second); got != tc.wantRequeue {
+				t.Errorf("expected a requeue after %s, got %s", tc.wantRequeue, got)
+			}
+
+			// the store is ready once the secret appears.
+			if err := kube.Create(context.Background(), &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "approle-secret", Namespace: "default"},
+				Data:       map[string][]byte{"secret-id": []byte("secret-id")},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			c, err := prov.newClient(context.Background(), store, kube, nil, "default")
+			if err != nil {
+				t.Fatalf("unexpected error once the secret exists: %v", err)
+			}
+			if token := c.(*client).client.Token(); token != "approle-token" {
+				t.Errorf("expected token %q, got %q", "approle-token", token)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// recentAuth is a successful auth of a store with a token.
+type recentAuth struct {
+	at             time.Time
+	tokenNamespace *string
+}
+
+var (
+	// loginDedupWindow is how long a successful auth of a store lets further
+	// auths with the same token skip checking it. Disabled if zero.
+	loginDedupWindow time.Duration
+
+	recentAuthsMu sync.Mutex
+	recentAuths   = map[string]recentAuth{}
+)
+
+// dedupWindow returns loginDedupWindow, capped at the threshold within which
+// tokens of the store are treated as expired, so that a token checked at the
+// start of the window is still valid at its end.
+func (c *client) dedupWindow() time.Duration {
+	return min(loginDedupWindow, c.expiryThreshold())
+}
+
+// recentAuthKey identifies the store and its configuration along with the
+// token, as the checks done on auth depend on both.
+func (c *client) recentAuthKey() (string, bool) {
+	token := c.client.Token()
+	if token == "" {
+		return "", false
+	}
+	key, err := c.authFailureKey()
+	if err != nil {
+		return "", false
+	}
+	return key + "/" + tokenKey(token), true
+}
+
+// recentlyAuthenticated reports whether the store authenticated with the
+// current token within the dedup window, in which case the token is used
+// as is.
+func (c *client) recentlyAuthenticated() bool {
+	if loginDedupWindow <= 0 {
+		return false
+	}
+	key, ok := c.recentAuthKey()
+	if !ok {
+		return false
+	}
+	recentAuthsMu.Lock()
+	auth, ok := recentAuths[key]
+	recentAuthsMu.Unlock()
+	if !ok || time.Since(auth.at) >= c.dedupWindow() {
+		return false
+	}
+	c.tokenNamespace = auth.tokenNamespace
+	c.log.V(1).Info("Re-using token of recent auth")
+	return true
+}
+
+// recordAuth remembers a successful auth of the store with the current
+// token for the dedup window.
+func (c *client) recordAuth() {
+	if loginDedupWindow <= 0 {
+		return
+	}
+	key, ok := c.recentAuthKey()
+	if !ok {
+		return
+	}
+	recentAuthsMu.Lock()
+	defer recentAuthsMu.Unlock()
+	for k, auth := range recentAuths {
+		if time.Since(auth.at) >= loginDedupWindow {
+			delete(recentAuths, k)
+		}
+	}
+	recentAuths[key] = recentAuth{at: time.Now(), tokenNamespace: c.tokenNamespace}
+}
+
+// forgetRecentAuths drops the recent auths with a token that is no longer
+// used.
+func forgetRecentAuths(token string) {
+	recentAuthsMu.Lock()
+	defer recentAuthsMu.Unlock()
+	suffix := "/" + tokenKey(token)
+	for k := range recentAuths {
+		if strings.HasSuffix(k, suffix) {
+			delete(recentAuths, k)
+		}
+	}
+}
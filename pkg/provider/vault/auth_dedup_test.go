@@ -0,0 +1,108 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLoginDedupWindow(t *testing.T) {
+	defer func(window time.Duration) { loginDedupWindow = window }(loginDedupWindow)
+
+	cases := map[string]struct {
+		window time.Duration
+		// age is how long ago the first auth happened.
+		age        time.Duration
+		otherStore bool
+		want       renewCounters
+	}{
+		"Disabled": {
+			want: renewCounters{lookups: 2},
+		},
+		"WithinWindow": {
+			window: 5 * time.Second,
+			age:    time.Second,
+			want:   renewCounters{lookups: 1},
+		},
+		"AfterWindow": {
+			window: 5 * time.Second,
+			age:    10 * time.Second,
+			want:   renewCounters{lookups: 2},
+		},
+		"CappedWindow": {
+			window: time.Hour,
+			age:    2 * tokenExpiryThreshold,
+			want:   renewCounters{lookups: 2},
+		},
+		"OtherStore": {
+			window:     5 * time.Second,
+			otherStore: true,
+			want:       renewCounters{lookups: 2},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			loginDedupWindow = tc.window
+			t.Cleanup(func() { recentAuths = map[string]recentAuth{} })
+			ctx := context.Background()
+			counters := renewCounters{}
+			lookup := makeTokenLookup(time.Hour, false)
+
+			if err := makeRenewClient(t, lookup, nil, &counters).setAuth(ctx, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			recentAuthsMu.Lock()
+			for k, auth := range recentAuths {
+				auth.at = auth.at.Add(-tc.age)
+				recentAuths[k] = auth
+			}
+			recentAuthsMu.Unlock()
+
+			c := makeRenewClient(t, lookup, nil, &counters)
+			if tc.otherStore {
+				c.storeName = "other-store"
+			}
+			if err := c.setAuth(ctx, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if counters != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, counters)
+			}
+		})
+	}
+}
+
+func TestLoginDedupWindowRevokedToken(t *testing.T) {
+	defer func(window time.Duration) { loginDedupWindow = window }(loginDedupWindow)
+	loginDedupWindow = 5 * time.Second
+	t.Cleanup(func() { recentAuths = map[string]recentAuth{} })
+
+	ctx := context.Background()
+	counters := renewCounters{}
+	c := makeRenewClient(t, makeTokenLookup(time.Hour, false), nil, &counters)
+	if err := c.setAuth(ctx, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	forgetRecentAuths("current-token")
+	if err := c.setAuth(ctx, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if counters.lookups != 2 {
+		t.Errorf("expected the token to be looked up again once forgotten, got %d lookups", counters.lookups)
+	}
+}
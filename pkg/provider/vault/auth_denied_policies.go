@@ -0,0 +1,91 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errDeniedPolicies       = "token carries denied policies %s"
+	errDeniedPoliciesLookup = "cannot look up the policies of the token: %w"
+)
+
+// checkDeniedPolicies checks that the token of a fresh login carries none
+// of the policies denied by the store. Each denied policy is counted; with
+// "warn" they are only logged, otherwise the token is dropped like after a
+// failed canary read. A warning check never fails the login, not even if
+// the policies of the token can't be looked up.
+func (c *client) checkDeniedPolicies(ctx context.Context) error {
+	denied := c.store.Auth.DeniedPolicies
+	if len(denied) == 0 {
+		return nil
+	}
+	warn := c.store.Auth.DeniedPoliciesAction == esv1.VaultDeniedPoliciesActionWarn
+	policies, err := c.tokenPolicies(ctx)
+	if err != nil {
+		if warn {
+			c.log.Error(err, "unable to check the token for denied policies")
+			return nil
+		}
+		c.dropLoginToken(ctx, "failed denied policies check")
+		return fmt.Errorf(errDeniedPoliciesLookup, err)
+	}
+
+	var found []string
+	for _, policy := range denied {
+		// Vault lowercases policy names.
+		policy = strings.ToLower(policy)
+		if slices.Contains(policies, policy) && !slices.Contains(found, policy) {
+			found = append(found, policy)
+			metrics.ObserveAuthDeniedPolicy(constants.ProviderHCVault, policy)
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	if warn {
+		c.log.Info("token carries denied policies", "policies", found)
+		return nil
+	}
+	c.dropLoginToken(ctx, "denied policies")
+	quoted := make([]string, len(found))
+	for i, policy := range found {
+		quoted[i] = fmt.Sprintf("%q", policy)
+	}
+	return fmt.Errorf(errDeniedPolicies, strings.Join(quoted, ", "))
+}
+
+// tokenPolicies returns the token and identity policies of the token, as
+// returned by the login, or by a lookup if the login didn't return them.
+func (c *client) tokenPolicies(ctx context.Context) ([]string, error) {
+	if auth := c.loginAuth; auth != nil && len(auth.Policies)+len(auth.IdentityPolicies) > 0 {
+		return append(slices.Clone(auth.Policies), auth.IdentityPolicies...), nil
+	}
+	lookup, err := c.tokenAPI().LookupSelfWithContext(ctx)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLookupSelf, err)
+	if err != nil {
+		return nil, err
+	}
+	// the policies of a lookup include those of the identity.
+	return lookup.TokenPolicies()
+}
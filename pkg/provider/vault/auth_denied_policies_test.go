@@ -0,0 +1,172 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestDeniedPolicies(t *testing.T) {
+	cases := map[string]struct {
+		denied []string
+		action esv1.VaultDeniedPoliciesAction
+		// loginAuth is the auth data returned by the login, the policies are
+		// looked up if it has none.
+		loginAuth        *vault.SecretAuth
+		lookupPolicies   []any
+		lookupIdentities []any
+		lookupErr        error
+		wantErr          string
+		// revoking the token looks it up first.
+		wantLookups int
+		wantRevoked int
+		wantToken   string
+	}{
+		"NoDeniedPolicies": {
+			loginAuth: &vault.SecretAuth{Policies: []string{"default", "root"}},
+			wantToken: "kubernetes-token",
+		},
+		"NotCarried": {
+			denied:    []string{"root", "admin"},
+			loginAuth: &vault.SecretAuth{Policies: []string{"default", "app"}},
+			wantToken: "kubernetes-token",
+		},
+		"Fail": {
+			denied:      []string{"root", "admin"},
+			loginAuth:   &vault.SecretAuth{Policies: []string{"default", "root"}},
+			wantErr:     `token carries denied policies "root"`,
+			wantLookups: 1,
+			wantRevoked: 1,
+		},
+		"Warn": {
+			denied:    []string{"root", "admin"},
+			action:    esv1.VaultDeniedPoliciesActionWarn,
+			loginAuth: &vault.SecretAuth{Policies: []string{"default", "root"}},
+			wantToken: "kubernetes-token",
+		},
+		"IdentityPolicy": {
+			denied:      []string{"admin"},
+			loginAuth:   &vault.SecretAuth{Policies: []string{"default"}, IdentityPolicies: []string{"admin"}},
+			wantErr:     `token carries denied policies "admin"`,
+			wantLookups: 1,
+			wantRevoked: 1,
+		},
+		// Vault lowercases policy names.
+		"MixedCase": {
+			denied:      []string{"Root"},
+			loginAuth:   &vault.SecretAuth{Policies: []string{"root"}},
+			wantErr:     `token carries denied policies "root"`,
+			wantLookups: 1,
+			wantRevoked: 1,
+		},
+		// the token is dropped, but a root token is never revoked.
+		"LookedUp": {
+			denied:           []string{"root", "admin"},
+			lookupPolicies:   []any{"default", "root"},
+			lookupIdentities: []any{"admin"},
+			wantErr:          `token carries denied policies "root", "admin"`,
+			wantLookups:      2,
+		},
+		// a token that can't be looked up isn't revoked either.
+		"LookupFails": {
+			denied:      []string{"root"},
+			lookupErr:   errors.New("permission denied"),
+			wantErr:     "cannot look up the policies of the token",
+			wantLookups: 2,
+		},
+		"LookupFailsWarn": {
+			denied:      []string{"root"},
+			action:      esv1.VaultDeniedPoliciesActionWarn,
+			lookupErr:   errors.New("permission denied"),
+			wantLookups: 1,
+			wantToken:   "kubernetes-token",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			lookups := 0
+			revoked := 0
+			token := ""
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, &logins)
+			c.store.Auth.DeniedPolicies = tc.denied
+			c.store.Auth.DeniedPoliciesAction = tc.action
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					logins++
+					token = "kubernetes-token"
+					return &vault.Secret{Auth: tc.loginAuth}, nil
+				},
+			}
+			authToken := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					lookups++
+					if tc.lookupErr != nil {
+						return nil, tc.lookupErr
+					}
+					lookup := makeTokenLookup(time.Hour, true)
+					lookup.Data["policies"] = tc.lookupPolicies
+					lookup.Data["identity_policies"] = tc.lookupIdentities
+					return lookup, nil
+				},
+				RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+					revoked++
+					return nil
+				},
+			}
+			c.token = authToken
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				ClearTokenFunc:   func() { token = "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+				AuthTokenField:   authToken,
+			}
+
+			err := c.setAuth(context.Background(), nil)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+			if lookups != tc.wantLookups {
+				t.Errorf("expected %d lookups, got %d", tc.wantLookups, lookups)
+			}
+			if revoked != tc.wantRevoked {
+				t.Errorf("expected %d revocations, got %d", tc.wantRevoked, revoked)
+			}
+			if token != tc.wantToken {
+				t.Errorf("expected token %q, got %q", tc.wantToken, token)
+			}
+		})
+	}
+}
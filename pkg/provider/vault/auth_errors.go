@@ -0,0 +1,208 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	vault "github.com/hashicorp/vault/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	// vaultSealedMessage is returned by Vault for requests while it is sealed.
+	vaultSealedMessage = "Vault is sealed"
+	// vaultNamespaceNotFoundMessage is returned by Vault Enterprise for
+	// requests to a namespace that doesn't exist.
+	vaultNamespaceNotFoundMessage = "namespace not found"
+
+	errRoleNotFound = "role of the %s auth method was not found in Vault, it may have been deleted: %w"
+)
+
+// vaultRoleNotFound matches the errors of auth methods whose role doesn't
+// exist, e.g. `invalid role name "demo"` on a Kubernetes login, or `role
+// "demo" does not exist during renewal` when renewing a token of a deleted
+// AppRole role. AppRole logins only report an "invalid role or secret ID",
+// which doesn't tell the deleted role from a wrong secret ID.
+var vaultRoleNotFound = regexp.MustCompile(`(?i)role not found|invalid role (name|id)\b|\brole\b.*\b(does not exist|could not be found)`)
+
+// roleNotFoundError is returned by logins failing because the role of the
+// auth method doesn't exist anymore.
+type roleNotFoundError struct {
+	method string
+	err    error
+}
+
+func (e *roleNotFoundError) Error() string {
+	return fmt.Errorf(errRoleNotFound, e.method, e.err).Error()
+}
+
+func (e *roleNotFoundError) Unwrap() error {
+	return e.err
+}
+
+var (
+	rolesNotFoundMu sync.Mutex
+	// rolesNotFound holds the tokens whose renewal failed because their
+	// role doesn't exist anymore, so that the failed login replacing them
+	// is reported as such.
+	rolesNotFound = map[string]struct{}{}
+)
+
+// isRoleNotFound reports whether Vault failed the request because the role
+// of the auth method doesn't exist.
+func isRoleNotFound(err error) bool {
+	var respErr *vault.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	for _, e := range respErr.Errors {
+		if vaultRoleNotFound.MatchString(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRenewalRoleNotFound remembers the token if its renewal failed
+// because its role doesn't exist anymore. It reports whether it did.
+func checkRenewalRoleNotFound(token string, err error) bool {
+	if !isRoleNotFound(err) {
+		return false
+	}
+	rolesNotFoundMu.Lock()
+	defer rolesNotFoundMu.Unlock()
+	rolesNotFound[token] = struct{}{}
+	return true
+}
+
+// renewalRoleNotFound reports whether the renewal of the token failed
+// because its role doesn't exist anymore, and forgets the token.
+func renewalRoleNotFound(token string) bool {
+	rolesNotFoundMu.Lock()
+	defer rolesNotFoundMu.Unlock()
+	_, ok := rolesNotFound[token]
+	delete(rolesNotFound, token)
+	return ok
+}
+
+// wrapRoleNotFound returns a roleNotFoundError for a failed login with the
+// named auth method if its role doesn't exist, either according to the
+// response or because the renewal of the replaced token said so.
+func (c *client) wrapRoleNotFound(method, replaced string, err error) error {
+	deleted := renewalRoleNotFound(replaced)
+	if err == nil {
+		return nil
+	}
+	if isRoleNotFound(err) || (deleted && c.classifyLoginError(method, err) == esv1.VaultAuthErrorClassAuthRejected) {
+		return &roleNotFoundError{method: method, err: err}
+	}
+	return err
+}
+
+// classifyLoginError classifies a failed login with the named auth method.
+// The status mapping of the store takes precedence over the defaults:
+// server errors and rate limiting are transient, other responses reject the
+// login. Errors without a response, like connection errors, are transient.
+func (c *client) classifyLoginError(method string, err error) esv1.VaultAuthErrorClass {
+	// the role keeps issuing tokens of the same type.
+	var typeErr *tokenTypeError
+	if errors.As(err, &typeErr) {
+		return esv1.VaultAuthErrorClassAuthRejected
+	}
+	var respErr *vault.ResponseError
+	if !errors.As(err, &respErr) {
+		return esv1.VaultAuthErrorClassTransient
+	}
+	if c.store.Auth != nil {
+		for _, mapping := range c.store.Auth.StatusMapping {
+			if mapping.StatusCode != respErr.StatusCode {
+				continue
+			}
+			if mapping.Method == "" || selectionMethods[mapping.Method] == method {
+				return mapping.Class
+			}
+		}
+	}
+	switch {
+	// a missing role won't come back on its own, even if Vault reports it
+	// as a server error.
+	case isRoleNotFound(respErr):
+		return esv1.VaultAuthErrorClassAuthRejected
+	case respErr.StatusCode == http.StatusServiceUnavailable && isSealedResponse(respErr):
+		return esv1.VaultAuthErrorClassSealed
+	case respErr.StatusCode >= http.StatusInternalServerError, respErr.StatusCode == http.StatusTooManyRequests:
+		return esv1.VaultAuthErrorClassTransient
+	}
+	return esv1.VaultAuthErrorClassAuthRejected
+}
+
+// isRetryableLoginError returns whether a failed login with the named auth
+// method may succeed when repeated, i.e. unless Vault rejected it.
+func (c *client) isRetryableLoginError(method string) func(error) bool {
+	return func(err error) bool {
+		return c.classifyLoginError(method, err) != esv1.VaultAuthErrorClassAuthRejected
+	}
+}
+
+// authFailureReason returns the reason a failed login is recorded with in
+// the auth failure metric.
+func authFailureReason(err error) string {
+	var respErr *vault.ResponseError
+	var roleErr *roleNotFoundError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &roleErr):
+		return metrics.AuthFailureRoleNotFound
+	case errors.As(err, &respErr):
+		switch {
+		case respErr.StatusCode == http.StatusServiceUnavailable && isSealedResponse(respErr):
+			return metrics.AuthFailureSealed
+		case responseContains(respErr, vaultNamespaceNotFoundMessage):
+			return metrics.AuthFailureNamespaceNotFound
+		case respErr.StatusCode == http.StatusForbidden, respErr.StatusCode == http.StatusUnauthorized:
+			return metrics.AuthFailurePermissionDenied
+		}
+	case errors.Is(err, errCredentialsMissing), apierrors.IsNotFound(err):
+		return metrics.AuthFailureCredentialMissing
+	case errors.As(err, &netErr), errors.Is(err, context.DeadlineExceeded):
+		return metrics.AuthFailureNetwork
+	}
+	return metrics.AuthFailureOther
+}
+
+func isSealedResponse(respErr *vault.ResponseError) bool {
+	return responseContains(respErr, vaultSealedMessage)
+}
+
+// responseContains reports whether any error of the response contains s.
+func responseContains(respErr *vault.ResponseError, s string) bool {
+	for _, e := range respErr.Errors {
+		if strings.Contains(e, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,332 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	vault "github.com/hashicorp/vault/api"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestClassifyLoginError(t *testing.T) {
+	mapping := []esv1.VaultAuthStatusMapping{
+		{Method: esv1.VaultAuthMethodAppRole, StatusCode: http.StatusTeapot, Class: esv1.VaultAuthErrorClassSealed},
+		{StatusCode: http.StatusTeapot, Class: esv1.VaultAuthErrorClassTransient},
+	}
+
+	cases := map[string]struct {
+		method  string
+		mapping []esv1.VaultAuthStatusMapping
+		err     error
+		want    esv1.VaultAuthErrorClass
+	}{
+		"ConnectionError": {
+			err:  errors.New("dial tcp: connection refused"),
+			want: esv1.VaultAuthErrorClassTransient,
+		},
+		"PermissionDenied": {
+			err:  &vault.ResponseError{StatusCode: http.StatusForbidden},
+			want: esv1.VaultAuthErrorClassAuthRejected,
+		},
+		"RateLimited": {
+			err:  &vault.ResponseError{StatusCode: http.StatusTooManyRequests},
+			want: esv1.VaultAuthErrorClassTransient,
+		},
+		"Sealed": {
+			err:  fmt.Errorf("login failed: %w", &vault.ResponseError{StatusCode: http.StatusServiceUnavailable, Errors: []string{"error performing token check: Vault is sealed"}}),
+			want: esv1.VaultAuthErrorClassSealed,
+		},
+		"Unavailable": {
+			err:  &vault.ResponseError{StatusCode: http.StatusServiceUnavailable},
+			want: esv1.VaultAuthErrorClassTransient,
+		},
+		"UnmappedStatus": {
+			err:  &vault.ResponseError{StatusCode: http.StatusTeapot},
+			want: esv1.VaultAuthErrorClassAuthRejected,
+		},
+		"RoleNotFound": {
+			err:  &vault.ResponseError{StatusCode: http.StatusInternalServerError, Errors: []string{`role "demo" does not exist during renewal`}},
+			want: esv1.VaultAuthErrorClassAuthRejected,
+		},
+		"MappedStatus": {
+			method:  authMethodKubernetes,
+			mapping: mapping,
+			err:     &vault.ResponseError{StatusCode: http.StatusTeapot},
+			want:    esv1.VaultAuthErrorClassTransient,
+		},
+		"MappedStatusForMethod": {
+			method:  authMethodAppRole,
+			mapping: mapping,
+			err:     &vault.ResponseError{StatusCode: http.StatusTeapot},
+			want:    esv1.VaultAuthErrorClassSealed,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &client{
+				store: &esv1.VaultProvider{
+					Auth: &esv1.VaultAuth{StatusMapping: tc.mapping},
+				},
+			}
+			if got := c.classifyLoginError(tc.method, tc.err); got != tc.want {
+				t.Errorf("expected class %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+// authMetricsOnce registers the auth metrics once per test binary.
+var authMetricsOnce sync.Once
+
+func TestSetAuthRecordsFailureReason(t *testing.T) {
+	authMetricsOnce.Do(func() { metrics.SetUpAuthMetrics("") })
+
+	cases := map[string]struct {
+		loginErr   error
+		secretName string
+		want       string
+	}{
+		"Sealed": {
+			loginErr: &vault.ResponseError{StatusCode: http.StatusServiceUnavailable, Errors: []string{"Vault is sealed"}},
+			want:     metrics.AuthFailureSealed,
+		},
+		"PermissionDenied": {
+			loginErr: &vault.ResponseError{StatusCode: http.StatusForbidden, Errors: []string{"permission denied"}},
+			want:     metrics.AuthFailurePermissionDenied,
+		},
+		"NamespaceNotFound": {
+			loginErr: &vault.ResponseError{StatusCode: http.StatusNotFound, Errors: []string{"namespace not found"}},
+			want:     metrics.AuthFailureNamespaceNotFound,
+		},
+		"Network": {
+			loginErr: &url.Error{Op: "Put", URL: "https://vault.example.com", Err: errors.New("connection refused")},
+			want:     metrics.AuthFailureNetwork,
+		},
+		"CredentialMissing": {
+			secretName: "missing-secret",
+			want:       metrics.AuthFailureCredentialMissing,
+		},
+		"RoleNotFound": {
+			loginErr: &vault.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{`invalid role name "kubernetes-auth-role"`}},
+			want:     metrics.AuthFailureRoleNotFound,
+		},
+		"Other": {
+			loginErr: &vault.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{"missing client token"}},
+			want:     metrics.AuthFailureOther,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			kubernetesAuth := &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), kubernetesAuth, &logins)
+			if tc.secretName != "" {
+				kubernetesAuth.SecretRef.Name = tc.secretName
+			}
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					return nil, tc.loginErr
+				},
+			}
+			c.logical = fake.Logical{
+				ReadWithDataWithContextFn: fake.NewReadWithContextFn(nil, errors.New("permission denied")),
+			}
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+			}
+
+			before := authFailureCount(t)
+			if err := c.setAuth(context.Background(), nil); err == nil {
+				t.Fatal("expected the login to fail")
+			}
+			after := authFailureCount(t)
+			for reason, count := range after {
+				want := before[reason]
+				if reason == tc.want {
+					want++
+				}
+				if count != want {
+					t.Errorf("expected %v failures with reason %q, got %v", want, reason, count)
+				}
+			}
+			if _, ok := after[tc.want]; !ok {
+				t.Errorf("expected a failure with reason %q, got %v", tc.want, after)
+			}
+		})
+	}
+}
+
+// authFailureCount returns the number of recorded Vault auth failures by
+// reason.
+func authFailureCount(t *testing.T) map[string]float64 {
+	t.Helper()
+	families, err := ctrlmetrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]float64{}
+	for _, f := range families {
+		if f.GetName() != "externalsecret_provider_auth_failure_count" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["provider"] == constants.ProviderHCVault {
+				counts[labels["reason"]] = m.GetCounter().GetValue()
+			}
+		}
+	}
+	return counts
+}
+
+func TestIsRoleNotFound(t *testing.T) {
+	cases := map[string]struct {
+		err  error
+		want bool
+	}{
+		"KubernetesLogin": {
+			err:  &vault.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{`invalid role name "demo"`}},
+			want: true,
+		},
+		"AppRoleRenewal": {
+			err:  fmt.Errorf("renewal failed: %w", &vault.ResponseError{StatusCode: http.StatusInternalServerError, Errors: []string{`role "demo" does not exist during renewal`}}),
+			want: true,
+		},
+		"JwtLogin": {
+			err:  &vault.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{`role "demo" could not be found`}},
+			want: true,
+		},
+		"LegacyAppRoleLogin": {
+			err:  &vault.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{"invalid role ID"}},
+			want: true,
+		},
+		// AppRole logins don't tell a deleted role from a wrong secret ID.
+		"AppRoleLogin": {
+			err: &vault.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{"invalid role or secret ID"}},
+		},
+		"PermissionDenied": {
+			err: &vault.ResponseError{StatusCode: http.StatusForbidden, Errors: []string{"permission denied"}},
+		},
+		"NoResponse": {
+			err: errors.New(`invalid role name "demo"`),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := isRoleNotFound(tc.err); got != tc.want {
+				t.Errorf("expected %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRoleNotFoundAfterRenewal(t *testing.T) {
+	authMetricsOnce.Do(func() { metrics.SetUpAuthMetrics("") })
+	defer func(renew bool) { renewExpiringTokens = renew }(renewExpiringTokens)
+	renewExpiringTokens = true
+
+	cases := map[string]struct {
+		renewErr error
+		loginErr error
+		want     string
+	}{
+		// the login of a deleted AppRole role is only rejected as an
+		// invalid role or secret ID.
+		"RoleDeleted": {
+			renewErr: &vault.ResponseError{StatusCode: http.StatusInternalServerError, Errors: []string{`role "approle" does not exist during renewal`}},
+			loginErr: &vault.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{"invalid role or secret ID"}},
+			want:     metrics.AuthFailureRoleNotFound,
+		},
+		"RoleDeletedLoginUnavailable": {
+			renewErr: &vault.ResponseError{StatusCode: http.StatusInternalServerError, Errors: []string{`role "approle" does not exist during renewal`}},
+			loginErr: &vault.ResponseError{StatusCode: http.StatusServiceUnavailable, Errors: []string{"Vault is sealed"}},
+			want:     metrics.AuthFailureSealed,
+		},
+		"RenewalFailed": {
+			renewErr: &vault.ResponseError{StatusCode: http.StatusForbidden, Errors: []string{"permission denied"}},
+			loginErr: &vault.ResponseError{StatusCode: http.StatusBadRequest, Errors: []string{"invalid role or secret ID"}},
+			want:     metrics.AuthFailureOther,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, &logins)
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					return nil, tc.loginErr
+				},
+			}
+			authToken := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					return makeTokenLookup(30*time.Second, true), nil
+				},
+				RenewSelfWithContextFn: func(ctx context.Context, increment int) (*vault.Secret, error) {
+					return nil, tc.renewErr
+				},
+			}
+			c.token = authToken
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return "expiring-token-" + name },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+				AuthTokenField:   authToken,
+			}
+
+			before := authFailureCount(t)
+			err := c.setAuth(context.Background(), nil)
+			if err == nil {
+				t.Fatal("expected the login to fail")
+			}
+			var roleErr *roleNotFoundError
+			if wantRoleErr := tc.want == metrics.AuthFailureRoleNotFound; errors.As(err, &roleErr) != wantRoleErr {
+				t.Errorf("expected role not found error: %t, got %v", wantRoleErr, err)
+			}
+			if got := authFailureCount(t)[tc.want] - before[tc.want]; got != 1 {
+				t.Errorf("expected a failure with reason %q, got %v", tc.want, got)
+			}
+		})
+	}
+}
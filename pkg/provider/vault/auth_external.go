@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const (
+	errExternalTokenUnset = "no token has been delivered by the application managing the vault token yet"
+)
+
+// authMethodExternal is the auth method reported for clients using an
+// ExternalToken.
+const authMethodExternal = "external"
+
+// ExternalToken is a Vault token managed by an application embedding
+// external-secrets. Clients of a Provider with an ExternalToken use its
+// current token instead of logging in with the store's auth, and are
+// switched over to every new token as it is delivered. The token is never
+// renewed or revoked by the clients.
+type ExternalToken struct {
+	mu      sync.Mutex
+	token   string
+	clients map[*client]struct{}
+}
+
+// NewExternalToken returns an ExternalToken without a token. Clients fail to
+// authenticate until the first token is delivered with Set or Watch.
+func NewExternalToken() *ExternalToken {
+	return &ExternalToken{clients: map[*client]struct{}{}}
+}
+
+// Set replaces the token. Clients using the token are updated immediately.
+func (t *ExternalToken) Set(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.token = token
+	for c := range t.clients {
+		c.client.SetToken(token)
+	}
+}
+
+// Watch sets every token received on the channel, until the channel is
+// closed or the context is done.
+func (t *ExternalToken) Watch(ctx context.Context, tokens <-chan string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case token, ok := <-tokens:
+			if !ok {
+				return
+			}
+			t.Set(token)
+		}
+	}
+}
+
+// use sets the current token on the client and keeps it updated until the
+// client is released.
+func (t *ExternalToken) use(c *client) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token == "" {
+		return errors.New(errExternalTokenUnset)
+	}
+	c.client.SetToken(t.token)
+	c.authMethod = authMethodExternal
+	t.clients[c] = struct{}{}
+	return nil
+}
+
+// release stops updating the token of the client.
+func (t *ExternalToken) release(c *client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.clients, c)
+}
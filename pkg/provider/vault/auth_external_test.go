@@ -0,0 +1,125 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestExternalToken(t *testing.T) {
+	var mu sync.Mutex
+	var reads, logins, revoked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		token := r.Header.Get("X-Vault-Token")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/auth/token/revoke"):
+			revoked = append(revoked, token)
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasPrefix(r.URL.Path, "/v1/auth/"):
+			logins = append(logins, r.URL.Path)
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			reads = append(reads, token)
+			_, _ = w.Write([]byte(`{"data": {"data": {"value": "secret"}}}`))
+		}
+	}))
+	defer server.Close()
+
+	store := &esv1.SecretStore{
+		TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vault-store",
+			Namespace: "default",
+		},
+		Spec: esv1.SecretStoreSpec{
+			Provider: &esv1.SecretStoreProvider{
+				Vault: &esv1.VaultProvider{
+					Server:  server.URL,
+					Version: esv1.VaultKVStoreV2,
+					Auth:    ptr.To(makeAppRoleAuth("default")),
+				},
+			},
+		},
+	}
+	kube := clientfake.NewClientBuilder().Build()
+	external := NewExternalToken()
+	prov := &Provider{NewVaultClient: NewVaultClient, ExternalToken: external}
+
+	// clients can't authenticate before the first token is delivered.
+	if _, err := prov.newClient(context.Background(), store, kube, nil, "default"); err == nil || !strings.Contains(err.Error(), errExternalTokenUnset) {
+		t.Fatalf("expected error %q, got %v", errExternalTokenUnset, err)
+	}
+
+	external.Set("token-1")
+	sc, err := prov.newClient(context.Background(), store, kube, nil, "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	read := func() {
+		t.Helper()
+		if _, err := sc.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "secret/foo"}); err != nil {
+			t.Fatalf("unexpected error reading: %v", err)
+		}
+	}
+	read()
+
+	// tokens pushed through the channel are used by the next operation.
+	tokens := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		external.Watch(context.Background(), tokens)
+		close(done)
+	}()
+	tokens <- "token-2"
+	close(tokens)
+	<-done
+	read()
+
+	if err := sc.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error on close: %v", err)
+	}
+	// closed clients aren't updated anymore.
+	external.Set("token-3")
+	if got := sc.(*client).client.Token(); got != "token-2" {
+		t.Errorf("expected closed client to keep token %q, got %q", "token-2", got)
+	}
+
+	if diff := cmp.Diff([]string{"token-1", "token-2"}, reads); diff != "" {
+		t.Errorf("unexpected tokens of reads (-want, +got):\n%s", diff)
+	}
+	if len(logins) != 0 {
+		t.Errorf("expected the store's auth to be skipped, got logins %v", logins)
+	}
+	if len(revoked) != 0 {
+		t.Errorf("expected external tokens not to be revoked, got %v", revoked)
+	}
+	if got := sc.(*client).AuthMethod(); got != authMethodExternal {
+		t.Errorf("expected auth method %q, got %q", authMethodExternal, got)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"sync"
+
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+	"github.com/external-secrets/external-secrets/pkg/utils/resolvers"
+)
+
+const (
+	// authMethodFallbackToken is recorded when the client uses the
+	// fallback token of the store.
+	authMethodFallbackToken = "fallbacktoken"
+	// authMethodNone is recorded as the primary method of a fallback if no
+	// auth method could log in.
+	authMethodNone = "none"
+)
+
+var (
+	fallbackTokensMu sync.Mutex
+	// fallbackTokens holds the fallback tokens clients switched over to,
+	// which are never revoked and only used once logging in failed again.
+	// It maps each token to the store that uses it, which only keeps its
+	// latest fallback token.
+	fallbackTokens = map[string]string{}
+)
+
+// useFallbackToken switches the client over to the fallback token of the
+// store after the primary auth method failed with cause, or none could log
+// in. It reports whether it did, which is only the case if Vault accepts
+// the token. Otherwise the token of the client is left unchanged.
+func (c *client) useFallbackToken(ctx context.Context, primary string, cause error) bool {
+	ref := c.store.Auth.FallbackTokenRef
+	if ref == nil {
+		return false
+	}
+	fallback, err := resolvers.SecretKeyRef(ctx, c.kube, c.storeKind, c.namespace, ref)
+	if err != nil {
+		c.log.Error(err, "cannot read fallback token")
+		return false
+	}
+	token := c.client.Token()
+	c.client.SetToken(fallback)
+	state, err := checkToken(ctx, c.tokenAPI(), c.expiryThreshold())
+	if err != nil || state != tokenValid {
+		c.log.Info("fallback token is not valid", "error", err)
+		c.client.SetToken(token)
+		return false
+	}
+	if primary == "" {
+		primary = authMethodNone
+	}
+	c.log.Info("using fallback token", "primary", primary, "error", cause)
+	metrics.ObserveAuthFallback(constants.ProviderHCVault, primary, authMethodFallbackToken)
+	c.markFallbackToken(fallback)
+	c.authMethod = authMethodFallbackToken
+	c.loginAuth = nil
+	return true
+}
+
+// markFallbackToken records the fallback token of the store, replacing the
+// one it used before, e.g. until the Secret was rotated.
+func (c *client) markFallbackToken(token string) {
+	store := c.storeKind + "/" + c.namespace + "/" + c.storeName
+	fallbackTokensMu.Lock()
+	defer fallbackTokensMu.Unlock()
+	for key, s := range fallbackTokens {
+		if s == store {
+			delete(fallbackTokens, key)
+		}
+	}
+	fallbackTokens[tokenKey(token)] = store
+}
+
+// isFallbackToken reports whether the token is the fallback token of a store.
+func isFallbackToken(token string) bool {
+	fallbackTokensMu.Lock()
+	defer fallbackTokensMu.Unlock()
+	_, ok := fallbackTokens[tokenKey(token)]
+	return ok
+}
@@ -0,0 +1,184 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestFallbackToken(t *testing.T) {
+	cases := map[string]struct {
+		// loginErr fails the Kubernetes login.
+		loginErr error
+		// noMethod configures no auth method besides the fallback token.
+		noMethod      bool
+		fallback      string
+		existingToken string
+		wantToken     string
+		wantMethod    string
+		wantLogins    int
+		wantErr       string
+	}{
+		"PrimarySucceeds": {
+			fallback:   "fallback-token",
+			wantToken:  "kubernetes-token",
+			wantMethod: authMethodKubernetes,
+			wantLogins: 1,
+		},
+		"PrimaryFails": {
+			loginErr:   errors.New("permission denied"),
+			fallback:   "fallback-token",
+			wantToken:  "fallback-token",
+			wantMethod: authMethodFallbackToken,
+			wantLogins: 1,
+		},
+		"NoMethodLoggedIn": {
+			noMethod:   true,
+			fallback:   "fallback-token",
+			wantToken:  "fallback-token",
+			wantMethod: authMethodFallbackToken,
+		},
+		// an invalid fallback token leaves the login error in place.
+		"FallbackInvalid": {
+			loginErr:   errors.New("permission denied"),
+			fallback:   "revoked-token",
+			wantLogins: 1,
+			wantErr:    "permission denied",
+		},
+		// the primary method is tried again instead of re-using the
+		// fallback token, which is switched back from once it logs in.
+		"FallbackInUse": {
+			fallback:      "fallback-token",
+			existingToken: "fallback-token",
+			wantToken:     "kubernetes-token",
+			wantMethod:    authMethodKubernetes,
+			wantLogins:    1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fallbackTokens = map[string]string{}
+			valid := map[string]bool{"kubernetes-token": true, "fallback-token": true}
+			logins := 0
+			token := tc.existingToken
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, nil), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, &logins)
+			if tc.noMethod {
+				c.store.Auth.Kubernetes = nil
+			}
+			c.store.Auth.FallbackTokenRef = &esmeta.SecretKeySelector{Name: "break-glass", Key: "token"}
+			if tc.existingToken != "" {
+				c.markFallbackToken(tc.existingToken)
+			}
+			if err := c.kube.Create(context.Background(), &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "break-glass", Namespace: "default"},
+				Data:       map[string][]byte{"token": []byte(tc.fallback)},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					logins++
+					if tc.loginErr != nil {
+						return nil, tc.loginErr
+					}
+					token = "kubernetes-token"
+					return &vault.Secret{}, nil
+				},
+			}
+			authToken := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					if !valid[token] {
+						return nil, errors.New("permission denied")
+					}
+					return makeTokenLookup(time.Hour, false), nil
+				},
+				RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+					t.Errorf("unexpected revocation of %q", token)
+					return nil
+				},
+			}
+			c.token = authToken
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				ClearTokenFunc:   func() { token = "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+				AuthTokenField:   authToken,
+			}
+
+			err := c.setAuth(context.Background(), nil)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token != tc.wantToken && tc.wantErr == "" {
+				t.Errorf("expected token %q, got %q", tc.wantToken, token)
+			}
+			if c.authMethod != tc.wantMethod {
+				t.Errorf("expected auth method %q, got %q", tc.wantMethod, c.authMethod)
+			}
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, logins)
+			}
+
+			// fallback tokens are never revoked.
+			if tc.wantMethod == authMethodFallbackToken {
+				if err := c.revokeLoginToken(context.Background()); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkFallbackToken(t *testing.T) {
+	t.Cleanup(func() { fallbackTokens = map[string]string{} })
+	c := &client{storeKind: esv1.SecretStoreKind, namespace: "default", storeName: "vault"}
+	other := &client{storeKind: esv1.SecretStoreKind, namespace: "default", storeName: "other"}
+	c.markFallbackToken("fallback-token")
+	other.markFallbackToken("other-token")
+	// a rotated fallback token replaces the previous one of the store.
+	c.markFallbackToken("rotated-token")
+
+	for token, want := range map[string]bool{"fallback-token": false, "rotated-token": true, "other-token": true} {
+		if got := isFallbackToken(token); got != want {
+			t.Errorf("isFallbackToken(%q) = %t, want %t", token, got, want)
+		}
+	}
+	if len(fallbackTokens) != 2 {
+		t.Errorf("expected 2 fallback tokens, got %d", len(fallbackTokens))
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	iam "cloud.google.com/go/iam/credentials/apiv1"
+	"cloud.google.com/go/iam/credentials/apiv1/credentialspb"
+	vault "github.com/hashicorp/vault/api"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+	"github.com/external-secrets/external-secrets/pkg/provider/gcp/secretmanager"
+)
+
+const (
+	defaultGcpAuthMountPath = "gcp"
+	// Vault rejects JWTs expiring in more than 15 minutes by default.
+	gcpJwtExpiry         = 10 * time.Minute
+	gcpSAAnnotation      = "iam.gke.io/gcp-service-account"
+	errGcpSAAnnotation   = "service account %q is missing annotation %s"
+	errGcpDefaultSA      = "cannot get Google service account of the controller from the metadata server: %w"
+	errGcpTokenSource    = "cannot get Google credentials for Vault GCP auth: %w"
+	errGcpSignJwt        = "cannot sign JWT for Vault GCP auth with Google service account %q: %w"
+	errGcpJwtMarshalling = "cannot marshal JWT claims for Vault GCP auth: %w"
+)
+
+// These are replaced in tests.
+var (
+	gcpTokenSource           = secretmanager.NewTokenSource
+	gcpDefaultServiceAccount = func(ctx context.Context) (string, error) {
+		return metadata.EmailWithContext(ctx, "default")
+	}
+	gcpSignJwt = signGcpJwt
+)
+
+func setGcpAuthToken(ctx context.Context, v *client) (bool, error) {
+	gcpAuth := v.store.Auth.Gcp
+	if gcpAuth != nil {
+		err := v.requestTokenWithGcpAuth(ctx, gcpAuth)
+		if err != nil {
+			return true, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (c *client) requestTokenWithGcpAuth(ctx context.Context, gcpAuth *esv1.VaultGcpAuth) error {
+	jwt, err := c.gcpSignedJwt(ctx, gcpAuth)
+	if err != nil {
+		return err
+	}
+
+	path := defaultGcpAuthMountPath
+	if gcpAuth.Path != "" {
+		path = gcpAuth.Path
+	}
+	parameters := map[string]any{
+		"role": gcpAuth.Role,
+		"jwt":  jwt,
+	}
+	// https://developer.hashicorp.com/vault/api-docs/auth/gcp#login
+	url := strings.Join([]string{"auth", path, "login"}, "/")
+	var vaultResult *vault.Secret
+	err = c.retryLogin(ctx, authMethodGcp, func() error {
+		var loginErr error
+		vaultResult, loginErr = c.writeLogin(ctx, url, parameters)
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, loginErr)
+		return loginErr
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.setLoginToken(ctx, vaultResult)
+}
+
+// gcpSignedJwt returns a JWT for the role signed by the Google service
+// account bound to the service account of the auth method, or by that of
+// the controller if there is none.
+func (c *client) gcpSignedJwt(ctx context.Context, gcpAuth *esv1.VaultGcpAuth) (string, error) {
+	var email string
+	var auth esv1.GCPSMAuth
+	if ref := gcpAuth.ServiceAccountRef; ref != nil {
+		namespace := c.namespace
+		if c.storeKind == esv1.ClusterSecretStoreKind && ref.Namespace != nil {
+			namespace = *ref.Namespace
+		}
+		sa := &corev1.ServiceAccount{}
+		if err := c.kube.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, sa); err != nil {
+			return "", fmt.Errorf(errGetKubeSA, ref.Name, err)
+		}
+		email = sa.Annotations[gcpSAAnnotation]
+		if email == "" {
+			return "", fmt.Errorf(errGcpSAAnnotation, ref.Name, gcpSAAnnotation)
+		}
+		auth.WorkloadIdentity = &esv1.GCPWorkloadIdentity{ServiceAccountRef: *ref}
+	} else {
+		var err error
+		email, err = gcpDefaultServiceAccount(ctx)
+		if err != nil {
+			return "", fmt.Errorf(errGcpDefaultSA, err)
+		}
+	}
+
+	ts, err := gcpTokenSource(ctx, auth, "", c.storeKind, c.kube, c.namespace)
+	if err != nil {
+		return "", fmt.Errorf(errGcpTokenSource, err)
+	}
+	claims, err := json.Marshal(map[string]any{
+		"sub": email,
+		"aud": "vault/" + gcpAuth.Role,
+		"exp": time.Now().Add(gcpJwtExpiry).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf(errGcpJwtMarshalling, err)
+	}
+	jwt, err := gcpSignJwt(ctx, ts, email, string(claims))
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultSignGcpJwt, err)
+	if err != nil {
+		return "", fmt.Errorf(errGcpSignJwt, email, err)
+	}
+	return jwt, nil
+}
+
+// signGcpJwt signs the claims with the system-managed key of the Google
+// service account using the IAM credentials API.
+func signGcpJwt(ctx context.Context, ts oauth2.TokenSource, email, claims string) (string, error) {
+	iamClient, err := iam.NewIamCredentialsClient(ctx,
+		option.WithUserAgent("external-secrets-operator"),
+		option.WithTokenSource(ts))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = iamClient.Close()
+	}()
+	resp, err := iamClient.SignJwt(ctx, &credentialspb.SignJwtRequest{
+		Name:    fmt.Sprintf("projects/-/serviceAccounts/%s", email),
+		Payload: claims,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetSignedJwt(), nil
+}
@@ -0,0 +1,180 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	kclient "sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+)
+
+func TestGcpAuth(t *testing.T) {
+	defer func(tokenSource func(context.Context, esv1.GCPSMAuth, string, string, kclient.Client, string) (oauth2.TokenSource, error)) {
+		gcpTokenSource = tokenSource
+	}(gcpTokenSource)
+	defer func(defaultSA func(context.Context) (string, error)) { gcpDefaultServiceAccount = defaultSA }(gcpDefaultServiceAccount)
+	defer func(sign func(context.Context, oauth2.TokenSource, string, string) (string, error)) {
+		gcpSignJwt = sign
+	}(gcpSignJwt)
+
+	cases := map[string]struct {
+		auth          *esv1.VaultGcpAuth
+		annotations   map[string]string
+		authNamespace *string
+		wantEmail     string
+		wantWorkload  *esv1.GCPWorkloadIdentity
+		wantPath      string
+		wantNamespace string
+		wantErr       string
+	}{
+		"ControllerServiceAccount": {
+			auth:      &esv1.VaultGcpAuth{Role: "demo"},
+			wantEmail: "controller@project.iam.gserviceaccount.com",
+			wantPath:  "/v1/auth/gcp/login",
+		},
+		"WorkloadIdentity": {
+			auth: &esv1.VaultGcpAuth{
+				Path:              "gcp-gke",
+				Role:              "demo",
+				ServiceAccountRef: &esmeta.ServiceAccountSelector{Name: "workload"},
+			},
+			annotations:  map[string]string{"iam.gke.io/gcp-service-account": "workload@project.iam.gserviceaccount.com"},
+			wantEmail:    "workload@project.iam.gserviceaccount.com",
+			wantWorkload: &esv1.GCPWorkloadIdentity{ServiceAccountRef: esmeta.ServiceAccountSelector{Name: "workload"}},
+			wantPath:     "/v1/auth/gcp-gke/login",
+		},
+		"WorkloadIdentityWithoutAnnotation": {
+			auth: &esv1.VaultGcpAuth{
+				Role:              "demo",
+				ServiceAccountRef: &esmeta.ServiceAccountSelector{Name: "workload"},
+			},
+			wantErr: `service account "workload" is missing annotation iam.gke.io/gcp-service-account`,
+		},
+		"AuthNamespace": {
+			auth:          &esv1.VaultGcpAuth{Role: "demo"},
+			authNamespace: ptr.To("auth-ns"),
+			wantEmail:     "controller@project.iam.gserviceaccount.com",
+			wantPath:      "/v1/auth/gcp/login",
+			wantNamespace: "auth-ns",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var workload *esv1.GCPWorkloadIdentity
+			gcpTokenSource = func(ctx context.Context, auth esv1.GCPSMAuth, projectID, storeKind string, kube kclient.Client, namespace string) (oauth2.TokenSource, error) {
+				workload = auth.WorkloadIdentity
+				return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "google-token"}), nil
+			}
+			gcpDefaultServiceAccount = func(context.Context) (string, error) {
+				return "controller@project.iam.gserviceaccount.com", nil
+			}
+			var email string
+			var claims map[string]any
+			gcpSignJwt = func(ctx context.Context, ts oauth2.TokenSource, e, payload string) (string, error) {
+				email = e
+				if err := json.Unmarshal([]byte(payload), &claims); err != nil {
+					t.Errorf("unexpected claims %q: %v", payload, err)
+				}
+				return "signed-jwt", nil
+			}
+
+			var loginPath, loginNamespace string
+			var loginData map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/login") {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				loginPath = r.URL.Path
+				loginNamespace = r.Header.Get("X-Vault-Namespace")
+				_ = json.NewDecoder(r.Body).Decode(&loginData)
+				_, _ = w.Write([]byte(`{"auth": {"client_token": "gcp-token"}}`))
+			}))
+			defer server.Close()
+
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "workload",
+					Namespace:   "default",
+					Annotations: tc.annotations,
+				},
+			}).Build()
+			store := &esv1.SecretStore{
+				TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vault-store",
+					Namespace: "default",
+				},
+				Spec: esv1.SecretStoreSpec{
+					Provider: &esv1.SecretStoreProvider{
+						Vault: &esv1.VaultProvider{
+							Server:  server.URL,
+							Version: esv1.VaultKVStoreV2,
+							Auth: &esv1.VaultAuth{
+								Namespace: tc.authNamespace,
+								Gcp:       tc.auth,
+							},
+						},
+					},
+				},
+			}
+			prov := &Provider{NewVaultClient: NewVaultClient}
+			_, err := prov.newClient(context.Background(), store, kube, nil, "default")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if email != tc.wantEmail {
+				t.Errorf("expected JWT signed by %q, got %q", tc.wantEmail, email)
+			}
+			if claims["sub"] != tc.wantEmail || claims["aud"] != "vault/demo" {
+				t.Errorf("unexpected claims %v", claims)
+			}
+			if diff := cmp.Diff(tc.wantWorkload, workload); diff != "" {
+				t.Errorf("unexpected workload identity: -want, +got:\n%s", diff)
+			}
+			if loginPath != tc.wantPath {
+				t.Errorf("expected login at %q, got %q", tc.wantPath, loginPath)
+			}
+			if loginNamespace != tc.wantNamespace {
+				t.Errorf("expected login in namespace %q, got %q", tc.wantNamespace, loginNamespace)
+			}
+			if diff := cmp.Diff(map[string]any{"role": "demo", "jwt": "signed-jwt"}, loginData); diff != "" {
+				t.Errorf("unexpected login data: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
@@ -19,11 +19,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/golang-jwt/jwt/v5"
+	vault "github.com/hashicorp/vault/api"
 	authaws "github.com/hashicorp/vault/api/auth/aws"
 	kclient "sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -37,6 +39,7 @@ import (
 const (
 	defaultAWSRegion                = "us-east-1"
 	defaultAWSAuthMountPath         = "aws"
+	defaultSTSProbeTimeout          = 3 * time.Second
 	errIrsaTokenEnvVarNotFoundOnPod = "expected env variable: %s not found on controller's pod"
 	errIrsaTokenFileNotFoundOnPod   = "web ddentity token file not found at %s location: %w"
 	errIrsaTokenFileNotReadable     = "could not read the web identity token from the file %s: %w"
@@ -44,6 +47,10 @@ const (
 	errPodInfoNotFoundOnToken       = "could not find pod identity info on token %s: %w"
 )
 
+// stsProbeTimeout is the timeout of the check that the STS endpoint is
+// reachable before requesting credentials. Disabled if zero.
+var stsProbeTimeout = defaultSTSProbeTimeout
+
 func setIamAuthToken(ctx context.Context, v *client, jwtProvider util.JwtProviderFactory, assumeRoler vaultiamauth.STSProvider) (bool, error) {
 	iamAuth := v.store.Auth.Iam
 	isClusterKind := v.storeKind == esv1.ClusterSecretStoreKind
@@ -153,6 +160,14 @@ func (c *client) requestTokenWithIamAuth(ctx context.Context, iamAuth *esv1.Vaul
 		}
 	}
 
+	// static credentials from a secretRef are only exchanged with STS if a role is assumed.
+	usesSTS := jwtAuth != nil || secretRefAuth == nil || iamAuth.AWSIAMRole != ""
+	if usesSTS && stsProbeTimeout > 0 {
+		if err := vaultiamauth.CheckSTSReachable(ctx, regionAWS, stsProbeTimeout); err != nil {
+			return err
+		}
+	}
+
 	getCreds, err := sess.Config.Credentials.Get()
 	if err != nil {
 		return err
@@ -176,10 +191,12 @@ func (c *client) requestTokenWithIamAuth(ctx context.Context, iamAuth *esv1.Vaul
 		}
 	}
 
-	_, err = c.auth.Login(ctx, awsAuthClient)
-	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, err)
-	if err != nil {
-		return err
-	}
-	return nil
+	var resp *vault.Secret
+	err = c.retryLogin(ctx, authMethodIam, func() error {
+		var loginErr error
+		resp, loginErr = c.login(ctx, awsAuthClient)
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, loginErr)
+		return loginErr
+	})
+	return c.checkLogin(ctx, resp, err)
 }
@@ -0,0 +1,149 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+)
+
+// fakeSTS assumes roles with static credentials.
+type fakeSTS struct {
+	stsiface.STSAPI
+	assumed *[]string
+}
+
+func (f fakeSTS) AssumeRoleWithContext(ctx aws.Context, input *sts.AssumeRoleInput, opts ...request.Option) (*sts.AssumeRoleOutput, error) {
+	*f.assumed = append(*f.assumed, aws.StringValue(input.RoleArn))
+	return &sts.AssumeRoleOutput{Credentials: &sts.Credentials{
+		AccessKeyId:     aws.String("assumed-key"),
+		SecretAccessKey: aws.String("assumed-secret"),
+		SessionToken:    aws.String("assumed-session"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+func TestIamAuthProviders(t *testing.T) {
+	defer func(timeout time.Duration) { stsProbeTimeout = timeout }(stsProbeTimeout)
+	stsProbeTimeout = 0
+
+	cases := map[string]struct {
+		role          string
+		wantRoleArns  []string
+		wantAssumed   []string
+		wantAccessKey string
+	}{
+		"JWTProvider": {
+			wantRoleArns:  []string{"arn:aws:iam::123456789012:role/vault-sa"},
+			wantAccessKey: "jwt-key",
+		},
+		"STSProvider": {
+			role:          "arn:aws:iam::123456789012:role/vault-store",
+			wantRoleArns:  []string{"arn:aws:iam::123456789012:role/vault-sa"},
+			wantAssumed:   []string{"arn:aws:iam::123456789012:role/vault-store"},
+			wantAccessKey: "assumed-key",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// IAM auth hands the credentials over to the login through the
+			// environment.
+			t.Setenv("AWS_ACCESS_KEY_ID", "")
+			t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+			t.Setenv("AWS_SESSION_TOKEN", "")
+
+			var roleArns, assumed []string
+			logins := 0
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "vault-sa",
+					Namespace:   "default",
+					Annotations: map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::123456789012:role/vault-sa"},
+				},
+			}).Build()
+			prov := &Provider{
+				NewVaultClient: NewVaultClient,
+				ClientOptions: []ClientOption{
+					WithJWTProvider(func(name, namespace, roleArn string, aud []string, region string) (credentials.Provider, error) {
+						roleArns = append(roleArns, roleArn)
+						return &credentials.StaticProvider{Value: credentials.Value{
+							AccessKeyID:     "jwt-key",
+							SecretAccessKey: "jwt-secret",
+						}}, nil
+					}),
+					WithSTSProvider(func(*session.Session) stsiface.STSAPI {
+						return fakeSTS{assumed: &assumed}
+					}),
+				},
+			}
+			c, _, err := prov.prepareConfig(context.Background(), kube, nil, &esv1.VaultProvider{
+				Server: "https://vault.example.com",
+				Auth: &esv1.VaultAuth{
+					Iam: &esv1.VaultIamAuth{
+						Role:       "vault-role",
+						AWSIAMRole: tc.role,
+						JWTAuth: &esv1.VaultAwsJWTAuth{
+							ServiceAccountRef: &esmeta.ServiceAccountSelector{Name: "vault-sa"},
+						},
+					},
+				},
+			}, nil, "default", esv1.SecretStoreKind)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					logins++
+					return &vault.Secret{}, nil
+				},
+			}
+
+			if _, err := setIamAuthToken(context.Background(), c, c.iamJWTProvider(), c.iamSTSProvider()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if logins != 1 {
+				t.Errorf("expected 1 login, got %d", logins)
+			}
+			if diff := cmp.Diff(tc.wantRoleArns, roleArns); diff != "" {
+				t.Errorf("unexpected JWT provider roles (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantAssumed, assumed); diff != "" {
+				t.Errorf("unexpected assumed roles (-want, +got):\n%s", diff)
+			}
+			if got := os.Getenv("AWS_ACCESS_KEY_ID"); got != tc.wantAccessKey {
+				t.Errorf("expected the login to use access key %q, got %q", tc.wantAccessKey, got)
+			}
+		})
+	}
+}
@@ -18,7 +18,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"text/template"
+
+	vault "github.com/hashicorp/vault/api"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
 	"github.com/external-secrets/external-secrets/pkg/constants"
@@ -27,9 +31,18 @@ import (
 )
 
 const (
-	errJwtNoTokenSource = "neither `secretRef` nor `kubernetesServiceAccountToken` was supplied as token source for jwt authentication"
+	errJwtNoTokenSource = "neither `secretRef`, `kubernetesServiceAccountToken` nor `httpSource` was supplied as token source for jwt authentication"
+	errJwtPathTemplate  = "cannot render JWT auth mount path %q: %w"
+	errJwtPathEmpty     = "cannot render JWT auth mount path %q: rendered path is empty"
 )
 
+// jwtPathData is the data a templated JWT auth mount path is rendered with.
+type jwtPathData struct {
+	Namespace string
+	StoreKind string
+	StoreName string
+}
+
 func setJwtAuthToken(ctx context.Context, v *client) (bool, error) {
 	jwtAuth := v.store.Auth.Jwt
 	if jwtAuth != nil {
@@ -49,45 +62,94 @@ func (c *client) requestTokenWithJwtAuth(ctx context.Context, jwtAuth *esv1.Vaul
 	if jwtAuth.SecretRef != nil {
 		jwt, err = resolvers.SecretKeyRef(ctx, c.kube, c.storeKind, c.namespace, jwtAuth.SecretRef)
 	} else if k8sServiceAccountToken := jwtAuth.KubernetesServiceAccountToken; k8sServiceAccountToken != nil {
-		audiences := k8sServiceAccountToken.Audiences
-		if audiences == nil {
-			audiences = &[]string{"vault"}
-		}
-		expirationSeconds := k8sServiceAccountToken.ExpirationSeconds
-		if expirationSeconds == nil {
-			tmp := int64(600)
-			expirationSeconds = &tmp
-		}
 		jwt, err = createServiceAccountToken(
 			ctx,
 			c.corev1,
 			c.storeKind,
 			c.namespace,
 			k8sServiceAccountToken.ServiceAccountRef,
-			*audiences,
-			*expirationSeconds)
+			jwtTokenRequest(k8sServiceAccountToken))
+	} else if jwtAuth.HTTPSource != nil {
+		jwt, err = c.fetchJwt(ctx, jwtAuth.HTTPSource)
 	} else {
 		err = errors.New(errJwtNoTokenSource)
 	}
 	if err != nil {
 		return err
 	}
+	if jwtAuth.TokenExchange != nil {
+		jwt, err = exchangeToken(ctx, jwtAuth.TokenExchange, jwt)
+		if err != nil {
+			return err
+		}
+	}
 
 	parameters := map[string]any{
 		"role": role,
 		"jwt":  jwt,
 	}
-	url := strings.Join([]string{"auth", jwtAuth.Path, "login"}, "/")
-	vaultResult, err := c.logical.WriteWithContext(ctx, url, parameters)
-	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultWriteSecretData, err)
+	path, err := renderJwtPath(jwtAuth.Path, jwtPathData{
+		Namespace: c.namespace,
+		StoreKind: c.storeKind,
+		StoreName: c.storeName,
+	})
+	if err != nil {
+		return err
+	}
+	url := strings.Join([]string{"auth", path, "login"}, "/")
+	var vaultResult *vault.Secret
+	err = c.retryLogin(ctx, authMethodJwt, func() error {
+		var loginErr error
+		vaultResult, loginErr = c.writeLogin(ctx, url, c.withLoginMetadata(parameters))
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultWriteSecretData, loginErr)
+		return loginErr
+	})
 	if err != nil {
 		return err
 	}
 
-	token, err := vaultResult.TokenID()
+	return c.setLoginToken(ctx, vaultResult)
+}
+
+// isTemplatedPath reports whether the mount path contains template markers.
+func isTemplatedPath(path string) bool {
+	return strings.Contains(path, "{{")
+}
+
+// renderJwtPath renders a templated JWT auth mount path, paths without
+// template markers are used as they are.
+func renderJwtPath(path string, data jwtPathData) (string, error) {
+	if !isTemplatedPath(path) {
+		return path, nil
+	}
+	tmpl, err := template.New("path").Parse(path)
 	if err != nil {
-		return fmt.Errorf(errVaultToken, err)
+		return "", fmt.Errorf(errJwtPathTemplate, path, err)
+	}
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf(errJwtPathTemplate, path, err)
+	}
+	if normalizeMountPath(rendered.String()) == "" {
+		return "", fmt.Errorf(errJwtPathEmpty, path)
+	}
+	return rendered.String(), nil
+}
+
+// jwtTokenRequest returns the request of the service account token of the
+// JWT auth. Unless configured with KubernetesTokenRequest, the deprecated
+// audiences, or the audience "vault" by default, are added to those of the
+// service account.
+func jwtTokenRequest(k8sServiceAccountToken *esv1.VaultKubernetesServiceAccountTokenAuth) *esv1.VaultKubernetesTokenRequest {
+	if k8sServiceAccountToken.KubernetesTokenRequest != nil {
+		return k8sServiceAccountToken.KubernetesTokenRequest
+	}
+	audiences := []string{"vault"}
+	if k8sServiceAccountToken.Audiences != nil {
+		audiences = *k8sServiceAccountToken.Audiences
+	}
+	return &esv1.VaultKubernetesTokenRequest{
+		Audiences:         append(slices.Clone(k8sServiceAccountToken.ServiceAccountRef.Audiences), audiences...),
+		ExpirationSeconds: k8sServiceAccountToken.ExpirationSeconds,
 	}
-	c.client.SetToken(token)
-	return nil
 }
@@ -0,0 +1,170 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errJwtTokenExchange         = "cannot exchange token for jwt authentication: %w"
+	errJwtTokenExchangeStatus   = "token endpoint returned %d: %s"
+	errJwtTokenExchangeNoToken  = "token endpoint returned no access_token"
+	errJwtTokenExchangeCABundle = "failed to parse the token endpoint CA bundle"
+)
+
+// https://datatracker.ietf.org/doc/html/rfc8693#section-2.1
+const (
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+const tokenExchangeTimeout = 30 * time.Second
+
+// tokenExchangeResponse is the successful response of a token exchange.
+// https://datatracker.ietf.org/doc/html/rfc8693#section-2.2.1
+type tokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+}
+
+// tokenExchangeError is the error response of a token exchange.
+// https://datatracker.ietf.org/doc/html/rfc6749#section-5.2
+type tokenExchangeError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchangeToken exchanges the subject token for a JWT at the token
+// exchange endpoint.
+func exchangeToken(ctx context.Context, exchange *esv1.VaultJwtTokenExchange, subjectToken string) (string, error) {
+	token, err := requestTokenExchange(ctx, exchange, subjectToken)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultTokenExchange, err)
+	if err != nil {
+		return "", fmt.Errorf(errJwtTokenExchange, err)
+	}
+	return token, nil
+}
+
+func requestTokenExchange(ctx context.Context, exchange *esv1.VaultJwtTokenExchange, subjectToken string) (string, error) {
+	httpClient, err := tokenExchangeClient(exchange)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":           {tokenExchangeGrantType},
+		"subject_token":        {subjectToken},
+		"subject_token_type":   {tokenTypeJWT},
+		"requested_token_type": {tokenTypeJWT},
+	}
+	if exchange.Audience != "" {
+		form.Set("audience", exchange.Audience)
+	}
+	if exchange.Scope != "" {
+		form.Set("scope", exchange.Scope)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, exchange.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var exchangeErr tokenExchangeError
+		msg := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &exchangeErr) == nil && exchangeErr.Error != "" {
+			msg = strings.TrimSpace(exchangeErr.Error + " " + exchangeErr.ErrorDescription)
+		}
+		return "", fmt.Errorf(errJwtTokenExchangeStatus, resp.StatusCode, msg)
+	}
+
+	var exchanged tokenExchangeResponse
+	if err := json.Unmarshal(body, &exchanged); err != nil {
+		return "", err
+	}
+	if exchanged.AccessToken == "" {
+		return "", errors.New(errJwtTokenExchangeNoToken)
+	}
+	return exchanged.AccessToken, nil
+}
+
+// tokenExchangeClient returns the HTTP client used for the token exchange,
+// trusting the CA bundle of the exchange if one is set.
+func tokenExchangeClient(exchange *esv1.VaultJwtTokenExchange) (*http.Client, error) {
+	httpClient, ok := httpClientWithCABundle(exchange.CABundle)
+	if !ok {
+		return nil, errors.New(errJwtTokenExchangeCABundle)
+	}
+	return httpClient, nil
+}
+
+// httpClientWithCABundle returns an HTTP client for requests to endpoints
+// other than Vault, trusting the PEM encoded CA bundle if one is set. It
+// returns false if the bundle holds no certificate.
+func httpClientWithCABundle(caBundle []byte) (*http.Client, bool) {
+	if len(caBundle) == 0 {
+		return &http.Client{Timeout: tokenExchangeTimeout}, true
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return nil, false
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	return &http.Client{Timeout: tokenExchangeTimeout, Transport: transport}, true
+}
+
+// validateTokenEndpoint checks that the token endpoint is an absolute
+// HTTP(S) URL.
+func validateTokenEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute http(s) URL", endpoint)
+	}
+	return nil
+}
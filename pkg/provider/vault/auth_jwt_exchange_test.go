@@ -0,0 +1,161 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestJwtTokenExchange(t *testing.T) {
+	cases := map[string]struct {
+		status    int
+		response  string
+		wantJwt   string
+		wantErr   string
+		wantLogin bool
+	}{
+		"Exchanged": {
+			status:    http.StatusOK,
+			response:  `{"access_token": "exchanged-jwt", "issued_token_type": "urn:ietf:params:oauth:token-type:jwt", "token_type": "N_A"}`,
+			wantJwt:   "exchanged-jwt",
+			wantLogin: true,
+		},
+		"Rejected": {
+			status:   http.StatusBadRequest,
+			response: `{"error": "invalid_target", "error_description": "unknown audience"}`,
+			wantErr:  "cannot exchange token for jwt authentication: token endpoint returned 400: invalid_target unknown audience",
+		},
+		"NoAccessToken": {
+			status:   http.StatusOK,
+			response: `{"issued_token_type": "urn:ietf:params:oauth:token-type:jwt"}`,
+			wantErr:  "cannot exchange token for jwt authentication: token endpoint returned no access_token",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Errorf("cannot parse token exchange request: %v", err)
+				}
+				want := map[string]string{
+					"grant_type":           tokenExchangeGrantType,
+					"subject_token":        "subject-jwt",
+					"subject_token_type":   tokenTypeJWT,
+					"requested_token_type": tokenTypeJWT,
+					"audience":             "vault",
+				}
+				for key, value := range want {
+					if got := r.PostForm.Get(key); got != value {
+						t.Errorf("expected %s %q, got %q", key, value, got)
+					}
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.response))
+			}))
+			defer server.Close()
+			caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "jwt-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"jwt": []byte("subject-jwt"),
+				},
+			}).Build()
+			loginJwt := ""
+			token := ""
+			c := &client{
+				kube:      kube,
+				log:       logger,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store: &esv1.VaultProvider{
+					Auth: &esv1.VaultAuth{
+						Jwt: &esv1.VaultJwtAuth{
+							Path: "jwt",
+							Role: "role",
+							SecretRef: &esmeta.SecretKeySelector{
+								Name: "jwt-secret",
+								Key:  "jwt",
+							},
+							TokenExchange: &esv1.VaultJwtTokenExchange{
+								TokenEndpoint: server.URL + "/oauth2/token",
+								Audience:      "vault",
+								CABundle:      caBundle,
+							},
+						},
+					},
+				},
+				client: &util.VaultClient{
+					SetTokenFunc: func(v string) { token = v },
+				},
+				logical: fake.Logical{
+					WriteWithContextFn: func(ctx context.Context, path string, data map[string]any) (*vault.Secret, error) {
+						loginJwt, _ = data["jwt"].(string)
+						return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "vault-token"}}, nil
+					},
+				},
+			}
+
+			_, err := setJwtAuthToken(context.Background(), c)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if loginJwt != tc.wantJwt {
+				t.Errorf("expected login with jwt %q, got %q", tc.wantJwt, loginJwt)
+			}
+			if loggedIn := token == "vault-token"; loggedIn != tc.wantLogin {
+				t.Errorf("expected logged in %t, got %t", tc.wantLogin, loggedIn)
+			}
+		})
+	}
+}
+
+func TestValidateTokenEndpoint(t *testing.T) {
+	cases := map[string]bool{
+		"https://sts.example.com/oauth2/token": true,
+		"http://sts.default.svc:8080/token":    true,
+		"sts.example.com/oauth2/token":         false,
+		"/oauth2/token":                        false,
+		"ftp://sts.example.com/token":          false,
+	}
+	for endpoint, valid := range cases {
+		if err := validateTokenEndpoint(endpoint); (err == nil) != valid {
+			t.Errorf("validateTokenEndpoint(%q): expected valid %t, got %v", endpoint, valid, err)
+		}
+	}
+}
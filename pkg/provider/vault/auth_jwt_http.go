@@ -0,0 +1,198 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+	"github.com/external-secrets/external-secrets/pkg/utils"
+	"github.com/external-secrets/external-secrets/pkg/utils/resolvers"
+)
+
+const (
+	errJwtHTTPSource         = "cannot fetch token for jwt authentication: %w"
+	errJwtHTTPSourceStatus   = "endpoint returned %d: %s"
+	errJwtHTTPSourceField    = "response has no string field %q"
+	errJwtHTTPSourceNoToken  = "endpoint returned no token"
+	errJwtHTTPSourceCABundle = "failed to parse the endpoint CA bundle"
+	errJwtHTTPSourceHeader   = "header %q needs either a value or a secretRef"
+)
+
+// jwtRefreshMargin is how long before their expiry fetched tokens are
+// fetched again, so that they don't expire during the login.
+const jwtRefreshMargin = 30 * time.Second
+
+// fetchedJwt is a token fetched from a JWT HTTP source.
+type fetchedJwt struct {
+	jwt    string
+	expiry time.Time
+}
+
+// Fetched tokens are kept per source rather than per client, as clients
+// are created for every reconcile.
+var (
+	fetchedJwtsMu sync.Mutex
+	fetchedJwts   = map[string]fetchedJwt{}
+)
+
+// fetchJwt returns the token of the HTTP source, re-using a token fetched
+// before until it expires.
+func (c *client) fetchJwt(ctx context.Context, source *esv1.VaultJwtHTTPSource) (string, error) {
+	headers, err := c.jwtSourceHeaders(ctx, source)
+	if err != nil {
+		return "", fmt.Errorf(errJwtHTTPSource, err)
+	}
+	key := jwtSourceKey(source, headers)
+	fetchedJwtsMu.Lock()
+	fetched, ok := fetchedJwts[key]
+	fetchedJwtsMu.Unlock()
+	if ok && time.Until(fetched.expiry) > jwtRefreshMargin {
+		c.log.V(1).Info("Using previously fetched jwt", "url", source.URL)
+		return fetched.jwt, nil
+	}
+
+	token, err := requestJwt(ctx, source, headers)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultFetchJwt, err)
+	if err != nil {
+		return "", fmt.Errorf(errJwtHTTPSource, err)
+	}
+	storeFetchedJwt(key, token)
+	return token, nil
+}
+
+// jwtSourceHeaders resolves the headers of the HTTP source.
+func (c *client) jwtSourceHeaders(ctx context.Context, source *esv1.VaultJwtHTTPSource) (http.Header, error) {
+	headers := http.Header{}
+	for _, h := range source.Headers {
+		value := h.Value
+		if h.SecretRef != nil {
+			var err error
+			value, err = resolvers.SecretKeyRef(ctx, c.kube, c.storeKind, c.namespace, h.SecretRef)
+			if err != nil {
+				return nil, err
+			}
+		}
+		headers.Add(h.Name, value)
+	}
+	return headers, nil
+}
+
+// jwtSourceKey identifies the tokens of a source. The resolved headers are
+// part of the key, so that changed credentials lead to a new token.
+func jwtSourceKey(source *esv1.VaultJwtHTTPSource, headers http.Header) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\n%s\n", source.URL, source.Field)
+	_ = headers.Write(h)
+	_, _ = h.Write(source.CABundle)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func requestJwt(ctx context.Context, source *esv1.VaultJwtHTTPSource, headers http.Header) (string, error) {
+	httpClient, ok := httpClientWithCABundle(source.CABundle)
+	if !ok {
+		return "", errors.New(errJwtHTTPSourceCABundle)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	req.Header = headers.Clone()
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf(errJwtHTTPSourceStatus, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	token := strings.TrimSpace(string(body))
+	if source.Field != "" {
+		var fields map[string]any
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return "", err
+		}
+		value, ok := fields[source.Field].(string)
+		if !ok {
+			return "", fmt.Errorf(errJwtHTTPSourceField, source.Field)
+		}
+		token = strings.TrimSpace(value)
+	}
+	if token == "" {
+		return "", errors.New(errJwtHTTPSourceNoToken)
+	}
+	return token, nil
+}
+
+// storeFetchedJwt keeps a fetched token until its expiry. Tokens without
+// an expiry are fetched again for each login.
+func storeFetchedJwt(key, token string) {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return
+	}
+	exp, err := parsed.Claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return
+	}
+	fetchedJwtsMu.Lock()
+	defer fetchedJwtsMu.Unlock()
+	for k, f := range fetchedJwts {
+		if time.Now().After(f.expiry) {
+			delete(fetchedJwts, k)
+		}
+	}
+	fetchedJwts[key] = fetchedJwt{jwt: token, expiry: exp.Time}
+}
+
+// validateJwtHTTPSource checks the URL and the headers of the source.
+func validateJwtHTTPSource(store esv1.GenericStore, source *esv1.VaultJwtHTTPSource) error {
+	if err := validateTokenEndpoint(source.URL); err != nil {
+		return err
+	}
+	for _, h := range source.Headers {
+		if h.Name == "" || (h.Value == "") == (h.SecretRef == nil) {
+			return fmt.Errorf(errJwtHTTPSourceHeader, h.Name)
+		}
+		if h.SecretRef != nil {
+			if err := utils.ValidateReferentSecretSelector(store, *h.SecretRef); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestJwtHTTPSource(t *testing.T) {
+	defer func() { fetchedJwts = map[string]fetchedJwt{} }()
+
+	cases := map[string]struct {
+		// lifetime of the tokens vended by the endpoint.
+		lifetime time.Duration
+		field    string
+		// wantFetches are the tokens used by two logins in a row, by the
+		// number of the fetch they were returned by.
+		wantFetches []int
+	}{
+		"ReusedUntilExpiry": {
+			lifetime:    time.Hour,
+			wantFetches: []int{1, 1},
+		},
+		"RefreshedOnExpiry": {
+			lifetime:    10 * time.Second,
+			wantFetches: []int{1, 2},
+		},
+		"JSONField": {
+			lifetime:    time.Hour,
+			field:       "token",
+			wantFetches: []int{1, 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fetchedJwts = map[string]fetchedJwt{}
+			fetches := 0
+			tokens := map[string]int{}
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "Bearer workload-credential" {
+					t.Errorf("expected the Authorization header from the secret, got %q", got)
+				}
+				if got := r.Header.Get("X-Audience"); got != "vault" {
+					t.Errorf("expected the X-Audience header, got %q", got)
+				}
+				fetches++
+				token := makeServiceAccountJWT(t, jwt.MapClaims{
+					"sub": fmt.Sprintf("fetch-%d", fetches),
+					"exp": time.Now().Add(tc.lifetime).Unix(),
+				})
+				tokens[token] = fetches
+				if tc.field != "" {
+					_ = json.NewEncoder(w).Encode(map[string]string{tc.field: token})
+					return
+				}
+				_, _ = w.Write([]byte(token + "\n"))
+			}))
+			defer server.Close()
+
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "jwt-source",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"authorization": []byte("Bearer workload-credential"),
+				},
+			}).Build()
+			var loginFetches []int
+			c := &client{
+				kube:      kube,
+				log:       logger,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store: &esv1.VaultProvider{
+					Auth: &esv1.VaultAuth{
+						Jwt: &esv1.VaultJwtAuth{
+							Path: "jwt",
+							Role: "role",
+							HTTPSource: &esv1.VaultJwtHTTPSource{
+								URL: server.URL + "/token",
+								Headers: []esv1.VaultJwtHTTPHeader{
+									{Name: "Authorization", SecretRef: &esmeta.SecretKeySelector{Name: "jwt-source", Key: "authorization"}},
+									{Name: "X-Audience", Value: "vault"},
+								},
+								Field:    tc.field,
+								CABundle: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+							},
+						},
+					},
+				},
+				client: &util.VaultClient{
+					SetTokenFunc: func(v string) {},
+				},
+				logical: fake.Logical{
+					WriteWithContextFn: func(ctx context.Context, path string, data map[string]any) (*vault.Secret, error) {
+						if path != "auth/jwt/login" {
+							t.Errorf("unexpected login path %q", path)
+						}
+						loginJwt, _ := data["jwt"].(string)
+						loginFetches = append(loginFetches, tokens[loginJwt])
+						return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "vault-token"}}, nil
+					},
+				},
+			}
+
+			for range 2 {
+				if _, err := setJwtAuthToken(context.Background(), c); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if diff := cmp.Diff(tc.wantFetches, loginFetches); diff != "" {
+				t.Errorf("unexpected tokens used for login (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJwtHTTPSourceErrors(t *testing.T) {
+	cases := map[string]struct {
+		status   int
+		response string
+		field    string
+		wantErr  string
+	}{
+		"ErrorStatus": {
+			status:   http.StatusForbidden,
+			response: "workload not allowed\n",
+			wantErr:  "cannot fetch token for jwt authentication: endpoint returned 403: workload not allowed",
+		},
+		"MissingField": {
+			status:   http.StatusOK,
+			response: `{"access_token": "jwt"}`,
+			field:    "token",
+			wantErr:  `cannot fetch token for jwt authentication: response has no string field "token"`,
+		},
+		"EmptyBody": {
+			status:  http.StatusOK,
+			wantErr: "cannot fetch token for jwt authentication: endpoint returned no token",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.response))
+			}))
+			defer server.Close()
+
+			c := &client{log: logger}
+			_, err := c.fetchJwt(context.Background(), &esv1.VaultJwtHTTPSource{URL: server.URL, Field: tc.field})
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestJwtMountPath(t *testing.T) {
+	cases := map[string]struct {
+		path string
+		// storeKind defaults to SecretStore.
+		storeKind string
+		wantURL   string
+		wantErr   string
+	}{
+		"Static": {
+			path:    "jwt",
+			wantURL: "auth/jwt/login",
+		},
+		"Namespace": {
+			path:    "jwt-{{ .Namespace }}",
+			wantURL: "auth/jwt-team-a/login",
+		},
+		// cluster stores log in to the mount of the requesting namespace.
+		"ClusterStoreNamespace": {
+			path:      "jwt-{{ .Namespace }}",
+			storeKind: esv1.ClusterSecretStoreKind,
+			wantURL:   "auth/jwt-team-a/login",
+		},
+		"Store": {
+			path:    "{{ .StoreName }}/jwt",
+			wantURL: "auth/vault-store/jwt/login",
+		},
+		"UnknownField": {
+			path:    "jwt-{{ .Team }}",
+			wantErr: `cannot render JWT auth mount path "jwt-{{ .Team }}"`,
+		},
+		"Empty": {
+			path:    "{{ if false }}jwt{{ end }}",
+			wantErr: "rendered path is empty",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			storeKind := esv1.SecretStoreKind
+			if tc.storeKind != "" {
+				storeKind = tc.storeKind
+			}
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "jwt-secret",
+					Namespace: "team-a",
+				},
+				Data: map[string][]byte{
+					"jwt": []byte("team-jwt"),
+				},
+			}).Build()
+			url := ""
+			c := &client{
+				kube:      kube,
+				log:       logger,
+				namespace: "team-a",
+				storeKind: storeKind,
+				storeName: "vault-store",
+				store: &esv1.VaultProvider{
+					Auth: &esv1.VaultAuth{
+						Jwt: &esv1.VaultJwtAuth{
+							Path: tc.path,
+							Role: "role",
+							SecretRef: &esmeta.SecretKeySelector{
+								Name:      "jwt-secret",
+								Key:       "jwt",
+								Namespace: ptr.To("team-a"),
+							},
+						},
+					},
+				},
+				client: &util.VaultClient{
+					SetTokenFunc: func(string) {},
+				},
+				logical: fake.Logical{
+					WriteWithContextFn: func(ctx context.Context, path string, data map[string]any) (*vault.Secret, error) {
+						url = path
+						return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "vault-token"}}, nil
+					},
+				},
+			}
+
+			_, err := setJwtAuthToken(context.Background(), c)
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if url != tc.wantURL {
+				t.Errorf("expected login at %q, got %q", tc.wantURL, url)
+			}
+			// clients of templated paths are kept per namespace.
+			if got, want := isReferentSpec(c.store), tc.path != "jwt"; got != want {
+				t.Errorf("expected referent spec %t, got %t", want, got)
+			}
+		})
+	}
+}
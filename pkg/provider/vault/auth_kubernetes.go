@@ -16,9 +16,14 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"slices"
 
+	"github.com/golang-jwt/jwt/v5"
+	vault "github.com/hashicorp/vault/api"
 	authkubernetes "github.com/hashicorp/vault/api/auth/kubernetes"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -37,6 +42,10 @@ const (
 	errGetKubeSASecrets       = "cannot find secrets bound to service account: %q"
 	errGetKubeSANoToken       = "cannot find token in secrets bound to service account: %q"
 	errServiceAccountNotFound = "serviceaccounts %q not found"
+	errKubeTokenIssuerParse   = "cannot parse service account token to validate its issuer: %w"
+	errKubeTokenIssuer        = "service account token issuer %q does not match expected issuer %q"
+	errKubeAuthDenied         = "kubernetes auth login denied for service account token with audiences %v, check the bound_audiences of role %q: %w"
+	errKubeAuthDeniedBound    = "kubernetes auth login denied for service account token with audiences %v, role %q expects bound_audiences %v: %w"
 )
 
 func setKubernetesAuthToken(ctx context.Context, v *client) (bool, error) {
@@ -56,15 +65,116 @@ func (c *client) requestTokenWithKubernetesAuth(ctx context.Context, kubernetesA
 	if err != nil {
 		return err
 	}
+	if kubernetesAuth.ExpectedIssuer != "" {
+		if err := validateTokenIssuer(jwtString, kubernetesAuth.ExpectedIssuer); err != nil {
+			return err
+		}
+	}
 	k, err := authkubernetes.NewKubernetesAuth(kubernetesAuth.Role, authkubernetes.WithServiceAccountToken(jwtString), authkubernetes.WithMountPath(kubernetesAuth.Path))
 	if err != nil {
 		return err
 	}
-	_, err = c.auth.Login(ctx, k)
-	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, err)
+	policy, err := newLoginRetryPolicy(c.store.Auth.LoginRetry)
+	if kubernetesAuth.LoginRetrySettings != nil {
+		policy, err = newRetryPolicy(kubernetesAuth.LoginRetrySettings)
+	}
 	if err != nil {
 		return err
 	}
+	var resp *vault.Secret
+	var loginErr error
+	err = policy.do(ctx, c.isRetryableLoginError(authMethodKubernetes), func() error {
+		resp, loginErr = c.login(ctx, k)
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, loginErr)
+		if resp != nil && resp.WrapInfo != nil {
+			// wrapped responses are unwrapped by checkLogin.
+			return nil
+		}
+		return loginErr
+	})
+	if isPermissionDenied(err) {
+		return c.kubernetesAuthDeniedError(ctx, kubernetesAuth, jwtString, err)
+	}
+	return c.checkLogin(ctx, resp, loginErr)
+}
+
+func isPermissionDenied(err error) bool {
+	var respErr *vault.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden
+}
+
+// kubernetesAuthDeniedError adds the audiences of the service account token
+// to a denied login, since a mismatch with the bound_audiences of the role
+// is a common cause. The bound_audiences are only included if the role is
+// readable.
+func (c *client) kubernetesAuthDeniedError(ctx context.Context, kubernetesAuth *esv1.VaultKubernetesAuth, jwtString string, err error) error {
+	audiences := tokenAudiences(jwtString)
+	boundAudiences := c.roleBoundAudiences(ctx, kubernetesAuth)
+	c.log.Info("Kubernetes auth login was denied", "role", kubernetesAuth.Role, "audiences", audiences, "boundAudiences", boundAudiences)
+	if boundAudiences != nil {
+		return fmt.Errorf(errKubeAuthDeniedBound, audiences, kubernetesAuth.Role, boundAudiences, err)
+	}
+	return fmt.Errorf(errKubeAuthDenied, audiences, kubernetesAuth.Role, err)
+}
+
+// tokenAudiences returns the `aud` claim of the service account token
+// without verifying its signature.
+func tokenAudiences(token string) []string {
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	parsed, _, err := parser.ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return nil
+	}
+	audiences, err := parsed.Claims.GetAudience()
+	if err != nil {
+		return nil
+	}
+	return audiences
+}
+
+// roleBoundAudiences reads the bound_audiences of the Kubernetes auth role.
+// It returns nil if the role can't be read, which is the case unless the
+// client already holds a token with read access to the role.
+func (c *client) roleBoundAudiences(ctx context.Context, kubernetesAuth *esv1.VaultKubernetesAuth) []string {
+	// https://developer.hashicorp.com/vault/api-docs/auth/kubernetes#read-role
+	role, err := c.logical.ReadWithDataWithContext(ctx, fmt.Sprintf("auth/%s/role/%s", kubernetesAuth.Path, kubernetesAuth.Role), nil)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultReadAuthRole, err)
+	if err != nil || role == nil {
+		c.log.V(1).Info("unable to read Kubernetes auth role", "role", kubernetesAuth.Role)
+		return nil
+	}
+	bound, ok := role.Data["bound_audiences"]
+	if !ok {
+		return nil
+	}
+	switch v := bound.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		audiences := make([]string, 0, len(v))
+		for _, aud := range v {
+			audiences = append(audiences, fmt.Sprint(aud))
+		}
+		return audiences
+	}
+	return nil
+}
+
+// validateTokenIssuer checks the `iss` claim of the service account token
+// without verifying its signature, which is left to Vault.
+func validateTokenIssuer(token, expectedIssuer string) error {
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	parsed, _, err := parser.ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return fmt.Errorf(errKubeTokenIssuerParse, err)
+	}
+	issuer, err := parsed.Claims.GetIssuer()
+	if err != nil {
+		return fmt.Errorf(errKubeTokenIssuerParse, err)
+	}
+	if issuer != expectedIssuer {
+		return fmt.Errorf(errKubeTokenIssuer, issuer, expectedIssuer)
+	}
 	return nil
 }
 
@@ -73,14 +183,29 @@ func getJwtString(ctx context.Context, v *client, kubernetesAuth *esv1.VaultKube
 		// Kubernetes >=v1.24: fetch token via TokenRequest API
 		// note: this is a massive change from vault perspective: the `iss` claim will very likely change.
 		// Vault 1.9 deprecated issuer validation by default, and authentication with Vault clusters <1.9 will likely fail.
-		jwt, err := createServiceAccountToken(
-			ctx,
-			v.corev1,
-			v.storeKind,
-			v.namespace,
-			*kubernetesAuth.ServiceAccountRef,
-			nil,
-			600)
+		request := kubernetesAuth.KubernetesTokenRequest
+		if request == nil && len(kubernetesAuth.Audiences) > 0 {
+			// the audiences of the auth method are added to those of the service account.
+			request = &esv1.VaultKubernetesTokenRequest{
+				Audiences: append(slices.Clone(kubernetesAuth.ServiceAccountRef.Audiences), kubernetesAuth.Audiences...),
+			}
+		}
+		policy, err := newRetryPolicy(kubernetesAuth.TokenRequestRetrySettings)
+		if err != nil {
+			return "", err
+		}
+		var jwt string
+		err = policy.do(ctx, isRetryableTokenRequestError, func() error {
+			var tokenErr error
+			jwt, tokenErr = createServiceAccountToken(
+				ctx,
+				v.corev1,
+				v.storeKind,
+				v.namespace,
+				*kubernetesAuth.ServiceAccountRef,
+				request)
+			return tokenErr
+		})
 		if jwt != "" && err == nil {
 			return jwt, nil
 		}
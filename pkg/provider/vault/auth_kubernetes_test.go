@@ -0,0 +1,403 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	utilfake "github.com/external-secrets/external-secrets/pkg/provider/util/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+const (
+	kubernetesIssuer = "https://kubernetes.default.svc.cluster.local"
+)
+
+func makeServiceAccountJWT(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("not-a-real-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// makeKubernetesAuthClient returns a client that reads the service account
+// token from a Secret and counts the number of login attempts.
+func makeKubernetesAuthClient(t *testing.T, jwtString string, kubernetesAuth *esv1.VaultKubernetesAuth, logins *int) *client {
+	t.Helper()
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vault-sa-token",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"token": []byte(jwtString),
+		},
+	}).Build()
+	kubernetesAuth.SecretRef = &esmeta.SecretKeySelector{
+		Name: "vault-sa-token",
+	}
+	return &client{
+		kube:      kube,
+		log:       logger,
+		namespace: "default",
+		storeKind: esv1.SecretStoreKind,
+		store: &esv1.VaultProvider{
+			Auth: &esv1.VaultAuth{
+				Kubernetes: kubernetesAuth,
+			},
+		},
+		auth: fake.Auth{
+			LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+				*logins++
+				return &vault.Secret{}, nil
+			},
+		},
+	}
+}
+
+func TestKubernetesAuthExpectedIssuer(t *testing.T) {
+	cases := map[string]struct {
+		claims         jwt.MapClaims
+		expectedIssuer string
+		wantErr        error
+		wantLogins     int
+	}{
+		"NoExpectedIssuer": {
+			claims:     jwt.MapClaims{"iss": "https://some.other.issuer"},
+			wantLogins: 1,
+		},
+		"MatchingIssuer": {
+			claims:         jwt.MapClaims{"iss": kubernetesIssuer},
+			expectedIssuer: kubernetesIssuer,
+			wantLogins:     1,
+		},
+		"MismatchingIssuer": {
+			claims:         jwt.MapClaims{"iss": "https://some.other.issuer"},
+			expectedIssuer: kubernetesIssuer,
+			wantErr:        fmt.Errorf(errKubeTokenIssuer, "https://some.other.issuer", kubernetesIssuer),
+		},
+		"MissingIssuer": {
+			claims:         jwt.MapClaims{"sub": "system:serviceaccount:default:vault"},
+			expectedIssuer: kubernetesIssuer,
+			wantErr:        fmt.Errorf(errKubeTokenIssuer, "", kubernetesIssuer),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			kubernetesAuth := &esv1.VaultKubernetesAuth{
+				Path:           "kubernetes",
+				Role:           "kubernetes-auth-role",
+				ExpectedIssuer: tc.expectedIssuer,
+			}
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, tc.claims), kubernetesAuth, &logins)
+
+			err := c.requestTokenWithKubernetesAuth(context.Background(), kubernetesAuth)
+			if tc.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != nil && (err == nil || err.Error() != tc.wantErr.Error()) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d login attempts, got %d", tc.wantLogins, logins)
+			}
+		})
+	}
+}
+
+func TestKubernetesAuthAudienceMismatch(t *testing.T) {
+	denied := &vault.ResponseError{StatusCode: http.StatusForbidden, Errors: []string{"permission denied"}}
+	audiences := []string{"vault", "https://kubernetes.default.svc"}
+
+	cases := map[string]struct {
+		loginErr  error
+		role      *vault.Secret
+		roleErr   error
+		wantErr   error
+		wantReads int
+	}{
+		"RoleNotReadable": {
+			loginErr:  denied,
+			roleErr:   denied,
+			wantErr:   fmt.Errorf(errKubeAuthDenied, audiences, "kubernetes-auth-role", denied),
+			wantReads: 1,
+		},
+		"RoleReadable": {
+			loginErr:  denied,
+			role:      &vault.Secret{Data: map[string]any{"bound_audiences": []any{"vault-prod"}}},
+			wantErr:   fmt.Errorf(errKubeAuthDeniedBound, audiences, "kubernetes-auth-role", []string{"vault-prod"}, denied),
+			wantReads: 1,
+		},
+		"OtherLoginError": {
+			loginErr: errors.New("connection refused"),
+			wantErr:  errors.New("connection refused"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			reads := 0
+			kubernetesAuth := &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{"aud": audiences}), kubernetesAuth, &logins)
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					return nil, tc.loginErr
+				},
+			}
+			c.logical = fake.Logical{
+				ReadWithDataWithContextFn: func(ctx context.Context, path string, data map[string][]string) (*vault.Secret, error) {
+					reads++
+					if path != "auth/kubernetes/role/kubernetes-auth-role" {
+						t.Errorf("unexpected role path %q", path)
+					}
+					return tc.role, tc.roleErr
+				},
+			}
+
+			err := c.requestTokenWithKubernetesAuth(context.Background(), kubernetesAuth)
+			if err == nil || err.Error() != tc.wantErr.Error() {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if !errors.Is(err, tc.loginErr) {
+				t.Errorf("expected login error to be wrapped, got %v", err)
+			}
+			if reads != tc.wantReads {
+				t.Errorf("expected %d role reads, got %d", tc.wantReads, reads)
+			}
+		})
+	}
+}
+
+func TestValidateTokenIssuerInvalidToken(t *testing.T) {
+	if err := validateTokenIssuer("not-a-jwt", kubernetesIssuer); err == nil {
+		t.Error("expected an error for a malformed token")
+	}
+}
+
+func TestServiceAccountTokenRequest(t *testing.T) {
+	serviceAccountRef := esmeta.ServiceAccountSelector{
+		Name:      "vault-sa",
+		Audiences: []string{"kubernetes-default"},
+	}
+	spec := func(audiences []string, expirationSeconds int64, bound *authv1.BoundObjectReference) authv1.TokenRequestSpec {
+		return authv1.TokenRequestSpec{Audiences: audiences, ExpirationSeconds: &expirationSeconds, BoundObjectRef: bound}
+	}
+
+	cases := map[string]struct {
+		auth     *esv1.VaultAuth
+		wantSpec authv1.TokenRequestSpec
+	}{
+		"KubernetesServiceAccountAudiences": {
+			auth: &esv1.VaultAuth{
+				Kubernetes: &esv1.VaultKubernetesAuth{
+					Path:              "kubernetes",
+					Role:              "team-a",
+					ServiceAccountRef: serviceAccountRef.DeepCopy(),
+				},
+			},
+			wantSpec: spec([]string{"kubernetes-default"}, 600, nil),
+		},
+		"KubernetesMethodAudiences": {
+			auth: &esv1.VaultAuth{
+				Kubernetes: &esv1.VaultKubernetesAuth{
+					Path:              "kubernetes",
+					Role:              "team-a",
+					ServiceAccountRef: serviceAccountRef.DeepCopy(),
+					Audiences:         []string{"vault-team-a"},
+				},
+			},
+			wantSpec: spec([]string{"kubernetes-default", "vault-team-a"}, 600, nil),
+		},
+		"KubernetesMethodDuplicateAudiences": {
+			auth: &esv1.VaultAuth{
+				Kubernetes: &esv1.VaultKubernetesAuth{
+					Path:              "kubernetes",
+					Role:              "team-a",
+					ServiceAccountRef: serviceAccountRef.DeepCopy(),
+					Audiences:         []string{"vault-team-a", "kubernetes-default", "vault", "vault-team-a"},
+				},
+			},
+			wantSpec: spec([]string{"kubernetes-default", "vault-team-a", "vault"}, 600, nil),
+		},
+		"KubernetesTokenRequest": {
+			auth: &esv1.VaultAuth{
+				Kubernetes: &esv1.VaultKubernetesAuth{
+					Path:              "kubernetes",
+					Role:              "team-a",
+					ServiceAccountRef: serviceAccountRef.DeepCopy(),
+					KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{
+						Audiences:         []string{"vault-team-a"},
+						ExpirationSeconds: ptr.To[int64](3600),
+						BoundObjectRef: &esv1.VaultKubernetesBoundObjectRef{
+							Kind: "Pod",
+							Name: "app",
+							UID:  "1234",
+						},
+					},
+				},
+			},
+			wantSpec: spec([]string{"vault-team-a"}, 3600, &authv1.BoundObjectReference{
+				Kind:       "Pod",
+				APIVersion: "v1",
+				Name:       "app",
+				UID:        "1234",
+			}),
+		},
+		"KubernetesTokenRequestDefaults": {
+			auth: &esv1.VaultAuth{
+				Kubernetes: &esv1.VaultKubernetesAuth{
+					Path:                   "kubernetes",
+					Role:                   "team-a",
+					ServiceAccountRef:      serviceAccountRef.DeepCopy(),
+					KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{},
+				},
+			},
+			wantSpec: spec([]string{"kubernetes-default"}, 600, nil),
+		},
+		"JwtMethodAudiences": {
+			auth: &esv1.VaultAuth{
+				Jwt: &esv1.VaultJwtAuth{
+					Path: "jwt",
+					Role: "team-b",
+					KubernetesServiceAccountToken: &esv1.VaultKubernetesServiceAccountTokenAuth{
+						ServiceAccountRef: esmeta.ServiceAccountSelector{Name: "vault-sa"},
+						Audiences:         &[]string{"vault-team-b"},
+					},
+				},
+			},
+			wantSpec: spec([]string{"vault-team-b"}, 600, nil),
+		},
+		"JwtDefaultAudience": {
+			auth: &esv1.VaultAuth{
+				Jwt: &esv1.VaultJwtAuth{
+					Path: "jwt",
+					Role: "team-b",
+					KubernetesServiceAccountToken: &esv1.VaultKubernetesServiceAccountTokenAuth{
+						ServiceAccountRef: serviceAccountRef,
+						ExpirationSeconds: ptr.To[int64](1200),
+					},
+				},
+			},
+			wantSpec: spec([]string{"kubernetes-default", "vault"}, 1200, nil),
+		},
+		// the default audience is already one of the service account.
+		"JwtDuplicateAudience": {
+			auth: &esv1.VaultAuth{
+				Jwt: &esv1.VaultJwtAuth{
+					Path: "jwt",
+					Role: "team-b",
+					KubernetesServiceAccountToken: &esv1.VaultKubernetesServiceAccountTokenAuth{
+						ServiceAccountRef: esmeta.ServiceAccountSelector{
+							Name:      "vault-sa",
+							Audiences: []string{"vault", "kubernetes-default"},
+						},
+					},
+				},
+			},
+			wantSpec: spec([]string{"vault", "kubernetes-default"}, 600, nil),
+		},
+		"JwtTokenRequest": {
+			auth: &esv1.VaultAuth{
+				Jwt: &esv1.VaultJwtAuth{
+					Path: "jwt",
+					Role: "team-b",
+					KubernetesServiceAccountToken: &esv1.VaultKubernetesServiceAccountTokenAuth{
+						ServiceAccountRef: serviceAccountRef,
+						KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{
+							BoundObjectRef: &esv1.VaultKubernetesBoundObjectRef{
+								Kind:       "Secret",
+								APIVersion: "v1",
+								Name:       "binding",
+							},
+						},
+					},
+				},
+			},
+			// the default audience isn't added to those of the service account.
+			wantSpec: spec([]string{"kubernetes-default"}, 600, &authv1.BoundObjectReference{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       "binding",
+			}),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			corev1Client := utilfake.NewCreateTokenMock().WithToken(makeServiceAccountJWT(t, jwt.MapClaims{}))
+			c := &client{
+				log:       logger,
+				corev1:    corev1Client,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store:     &esv1.VaultProvider{Auth: tc.auth},
+				client: &util.VaultClient{
+					SetTokenFunc: func(string) {},
+				},
+				auth: fake.Auth{
+					LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+						return &vault.Secret{}, nil
+					},
+				},
+				logical: fake.Logical{
+					WriteWithContextFn: func(ctx context.Context, path string, data map[string]any) (*vault.Secret, error) {
+						return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "jwt-token"}}, nil
+					},
+				},
+			}
+
+			var err error
+			if tc.auth.Kubernetes != nil {
+				err = c.requestTokenWithKubernetesAuth(context.Background(), tc.auth.Kubernetes)
+			} else {
+				err = c.requestTokenWithJwtAuth(context.Background(), tc.auth.Jwt)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			requests := corev1Client.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("expected 1 token request, got %d", len(requests))
+			}
+			if diff := cmp.Diff(tc.wantSpec, requests[0].Spec); diff != "" {
+				t.Errorf("unexpected token request: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
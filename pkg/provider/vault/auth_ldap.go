@@ -18,6 +18,7 @@ import (
 	"context"
 	"strings"
 
+	vault "github.com/hashicorp/vault/api"
 	authldap "github.com/hashicorp/vault/api/auth/ldap"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
@@ -39,6 +40,9 @@ func setLdapAuthToken(ctx context.Context, v *client) (bool, error) {
 }
 
 func (c *client) requestTokenWithLdapAuth(ctx context.Context, ldapAuth *esv1.VaultLdapAuth) error {
+	if err := c.checkLoginSecrets(ctx, &ldapAuth.SecretRef); err != nil {
+		return err
+	}
 	username := strings.TrimSpace(ldapAuth.Username)
 	password, err := resolvers.SecretKeyRef(ctx, c.kube, c.storeKind, c.namespace, &ldapAuth.SecretRef)
 	if err != nil {
@@ -49,10 +53,31 @@ func (c *client) requestTokenWithLdapAuth(ctx context.Context, ldapAuth *esv1.Va
 	if err != nil {
 		return err
 	}
-	_, err = c.auth.Login(ctx, l)
-	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, err)
-	if err != nil {
+	var resp *vault.Secret
+	err = c.retryLogin(ctx, authMethodLdap, func() error {
+		var loginErr error
+		resp, loginErr = c.login(ctx, l)
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, loginErr)
+		return loginErr
+	})
+	if err := c.checkLogin(ctx, resp, err); err != nil {
 		return err
 	}
+	c.logLdapPolicies(username)
 	return nil
 }
+
+// logLdapPolicies logs the policies Vault mapped the LDAP user and its
+// groups to, to help debugging logins that succeed but can't read secrets.
+// The token itself is never logged.
+func (c *client) logLdapPolicies(username string) {
+	auth := c.loginAuth
+	if auth == nil {
+		return
+	}
+	c.log.V(1).Info("ldap login mapped to policies",
+		"username", username,
+		"policies", auth.Policies,
+		"token_policies", auth.TokenPolicies,
+		"identity_policies", auth.IdentityPolicies)
+}
@@ -0,0 +1,120 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestLdapPolicyLogging(t *testing.T) {
+	auth := &vault.SecretAuth{
+		ClientToken:      "hvs.secret-token",
+		Policies:         []string{"default", "ldap-devs", "team"},
+		TokenPolicies:    []string{"default", "ldap-devs"},
+		IdentityPolicies: []string{"team"},
+	}
+	cases := map[string]struct {
+		resp      *vault.Secret
+		verbosity int
+		wantLogs  int
+	}{
+		"Debug": {
+			resp:      &vault.Secret{Auth: auth},
+			verbosity: 1,
+			wantLogs:  1,
+		},
+		"Info": {
+			resp: &vault.Secret{Auth: auth},
+		},
+		"NoAuth": {
+			resp:      &vault.Secret{},
+			verbosity: 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var logged []string
+			token := ""
+			c := &client{
+				kube: clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "ldap-secret", Namespace: "default"},
+					Data:       map[string][]byte{"password": []byte("ldap-password")},
+				}).Build(),
+				log: funcr.New(func(prefix, args string) {
+					if strings.Contains(args, `"msg"="ldap login mapped to policies"`) {
+						logged = append(logged, args)
+					}
+				}, funcr.Options{Verbosity: tc.verbosity}),
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store: &esv1.VaultProvider{
+					Auth: &esv1.VaultAuth{
+						Ldap: &esv1.VaultLdapAuth{
+							Path:      "ldap",
+							Username:  "alice",
+							SecretRef: esmeta.SecretKeySelector{Name: "ldap-secret", Key: "password"},
+						},
+					},
+				},
+				client: &util.VaultClient{
+					TokenFunc:    func() string { return token },
+					SetTokenFunc: func(v string) { token = v },
+				},
+				auth: fake.Auth{
+					LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+						token = "hvs.secret-token"
+						return tc.resp, nil
+					},
+				},
+			}
+
+			if _, err := setLdapAuthToken(context.Background(), c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(logged) != tc.wantLogs {
+				t.Fatalf("expected %d policy logs, got %v", tc.wantLogs, logged)
+			}
+			for _, line := range logged {
+				for _, field := range []string{
+					`"username"="alice"`,
+					` "policies"=["default" "ldap-devs" "team"]`,
+					`"token_policies"=["default" "ldap-devs"]`,
+					`"identity_policies"=["team"]`,
+				} {
+					if !strings.Contains(line, field) {
+						t.Errorf("expected log %q to contain %s", line, field)
+					}
+				}
+				if strings.Contains(line, auth.ClientToken) {
+					t.Errorf("expected log %q not to contain the token", line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	kclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+const (
+	errAuthNotElected = "not logging in to vault before this controller is elected leader"
+
+	// warmUpConcurrency bounds the logins running at once after an election.
+	warmUpConcurrency = 4
+	// warmUpRate is how many logins per second are started after an election.
+	warmUpRate rate.Limit = 5
+	// warmUpTimeout bounds the logins after an election, stores that didn't
+	// log in by then log in on their next reconcile.
+	warmUpTimeout = 5 * time.Minute
+)
+
+var (
+	// authLeaderOnly defers all logins until the controller is elected leader,
+	// so that standby replicas don't hold tokens.
+	authLeaderOnly bool
+	// elected is set once the controller has been elected leader.
+	elected atomic.Bool
+)
+
+// checkElected returns an error if logins are deferred until the controller
+// is elected leader and it hasn't been yet.
+func checkElected() error {
+	if authLeaderOnly && !elected.Load() {
+		return errors.New(errAuthNotElected)
+	}
+	return nil
+}
+
+// onElected allows logins from now on and cleans up the Secrets of tokens
+// shared between replicas until the context is done. If sharing is
+// disabled, the finalizers of Secrets left behind by an earlier run are
+// released instead. With the token cache enabled, all stores using the
+// Vault provider log in right away with the client and config of the
+// manager, so that the first reconciles of the new leader don't have to
+// wait for their logins.
+func (p *Provider) onElected(ctx context.Context, kube kclient.Client, restCfg *rest.Config) {
+	elected.Store(true)
+	if replicaTokenNamespace == "" {
+		releaseReplicaTokens(ctx, kube)
+	} else {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cleanupReplicaTokens(ctx, kube)
+		}()
+		defer wg.Wait()
+	}
+	if !authLeaderOnly || !enableCache {
+		return
+	}
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		logger.Error(err, "cannot log in to vault stores after election")
+		return
+	}
+	warmUpTokens(ctx, p, kube, clientset.CoreV1())
+}
+
+// warmUpTokens logs in to all stores using the Vault provider and leaves
+// their tokens in the cache. Stores failing to log in are logged and left
+// to their next reconcile, as are all stores if they can't be listed. At
+// most warmUpConcurrency logins run at once, started at warmUpRate, so that
+// an election doesn't flood Vault with the logins of all stores.
+func warmUpTokens(ctx context.Context, p *Provider, kube kclient.Client, corev1 typedcorev1.CoreV1Interface) {
+	ctx, cancel := context.WithTimeout(ctx, warmUpTimeout)
+	defer cancel()
+	stores, err := vaultStores(ctx, kube)
+	if err != nil {
+		logger.Error(err, "cannot list stores to log in to vault after election")
+		return
+	}
+
+	limiter := rate.NewLimiter(warmUpRate, warmUpConcurrency)
+	sem := make(chan struct{}, warmUpConcurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, store := range stores {
+		if err := limiter.Wait(ctx); err != nil {
+			logger.Error(err, "stopping vault logins after election")
+			return
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			logger.Error(ctx.Err(), "stopping vault logins after election")
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			// referent cluster stores have no credentials of their own and
+			// are left out by newClient.
+			c, err := p.newClient(ctx, store, kube, corev1, store.GetNamespace())
+			if err != nil {
+				logger.Error(err, "cannot log in to vault after election", "kind", store.GetKind(), "store", store.GetNamespacedName())
+				return
+			}
+			if err := c.Close(ctx); err != nil {
+				logger.Error(err, "cannot close vault client after election", "kind", store.GetKind(), "store", store.GetNamespacedName())
+			}
+		}()
+	}
+}
+
+// vaultStores lists the SecretStores and ClusterSecretStores using the
+// Vault provider.
+func vaultStores(ctx context.Context, kube kclient.Client) ([]esv1.GenericStore, error) {
+	var stores []esv1.GenericStore
+	var storeList esv1.SecretStoreList
+	if err := kube.List(ctx, &storeList); err != nil {
+		return nil, err
+	}
+	for i := range storeList.Items {
+		store := &storeList.Items[i]
+		store.Kind = esv1.SecretStoreKind
+		stores = append(stores, store)
+	}
+	var clusterStoreList esv1.ClusterSecretStoreList
+	if err := kube.List(ctx, &clusterStoreList); err != nil {
+		return nil, err
+	}
+	for i := range clusterStoreList.Items {
+		store := &clusterStoreList.Items[i]
+		store.Kind = esv1.ClusterSecretStoreKind
+		stores = append(stores, store)
+	}
+	return slices.DeleteFunc(stores, func(store esv1.GenericStore) bool {
+		spec := store.GetSpec()
+		return spec.Provider == nil || spec.Provider.Vault == nil
+	}), nil
+}
@@ -0,0 +1,270 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+	kclient "sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestAuthLeaderOnly(t *testing.T) {
+	t.Cleanup(func() {
+		authLeaderOnly = false
+		elected.Store(false)
+		resetCache()
+	})
+	authLeaderOnly = true
+	elected.Store(false)
+	enableCache = true
+	initCache(defaultCacheSize)
+
+	var logins atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			logins.Add(1)
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token", "lease_duration": 3600}}`))
+		case "/v1/auth/token/lookup-self":
+			_, _ = w.Write([]byte(`{"data": {"type": "service", "ttl": 3600, "expire_time": null}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store := &esv1.SecretStore{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vault-store",
+			Namespace: "default",
+		},
+		Spec: esv1.SecretStoreSpec{
+			Provider: &esv1.SecretStoreProvider{
+				Vault: &esv1.VaultProvider{
+					Server:  server.URL,
+					Version: esv1.VaultKVStoreV2,
+					Auth:    ptr.To(makeAppRoleAuth("default")),
+				},
+			},
+		},
+	}
+	otherStore := &esv1.SecretStore{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-store",
+			Namespace: "default",
+		},
+		Spec: esv1.SecretStoreSpec{
+			Provider: &esv1.SecretStoreProvider{
+				Kubernetes: &esv1.KubernetesProvider{},
+			},
+		},
+	}
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := esv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	kube := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "approle-secret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"secret-id": []byte("secret-id"),
+		},
+	}, store, otherStore).Build()
+	prov := &Provider{NewVaultClient: NewVaultClient}
+	newStoreClient := func() error {
+		s := store.DeepCopy()
+		s.Kind = esv1.SecretStoreKind
+		c, err := prov.newClient(context.Background(), s, kube, nil, "default")
+		if err != nil {
+			return err
+		}
+		return c.Close(context.Background())
+	}
+
+	// standby replicas don't log in.
+	if err := newStoreClient(); err == nil || !strings.Contains(err.Error(), errAuthNotElected) {
+		t.Fatalf("expected error %q on standby, got %v", errAuthNotElected, err)
+	}
+	if got := logins.Load(); got != 0 {
+		t.Fatalf("expected no logins on standby, got %d", got)
+	}
+
+	// the new leader logs in to the vault stores right away, with the
+	// client and config of the manager.
+	prov.onElected(context.Background(), kube, &rest.Config{Host: server.URL})
+	if got := logins.Load(); got != 1 {
+		t.Fatalf("expected 1 login on election, got %d", got)
+	}
+
+	// reconciles of the leader use the token of the election.
+	if err := newStoreClient(); err != nil {
+		t.Fatalf("unexpected error after election: %v", err)
+	}
+	if got := logins.Load(); got != 1 {
+		t.Errorf("expected the token of the election to be reused, got %d logins", got)
+	}
+}
+
+func TestCheckElected(t *testing.T) {
+	t.Cleanup(func() {
+		authLeaderOnly = false
+		elected.Store(false)
+	})
+	cases := map[string]struct {
+		leaderOnly bool
+		elected    bool
+		wantErr    bool
+	}{
+		"Disabled":  {},
+		"Standby":   {leaderOnly: true, wantErr: true},
+		"Leader":    {leaderOnly: true, elected: true},
+		"NotNeeded": {elected: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			authLeaderOnly = tc.leaderOnly
+			elected.Store(tc.elected)
+			if err := checkElected(); (err != nil) != tc.wantErr {
+				t.Errorf("expected error %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWarmUpTokens(t *testing.T) {
+	errBoom := errors.New("boom")
+	cases := map[string]struct {
+		stores int
+		// failList fails the list of this kind of stores.
+		failList   kclient.ObjectList
+		wantLogins int32
+	}{
+		"AllStores": {
+			stores:     2 * warmUpConcurrency,
+			wantLogins: 2 * warmUpConcurrency,
+		},
+		"ListSecretStoresFails": {
+			stores:   2,
+			failList: &esv1.SecretStoreList{},
+		},
+		"ListClusterSecretStoresFails": {
+			stores:   2,
+			failList: &esv1.ClusterSecretStoreList{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var logins, inFlight, maxInFlight int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/auth/approle/login":
+					mu.Lock()
+					logins++
+					inFlight++
+					maxInFlight = max(maxInFlight, inFlight)
+					mu.Unlock()
+					time.Sleep(20 * time.Millisecond)
+					mu.Lock()
+					inFlight--
+					mu.Unlock()
+					_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token", "lease_duration": 3600}}`))
+				case "/v1/auth/token/lookup-self":
+					_, _ = w.Write([]byte(`{"data": {"type": "service", "ttl": 3600, "expire_time": null}}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := esv1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			builder := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "approle-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"secret-id": []byte("secret-id"),
+				},
+			})
+			for i := range tc.stores {
+				builder = builder.WithObjects(&esv1.SecretStore{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      fmt.Sprintf("vault-store-%d", i),
+						Namespace: "default",
+					},
+					Spec: esv1.SecretStoreSpec{
+						Provider: &esv1.SecretStoreProvider{
+							Vault: &esv1.VaultProvider{
+								Server:  server.URL,
+								Version: esv1.VaultKVStoreV2,
+								Auth:    ptr.To(makeAppRoleAuth(fmt.Sprintf("role-%d", i))),
+							},
+						},
+					},
+				})
+			}
+			kube := builder.WithInterceptorFuncs(interceptor.Funcs{
+				List: func(ctx context.Context, client kclient.WithWatch, list kclient.ObjectList, opts ...kclient.ListOption) error {
+					if tc.failList != nil && reflect.TypeOf(list) == reflect.TypeOf(tc.failList) {
+						return errBoom
+					}
+					return client.List(ctx, list, opts...)
+				},
+			}).Build()
+
+			warmUpTokens(context.Background(), &Provider{NewVaultClient: NewVaultClient}, kube, nil)
+
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, logins)
+			}
+			if maxInFlight > warmUpConcurrency {
+				t.Errorf("expected at most %d logins at once, got %d", warmUpConcurrency, maxInFlight)
+			}
+		})
+	}
+}
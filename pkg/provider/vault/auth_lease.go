@@ -0,0 +1,157 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+const (
+	// Tokens with at most this many uses are not validated with a
+	// LookupSelf, as the lookup itself would consume one of the uses.
+	maxLimitedTokenUses = 2
+
+	// Tokens expiring within this threshold are treated as expired, unless
+	// the store sets a different one.
+	tokenExpiryThreshold = 60 * time.Second
+
+	defaultTokenExpiryJitter = 5 * time.Second
+)
+
+// tokenExpiryJitter is the maximum jitter added to the expiry threshold, so
+// that clients holding tokens of the same TTL don't all log in again at
+// once. Disabled if zero.
+var tokenExpiryJitter = defaultTokenExpiryJitter
+
+// tokenLease holds the lease information of a token, as returned by the
+// login that issued it or by a lookup.
+type tokenLease struct {
+	// expiry is zero for non-expirable tokens.
+	expiry time.Time
+	// renewable is only known for tokens that have been looked up.
+	renewable bool
+}
+
+// expiresWithin reports whether the token expires within d.
+func (l tokenLease) expiresWithin(d time.Duration) bool {
+	return !l.expiry.IsZero() && time.Until(l.expiry) < d
+}
+
+// Leases are kept per token rather than per client, as cached Vault
+// clients are wrapped by a new client for every reconcile. Like all state
+// kept per token, they are keyed by tokenKey.
+var (
+	tokenLeasesMu sync.Mutex
+	tokenLeases   = map[string]tokenLease{}
+)
+
+// tokenKey identifies a token in the state kept per token, so that the
+// maps holding it contain no credentials.
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// expiryThreshold returns the threshold within which tokens of the store
+// are treated as expired, including the jitter of the current token.
+func (c *client) expiryThreshold() time.Duration {
+	threshold := tokenExpiryThreshold
+	if c.store != nil && c.store.Auth != nil && c.store.Auth.TokenExpirationLeewaySeconds > 0 {
+		threshold = time.Duration(c.store.Auth.TokenExpirationLeewaySeconds) * time.Second
+	}
+	return threshold + c.expiryJitter()
+}
+
+// expiryJitter returns the jitter of up to tokenExpiryJitter added to the
+// expiry threshold of the current token. It is derived from the token, so
+// that it is the same for every client using the token and only changes
+// with the next login.
+func (c *client) expiryJitter() time.Duration {
+	if tokenExpiryJitter <= 0 || c.client == nil {
+		return 0
+	}
+	token := c.client.Token()
+	if token == "" {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(token))
+	return time.Duration(h.Sum64() % uint64(tokenExpiryJitter))
+}
+
+// limitedUseToken reports whether the store issues tokens with few enough
+// uses that looking them up would burn one of them.
+func (c *client) limitedUseToken() bool {
+	if c.store.Auth == nil || c.store.Auth.TokenNumUses == nil {
+		return false
+	}
+	numUses := *c.store.Auth.TokenNumUses
+	return numUses > 0 && numUses <= maxLimitedTokenUses
+}
+
+// recordLease stores the lease information of a login response if it
+// issued a limited-use token.
+func (c *client) recordLease(secret *vault.Secret) {
+	if !c.limitedUseToken() || secret == nil || secret.Auth == nil {
+		return
+	}
+	lease := tokenLease{}
+	if secret.Auth.LeaseDuration > 0 {
+		lease.expiry = time.Now().Add(time.Duration(secret.Auth.LeaseDuration) * time.Second)
+	}
+
+	tokenLeasesMu.Lock()
+	defer tokenLeasesMu.Unlock()
+	for key, l := range tokenLeases {
+		if l.expiresWithin(0) {
+			delete(tokenLeases, key)
+		}
+	}
+	tokenLeases[tokenKey(secret.Auth.ClientToken)] = lease
+}
+
+// checkLimitedUseToken checks whether the current limited-use token is
+// still valid for at least threshold, based on the lease returned at login.
+// Tokens without a known lease are treated as invalid, so that a new one
+// is requested instead of burning a use on a lookup.
+func (c *client) checkLimitedUseToken(threshold time.Duration) bool {
+	tokenLeasesMu.Lock()
+	defer tokenLeasesMu.Unlock()
+	lease, ok := tokenLeases[tokenKey(c.client.Token())]
+	return ok && !lease.expiresWithin(threshold)
+}
+
+// renewLease replaces the lease of a renewed token, if it is known.
+func renewLease(token string, lease tokenLease) {
+	tokenLeasesMu.Lock()
+	defer tokenLeasesMu.Unlock()
+	key := tokenKey(token)
+	if _, ok := tokenLeases[key]; ok {
+		tokenLeases[key] = lease
+	}
+}
+
+// forgetLease drops the lease of a token that is no longer used.
+func forgetLease(token string) {
+	tokenLeasesMu.Lock()
+	defer tokenLeasesMu.Unlock()
+	delete(tokenLeases, tokenKey(token))
+}
@@ -0,0 +1,210 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	vault "github.com/hashicorp/vault/api"
+	"k8s.io/utils/ptr"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+// makeLimitedUseClient returns a client whose Kubernetes auth login issues
+// tokens with the given lease duration, counting logins and lookups.
+func makeLimitedUseClient(t *testing.T, tokenNumUses *int, leaseDuration int, counters *renewCounters) *client {
+	t.Helper()
+	kubernetesAuth := &esv1.VaultKubernetesAuth{
+		Path: "kubernetes",
+		Role: "kubernetes-auth-role",
+	}
+	c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), kubernetesAuth, &counters.logins)
+	c.store.Auth.TokenNumUses = tokenNumUses
+	c.store.Version = esv1.VaultKVStoreV2
+	c.store.Path = ptr.To("secret")
+
+	token := ""
+	c.client = &util.VaultClient{
+		SetTokenFunc:     func(v string) { token = v },
+		TokenFunc:        func() string { return token },
+		ClearTokenFunc:   func() { token = "" },
+		NamespaceFunc:    func() string { return "" },
+		SetNamespaceFunc: func(string) {},
+	}
+	c.auth = fake.Auth{
+		LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+			counters.logins++
+			token = "limited-use-token"
+			return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: token, LeaseDuration: leaseDuration}}, nil
+		},
+	}
+	c.token = fake.Token{
+		LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+			counters.lookups++
+			return nil, errors.New("permission denied")
+		},
+		RevokeSelfWithContextFn: func(ctx context.Context, token string) error {
+			return errors.New("permission denied")
+		},
+	}
+	c.logical = fake.Logical{
+		ReadWithDataWithContextFn: fake.NewReadWithContextFn(map[string]any{"data": map[string]any{"foo": "bar"}}, nil),
+	}
+	t.Cleanup(func() { forgetLease("limited-use-token") })
+	return c
+}
+
+func TestLimitedUseToken(t *testing.T) {
+	cases := map[string]struct {
+		tokenNumUses  *int
+		leaseDuration int
+		want          renewCounters
+	}{
+		"SingleUseSkipsLookup": {
+			tokenNumUses:  ptr.To(1),
+			leaseDuration: 600,
+			want:          renewCounters{logins: 1},
+		},
+		"TwoUsesSkipsLookup": {
+			tokenNumUses:  ptr.To(2),
+			leaseDuration: 600,
+			want:          renewCounters{logins: 1},
+		},
+		"ExpiringLeaseLogsInAgain": {
+			tokenNumUses:  ptr.To(1),
+			leaseDuration: 30,
+			want:          renewCounters{logins: 2},
+		},
+		"UnlimitedUsesLooksUpToken": {
+			leaseDuration: 600,
+			want:          renewCounters{logins: 2, lookups: 1},
+		},
+		"ManyUsesLooksUpToken": {
+			tokenNumUses:  ptr.To(10),
+			leaseDuration: 600,
+			want:          renewCounters{logins: 2, lookups: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			counters := renewCounters{}
+			c := makeLimitedUseClient(t, tc.tokenNumUses, tc.leaseDuration, &counters)
+
+			// the first call logs in, the second one checks the existing token.
+			if err := c.setAuth(context.Background(), nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := c.setAuth(context.Background(), nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if counters != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, counters)
+			}
+
+			got, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "foo", Property: "foo"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != "bar" {
+				t.Errorf("expected secret value %q, got %q", "bar", got)
+			}
+		})
+	}
+}
+
+func TestLimitedUseTokenClose(t *testing.T) {
+	counters := renewCounters{}
+	c := makeLimitedUseClient(t, ptr.To(1), int((10 * time.Minute).Seconds()), &counters)
+	if err := c.setAuth(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if counters.lookups != 0 {
+		t.Errorf("expected no token lookups, got %d", counters.lookups)
+	}
+	if _, ok := tokenLeases[tokenKey("limited-use-token")]; ok {
+		t.Error("expected the lease to be dropped on close")
+	}
+}
+
+func TestExpiryJitter(t *testing.T) {
+	defer func(jitter time.Duration) { tokenExpiryJitter = jitter }(tokenExpiryJitter)
+
+	cases := map[string]struct {
+		jitter time.Duration
+		leeway int
+		// wantBase is the threshold the jitter is added to.
+		wantBase time.Duration
+	}{
+		"Disabled": {
+			wantBase: tokenExpiryThreshold,
+		},
+		"DefaultThreshold": {
+			jitter:   5 * time.Second,
+			wantBase: tokenExpiryThreshold,
+		},
+		"StoreLeeway": {
+			jitter:   10 * time.Second,
+			leeway:   300,
+			wantBase: 5 * time.Minute,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tokenExpiryJitter = tc.jitter
+			token := ""
+			c := &client{
+				store: &esv1.VaultProvider{Auth: &esv1.VaultAuth{TokenExpirationLeewaySeconds: tc.leeway}},
+				client: &util.VaultClient{
+					TokenFunc:    func() string { return token },
+					SetTokenFunc: func(v string) { token = v },
+				},
+			}
+			if got := c.expiryThreshold(); got != tc.wantBase {
+				t.Errorf("expected no jitter without a token, got threshold %s", got)
+			}
+
+			jitters := map[time.Duration]bool{}
+			for i := range 100 {
+				c.client.SetToken(fmt.Sprintf("token-%d", i))
+				threshold := c.expiryThreshold()
+				jitter := threshold - tc.wantBase
+				if jitter < 0 || (tc.jitter == 0 && jitter != 0) || (tc.jitter > 0 && jitter >= tc.jitter) {
+					t.Fatalf("expected a jitter within [0, %s), got %s", tc.jitter, jitter)
+				}
+				// the jitter stays the same for all uses of the token.
+				if again := c.expiryThreshold(); again != threshold {
+					t.Fatalf("expected the threshold %s to stay the same for the token, got %s", threshold, again)
+				}
+				jitters[jitter] = true
+			}
+			if tc.jitter > 0 && len(jitters) < 10 {
+				t.Errorf("expected the jitter to spread out between tokens, got %d distinct values", len(jitters))
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+// noRequestForwardingHeader tells a performance standby node to handle a
+// request itself instead of forwarding it to the active node.
+const noRequestForwardingHeader = "X-Vault-No-Request-Forwarding"
+
+// withLocalMount returns the client to log in with. If the auth method is
+// mounted locally to the cluster, this is a copy whose requests aren't
+// forwarded to the active node, so that the shared Vault client keeps
+// forwarding all other requests.
+func (c *client) withLocalMount() *client {
+	if c.store.Auth == nil || !c.store.Auth.LocalMount {
+		return c
+	}
+	c.log.V(1).Info("Logging in to a local auth mount without request forwarding")
+	local := c.withNamespace(c.client.Namespace())
+	local.client.AddHeader(noRequestForwardingHeader, "true")
+	return local
+}
@@ -0,0 +1,136 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestLocalMountLogin(t *testing.T) {
+	cases := map[string]struct {
+		localMount    bool
+		authNamespace *string
+		wantLocal     map[string]bool
+		wantNamespace string
+	}{
+		"Forwarded": {
+			wantLocal: map[string]bool{
+				"/v1/auth/approle/login": false,
+				"/v1/secret/data/foo":    false,
+			},
+			wantNamespace: "team-a",
+		},
+		"LocalMount": {
+			localMount: true,
+			wantLocal: map[string]bool{
+				"/v1/auth/approle/login": true,
+				"/v1/secret/data/foo":    false,
+			},
+			wantNamespace: "team-a",
+		},
+		"LocalMountInAuthNamespace": {
+			localMount:    true,
+			authNamespace: ptr.To("admin"),
+			wantLocal: map[string]bool{
+				"/v1/auth/approle/login": true,
+				"/v1/secret/data/foo":    false,
+			},
+			wantNamespace: "admin",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			local := map[string]bool{}
+			loginNamespace := ""
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				local[r.URL.Path] = r.Header.Get(noRequestForwardingHeader) == "true"
+				switch r.URL.Path {
+				case "/v1/auth/approle/login":
+					loginNamespace = r.Header.Get("X-Vault-Namespace")
+					_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token", "lease_duration": 3600}}`))
+				case "/v1/secret/data/foo":
+					_, _ = w.Write([]byte(`{"data": {"data": {"foo": "bar"}}}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "approle-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"secret-id": []byte("secret-id"),
+				},
+			}).Build()
+			auth := makeAppRoleAuth("role-id")
+			auth.LocalMount = tc.localMount
+			auth.Namespace = tc.authNamespace
+			store := &esv1.SecretStore{
+				TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vault-store",
+					Namespace: "default",
+				},
+				Spec: esv1.SecretStoreSpec{
+					Provider: &esv1.SecretStoreProvider{
+						Vault: &esv1.VaultProvider{
+							Server:    server.URL,
+							Namespace: ptr.To("team-a"),
+							Version:   esv1.VaultKVStoreV2,
+							Auth:      &auth,
+						},
+					},
+				},
+			}
+			prov := &Provider{NewVaultClient: NewVaultClient}
+			c, err := prov.newClient(context.Background(), store, kube, nil, "default")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "secret/foo"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			// only the login must bypass request forwarding.
+			if diff := cmp.Diff(tc.wantLocal, local); diff != "" {
+				t.Errorf("unexpected requests without forwarding (-want, +got):\n%s", diff)
+			}
+			if loginNamespace != tc.wantNamespace {
+				t.Errorf("expected login in namespace %q, got %q", tc.wantNamespace, loginNamespace)
+			}
+		})
+	}
+}
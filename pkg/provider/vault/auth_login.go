@@ -0,0 +1,145 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errVaultNoLoginToken = "login response contains neither an auth token nor a wrapped token"
+	errVaultUnwrapLogin  = "cannot unwrap login response: %w"
+	errVaultLoginWarning = "login returned an escalated warning: %s"
+)
+
+var _ esv1.WarningReporter = &client{}
+
+// loginSecret returns the response carrying the auth data of a login.
+// A token returned directly is preferred, the response is only unwrapped
+// if it contains a wrapping token instead.
+func (c *client) loginSecret(ctx context.Context, resp *vault.Secret) (*vault.Secret, error) {
+	if resp != nil && resp.Auth != nil && resp.Auth.ClientToken != "" {
+		return resp, nil
+	}
+	if resp == nil || resp.WrapInfo == nil || resp.WrapInfo.Token == "" {
+		return nil, errors.New(errVaultNoLoginToken)
+	}
+
+	// https://developer.hashicorp.com/vault/api-docs/system/wrapping-unwrap
+	unwrapped, err := c.logical.UnwrapWithContext(ctx, resp.WrapInfo.Token)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultUnwrap, err)
+	if err != nil {
+		return nil, fmt.Errorf(errVaultUnwrapLogin, err)
+	}
+	if unwrapped == nil || unwrapped.Auth == nil || unwrapped.Auth.ClientToken == "" {
+		return nil, fmt.Errorf(errVaultUnwrapLogin, errors.New(errVaultNoLoginToken))
+	}
+	return unwrapped, nil
+}
+
+// setLoginToken sets the token returned by a login as the client token.
+// The token is set before the warnings are checked, so that an escalated
+// warning revokes it.
+func (c *client) setLoginToken(ctx context.Context, resp *vault.Secret) error {
+	secret, err := c.loginSecret(ctx, resp)
+	if err != nil {
+		return fmt.Errorf(errVaultToken, err)
+	}
+	c.client.SetToken(secret.Auth.ClientToken)
+	c.suppliedToken = false
+	if err := c.checkLoginWarnings(ctx, resp); err != nil {
+		return err
+	}
+	c.loginAuth = secret.Auth
+	c.recordLease(secret)
+	c.logTokenAcquired(secret)
+	return nil
+}
+
+// checkLogin handles the result of a login through the auth methods of the
+// Vault client. These already set tokens that are returned directly, but
+// fail for wrapped responses, which are unwrapped here.
+func (c *client) checkLogin(ctx context.Context, resp *vault.Secret, err error) error {
+	if err != nil {
+		if resp == nil || resp.WrapInfo == nil {
+			return err
+		}
+		return c.setLoginToken(ctx, resp)
+	}
+	if err := c.checkLoginWarnings(ctx, resp); err != nil {
+		return err
+	}
+	if resp != nil {
+		c.loginAuth = resp.Auth
+	}
+	c.recordLease(resp)
+	c.logTokenAcquired(resp)
+	return nil
+}
+
+// logTokenAcquired logs the lease of the token issued by a login, to help
+// debugging logins piling up leases. The token itself is never logged.
+func (c *client) logTokenAcquired(secret *vault.Secret) {
+	if secret == nil || secret.Auth == nil {
+		return
+	}
+	c.log.V(1).Info("token acquired",
+		"lease_duration", secret.Auth.LeaseDuration,
+		"renewable", secret.Auth.Renewable,
+		"policies", len(secret.Auth.Policies),
+		"accessor", secret.Auth.Accessor)
+}
+
+// checkLoginWarnings logs the warnings of a login response and keeps them
+// for Warnings. A warning matching one of the escalated strings fails the
+// login, revoking the token it issued.
+func (c *client) checkLoginWarnings(ctx context.Context, resp *vault.Secret) error {
+	if resp == nil {
+		return nil
+	}
+	c.loginWarnings = resp.Warnings
+	var escalate []string
+	if c.store.Auth != nil && c.store.Auth.LoginWarnings != nil {
+		escalate = c.store.Auth.LoginWarnings.Escalate
+	}
+	for _, warning := range resp.Warnings {
+		c.log.Info("Vault login returned a warning", "warning", warning)
+		for _, s := range escalate {
+			if s != "" && strings.Contains(warning, s) {
+				c.dropLoginToken(ctx, "an escalated login warning")
+				return fmt.Errorf(errVaultLoginWarning, warning)
+			}
+		}
+	}
+	return nil
+}
+
+// Warnings returns the warnings of the last login, if they are reported
+// in a condition of the store.
+func (c *client) Warnings() []string {
+	if c.store.Auth == nil || c.store.Auth.LoginWarnings == nil || !c.store.Auth.LoginWarnings.Condition {
+		return nil
+	}
+	return c.loginWarnings
+}
@@ -0,0 +1,301 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestSetLoginToken(t *testing.T) {
+	wrapped := &vault.SecretWrapInfo{Token: "wrapping-token"}
+	unwrappedAuth := &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "unwrapped-token"}}
+
+	cases := map[string]struct {
+		resp       *vault.Secret
+		unwrapped  *vault.Secret
+		unwrapErr  error
+		wantToken  string
+		wantErr    error
+		wantUnwrap int
+	}{
+		"DirectToken": {
+			resp:      &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "direct-token"}},
+			wantToken: "direct-token",
+		},
+		"DirectTokenPreferredOverWrapped": {
+			resp:      &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "direct-token"}, WrapInfo: wrapped},
+			unwrapped: unwrappedAuth,
+			wantToken: "direct-token",
+		},
+		"WrappedOnly": {
+			resp:       &vault.Secret{WrapInfo: wrapped},
+			unwrapped:  unwrappedAuth,
+			wantToken:  "unwrapped-token",
+			wantUnwrap: 1,
+		},
+		"Neither": {
+			resp:    &vault.Secret{Auth: &vault.SecretAuth{}},
+			wantErr: fmt.Errorf(errVaultToken, errors.New(errVaultNoLoginToken)),
+		},
+		"NoResponse": {
+			wantErr: fmt.Errorf(errVaultToken, errors.New(errVaultNoLoginToken)),
+		},
+		"WrappedWithoutAuth": {
+			resp:       &vault.Secret{WrapInfo: wrapped},
+			unwrapped:  &vault.Secret{Data: map[string]any{"foo": "bar"}},
+			wantErr:    fmt.Errorf(errVaultToken, fmt.Errorf(errVaultUnwrapLogin, errors.New(errVaultNoLoginToken))),
+			wantUnwrap: 1,
+		},
+		"UnwrapFails": {
+			resp:       &vault.Secret{WrapInfo: wrapped},
+			unwrapErr:  errors.New("wrapping token is not valid or does not exist"),
+			wantErr:    fmt.Errorf(errVaultToken, fmt.Errorf(errVaultUnwrapLogin, errors.New("wrapping token is not valid or does not exist"))),
+			wantUnwrap: 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			token := ""
+			unwraps := 0
+			c := &client{
+				log:   logger,
+				store: &esv1.VaultProvider{},
+				client: &util.VaultClient{
+					SetTokenFunc: func(v string) { token = v },
+				},
+				logical: fake.Logical{
+					UnwrapWithContextFn: func(ctx context.Context, wrappingToken string) (*vault.Secret, error) {
+						unwraps++
+						if wrappingToken != wrapped.Token {
+							t.Errorf("expected wrapping token %q, got %q", wrapped.Token, wrappingToken)
+						}
+						return tc.unwrapped, tc.unwrapErr
+					},
+				},
+			}
+
+			err := c.setLoginToken(context.Background(), tc.resp)
+			if tc.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != nil && (err == nil || err.Error() != tc.wantErr.Error()) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if token != tc.wantToken {
+				t.Errorf("expected token %q, got %q", tc.wantToken, token)
+			}
+			if unwraps != tc.wantUnwrap {
+				t.Errorf("expected %d unwrap calls, got %d", tc.wantUnwrap, unwraps)
+			}
+		})
+	}
+}
+
+func TestCheckLoginWrappedResponse(t *testing.T) {
+	token := ""
+	c := &client{
+		log:   logger,
+		store: &esv1.VaultProvider{},
+		client: &util.VaultClient{
+			SetTokenFunc: func(v string) { token = v },
+		},
+		logical: fake.Logical{
+			UnwrapWithContextFn: func(ctx context.Context, wrappingToken string) (*vault.Secret, error) {
+				return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "unwrapped-token"}}, nil
+			},
+		},
+	}
+
+	// the Vault client's auth methods fail if no token is returned directly.
+	loginErr := errors.New("response did not return ClientToken, client token not set")
+	resp := &vault.Secret{WrapInfo: &vault.SecretWrapInfo{Token: "wrapping-token"}}
+	if err := c.checkLogin(context.Background(), resp, loginErr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "unwrapped-token" {
+		t.Errorf("expected token %q, got %q", "unwrapped-token", token)
+	}
+
+	if err := c.checkLogin(context.Background(), nil, loginErr); !errors.Is(err, loginErr) {
+		t.Errorf("expected login error to be returned, got %v", err)
+	}
+}
+
+func TestCheckLoginWarnings(t *testing.T) {
+	warnings := []string{
+		"policy \"legacy\" is deprecated",
+		"TTL of \"768h\" exceeded the effective max_ttl of \"24h\"; TTL value is capped accordingly",
+	}
+
+	cases := map[string]struct {
+		config       *esv1.VaultLoginWarnings
+		wantErr      error
+		wantWarnings []string
+	}{
+		"LoggedOnly": {},
+		"Condition": {
+			config:       &esv1.VaultLoginWarnings{Condition: true},
+			wantWarnings: warnings,
+		},
+		"EscalatedWarning": {
+			config:  &esv1.VaultLoginWarnings{Escalate: []string{"is deprecated"}},
+			wantErr: fmt.Errorf(errVaultLoginWarning, warnings[0]),
+		},
+		"OtherWarningEscalated": {
+			config: &esv1.VaultLoginWarnings{Escalate: []string{"unknown role"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var logged []string
+			token := "login-token"
+			revoked := 0
+			c := &client{
+				log: funcr.New(func(prefix, args string) {
+					logged = append(logged, args)
+				}, funcr.Options{}),
+				store: &esv1.VaultProvider{
+					Auth: &esv1.VaultAuth{LoginWarnings: tc.config},
+				},
+				client: &util.VaultClient{
+					TokenFunc:      func() string { return token },
+					ClearTokenFunc: func() { token = "" },
+					AuthTokenField: fake.Token{
+						LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+							return makeTokenLookup(time.Hour, true), nil
+						},
+						RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+							revoked++
+							return nil
+						},
+					},
+				},
+			}
+
+			err := c.checkLogin(context.Background(), &vault.Secret{
+				Auth:     &vault.SecretAuth{ClientToken: token},
+				Warnings: warnings,
+			}, nil)
+			if tc.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != nil && (err == nil || err.Error() != tc.wantErr.Error()) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if escalated := token == ""; escalated != (tc.wantErr != nil) {
+				t.Errorf("expected token to be cleared only on escalation, got %q", token)
+			}
+			// the token of an escalated login is revoked rather than left
+			// to expire.
+			if wantRevoked := tc.wantErr != nil; wantRevoked != (revoked == 1) {
+				t.Errorf("expected token revoked: %t, got %d revocations", wantRevoked, revoked)
+			}
+			if len(logged) == 0 || !strings.Contains(logged[0], "deprecated") {
+				t.Errorf("expected the warning to be logged, got %v", logged)
+			}
+			if diff := cmp.Diff(tc.wantWarnings, c.Warnings()); diff != "" {
+				t.Errorf("unexpected warnings (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLogTokenAcquired(t *testing.T) {
+	auth := &vault.SecretAuth{
+		ClientToken:   "hvs.secret-token",
+		Accessor:      "token-accessor",
+		Policies:      []string{"default", "app"},
+		LeaseDuration: 3600,
+		Renewable:     true,
+	}
+	cases := map[string]struct {
+		resp     *vault.Secret
+		unwrap   *vault.Secret
+		wantLogs int
+	}{
+		"Direct": {
+			resp:     &vault.Secret{Auth: auth},
+			wantLogs: 1,
+		},
+		"Wrapped": {
+			resp:     &vault.Secret{WrapInfo: &vault.SecretWrapInfo{Token: "wrapping-token"}},
+			unwrap:   &vault.Secret{Auth: auth},
+			wantLogs: 1,
+		},
+		"NoAuth": {
+			resp: &vault.Secret{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var logged []string
+			c := &client{
+				log: funcr.New(func(prefix, args string) {
+					if strings.Contains(args, `"msg"="token acquired"`) {
+						logged = append(logged, args)
+					}
+				}, funcr.Options{Verbosity: 1}),
+				store: &esv1.VaultProvider{Auth: &esv1.VaultAuth{}},
+				client: &util.VaultClient{
+					SetTokenFunc: func(string) {},
+				},
+				logical: fake.Logical{
+					UnwrapWithContextFn: func(ctx context.Context, wrappingToken string) (*vault.Secret, error) {
+						return tc.unwrap, nil
+					},
+				},
+			}
+
+			var err error
+			if tc.unwrap != nil {
+				err = c.setLoginToken(context.Background(), tc.resp)
+			} else {
+				err = c.checkLogin(context.Background(), tc.resp, nil)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(logged) != tc.wantLogs {
+				t.Fatalf("expected %d token acquired logs, got %v", tc.wantLogs, logged)
+			}
+			for _, line := range logged {
+				for _, field := range []string{`"lease_duration"=3600`, `"renewable"=true`, `"policies"=2`, `"accessor"="token-accessor"`} {
+					if !strings.Contains(line, field) {
+						t.Errorf("expected log %q to contain %s", line, field)
+					}
+				}
+				if strings.Contains(line, auth.ClientToken) {
+					t.Errorf("expected log %q not to contain the token", line)
+				}
+			}
+		})
+	}
+}
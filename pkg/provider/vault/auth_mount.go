@@ -0,0 +1,165 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+const (
+	errVaultMountAuth = "cannot log in for reads below %q: %w"
+	errVaultWriteAuth = "cannot log in for writes: %w"
+)
+
+// mountClients holds the clients logged in with the auth of a mount, so
+// that each mount logs in at most once for the lifetime of a client, and
+// the client logged in with the WriteAuth of the store.
+type mountClients struct {
+	mu      sync.Mutex
+	clients map[string]*client
+	writer  *client
+}
+
+func newMountClients() *mountClients {
+	return &mountClients{clients: map[string]*client{}}
+}
+
+// normalizeMountPath trims the slashes of a mount path.
+func normalizeMountPath(path string) string {
+	return strings.Trim(path, "/")
+}
+
+// mountAuthFor returns the mount auth with the longest path that the Vault
+// path is below, or nil if the path isn't below any of them.
+func (c *client) mountAuthFor(path string) *esv1.VaultMountAuth {
+	path = normalizeMountPath(path)
+	var match *esv1.VaultMountAuth
+	matchLen := -1
+	for i := range c.store.MountAuth {
+		mount := &c.store.MountAuth[i]
+		prefix := normalizeMountPath(mount.Path)
+		if prefix == "" || len(prefix) <= matchLen {
+			continue
+		}
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			match, matchLen = mount, len(prefix)
+		}
+	}
+	return match
+}
+
+// logicalFor returns the Logical to read the Vault path with. Paths below
+// a mount with its own auth are read with a token of that auth.
+func (c *client) logicalFor(ctx context.Context, path string) (util.Logical, error) {
+	scoped, err := c.clientFor(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return scoped.logical, nil
+}
+
+// clientFor returns the client to read the Vault path with, which is the
+// client logged in for the mount with its own auth the path is below, if
+// there is one.
+func (c *client) clientFor(ctx context.Context, path string) (*client, error) {
+	mount := c.mountAuthFor(path)
+	// auth override and external tokens are used for all paths.
+	if mount == nil || c.mounts == nil || c.authOverride != nil || c.externalToken != nil {
+		return c, nil
+	}
+	key := normalizeMountPath(mount.Path)
+
+	c.mounts.mu.Lock()
+	defer c.mounts.mu.Unlock()
+	if scoped, ok := c.mounts.clients[key]; ok {
+		return scoped, nil
+	}
+	scoped, err := c.loginScoped(ctx, &mount.Auth)
+	if err != nil {
+		return nil, fmt.Errorf(errVaultMountAuth, mount.Path, err)
+	}
+	c.mounts.clients[key] = scoped
+	return scoped, nil
+}
+
+// writeClient returns the client to write secrets with, which is logged in
+// with the WriteAuth of the store, if it has one.
+func (c *client) writeClient(ctx context.Context) (*client, error) {
+	// auth override and external tokens are used for all writes.
+	if c.store.WriteAuth == nil || c.mounts == nil || c.authOverride != nil || c.externalToken != nil {
+		return c, nil
+	}
+
+	c.mounts.mu.Lock()
+	defer c.mounts.mu.Unlock()
+	if c.mounts.writer != nil {
+		return c.mounts.writer, nil
+	}
+	writer, err := c.loginScoped(ctx, c.store.WriteAuth)
+	if err != nil {
+		return nil, fmt.Errorf(errVaultWriteAuth, err)
+	}
+	c.mounts.writer = writer
+	return writer, nil
+}
+
+// loginScoped returns a copy of the client using a clone of the Vault
+// client logged in with the auth, e.g. that of a mount.
+func (c *client) loginScoped(ctx context.Context, auth *esv1.VaultAuth) (*client, error) {
+	store := *c.store
+	store.Auth = auth
+	store.MountAuth = nil
+	store.WriteAuth = nil
+	scoped := c.withNamespace(c.client.Namespace())
+	scoped.store = &store
+	scoped.mounts = nil
+	scoped.heldToken = ""
+	scoped.client.ClearToken()
+	if err := scoped.setAuth(ctx, c.config); err != nil {
+		return nil, err
+	}
+	return scoped, nil
+}
+
+// closeMounts revokes the tokens of the mounts and of the writes. These are
+// never cached, so they are revoked even if token caching is enabled.
+func (c *client) closeMounts(ctx context.Context) error {
+	if c.mounts == nil {
+		return nil
+	}
+	c.mounts.mu.Lock()
+	defer c.mounts.mu.Unlock()
+	var errs []error
+	for key, scoped := range c.mounts.clients {
+		if !scoped.releaseToken(ctx) {
+			errs = append(errs, scoped.revokeLoginToken(ctx))
+		}
+		delete(c.mounts.clients, key)
+	}
+	if writer := c.mounts.writer; writer != nil {
+		if !writer.releaseToken(ctx) {
+			errs = append(errs, writer.revokeLoginToken(ctx))
+		}
+		c.mounts.writer = nil
+	}
+	return errors.Join(errs...)
+}
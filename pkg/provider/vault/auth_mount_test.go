@@ -0,0 +1,241 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	testingfake "github.com/external-secrets/external-secrets/pkg/provider/testing/fake"
+)
+
+func makeAppRoleAuth(roleID string) esv1.VaultAuth {
+	return esv1.VaultAuth{
+		AppRole: &esv1.VaultAppRole{
+			Path:   "approle",
+			RoleID: roleID,
+			SecretRef: esmeta.SecretKeySelector{
+				Name: "approle-secret",
+				Key:  "secret-id",
+			},
+		},
+	}
+}
+
+func TestMountAuth(t *testing.T) {
+	var mu sync.Mutex
+	logins := map[string]int{}
+	reads := map[string]string{}
+	var revoked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		token := r.Header.Get("X-Vault-Token")
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("cannot decode login request: %v", err)
+			}
+			logins[body["role_id"]]++
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "token-` + body["role_id"] + `", "lease_duration": 3600}}`))
+		case "/v1/auth/token/lookup-self":
+			_, _ = w.Write([]byte(`{"data": {"type": "service", "ttl": 3600, "expire_time": null}}`))
+		case "/v1/auth/token/revoke-self":
+			revoked = append(revoked, token)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			reads[r.URL.Path] = token
+			_, _ = w.Write([]byte(`{"data": {"data": {"value": "secret"}}}`))
+		}
+	}))
+	defer server.Close()
+
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "approle-secret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"secret-id": []byte("secret-id"),
+		},
+	}).Build()
+	store := &esv1.SecretStore{
+		TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vault-store",
+			Namespace: "default",
+		},
+		Spec: esv1.SecretStoreSpec{
+			Provider: &esv1.SecretStoreProvider{
+				Vault: &esv1.VaultProvider{
+					Server:  server.URL,
+					Version: esv1.VaultKVStoreV2,
+					Auth:    ptr.To(makeAppRoleAuth("default")),
+					MountAuth: []esv1.VaultMountAuth{
+						{Path: "team-a", Auth: makeAppRoleAuth("team-a")},
+						{Path: "/team-a/data/restricted/", Auth: makeAppRoleAuth("restricted")},
+						{Path: "team-b", Auth: makeAppRoleAuth("team-b")},
+					},
+				},
+			},
+		},
+	}
+	prov := &Provider{NewVaultClient: NewVaultClient}
+	c, err := prov.newClient(context.Background(), store, kube, nil, "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, key := range []string{"secret/foo", "team-a/bar", "team-a/baz", "team-a/restricted/qux", "team-ab/quux"} {
+		if _, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: key}); err != nil {
+			t.Fatalf("unexpected error reading %q: %v", key, err)
+		}
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error on close: %v", err)
+	}
+
+	wantReads := map[string]string{
+		"/v1/secret/data/foo":            "token-default",
+		"/v1/team-a/data/bar":            "token-team-a",
+		"/v1/team-a/data/baz":            "token-team-a",
+		"/v1/team-a/data/restricted/qux": "token-restricted",
+		"/v1/team-ab/data/quux":          "token-default",
+	}
+	if diff := cmp.Diff(wantReads, reads); diff != "" {
+		t.Errorf("unexpected tokens per read path (-want, +got):\n%s", diff)
+	}
+	// mounts log in on their first read only, unused mounts never.
+	wantLogins := map[string]int{"default": 1, "team-a": 1, "restricted": 1}
+	if diff := cmp.Diff(wantLogins, logins); diff != "" {
+		t.Errorf("unexpected logins per role (-want, +got):\n%s", diff)
+	}
+	slices.Sort(revoked)
+	wantRevoked := []string{"token-default", "token-restricted", "token-team-a"}
+	if diff := cmp.Diff(wantRevoked, revoked); diff != "" {
+		t.Errorf("unexpected revoked tokens (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWriteAuth(t *testing.T) {
+	var mu sync.Mutex
+	logins := map[string]int{}
+	requests := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("cannot decode login request: %v", err)
+			}
+			logins[body["role_id"]]++
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "token-` + body["role_id"] + `", "lease_duration": 3600}}`))
+		case "/v1/auth/token/lookup-self":
+			_, _ = w.Write([]byte(`{"data": {"type": "service", "ttl": 3600, "expire_time": null}}`))
+		case "/v1/auth/token/revoke-self":
+			w.WriteHeader(http.StatusNoContent)
+		case "/v1/secret/metadata/foo":
+			requests[r.Method+" "+r.URL.Path] = r.Header.Get("X-Vault-Token")
+			_, _ = w.Write([]byte(`{"data": {"custom_metadata": {"managed-by": "external-secrets"}}}`))
+		default:
+			requests[r.Method+" "+r.URL.Path] = r.Header.Get("X-Vault-Token")
+			_, _ = w.Write([]byte(`{"data": {"data": {"value": "secret"}}}`))
+		}
+	}))
+	defer server.Close()
+
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "approle-secret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"secret-id": []byte("secret-id"),
+		},
+	}).Build()
+	store := &esv1.SecretStore{
+		TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vault-store",
+			Namespace: "default",
+		},
+		Spec: esv1.SecretStoreSpec{
+			Provider: &esv1.SecretStoreProvider{
+				Vault: &esv1.VaultProvider{
+					Server:    server.URL,
+					Path:      ptr.To("secret"),
+					Version:   esv1.VaultKVStoreV2,
+					Auth:      ptr.To(makeAppRoleAuth("read")),
+					WriteAuth: ptr.To(makeAppRoleAuth("write")),
+				},
+			},
+		},
+	}
+	prov := &Provider{NewVaultClient: NewVaultClient}
+	c, err := prov.newClient(context.Background(), store, kube, nil, "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "bar"}); err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	}
+	secret := &corev1.Secret{Data: map[string][]byte{"value": []byte("new-secret")}}
+	data := testingfake.PushSecretData{SecretKey: "value", RemoteKey: "foo", Property: "value"}
+	if err := c.PushSecret(context.Background(), secret, data); err != nil {
+		t.Fatalf("unexpected error pushing: %v", err)
+	}
+	if err := c.DeleteSecret(context.Background(), data); err != nil {
+		t.Fatalf("unexpected error deleting: %v", err)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error on close: %v", err)
+	}
+
+	// the pushes read the current secret with the write token as well.
+	wantRequests := map[string]string{
+		"GET /v1/secret/data/bar":        "token-read",
+		"GET /v1/secret/data/foo":        "token-write",
+		"GET /v1/secret/metadata/foo":    "token-write",
+		"PUT /v1/secret/metadata/foo":    "token-write",
+		"PUT /v1/secret/data/foo":        "token-write",
+		"DELETE /v1/secret/data/foo":     "token-write",
+		"DELETE /v1/secret/metadata/foo": "token-write",
+	}
+	if diff := cmp.Diff(wantRequests, requests); diff != "" {
+		t.Errorf("unexpected tokens per request (-want, +got):\n%s", diff)
+	}
+	// the write auth logs in on the first write only.
+	wantLogins := map[string]int{"read": 1, "write": 1}
+	if diff := cmp.Diff(wantLogins, logins); diff != "" {
+		t.Errorf("unexpected logins per role (-want, +got):\n%s", diff)
+	}
+}
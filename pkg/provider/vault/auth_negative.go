@@ -0,0 +1,140 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+const (
+	errCachedAuthFailure = "not logging in again before %s, the previous login was rejected: %w"
+)
+
+// authFailure is a rejected login of a store configuration.
+type authFailure struct {
+	method string
+	err    error
+	until  time.Time
+	// credentials are the versions of the Secrets the login read its
+	// credentials from, see credentialsVersion.
+	credentials string
+}
+
+var (
+	// negativeAuthCacheTTL is how long a rejected login fails the logins of
+	// the same store configuration without contacting Vault. Disabled if
+	// zero.
+	negativeAuthCacheTTL time.Duration
+
+	authFailuresMu sync.Mutex
+	authFailures   = map[string]authFailure{}
+)
+
+// authFailureKey identifies the store and its configuration, so that a
+// change of the configuration invalidates a cached failure.
+func (c *client) authFailureKey() (string, error) {
+	data, err := json.Marshal(c.store)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s/%s/%s/", c.storeKind, c.namespace, c.storeName)
+	_, _ = h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// credentialsVersion identifies the versions of the Secrets holding the
+// AppRole credentials of the store, so that rotating them invalidates a
+// cached failure as well.
+func (c *client) credentialsVersion(ctx context.Context) string {
+	if c.store.Auth == nil || c.store.Auth.AppRole == nil {
+		return ""
+	}
+	var versions []string
+	for _, ref := range appRoleSecretRefs(c.store.Auth.AppRole) {
+		secret := &corev1.Secret{}
+		// a missing Secret has no version, so that creating it counts as a
+		// change.
+		_ = c.kube.Get(ctx, types.NamespacedName{Namespace: c.secretRefNamespace(ref), Name: ref.Name}, secret)
+		versions = append(versions, ref.Name+"@"+secret.ResourceVersion)
+	}
+	return strings.Join(versions, ",")
+}
+
+// cachedAuthFailure returns the auth method and error of a rejected login
+// of the store configuration within negativeAuthCacheTTL, if any. Failures
+// are dropped once the credentials of the login were rotated.
+func (c *client) cachedAuthFailure(ctx context.Context) (string, error) {
+	if negativeAuthCacheTTL <= 0 {
+		return "", nil
+	}
+	key, err := c.authFailureKey()
+	if err != nil {
+		return "", nil
+	}
+	credentials := c.credentialsVersion(ctx)
+	authFailuresMu.Lock()
+	defer authFailuresMu.Unlock()
+	failure, ok := authFailures[key]
+	if !ok {
+		return "", nil
+	}
+	if !time.Now().Before(failure.until) {
+		delete(authFailures, key)
+		return "", nil
+	}
+	if failure.credentials != credentials {
+		delete(authFailures, key)
+		c.log.V(1).Info("Login credentials changed since the cached login failure")
+		return "", nil
+	}
+	c.log.V(1).Info("Using cached login failure", "until", failure.until)
+	return failure.method, fmt.Errorf(errCachedAuthFailure, failure.until.Format(time.RFC3339), failure.err)
+}
+
+// rememberAuthFailure caches the error of a login with the named auth
+// method if Vault rejected it. Transient failures are not cached, as the
+// next login may succeed.
+func (c *client) rememberAuthFailure(ctx context.Context, method string, loginErr error) {
+	if negativeAuthCacheTTL <= 0 || c.classifyLoginError(method, loginErr) != esv1.VaultAuthErrorClassAuthRejected {
+		return
+	}
+	key, err := c.authFailureKey()
+	if err != nil {
+		return
+	}
+	credentials := c.credentialsVersion(ctx)
+	authFailuresMu.Lock()
+	defer authFailuresMu.Unlock()
+	now := time.Now()
+	for k, failure := range authFailures {
+		if !now.Before(failure.until) {
+			delete(authFailures, k)
+		}
+	}
+	authFailures[key] = authFailure{method: method, err: loginErr, until: now.Add(negativeAuthCacheTTL), credentials: credentials}
+}
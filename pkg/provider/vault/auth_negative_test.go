@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestNegativeAuthCache(t *testing.T) {
+	defer func(ttl time.Duration) { negativeAuthCacheTTL = ttl }(negativeAuthCacheTTL)
+	negativeAuthCacheTTL = time.Minute
+	defer func() { authFailures = map[string]authFailure{} }()
+
+	cases := map[string]struct {
+		loginErr error
+		// change is applied to the store before the third login.
+		change     func(c *client)
+		wantLogins int
+	}{
+		// the second login fails with the cached error, the third one
+		// logs in again as the role changed.
+		"RejectedInvalidatedOnConfigChange": {
+			loginErr: &vault.ResponseError{StatusCode: http.StatusForbidden},
+			change: func(c *client) {
+				c.store.Auth.Kubernetes.Role = "other-role"
+			},
+			wantLogins: 2,
+		},
+		"RejectedExpired": {
+			loginErr: &vault.ResponseError{StatusCode: http.StatusForbidden},
+			change: func(c *client) {
+				authFailuresMu.Lock()
+				defer authFailuresMu.Unlock()
+				for key, failure := range authFailures {
+					failure.until = time.Now()
+					authFailures[key] = failure
+				}
+			},
+			wantLogins: 2,
+		},
+		"TransientNotCached": {
+			loginErr:   &vault.ResponseError{StatusCode: http.StatusInternalServerError},
+			change:     func(c *client) {},
+			wantLogins: 3,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			authFailures = map[string]authFailure{}
+			logins := 0
+			kubernetesAuth := &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), kubernetesAuth, &logins)
+			c.storeName = "vault-store"
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					logins++
+					return nil, tc.loginErr
+				},
+			}
+			c.logical = fake.Logical{
+				ReadWithDataWithContextFn: fake.NewReadWithContextFn(nil, errors.New("permission denied")),
+			}
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+			}
+
+			for i := range 3 {
+				if i == 2 {
+					tc.change(c)
+				}
+				err := c.setAuth(context.Background(), nil)
+				var respErr *vault.ResponseError
+				if !errors.As(err, &respErr) {
+					t.Fatalf("login %d: expected the login error, got %v", i, err)
+				}
+			}
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, logins)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/utils/resolvers"
+)
+
+const (
+	errAuthOverride        = "cannot use auth override token: %w"
+	errAuthOverrideInvalid = "token is a batch token or about to expire"
+	errAuthOverrideStore   = "the store doesn't allow auth overrides"
+	errAuthOverrideNS      = "the store doesn't allow auth overrides in namespace %q"
+)
+
+// authOverrideAllowed checks that the store opted in to auth overrides.
+// ClusterSecretStores must also list the namespace of the ExternalSecret.
+func authOverrideAllowed(store esv1.GenericStore, auth *esv1.VaultAuth, namespace string) error {
+	if auth == nil || auth.AuthOverride == nil {
+		return errors.New(errAuthOverrideStore)
+	}
+	if store.GetKind() == esv1.ClusterSecretStoreKind && !slices.Contains(auth.AuthOverride.Namespaces, namespace) {
+		return fmt.Errorf(errAuthOverrideNS, namespace)
+	}
+	return nil
+}
+
+// useAuthOverride sets the token of the auth override secret instead of
+// authenticating with the store's configured auth. The secret is always
+// read from the namespace of the ExternalSecret.
+func (c *client) useAuthOverride(ctx context.Context) error {
+	token, err := resolvers.SecretKeyRef(ctx, c.kube, esv1.SecretStoreKind, c.namespace, c.authOverride)
+	if err != nil {
+		return fmt.Errorf(errAuthOverride, err)
+	}
+	c.client.SetToken(token)
+	state, err := checkToken(ctx, c.token, c.expiryThreshold())
+	if err != nil {
+		return fmt.Errorf(errAuthOverride, err)
+	}
+	if state != tokenValid {
+		return fmt.Errorf(errAuthOverride, errors.New(errAuthOverrideInvalid))
+	}
+	c.log.Info("Using auth override token instead of the store's auth", "namespace", c.namespace, "secret", c.authOverride.Name)
+	return nil
+}
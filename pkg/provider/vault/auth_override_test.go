@@ -0,0 +1,252 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	utilfake "github.com/external-secrets/external-secrets/pkg/provider/util/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+const (
+	overrideSecretName = "break-glass-token"
+	overrideToken      = "override-token"
+	storeToken         = "store-token"
+)
+
+// overrideTestClient is a Vault client created by the provider under test.
+type overrideTestClient struct {
+	token   string
+	logins  int
+	revokes int
+}
+
+func makeOverrideProvider(lookup *vault.Secret, clients *[]*overrideTestClient) *Provider {
+	return &Provider{
+		NewVaultClient: func(config *vault.Config) (util.Client, error) {
+			tc := &overrideTestClient{}
+			*clients = append(*clients, tc)
+			tokens := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					return lookup, nil
+				},
+				RevokeSelfWithContextFn: func(ctx context.Context, token string) error {
+					tc.revokes++
+					return nil
+				},
+			}
+			return &util.VaultClient{
+				SetTokenFunc:     func(v string) { tc.token = v },
+				TokenFunc:        func() string { return tc.token },
+				ClearTokenFunc:   func() { tc.token = "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+				AddHeaderFunc:    func(string, string) {},
+				AuthTokenField:   tokens,
+				LogicalField:     fake.NewVaultLogical(),
+				AuthField: fake.Auth{
+					LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+						tc.logins++
+						tc.token = storeToken
+						return nil, nil
+					},
+				},
+			}, nil
+		},
+	}
+}
+
+func allowAuthOverride(namespaces ...string) secretStoreTweakFn {
+	return func(store *esv1.SecretStore) {
+		store.Spec.Provider.Vault.Auth.AuthOverride = &esv1.VaultAuthOverride{Namespaces: namespaces}
+	}
+}
+
+func TestAuthOverride(t *testing.T) {
+	store := makeValidSecretStore()
+	allowAuthOverride()(store)
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      overrideSecretName,
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			esv1.AuthOverrideSecretKey: []byte(overrideToken),
+		},
+	}).Build()
+	corev1Client := utilfake.NewCreateTokenMock().WithToken("ok")
+	overrideCtx := esv1.ContextWithAuthOverride(context.Background(), esmeta.SecretKeySelector{
+		Name: overrideSecretName,
+		Key:  esv1.AuthOverrideSecretKey,
+	})
+
+	var clients []*overrideTestClient
+	prov := makeOverrideProvider(makeTokenLookup(time.Hour, true), &clients)
+
+	regular, err := prov.newClient(context.Background(), store, kube, corev1Client, "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	override, err := prov.newClient(overrideCtx, store, kube, corev1Client, "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(clients) != 2 {
+		t.Fatalf("expected 2 Vault clients, got %d", len(clients))
+	}
+
+	// the override token is only used by the client of that request.
+	if clients[1].token != overrideToken {
+		t.Errorf("expected override token %q, got %q", overrideToken, clients[1].token)
+	}
+	if clients[1].logins != 0 {
+		t.Errorf("expected no login with an override token, got %d", clients[1].logins)
+	}
+	if clients[0].token != storeToken || clients[0].logins != 1 {
+		t.Errorf("expected the store's token to be untouched, got %q after %d logins", clients[0].token, clients[0].logins)
+	}
+
+	// the store's token is revoked on close, the override token is not.
+	if err := override.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := regular.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clients[1].revokes != 0 {
+		t.Errorf("expected override token not to be revoked, got %d revokes", clients[1].revokes)
+	}
+	if clients[0].revokes != 1 {
+		t.Errorf("expected store token to be revoked, got %d revokes", clients[0].revokes)
+	}
+}
+
+func TestAuthOverrideAllowed(t *testing.T) {
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      overrideSecretName,
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			esv1.AuthOverrideSecretKey: []byte(overrideToken),
+		},
+	}).Build()
+	ref := esmeta.SecretKeySelector{Name: overrideSecretName, Key: esv1.AuthOverrideSecretKey}
+
+	cases := map[string]struct {
+		store esv1.GenericStore
+	}{
+		"SecretStore": {
+			store: makeSecretStore(allowAuthOverride("other")),
+		},
+		"ClusterSecretStoreNamespace": {
+			store: makeClusterSecretStore(allowAuthOverride("other", "default")),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var clients []*overrideTestClient
+			prov := makeOverrideProvider(makeTokenLookup(time.Hour, true), &clients)
+			ctx := esv1.ContextWithAuthOverride(context.Background(), ref)
+			if _, err := prov.newClient(ctx, tc.store, kube, utilfake.NewCreateTokenMock().WithToken("ok"), "default"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if clients[0].token != overrideToken {
+				t.Errorf("expected override token %q, got %q", overrideToken, clients[0].token)
+			}
+		})
+	}
+}
+
+func TestAuthOverrideInvalid(t *testing.T) {
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      overrideSecretName,
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			esv1.AuthOverrideSecretKey: []byte(overrideToken),
+		},
+	}).Build()
+
+	ref := esmeta.SecretKeySelector{Name: overrideSecretName, Key: esv1.AuthOverrideSecretKey}
+
+	cases := map[string]struct {
+		store   esv1.GenericStore
+		ref     esmeta.SecretKeySelector
+		lookup  *vault.Secret
+		wantErr error
+	}{
+		"NotAllowed": {
+			store:   makeValidSecretStore(),
+			ref:     ref,
+			lookup:  makeTokenLookup(time.Hour, true),
+			wantErr: fmt.Errorf(errAuthOverride, errors.New(errAuthOverrideStore)),
+		},
+		"ClusterSecretStoreNotAllowed": {
+			store:   makeClusterSecretStore(),
+			ref:     ref,
+			lookup:  makeTokenLookup(time.Hour, true),
+			wantErr: fmt.Errorf(errAuthOverride, errors.New(errAuthOverrideStore)),
+		},
+		"ClusterSecretStoreNamespaceNotAllowed": {
+			store:   makeClusterSecretStore(allowAuthOverride("other")),
+			ref:     ref,
+			lookup:  makeTokenLookup(time.Hour, true),
+			wantErr: fmt.Errorf(errAuthOverride, fmt.Errorf(errAuthOverrideNS, "default")),
+		},
+		"ExpiringToken": {
+			store:   makeSecretStore(allowAuthOverride()),
+			ref:     ref,
+			lookup:  makeTokenLookup(30*time.Second, true),
+			wantErr: fmt.Errorf(errAuthOverride, errors.New(errAuthOverrideInvalid)),
+		},
+		"MissingSecret": {
+			store:   makeSecretStore(allowAuthOverride()),
+			ref:     esmeta.SecretKeySelector{Name: "missing", Key: esv1.AuthOverrideSecretKey},
+			lookup:  makeTokenLookup(time.Hour, true),
+			wantErr: fmt.Errorf(errAuthOverride, fmt.Errorf(`cannot get Kubernetes secret "missing" from namespace "default": %w`, errors.New(`secrets "missing" not found`))),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var clients []*overrideTestClient
+			prov := makeOverrideProvider(tc.lookup, &clients)
+			ctx := esv1.ContextWithAuthOverride(context.Background(), tc.ref)
+			_, err := prov.newClient(ctx, tc.store, kube, utilfake.NewCreateTokenMock().WithToken("ok"), "default")
+			if err == nil || err.Error() != tc.wantErr.Error() {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if len(clients) > 0 && clients[0].logins != 0 {
+				t.Errorf("expected no fallback to the store's auth, got %d logins", clients[0].logins)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+	"github.com/external-secrets/external-secrets/pkg/utils/resolvers"
+)
+
+const (
+	errPluginParameter = "cannot read login parameter %q: %w"
+	errPluginLogin     = "cannot log in to auth/%s/login: %w"
+)
+
+func setPluginAuthToken(ctx context.Context, v *client) (bool, error) {
+	pluginAuth := v.store.Auth.Plugin
+	if pluginAuth != nil {
+		err := v.requestTokenWithPluginAuth(ctx, pluginAuth)
+		if err != nil {
+			return true, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (c *client) requestTokenWithPluginAuth(ctx context.Context, pluginAuth *esv1.VaultPluginAuth) error {
+	params := maps.Clone(pluginAuth.Parameters)
+	if params == nil {
+		params = map[string]string{}
+	}
+	for _, name := range slices.Sorted(maps.Keys(pluginAuth.SecretParameters)) {
+		ref := pluginAuth.SecretParameters[name]
+		if err := c.checkLoginSecrets(ctx, &ref); err != nil {
+			return err
+		}
+		value, err := resolvers.SecretKeyRef(ctx, c.kube, c.storeKind, c.namespace, &ref)
+		if err != nil {
+			return fmt.Errorf(errPluginParameter, name, err)
+		}
+		params[name] = value
+	}
+	l := &pluginLogin{
+		path:        strings.Trim(pluginAuth.Path, "/"),
+		params:      params,
+		encoding:    pluginAuth.Encoding,
+		contentType: pluginAuth.ContentType,
+	}
+	var resp *vault.Secret
+	err := c.retryLogin(ctx, authMethodPlugin, func() error {
+		var loginErr error
+		resp, loginErr = c.login(ctx, l)
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, loginErr)
+		return loginErr
+	})
+	return c.checkLogin(ctx, resp, err)
+}
+
+// pluginLogin posts the parameters of the plugin auth method to its login
+// endpoint, encoded as configured.
+type pluginLogin struct {
+	path        string
+	params      map[string]string
+	encoding    esv1.VaultLoginEncoding
+	contentType string
+}
+
+func (l *pluginLogin) Login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	body, contentType, err := l.body()
+	if err != nil {
+		return nil, fmt.Errorf(errPluginLogin, l.path, err)
+	}
+	if l.contentType != "" {
+		contentType = l.contentType
+	}
+	// the content type is set on a copy, so that it is only sent with the
+	// login.
+	loginClient, err := client.CloneWithHeaders()
+	if err != nil {
+		return nil, fmt.Errorf(errPluginLogin, l.path, err)
+	}
+	loginClient.AddHeader("Content-Type", contentType)
+	resp, err := loginClient.Logical().WriteRawWithContext(ctx, "auth/"+l.path+"/login", body)
+	if resp != nil {
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+	}
+	if err != nil {
+		return nil, fmt.Errorf(errPluginLogin, l.path, err)
+	}
+	secret, err := vault.ParseSecret(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf(errPluginLogin, l.path, err)
+	}
+	return secret, nil
+}
+
+// body returns the encoded parameters and their content type.
+func (l *pluginLogin) body() ([]byte, string, error) {
+	if l.encoding == esv1.VaultLoginEncodingForm {
+		form := url.Values{}
+		for name, value := range l.params {
+			form.Set(name, value)
+		}
+		return []byte(form.Encode()), "application/x-www-form-urlencoded", nil
+	}
+	body, err := json.Marshal(l.params)
+	return body, "application/json", err
+}
@@ -0,0 +1,159 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+)
+
+func TestPluginAuth(t *testing.T) {
+	cases := map[string]struct {
+		encoding        esv1.VaultLoginEncoding
+		contentType     string
+		loginStatus     int
+		wantContentType string
+		wantBody        string
+		wantErr         string
+	}{
+		"JSON": {
+			loginStatus:     http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"password":"s3cr=t\u0026","role":"eso"}`,
+		},
+		"Form": {
+			encoding:        esv1.VaultLoginEncodingForm,
+			loginStatus:     http.StatusOK,
+			wantContentType: "application/x-www-form-urlencoded",
+			wantBody:        "password=s3cr%3Dt%26&role=eso",
+		},
+		"ContentType": {
+			encoding:        esv1.VaultLoginEncodingForm,
+			contentType:     "application/x-www-form-urlencoded; charset=utf-8",
+			loginStatus:     http.StatusOK,
+			wantContentType: "application/x-www-form-urlencoded; charset=utf-8",
+			wantBody:        "password=s3cr%3Dt%26&role=eso",
+		},
+		"Denied": {
+			loginStatus:     http.StatusForbidden,
+			wantContentType: "application/json",
+			wantBody:        `{"password":"s3cr=t\u0026","role":"eso"}`,
+			wantErr:         "cannot log in to auth/my-plugin/login",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var contentType, body, secretToken string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/auth/my-plugin/login":
+					contentType = r.Header.Get("Content-Type")
+					b, _ := io.ReadAll(r.Body)
+					body = string(b)
+					w.WriteHeader(tc.loginStatus)
+					if tc.loginStatus != http.StatusOK {
+						_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+						return
+					}
+					_, _ = w.Write([]byte(`{"auth": {"client_token": "plugin-token", "lease_duration": 1800, "renewable": true}}`))
+				case "/v1/secret/data/foo":
+					secretToken = r.Header.Get("X-Vault-Token")
+					// only the login is sent with the content type of the encoding.
+					if got := r.Header.Get("Content-Type"); strings.Contains(got, "form") {
+						t.Errorf("unexpected content type %q", got)
+					}
+					_, _ = w.Write([]byte(`{"data": {"data": {"foo": "bar"}}}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "plugin-credentials",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"password": []byte("s3cr=t&"),
+				},
+			}).Build()
+			store := &esv1.SecretStore{
+				TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vault-store",
+					Namespace: "default",
+				},
+				Spec: esv1.SecretStoreSpec{
+					Provider: &esv1.SecretStoreProvider{
+						Vault: &esv1.VaultProvider{
+							Server:  server.URL,
+							Version: esv1.VaultKVStoreV2,
+							Auth: &esv1.VaultAuth{
+								Plugin: &esv1.VaultPluginAuth{
+									Path:       "my-plugin",
+									Parameters: map[string]string{"role": "eso", "password": "overridden"},
+									SecretParameters: map[string]esmeta.SecretKeySelector{
+										"password": {Name: "plugin-credentials", Key: "password"},
+									},
+									Encoding:    tc.encoding,
+									ContentType: tc.contentType,
+								},
+							},
+						},
+					},
+				},
+			}
+			prov := &Provider{NewVaultClient: NewVaultClient}
+			c, err := prov.newClient(context.Background(), store, kube, nil, "default")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				// the token of the login response is used.
+				if _, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "secret/foo"}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if secretToken != "plugin-token" {
+					t.Errorf("expected token %q, got %q", "plugin-token", secretToken)
+				}
+			}
+
+			if contentType != tc.wantContentType {
+				t.Errorf("expected content type %q, got %q", tc.wantContentType, contentType)
+			}
+			if diff := cmp.Diff(tc.wantBody, body); diff != "" {
+				t.Errorf("unexpected login body (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
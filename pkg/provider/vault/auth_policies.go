@@ -0,0 +1,179 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errPolicyConfigMap    = "cannot read expected policies from ConfigMap %q: %w"
+	errPolicyConfigMapKey = "ConfigMap %q has no key %q"
+	errPolicyRole         = "cannot read expected policies from role %q: %w"
+)
+
+// policySnapshot holds the expected policies read from the policy source of
+// the store when a token was issued.
+type policySnapshot struct {
+	policies string
+	// expiry is zero for tokens without a known lease.
+	expiry time.Time
+}
+
+// Snapshots are kept per token rather than per client, as cached Vault
+// clients are wrapped by a new client for every reconcile.
+var (
+	policySnapshotsMu sync.Mutex
+	policySnapshots   = map[string]policySnapshot{}
+)
+
+// recordPolicySource remembers the expected policies of the store for the
+// token of a fresh login. A policy source that can't be read is only
+// logged, the policies are then recorded on the next reuse of the token.
+func (c *client) recordPolicySource(ctx context.Context) {
+	if c.store.Auth == nil || c.store.Auth.PolicySource == nil {
+		return
+	}
+	policies, err := c.expectedPolicies(ctx, c.store.Auth.PolicySource)
+	if err != nil {
+		c.log.Error(err, "unable to read expected policies")
+		return
+	}
+	snapshot := policySnapshot{policies: policies}
+	if c.loginAuth != nil && c.loginAuth.LeaseDuration > 0 {
+		snapshot.expiry = time.Now().Add(time.Duration(c.loginAuth.LeaseDuration) * time.Second)
+	}
+
+	policySnapshotsMu.Lock()
+	defer policySnapshotsMu.Unlock()
+	for key, s := range policySnapshots {
+		if !s.expiry.IsZero() && !time.Now().Before(s.expiry) {
+			delete(policySnapshots, key)
+		}
+	}
+	policySnapshots[tokenKey(c.client.Token())] = snapshot
+}
+
+// policySourceChanged reports whether the expected policies of the store
+// changed since the current token was issued. Reading the policy source
+// anew is never treated as a change if it fails.
+func (c *client) policySourceChanged(ctx context.Context) bool {
+	if c.store.Auth == nil || c.store.Auth.PolicySource == nil {
+		return false
+	}
+	policies, err := c.expectedPolicies(ctx, c.store.Auth.PolicySource)
+	if err != nil {
+		c.log.Error(err, "unable to read expected policies")
+		return false
+	}
+
+	key := tokenKey(c.client.Token())
+	policySnapshotsMu.Lock()
+	defer policySnapshotsMu.Unlock()
+	snapshot, ok := policySnapshots[key]
+	if !ok {
+		policySnapshots[key] = policySnapshot{policies: policies}
+		return false
+	}
+	if snapshot.policies == policies {
+		return false
+	}
+	c.log.Info("Expected policies changed, re-authenticating", "previous", snapshot.policies, "current", policies)
+	delete(policySnapshots, key)
+	return true
+}
+
+// expectedPolicies reads the policies of the policy source and returns them
+// sorted and joined by commas, so that reordering them isn't a change.
+func (c *client) expectedPolicies(ctx context.Context, source *esv1.VaultPolicySource) (string, error) {
+	var policies []string
+	switch {
+	case source.ConfigMapRef != nil:
+		ref := source.ConfigMapRef
+		key := types.NamespacedName{Namespace: c.namespace, Name: ref.Name}
+		if c.storeKind == esv1.ClusterSecretStoreKind && ref.Namespace != nil {
+			key.Namespace = *ref.Namespace
+		}
+		configMap := &corev1.ConfigMap{}
+		if err := c.kube.Get(ctx, key, configMap); err != nil {
+			return "", fmt.Errorf(errPolicyConfigMap, ref.Name, err)
+		}
+		value, ok := configMap.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf(errPolicyConfigMap, ref.Name, fmt.Errorf(errPolicyConfigMapKey, ref.Name, ref.Key))
+		}
+		policies = strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' })
+	case source.RolePath != "":
+		// the role is read in the namespace the token was issued in.
+		role, err := c.tokenClient().Logical().ReadWithDataWithContext(ctx, source.RolePath, nil)
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultReadAuthRole, err)
+		if err != nil {
+			return "", fmt.Errorf(errPolicyRole, source.RolePath, err)
+		}
+		if role == nil {
+			return "", fmt.Errorf(errPolicyRole, source.RolePath, errors.New("role not found"))
+		}
+		policies = rolePolicies(role.Data)
+	default:
+		return "", errors.New(errInvalidPolicySource)
+	}
+
+	for i := range policies {
+		policies[i] = strings.TrimSpace(policies[i])
+	}
+	policies = slices.DeleteFunc(policies, func(p string) bool { return p == "" })
+	slices.Sort(policies)
+	return strings.Join(slices.Compact(policies), ","), nil
+}
+
+// rolePolicies returns the token_policies of a role, or its policies for
+// auth methods that predate token_policies.
+func rolePolicies(data map[string]any) []string {
+	raw, ok := data["token_policies"]
+	if !ok {
+		raw = data["policies"]
+	}
+	var policies []string
+	switch v := raw.(type) {
+	case []any:
+		for _, p := range v {
+			policies = append(policies, fmt.Sprint(p))
+		}
+	case string:
+		policies = strings.Split(v, ",")
+	}
+	return policies
+}
+
+// forgetPolicySnapshot drops the expected policies recorded for a token
+// that is no longer used.
+func forgetPolicySnapshot(token string) {
+	policySnapshotsMu.Lock()
+	defer policySnapshotsMu.Unlock()
+	delete(policySnapshots, tokenKey(token))
+}
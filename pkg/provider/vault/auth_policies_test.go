@@ -0,0 +1,209 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestPolicySourceReauth(t *testing.T) {
+	configMapSource := &esv1.VaultPolicySource{
+		ConfigMapRef: &esv1.VaultConfigMapKeySelector{Name: "vault-policies", Key: "policies"},
+	}
+	roleSource := &esv1.VaultPolicySource{RolePath: "auth/kubernetes/role/kubernetes-auth-role"}
+
+	cases := map[string]struct {
+		source      *esv1.VaultPolicySource
+		before      string
+		after       string
+		deleted     bool
+		wantLogins  int
+		wantRevoked int
+	}{
+		"NoPolicySource": {
+			wantLogins: 1,
+		},
+		"ConfigMapUnchanged": {
+			source:     configMapSource,
+			before:     "app-read,app-write",
+			after:      "app-read,app-write",
+			wantLogins: 1,
+		},
+		"ConfigMapReordered": {
+			source:     configMapSource,
+			before:     "app-read,app-write",
+			after:      "app-write\napp-read\n",
+			wantLogins: 1,
+		},
+		"ConfigMapChanged": {
+			source:      configMapSource,
+			before:      "app-read",
+			after:       "app-read,app-write",
+			wantLogins:  2,
+			wantRevoked: 1,
+		},
+		"ConfigMapUnreadable": {
+			source:     configMapSource,
+			before:     "app-read",
+			deleted:    true,
+			wantLogins: 1,
+		},
+		"RoleUnchanged": {
+			source:     roleSource,
+			before:     "app-read",
+			after:      "app-read",
+			wantLogins: 1,
+		},
+		"RoleChanged": {
+			source:      roleSource,
+			before:      "app-read",
+			after:       "app-admin",
+			wantLogins:  2,
+			wantRevoked: 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Cleanup(func() {
+				policySnapshotsMu.Lock()
+				clear(policySnapshots)
+				policySnapshotsMu.Unlock()
+			})
+			ctx := context.Background()
+			logins := 0
+			revoked := 0
+			token := ""
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, &logins)
+			c.store.Auth.PolicySource = tc.source
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					logins++
+					token = "kubernetes-token-" + strconv.Itoa(logins)
+					return &vault.Secret{}, nil
+				},
+			}
+			authToken := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					return makeTokenLookup(time.Hour, false), nil
+				},
+				RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+					revoked++
+					return nil
+				},
+			}
+			grantedPolicies := tc.before
+			c.token = authToken
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				ClearTokenFunc:   func() { token = "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+				AuthTokenField:   authToken,
+				LogicalField: fake.Logical{
+					ReadWithDataWithContextFn: func(ctx context.Context, path string, data map[string][]string) (*vault.Secret, error) {
+						if path != roleSource.RolePath {
+							t.Errorf("unexpected role path %q", path)
+						}
+						return &vault.Secret{Data: map[string]any{"token_policies": []any{grantedPolicies}}}, nil
+					},
+				},
+			}
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "vault-policies", Namespace: "default"},
+				Data:       map[string]string{"policies": tc.before},
+			}
+			if err := c.kube.Create(ctx, configMap); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := c.setAuth(ctx, nil); err != nil {
+				t.Fatalf("unexpected error on login: %v", err)
+			}
+			grantedPolicies = tc.after
+			if tc.deleted {
+				if err := c.kube.Delete(ctx, configMap); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				configMap.Data["policies"] = tc.after
+				if err := c.kube.Update(ctx, configMap); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := c.setAuth(ctx, nil); err != nil {
+				t.Fatalf("unexpected error on reuse: %v", err)
+			}
+
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, logins)
+			}
+			if revoked != tc.wantRevoked {
+				t.Errorf("expected %d revocations, got %d", tc.wantRevoked, revoked)
+			}
+			if want := "kubernetes-token-" + strconv.Itoa(tc.wantLogins); token != want {
+				t.Errorf("expected token %q, got %q", want, token)
+			}
+		})
+	}
+}
+
+func TestRolePolicies(t *testing.T) {
+	cases := map[string]struct {
+		data map[string]any
+		want []string
+	}{
+		"TokenPolicies": {
+			data: map[string]any{"token_policies": []any{"a", "b"}, "policies": []any{"c"}},
+			want: []string{"a", "b"},
+		},
+		"LegacyPolicies": {
+			data: map[string]any{"policies": []any{"c"}},
+			want: []string{"c"},
+		},
+		"CommaSeparated": {
+			data: map[string]any{"token_policies": "a,b"},
+			want: []string{"a", "b"},
+		},
+		"None": {
+			data: map[string]any{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, rolePolicies(tc.data)); diff != "" {
+				t.Errorf("unexpected policies (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"sync"
+)
+
+// Tokens may be used by several clients at once, e.g. cached tokens shared
+// between stores or a static token referenced by several stores. With
+// countTokenReferences, a token is only revoked once no client uses it
+// anymore.
+var (
+	countTokenReferences bool
+
+	tokenRefsMu sync.Mutex
+	tokenRefs   = map[string]int{}
+	// pendingRevocations are revocations of tokens evicted from the cache
+	// while still in use, run once the last reference is released.
+	pendingRevocations = map[string]func(ctx context.Context){}
+)
+
+// acquireToken counts the reference of the client to its current token,
+// releasing the reference to a token it held before.
+func (c *client) acquireToken(ctx context.Context) {
+	if !countTokenReferences {
+		return
+	}
+	token := c.client.Token()
+	if token == c.heldToken {
+		return
+	}
+	c.releaseToken(ctx)
+	if token == "" {
+		return
+	}
+	tokenRefsMu.Lock()
+	defer tokenRefsMu.Unlock()
+	tokenRefs[tokenKey(token)]++
+	c.heldToken = token
+}
+
+// releaseToken drops the reference of the client to its token and reports
+// whether other clients still use the token. A revocation deferred while
+// the token was in use is run when the last reference is released.
+func (c *client) releaseToken(ctx context.Context) bool {
+	token := c.heldToken
+	if token == "" {
+		return false
+	}
+	c.heldToken = ""
+
+	key := tokenKey(token)
+	tokenRefsMu.Lock()
+	if n := tokenRefs[key] - 1; n > 0 {
+		tokenRefs[key] = n
+		tokenRefsMu.Unlock()
+		c.log.V(1).Info("Not revoking token still used by other clients", "references", n)
+		return true
+	}
+	delete(tokenRefs, key)
+	revoke := pendingRevocations[key]
+	delete(pendingRevocations, key)
+	tokenRefsMu.Unlock()
+
+	if revoke != nil {
+		revoke(ctx)
+	}
+	return false
+}
+
+// deferRevocation delays the revocation of a token until the last client
+// using it releases it. It returns false if no client uses the token, so
+// that the caller has to revoke it right away.
+func deferRevocation(token string, revoke func(ctx context.Context)) bool {
+	if !countTokenReferences || token == "" {
+		return false
+	}
+	tokenRefsMu.Lock()
+	defer tokenRefsMu.Unlock()
+	key := tokenKey(token)
+	if tokenRefs[key] == 0 {
+		return false
+	}
+	pendingRevocations[key] = revoke
+	return true
+}
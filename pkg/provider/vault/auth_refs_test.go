@@ -0,0 +1,158 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestSharedTokenRevocation(t *testing.T) {
+	defer func(count bool) {
+		countTokenReferences = count
+		tokenRefs = map[string]int{}
+	}(countTokenReferences)
+
+	cases := map[string]struct {
+		count bool
+		// wantRevoked is the number of revocations after each close.
+		wantRevoked []int
+	}{
+		"RevokedOnLastRelease": {
+			count:       true,
+			wantRevoked: []int{0, 1},
+		},
+		"RevokedOnEachClose": {
+			wantRevoked: []int{1, 2},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			countTokenReferences = tc.count
+			tokenRefs = map[string]int{}
+			var mu sync.Mutex
+			revoked := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/auth/token/lookup-self":
+					_, _ = w.Write([]byte(`{"data": {"type": "service", "ttl": 3600, "expire_time": "2100-01-01T00:00:00Z"}}`))
+				case "/v1/auth/token/revoke-self":
+					mu.Lock()
+					revoked++
+					mu.Unlock()
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vault-token",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"token": []byte("shared-token"),
+				},
+			}).Build()
+			// two stores referencing the same static token.
+			var clients []*client
+			for range 2 {
+				cfg := vault.DefaultConfig()
+				cfg.Address = server.URL
+				vaultClient, err := NewVaultClient(cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				c := &client{
+					kube:      kube,
+					log:       logger,
+					namespace: "default",
+					storeKind: esv1.SecretStoreKind,
+					store: &esv1.VaultProvider{
+						Auth: &esv1.VaultAuth{
+							TokenSecretRef: &esmeta.SecretKeySelector{
+								Name: "vault-token",
+								Key:  "token",
+							},
+							RevokeStaticToken: true,
+						},
+					},
+					client:  vaultClient,
+					auth:    vaultClient.Auth(),
+					logical: vaultClient.Logical(),
+					token:   vaultClient.AuthToken(),
+				}
+				if err := c.setAuth(context.Background(), cfg); err != nil {
+					t.Fatalf("unexpected login error: %v", err)
+				}
+				clients = append(clients, c)
+			}
+
+			for i, c := range clients {
+				if err := c.Close(context.Background()); err != nil {
+					t.Fatalf("unexpected error on close: %v", err)
+				}
+				if revoked != tc.wantRevoked[i] {
+					t.Errorf("expected %d revocations after close %d, got %d", tc.wantRevoked[i], i+1, revoked)
+				}
+			}
+		})
+	}
+}
+
+func TestDeferRevocation(t *testing.T) {
+	defer func(count bool) {
+		countTokenReferences = count
+		tokenRefs = map[string]int{}
+		pendingRevocations = map[string]func(ctx context.Context){}
+	}(countTokenReferences)
+	countTokenReferences = true
+
+	token := "cached-token"
+	vaultClient := &util.VaultClient{TokenFunc: func() string { return token }}
+	first := &client{log: logger, client: vaultClient}
+	second := &client{log: logger, client: vaultClient}
+	first.acquireToken(context.Background())
+	second.acquireToken(context.Background())
+
+	revocations := 0
+	if !deferRevocation(token, func(ctx context.Context) { revocations++ }) {
+		t.Fatal("expected the revocation of a token in use to be deferred")
+	}
+	if inUse := first.releaseToken(context.Background()); !inUse || revocations != 0 {
+		t.Errorf("expected the token to be in use and not revoked, got in use %t and %d revocations", inUse, revocations)
+	}
+	if inUse := second.releaseToken(context.Background()); inUse || revocations != 1 {
+		t.Errorf("expected the token to be revoked on the last release, got in use %t and %d revocations", inUse, revocations)
+	}
+	if deferRevocation(token, func(ctx context.Context) {}) {
+		t.Error("expected the revocation of an unused token not to be deferred")
+	}
+}
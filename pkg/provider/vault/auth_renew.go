@@ -0,0 +1,205 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errVaultRenewToken           = "error while renewing token: %w"
+	errVaultTokenMaxTTL          = "token reached its max TTL"
+	errVaultRenewIncrementCapped = "renewal granted a TTL of %s instead of the requested %s"
+)
+
+var (
+	// renewExpiringTokens renews renewable tokens found to be about to
+	// expire instead of logging in again.
+	renewExpiringTokens bool
+	// renewStaticTokens renews renewable tokens read from a secret. This is
+	// disabled by default, as these tokens are managed outside of the
+	// controller.
+	renewStaticTokens bool
+)
+
+// ActivityNotifier is implemented by clients that can prepare for an upcoming
+// burst of operations, e.g. right before a batch of ExternalSecrets using the
+// same store is reconciled.
+type ActivityNotifier interface {
+	ExpectActivity(ctx context.Context) error
+}
+
+var _ ActivityNotifier = &client{}
+
+// ExpectActivity signals that operations are expected soon. If the current
+// token expires within tokenWarmupWindow it is renewed ahead of time, or
+// replaced with a new one if it can't be renewed, so that the burst doesn't
+// have to wait for a re-login.
+func (c *client) ExpectActivity(ctx context.Context) error {
+	// override and external tokens are provided as is and can't be replaced.
+	if c.store.Auth == nil || c.authOverride != nil || c.externalToken != nil {
+		return nil
+	}
+	if c.client.Token() == "" {
+		return c.setAuth(ctx, c.config)
+	}
+	// looking up a limited-use token would consume one of its uses.
+	if c.limitedUseToken() {
+		if c.checkLimitedUseToken(tokenWarmupWindow) {
+			return nil
+		}
+		c.client.ClearToken()
+		return c.setAuth(ctx, c.config)
+	}
+
+	resp, err := c.tokenAPI().LookupSelfWithContext(ctx)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLookupSelf, err)
+	if err != nil || resp == nil {
+		c.client.ClearToken()
+		return c.setAuth(ctx, c.config)
+	}
+	ttl, err := resp.TokenTTL()
+	if err != nil {
+		return fmt.Errorf("invalid token TTL: %w", err)
+	}
+	// non-expirable tokens don't need to be warmed up.
+	if resp.Data["expire_time"] == nil || ttl > tokenWarmupWindow {
+		return nil
+	}
+
+	if tokenRenewable(resp) {
+		_, err = c.renewToken(ctx)
+		if err == nil {
+			c.log.V(1).Info("renewed token ahead of expected activity", "ttl", ttl.String())
+			return nil
+		}
+		c.log.V(1).Info("unable to renew token ahead of expected activity, re-authenticating", "error", err.Error())
+	}
+	c.client.ClearToken()
+	return c.setAuth(ctx, c.config)
+}
+
+// renewExpiringToken renews a token that is about to expire instead of
+// logging in again, if enabled. It returns tokenInvalid if the token still
+// has to be replaced.
+func (c *client) renewExpiringToken(ctx context.Context) tokenState {
+	if !renewExpiringTokens {
+		return tokenInvalid
+	}
+	ttl, err := c.renewToken(ctx)
+	if err != nil {
+		c.log.V(1).Info("unable to renew expiring token, re-authenticating", "error", err.Error())
+		return tokenInvalid
+	}
+	c.log.V(1).Info("renewed expiring token", "ttl", ttl.String())
+	return tokenValid
+}
+
+// renewStaticToken renews the token read from a secret if enabled, and if
+// the token is renewable and expires within tokenWarmupWindow. The token
+// is used as is if it can't be renewed.
+func (c *client) renewStaticToken(ctx context.Context) {
+	if !renewStaticTokens {
+		return
+	}
+	_, lease, err := lookupToken(ctx, c.tokenAPI(), c.expiryThreshold())
+	if err != nil || !lease.renewable || !lease.expiresWithin(tokenWarmupWindow) {
+		return
+	}
+	ttl, err := c.renewToken(ctx)
+	if err != nil {
+		c.log.V(1).Info("unable to renew static token", "error", err.Error())
+		return
+	}
+	c.log.V(1).Info("renewed static token", "ttl", ttl.String())
+}
+
+// renewToken renews the current token for the increment of the store and
+// returns the TTL Vault granted.
+func (c *client) renewToken(ctx context.Context) (time.Duration, error) {
+	resp, err := c.tokenAPI().RenewSelfWithContext(ctx, int(c.renewIncrement().Seconds()))
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultRenewSelf, err)
+	if err != nil {
+		if checkRenewalRoleNotFound(c.client.Token(), err) {
+			c.log.Error(err, "cannot renew token, its role was not found in Vault and may have been deleted")
+		}
+		return 0, fmt.Errorf(errVaultRenewToken, err)
+	}
+	if resp == nil || resp.Auth == nil {
+		return 0, fmt.Errorf(errVaultRenewToken, errors.New("no auth data in renewal response"))
+	}
+	ttl := recordRenewal(c.client.Token(), resp.Auth)
+	if err := c.checkRenewIncrement(ttl); err != nil {
+		return ttl, fmt.Errorf(errVaultRenewToken, err)
+	}
+	// a token that reached its max TTL can't be extended any further, so it
+	// is still treated as expired and has to be replaced.
+	if ttl < c.expiryThreshold() {
+		return ttl, fmt.Errorf(errVaultRenewToken, errors.New(errVaultTokenMaxTTL))
+	}
+	return ttl, nil
+}
+
+// renewIncrement returns the increment renewals ask for, or zero for the
+// default TTL of the token.
+func (c *client) renewIncrement() time.Duration {
+	if c.store == nil || c.store.Auth == nil || c.store.Auth.TokenRenewIncrement == nil {
+		return 0
+	}
+	return c.store.Auth.TokenRenewIncrement.Duration
+}
+
+// checkRenewIncrement checks the TTL granted by a renewal against the
+// increment it asked for, as Vault silently caps increments exceeding the
+// max TTL of the token.
+func (c *client) checkRenewIncrement(ttl time.Duration) error {
+	increment := c.renewIncrement()
+	if increment <= 0 || ttl >= increment.Truncate(time.Second) {
+		return nil
+	}
+	switch c.store.Auth.TokenRenewIncrementCheck {
+	case esv1.VaultTokenRenewIncrementCheckFail:
+		return fmt.Errorf(errVaultRenewIncrementCapped, ttl, increment)
+	case esv1.VaultTokenRenewIncrementCheckWarn:
+		c.log.Info("vault granted a shorter TTL than requested for the token renewal", "requested", increment.String(), "granted", ttl.String())
+	}
+	return nil
+}
+
+// recordRenewal records the TTL granted to a renewed token and returns it.
+// The lease of the token and a shared lookup result of it are replaced, so
+// that they reflect the granted TTL rather than the requested one.
+func recordRenewal(token string, auth *vault.SecretAuth) time.Duration {
+	ttl := time.Duration(auth.LeaseDuration) * time.Second
+	metrics.ObserveAuthTokenTTL(constants.ProviderHCVault, ttl)
+	lease := tokenLease{renewable: auth.Renewable}
+	if ttl > 0 {
+		lease.expiry = time.Now().Add(ttl)
+	}
+	renewLease(token, lease)
+	if tokenValidityCacheTTL > 0 {
+		storeValidity(token, tokenValidity{tokenLease: lease, checked: time.Now()})
+	}
+	return ttl
+}
@@ -0,0 +1,137 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+var (
+	// renewInBackground renews renewable tokens issued by a login in
+	// background, so that long-lived tokens don't have to be replaced by a
+	// new login once their lease runs out.
+	renewInBackground bool
+
+	// Renewals are kept per token rather than per client, as cached Vault
+	// clients are wrapped by a new client for every reconcile.
+	tokenRenewalsMu sync.Mutex
+	tokenRenewals   = map[string]context.CancelFunc{}
+)
+
+// renewalDelay is the time after which a lease of the given TTL is renewed.
+func renewalDelay(ttl time.Duration) time.Duration {
+	return ttl * 2 / 3
+}
+
+// startTokenRenewal renews the token issued by the last login in background,
+// if enabled and if the token is renewable with a known lease. The renewal
+// isn't bound to the request that logged in, it stops once the token is
+// revoked, e.g. when the client is closed or evicted from the cache.
+func (c *client) startTokenRenewal(ctx context.Context) {
+	auth := c.loginAuth
+	if !renewInBackground || auth == nil || !auth.Renewable || auth.LeaseDuration <= 0 {
+		return
+	}
+	// renewing a limited-use token would consume one of its uses.
+	if c.limitedUseToken() {
+		return
+	}
+	token := c.client.Token()
+	if token == "" {
+		return
+	}
+
+	renewCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	tokenRenewalsMu.Lock()
+	if _, ok := tokenRenewals[tokenKey(token)]; ok {
+		tokenRenewalsMu.Unlock()
+		cancel()
+		return
+	}
+	tokenRenewals[tokenKey(token)] = cancel
+	tokenRenewalsMu.Unlock()
+
+	// the renewal uses a copy of the client, so that it keeps renewing this
+	// token even once the client switched to another one.
+	tokenClient := c.tokenClient()
+	renewer := tokenClient.WithNamespace(tokenClient.Namespace())
+	ttl := time.Duration(auth.LeaseDuration) * time.Second
+	go func() {
+		defer stopTokenRenewal(token)
+		c.renewUntilMaxTTL(renewCtx, renewer.AuthToken(), token, ttl)
+	}()
+}
+
+// renewUntilMaxTTL renews the token after two thirds of each lease, until
+// the context is done, a renewal fails or the token reached its max TTL.
+// The token is then left to expire, so that the next auth logs in again.
+func (c *client) renewUntilMaxTTL(ctx context.Context, tokenAPI util.Token, token string, ttl time.Duration) {
+	timer := time.NewTimer(renewalDelay(ttl))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		// https://developer.hashicorp.com/vault/api-docs/auth/token#renew-a-token-self
+		resp, err := tokenAPI.RenewSelfWithContext(ctx, int(c.renewIncrement().Seconds()))
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultRenewSelf, err)
+		if err != nil {
+			if checkRenewalRoleNotFound(token, err) {
+				c.log.Error(err, "cannot renew token in background, its role was not found in Vault and may have been deleted")
+			} else if ctx.Err() == nil {
+				c.log.V(1).Info("unable to renew token in background", "error", err.Error())
+			}
+			return
+		}
+		if resp == nil || resp.Auth == nil || !resp.Auth.Renewable {
+			c.log.V(1).Info("token is no longer renewable, stopping background renewal")
+			return
+		}
+		renewed := recordRenewal(token, resp.Auth)
+		c.log.V(1).Info("renewed token in background", "ttl", renewed.String())
+		if err := c.checkRenewIncrement(renewed); err != nil {
+			c.log.V(1).Info("stopping background renewal", "error", err.Error())
+			return
+		}
+		// a shorter lease than before means the token is capped by its max
+		// TTL, further renewals won't extend it.
+		if renewed < ttl {
+			c.log.V(1).Info("token reached its max TTL, stopping background renewal", "ttl", renewed.String())
+			return
+		}
+		ttl = renewed
+		timer.Reset(renewalDelay(ttl))
+	}
+}
+
+// stopTokenRenewal stops the background renewal of a token, if any.
+func stopTokenRenewal(token string) {
+	tokenRenewalsMu.Lock()
+	defer tokenRenewalsMu.Unlock()
+	key := tokenKey(token)
+	if cancel, ok := tokenRenewals[key]; ok {
+		cancel()
+		delete(tokenRenewals, key)
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+)
+
+func TestRenewUntilMaxTTL(t *testing.T) {
+	cases := map[string]struct {
+		leases       []int
+		renewErr     error
+		notRenewable bool
+		wantRenewals int
+	}{
+		"RenewsUntilMaxTTL": {
+			// the second renewal is capped by the max TTL of the token.
+			leases:       []int{1, 0},
+			wantRenewals: 2,
+		},
+		"RenewalFails": {
+			renewErr:     errors.New("permission denied"),
+			wantRenewals: 1,
+		},
+		"NoLongerRenewable": {
+			leases:       []int{1},
+			notRenewable: true,
+			wantRenewals: 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			renewals := 0
+			c := &client{log: logger}
+			tokenAPI := fake.Token{
+				RenewSelfWithContextFn: func(ctx context.Context, increment int) (*vault.Secret, error) {
+					renewals++
+					if tc.renewErr != nil {
+						return nil, tc.renewErr
+					}
+					lease := tc.leases[renewals-1]
+					return &vault.Secret{Auth: &vault.SecretAuth{Renewable: !tc.notRenewable, LeaseDuration: lease}}, nil
+				},
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				c.renewUntilMaxTTL(context.Background(), tokenAPI, "renewed-token", 30*time.Millisecond)
+			}()
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("expected the renewal to stop")
+			}
+			if renewals != tc.wantRenewals {
+				t.Errorf("expected %d renewals, got %d", tc.wantRenewals, renewals)
+			}
+		})
+	}
+}
+
+func TestRenewUntilMaxTTLContextCanceled(t *testing.T) {
+	c := &client{log: logger}
+	var renewals atomic.Int32
+	tokenAPI := fake.Token{
+		RenewSelfWithContextFn: func(ctx context.Context, increment int) (*vault.Secret, error) {
+			renewals.Add(1)
+			return &vault.Secret{Auth: &vault.SecretAuth{Renewable: true, LeaseDuration: 3600}}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.renewUntilMaxTTL(ctx, tokenAPI, "renewed-token", time.Hour)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the renewal to stop with its context")
+	}
+	if n := renewals.Load(); n != 0 {
+		t.Errorf("expected no renewals, got %d", n)
+	}
+}
+
+func TestBackgroundRenewal(t *testing.T) {
+	defer func(renew bool) { renewInBackground = renew }(renewInBackground)
+
+	cases := map[string]struct {
+		renew        bool
+		renewable    bool
+		wantRenewals bool
+	}{
+		"Renewable": {
+			renew:        true,
+			renewable:    true,
+			wantRenewals: true,
+		},
+		"NotRenewable": {
+			renew: true,
+		},
+		"Disabled": {
+			renewable: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			renewInBackground = tc.renew
+			var renewals atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/auth/approle/login":
+					if tc.renewable {
+						_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token", "renewable": true, "lease_duration": 1}}`))
+						return
+					}
+					_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token", "lease_duration": 1}}`))
+				case "/v1/auth/token/renew-self":
+					renewals.Add(1)
+					_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token", "renewable": true, "lease_duration": 1}}`))
+				case "/v1/auth/token/lookup-self":
+					_, _ = w.Write([]byte(`{"data": {"type": "service", "ttl": 3600, "expire_time": null}}`))
+				case "/v1/auth/token/revoke-self":
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "approle-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"secret-id": []byte("secret-id"),
+				},
+			}).Build()
+			store := &esv1.SecretStore{
+				TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vault-store",
+					Namespace: "default",
+				},
+				Spec: esv1.SecretStoreSpec{
+					Provider: &esv1.SecretStoreProvider{
+						Vault: &esv1.VaultProvider{
+							Server:  server.URL,
+							Version: esv1.VaultKVStoreV2,
+							Auth:    ptr.To(makeAppRoleAuth("role-id")),
+						},
+					},
+				},
+			}
+			prov := &Provider{NewVaultClient: NewVaultClient}
+			c, err := prov.newClient(context.Background(), store, kube, nil, "default")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the token is renewed after two thirds of its lease of a second.
+			deadline := time.Now().Add(3 * time.Second)
+			for tc.wantRenewals && renewals.Load() == 0 && time.Now().Before(deadline) {
+				time.Sleep(50 * time.Millisecond)
+			}
+			if got := renewals.Load() > 0; got != tc.wantRenewals {
+				t.Errorf("expected renewals: %t, got %d", tc.wantRenewals, renewals.Load())
+			}
+
+			if err := c.Close(context.Background()); err != nil {
+				t.Fatalf("unexpected error on close: %v", err)
+			}
+			// revoking the token on close stops its renewal.
+			tokenRenewalsMu.Lock()
+			_, renewing := tokenRenewals[tokenKey("approle-token")]
+			tokenRenewalsMu.Unlock()
+			if renewing {
+				t.Errorf("expected the renewal to stop once the client is closed")
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+type renewCounters struct {
+	lookups int
+	renews  int
+	logins  int
+}
+
+// makeRenewClient returns a client holding a token described by lookup,
+// authenticating with Kubernetes auth when a new token is needed.
+func makeRenewClient(t *testing.T, lookup *vault.Secret, renewErr error, counters *renewCounters) *client {
+	t.Helper()
+	token := "current-token"
+	kubernetesAuth := &esv1.VaultKubernetesAuth{
+		Path: "kubernetes",
+		Role: "kubernetes-auth-role",
+	}
+	c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), kubernetesAuth, &counters.logins)
+	c.client = &util.VaultClient{
+		SetTokenFunc:     func(v string) { token = v },
+		TokenFunc:        func() string { return token },
+		ClearTokenFunc:   func() { token = "" },
+		NamespaceFunc:    func() string { return "" },
+		SetNamespaceFunc: func(string) {},
+	}
+	c.token = fake.Token{
+		LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+			counters.lookups++
+			return lookup, nil
+		},
+		RenewSelfWithContextFn: func(ctx context.Context, increment int) (*vault.Secret, error) {
+			counters.renews++
+			if renewErr != nil {
+				return nil, renewErr
+			}
+			return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: token, Renewable: true, LeaseDuration: 3600}}, nil
+		},
+	}
+	return c
+}
+
+func makeTokenLookup(ttl time.Duration, renewable any) *vault.Secret {
+	return &vault.Secret{
+		Data: map[string]any{
+			"expire_time": expireIn(ttl),
+			"ttl":         json.Number(strconv.Itoa(int(ttl.Seconds()))),
+			"type":        "service",
+			"renewable":   renewable,
+		},
+	}
+}
+
+func TestExpectActivity(t *testing.T) {
+	defer func(window time.Duration) { tokenWarmupWindow = window }(tokenWarmupWindow)
+	tokenWarmupWindow = 5 * time.Minute
+
+	cases := map[string]struct {
+		lookup   *vault.Secret
+		renewErr error
+		want     renewCounters
+	}{
+		"OutsideWindow": {
+			lookup: makeTokenLookup(time.Hour, true),
+			want:   renewCounters{lookups: 1},
+		},
+		"RenewableWithinWindow": {
+			lookup: makeTokenLookup(2*time.Minute, true),
+			want:   renewCounters{lookups: 1, renews: 1},
+		},
+		"NonRenewableWithinWindow": {
+			lookup: makeTokenLookup(2*time.Minute, false),
+			want:   renewCounters{lookups: 1, logins: 1},
+		},
+		"RenewableStringWithinWindow": {
+			lookup: makeTokenLookup(2*time.Minute, "true"),
+			want:   renewCounters{lookups: 1, renews: 1},
+		},
+		"NonRenewableStringWithinWindow": {
+			lookup: makeTokenLookup(2*time.Minute, "false"),
+			want:   renewCounters{lookups: 1, logins: 1},
+		},
+		"InvalidRenewableWithinWindow": {
+			lookup: makeTokenLookup(2*time.Minute, "maybe"),
+			want:   renewCounters{lookups: 1, logins: 1},
+		},
+		"RenewalFailsWithinWindow": {
+			lookup:   makeTokenLookup(2*time.Minute, true),
+			renewErr: errors.New("max TTL reached"),
+			want:     renewCounters{lookups: 1, renews: 1, logins: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			counters := renewCounters{}
+			c := makeRenewClient(t, tc.lookup, tc.renewErr, &counters)
+			if err := c.ExpectActivity(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if counters != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, counters)
+			}
+		})
+	}
+}
+
+func TestCheckTokenExpiring(t *testing.T) {
+	cases := map[string]struct {
+		lookup *vault.Secret
+		want   tokenState
+	}{
+		"Valid": {
+			lookup: makeTokenLookup(time.Hour, true),
+			want:   tokenValid,
+		},
+		"RenewableNearExpiry": {
+			lookup: makeTokenLookup(30*time.Second, true),
+			want:   tokenExpiring,
+		},
+		"RenewableStringNearExpiry": {
+			lookup: makeTokenLookup(30*time.Second, "true"),
+			want:   tokenExpiring,
+		},
+		"NonRenewableNearExpiry": {
+			lookup: makeTokenLookup(30*time.Second, false),
+			want:   tokenInvalid,
+		},
+		"NonRenewableStringNearExpiry": {
+			lookup: makeTokenLookup(30*time.Second, "false"),
+			want:   tokenInvalid,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			token := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					return tc.lookup, nil
+				},
+			}
+			state, err := checkToken(context.Background(), token, tokenExpiryThreshold)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if state != tc.want {
+				t.Errorf("expected token state %d, got %d", tc.want, state)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRenewsExpiringToken(t *testing.T) {
+	defer func(renew bool) { renewExpiringTokens = renew }(renewExpiringTokens)
+
+	cases := map[string]struct {
+		renew      bool
+		lookup     *vault.Secret
+		renewErr   error
+		renewedTTL int
+		want       renewCounters
+	}{
+		"Valid": {
+			renew:  true,
+			lookup: makeTokenLookup(time.Hour, true),
+			want:   renewCounters{lookups: 1},
+		},
+		"RenewNeeded": {
+			renew:  true,
+			lookup: makeTokenLookup(30*time.Second, true),
+			want:   renewCounters{lookups: 1, renews: 1},
+		},
+		"RenewDisabled": {
+			lookup: makeTokenLookup(30*time.Second, true),
+			want:   renewCounters{lookups: 1, logins: 1},
+		},
+		"ReauthNeeded": {
+			renew:  true,
+			lookup: makeTokenLookup(30*time.Second, false),
+			want:   renewCounters{lookups: 1, logins: 1},
+		},
+		"RenewalFails": {
+			renew:    true,
+			lookup:   makeTokenLookup(30*time.Second, true),
+			renewErr: errors.New("max TTL reached"),
+			want:     renewCounters{lookups: 1, renews: 1, logins: 1},
+		},
+		"MaxTTLReached": {
+			renew:  true,
+			lookup: makeTokenLookup(30*time.Second, true),
+			// the renewal succeeds, but can't extend the token beyond its max TTL.
+			renewedTTL: 20,
+			want:       renewCounters{lookups: 1, renews: 1, logins: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			renewExpiringTokens = tc.renew
+			counters := renewCounters{}
+			c := makeRenewClient(t, tc.lookup, tc.renewErr, &counters)
+			if tc.renewedTTL != 0 {
+				c.token = fake.Token{
+					LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+						counters.lookups++
+						return tc.lookup, nil
+					},
+					RenewSelfWithContextFn: func(ctx context.Context, increment int) (*vault.Secret, error) {
+						counters.renews++
+						return &vault.Secret{Auth: &vault.SecretAuth{Renewable: true, LeaseDuration: tc.renewedTTL}}, nil
+					},
+				}
+			}
+			loggedIn, err := c.authenticate(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if loggedIn != (tc.want.logins > 0) {
+				t.Errorf("expected logged in %t, got %t", tc.want.logins > 0, loggedIn)
+			}
+			if counters != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, counters)
+			}
+		})
+	}
+}
+
+func TestRenewStaticToken(t *testing.T) {
+	defer func(renew bool, ttl time.Duration) {
+		renewStaticTokens = renew
+		tokenValidityCacheTTL = ttl
+		tokenValidities = map[string]tokenValidity{}
+	}(renewStaticTokens, tokenValidityCacheTTL)
+	tokenValidityCacheTTL = time.Minute
+
+	cases := map[string]struct {
+		renew  bool
+		lookup *vault.Secret
+		want   renewCounters
+	}{
+		"Renewed": {
+			renew:  true,
+			lookup: makeTokenLookup(2*time.Minute, true),
+			want:   renewCounters{lookups: 1, renews: 1},
+		},
+		"RenewDisabled": {
+			lookup: makeTokenLookup(2*time.Minute, true),
+		},
+		"NotExpiring": {
+			renew:  true,
+			lookup: makeTokenLookup(time.Hour, true),
+			want:   renewCounters{lookups: 1},
+		},
+		"NonRenewable": {
+			renew:  true,
+			lookup: makeTokenLookup(2*time.Minute, false),
+			want:   renewCounters{lookups: 1},
+		},
+		"RenewableString": {
+			renew:  true,
+			lookup: makeTokenLookup(2*time.Minute, "true"),
+			want:   renewCounters{lookups: 1, renews: 1},
+		},
+		"NonRenewableString": {
+			renew:  true,
+			lookup: makeTokenLookup(2*time.Minute, "false"),
+			want:   renewCounters{lookups: 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			renewStaticTokens = tc.renew
+			tokenValidities = map[string]tokenValidity{}
+			counters := renewCounters{}
+			c := makeRenewClient(t, tc.lookup, nil, &counters)
+			c.store.Auth = &esv1.VaultAuth{
+				TokenSecretRef: &esmeta.SecretKeySelector{
+					Name: "vault-sa-token",
+					Key:  "token",
+				},
+			}
+
+			loggedIn, err := setSecretKeyToken(context.Background(), c)
+			if err != nil || !loggedIn {
+				t.Fatalf("unexpected result: %t, %v", loggedIn, err)
+			}
+			if counters != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, counters)
+			}
+			// the renewed TTL replaces the one the token was read with.
+			validity, ok := tokenValidities[tokenKey(c.client.Token())]
+			if renewed := ok && validity.expiresWithin(time.Hour) && !validity.expiresWithin(59*time.Minute); renewed != (tc.want.renews > 0) {
+				t.Errorf("expected renewed TTL %t, got %+v", tc.want.renews > 0, validity)
+			}
+		})
+	}
+}
+
+func TestRenewIncrement(t *testing.T) {
+	defer func(renew bool, ttl time.Duration) {
+		renewExpiringTokens = renew
+		tokenValidityCacheTTL = ttl
+		tokenValidities = map[string]tokenValidity{}
+		tokenLeases = map[string]tokenLease{}
+	}(renewExpiringTokens, tokenValidityCacheTTL)
+	renewExpiringTokens = true
+	tokenValidityCacheTTL = time.Minute
+
+	cases := map[string]struct {
+		check esv1.VaultTokenRenewIncrementCheck
+		// granted is the TTL Vault grants for the requested hour, in seconds.
+		granted    int
+		wantLogins int
+	}{
+		"Granted": {
+			granted: 3600,
+		},
+		"Capped": {
+			granted: 600,
+		},
+		"CappedWarn": {
+			check:   esv1.VaultTokenRenewIncrementCheckWarn,
+			granted: 600,
+		},
+		"CappedFail": {
+			check:      esv1.VaultTokenRenewIncrementCheckFail,
+			granted:    600,
+			wantLogins: 1,
+		},
+		"GrantedFail": {
+			check:   esv1.VaultTokenRenewIncrementCheckFail,
+			granted: 3600,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tokenValidities = map[string]tokenValidity{}
+			tokenLeases = map[string]tokenLease{tokenKey("current-token"): {expiry: time.Now().Add(30 * time.Second)}}
+			counters := renewCounters{}
+			lookup := makeTokenLookup(30*time.Second, true)
+			c := makeRenewClient(t, lookup, nil, &counters)
+			c.store.Auth.TokenRenewIncrement = &metav1.Duration{Duration: time.Hour}
+			c.store.Auth.TokenRenewIncrementCheck = tc.check
+			var increments []int
+			c.token = fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					counters.lookups++
+					return lookup, nil
+				},
+				RenewSelfWithContextFn: func(ctx context.Context, increment int) (*vault.Secret, error) {
+					counters.renews++
+					increments = append(increments, increment)
+					return &vault.Secret{Auth: &vault.SecretAuth{Renewable: true, LeaseDuration: tc.granted}}, nil
+				},
+			}
+
+			if _, err := c.authenticate(context.Background(), nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff([]int{3600}, increments); diff != "" {
+				t.Errorf("unexpected increments (-want, +got):\n%s", diff)
+			}
+			if counters.logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, counters.logins)
+			}
+			// the local state reflects the granted TTL, not the requested one.
+			granted := time.Duration(tc.granted) * time.Second
+			validity := tokenValidities[tokenKey("current-token")]
+			if !validity.expiresWithin(granted) || validity.expiresWithin(granted-time.Minute) {
+				t.Errorf("expected the shared lookup to expire in %s, got %+v", granted, validity)
+			}
+			lease := tokenLeases[tokenKey("current-token")]
+			if !lease.expiresWithin(granted) || lease.expiresWithin(granted-time.Minute) {
+				t.Errorf("expected the lease to expire in %s, got %+v", granted, lease)
+			}
+		})
+	}
+}
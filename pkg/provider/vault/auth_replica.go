@@ -0,0 +1,408 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+const (
+	// replicaTokenPrefix prefixes the names of the Secrets holding the
+	// tokens shared between replicas, followed by the hash of the identity.
+	replicaTokenPrefix = "vault-token-"
+	replicaTokenKey    = "token"
+	// replicaTokenHashLength keeps the Secret names short.
+	replicaTokenHashLength = 32
+	replicaTokenLabel      = "external-secrets.io/vault-replica-token"
+	// replicaTokenStoreAnnotation names the store that shared the token, as
+	// kind/namespace/name.
+	replicaTokenStoreAnnotation = "external-secrets.io/vault-replica-store"
+	// replicaTokenExpiryAnnotation is when the shared token expires, unset
+	// for tokens that don't.
+	replicaTokenExpiryAnnotation = "external-secrets.io/vault-token-expiry"
+	// replicaTokenFinalizer keeps the Secret of a token until it is revoked.
+	replicaTokenFinalizer = "external-secrets.io/vault-token-revocation"
+	// replicaTokenCleanupInterval is how often the leader cleans up the
+	// Secrets of shared tokens, see cleanReplicaTokens.
+	replicaTokenCleanupInterval = 5 * time.Minute
+	// replicaTokenReleaseTimeout bounds the release of the finalizers on
+	// shutdown, which must finish within the graceful shutdown timeout of
+	// the manager.
+	replicaTokenReleaseTimeout = 10 * time.Second
+)
+
+var (
+	// replicaTokenNamespace is the namespace of the Secrets through which
+	// controller replicas share one token per Vault identity. Sharing is
+	// disabled if empty.
+	replicaTokenNamespace string
+
+	replicaTokensMu sync.Mutex
+	// replicaTokens holds the tokens shared with other replicas, which are
+	// left to expire instead of being revoked by this replica.
+	replicaTokens = map[string]struct{}{}
+	// replicaTokenClients are the clients that shared or used the token of
+	// a Secret last, by Secret name, to revoke the token with.
+	replicaTokenClients = map[string]util.Client{}
+)
+
+// replicaTokenSecret returns the name of the Secret holding the token
+// shared by all replicas for the identity of the client, or false if its
+// token isn't shared. The identity is the connection and auth configuration
+// of the store and the namespace it reads its credentials from, as for
+// tokens shared between stores.
+func (c *client) replicaTokenSecret() (string, bool) {
+	if replicaTokenNamespace == "" || c.store.Auth == nil || c.store.Auth.TokenSecretRef != nil || c.store.Auth.TokenFilePath != "" || c.authOverride != nil {
+		return "", false
+	}
+	namespace := c.namespace
+	if c.storeKind == esv1.ClusterSecretStoreKind && !isReferentSpec(c.store) {
+		namespace = ""
+	}
+	_, hash, err := sharedCacheKey(c.store, namespace)
+	if err != nil {
+		c.log.Error(err, "cannot share vault token between replicas")
+		return "", false
+	}
+	return replicaTokenPrefix + hash[:replicaTokenHashLength], true
+}
+
+// useReplicaToken switches the client over to the token shared by the
+// replicas, if there is one that is still valid. It reports whether it did.
+func (c *client) useReplicaToken(ctx context.Context) bool {
+	name, ok := c.replicaTokenSecret()
+	if !ok {
+		return false
+	}
+	secret, err := c.getReplicaToken(ctx, name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			c.log.Error(err, "cannot read vault token shared between replicas", "secret", name)
+		}
+		return false
+	}
+	if secret.DeletionTimestamp != nil {
+		return false
+	}
+	shared := string(secret.Data[replicaTokenKey])
+	token := c.client.Token()
+	if shared == "" || shared == token {
+		return false
+	}
+	if !c.replicaTokenValid(ctx, shared) {
+		c.client.SetToken(token)
+		return false
+	}
+	c.recordReplicaTokenClient(name)
+	c.log.V(1).Info("using vault token shared between replicas", "secret", name)
+	return true
+}
+
+// shareReplicaToken shares the token of a login with the other replicas.
+// If another replica shared a valid token first, the client revokes its
+// own and uses that one instead, which it reports. A failure to share is
+// only logged, the client then keeps its own token.
+func (c *client) shareReplicaToken(ctx context.Context) bool {
+	name, ok := c.replicaTokenSecret()
+	if !ok || c.limitedUseToken() {
+		return false
+	}
+	token := c.client.Token()
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: replicaTokenNamespace}}
+	c.setReplicaToken(ctx, secret, token)
+	err := c.kube.Create(ctx, secret)
+	if err == nil {
+		markReplicaToken(token)
+		c.recordReplicaTokenClient(name)
+		return false
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		c.log.Error(err, "cannot share vault token between replicas", "secret", name)
+		return false
+	}
+
+	// another replica shared a token before, which is used if it is still
+	// valid and replaced otherwise. Losing the race to replace it means
+	// that the token of the winner is read again.
+	for range 2 {
+		secret, err := c.getReplicaToken(ctx, name)
+		if err != nil {
+			c.log.Error(err, "cannot read vault token shared between replicas", "secret", name)
+			return false
+		}
+		// the Secret of a store that was torn down is being cleaned up.
+		if secret.DeletionTimestamp != nil {
+			return false
+		}
+		shared := string(secret.Data[replicaTokenKey])
+		if shared != "" && shared != token {
+			valid := c.replicaTokenValid(ctx, shared)
+			c.client.SetToken(token)
+			if valid {
+				forgetLease(token)
+				// the token is revoked in the namespace it was issued in.
+				if err := revokeToken(ctx, c.tokenClient(), esv1.VaultTokenRevokeScopeSelf); err != nil {
+					c.log.Error(err, "cannot revoke vault token replaced by the one shared between replicas")
+				}
+				c.client.SetToken(shared)
+				c.loginAuth = nil
+				c.recordReplicaTokenClient(name)
+				c.log.V(1).Info("using vault token shared between replicas", "secret", name)
+				return true
+			}
+		}
+		unmarkReplicaToken(shared)
+		c.setReplicaToken(ctx, secret, token)
+		err = c.kube.Update(ctx, secret)
+		if err == nil {
+			markReplicaToken(token)
+			c.recordReplicaTokenClient(name)
+			return false
+		}
+		if !apierrors.IsConflict(err) {
+			c.log.Error(err, "cannot share vault token between replicas", "secret", name)
+			return false
+		}
+	}
+	return false
+}
+
+// setReplicaToken stores the token of the client in the Secret along with
+// the store and the expiry of the token. The Secret is owned by the store if
+// Kubernetes allows it, which ClusterSecretStores always do and SecretStores
+// only in their own namespace, so that it is deleted along with the store.
+func (c *client) setReplicaToken(ctx context.Context, secret *corev1.Secret, token string) {
+	namespace := c.namespace
+	if c.storeKind == esv1.ClusterSecretStoreKind {
+		namespace = ""
+	}
+	secret.Labels = map[string]string{replicaTokenLabel: "true"}
+	secret.Annotations = map[string]string{
+		replicaTokenStoreAnnotation: strings.Join([]string{c.storeKind, namespace, c.storeName}, "/"),
+	}
+	// without a lookup, the Secret is only cleaned up with its store.
+	if _, lease, err := lookupToken(ctx, c.tokenAPI(), 0); err == nil && !lease.expiry.IsZero() {
+		secret.Annotations[replicaTokenExpiryAnnotation] = lease.expiry.UTC().Format(time.RFC3339)
+	}
+	secret.OwnerReferences = nil
+	if c.storeUID != "" && (c.storeKind == esv1.ClusterSecretStoreKind || c.namespace == replicaTokenNamespace) {
+		secret.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: esv1.SchemeGroupVersion.String(),
+			Kind:       c.storeKind,
+			Name:       c.storeName,
+			UID:        c.storeUID,
+		}}
+	}
+	secret.Finalizers = []string{replicaTokenFinalizer}
+	secret.Data = map[string][]byte{replicaTokenKey: []byte(token)}
+}
+
+func (c *client) recordReplicaTokenClient(name string) {
+	replicaTokensMu.Lock()
+	defer replicaTokensMu.Unlock()
+	replicaTokenClients[name] = c.tokenClient()
+}
+
+// replicaTokenValid sets the shared token on the client and reports
+// whether it is valid. It is marked as shared if it is, and left on the
+// client for the caller to restore the previous token otherwise.
+func (c *client) replicaTokenValid(ctx context.Context, token string) bool {
+	c.client.SetToken(token)
+	state, err := checkToken(ctx, c.tokenAPI(), c.expiryThreshold())
+	if err != nil || state != tokenValid {
+		return false
+	}
+	markReplicaToken(token)
+	return true
+}
+
+func (c *client) getReplicaToken(ctx context.Context, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := c.kube.Get(ctx, types.NamespacedName{Namespace: replicaTokenNamespace, Name: name}, secret)
+	return secret, err
+}
+
+func markReplicaToken(token string) {
+	replicaTokensMu.Lock()
+	defer replicaTokensMu.Unlock()
+	replicaTokens[tokenKey(token)] = struct{}{}
+}
+
+func unmarkReplicaToken(token string) {
+	replicaTokensMu.Lock()
+	defer replicaTokensMu.Unlock()
+	delete(replicaTokens, tokenKey(token))
+}
+
+// isReplicaToken reports whether the token is shared with other replicas.
+func isReplicaToken(token string) bool {
+	replicaTokensMu.Lock()
+	defer replicaTokensMu.Unlock()
+	_, ok := replicaTokens[tokenKey(token)]
+	return ok
+}
+
+// cleanupReplicaTokens cleans up the Secrets of shared tokens right away
+// and then every replicaTokenCleanupInterval, until the context is done,
+// i.e. the controller shuts down. The finalizers of the Secrets are then
+// released, so that they aren't stuck in deletion without a leader, e.g.
+// once the controller is uninstalled.
+func cleanupReplicaTokens(ctx context.Context, kube kclient.Client) {
+	ticker := time.NewTicker(replicaTokenCleanupInterval)
+	defer ticker.Stop()
+	for {
+		cleanReplicaTokens(ctx, kube)
+		select {
+		case <-ctx.Done():
+			releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), replicaTokenReleaseTimeout)
+			defer cancel()
+			releaseReplicaTokens(releaseCtx, kube, kclient.InNamespace(replicaTokenNamespace))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// releaseReplicaTokens removes the finalizer of the Secrets of shared
+// tokens. The tokens of Secrets deleted without it are left to expire.
+func releaseReplicaTokens(ctx context.Context, kube kclient.Client, opts ...kclient.ListOption) {
+	var secrets corev1.SecretList
+	opts = append(opts, kclient.MatchingLabels{replicaTokenLabel: "true"})
+	if err := kube.List(ctx, &secrets, opts...); err != nil {
+		logger.Error(err, "cannot list vault tokens shared between replicas")
+		return
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if !controllerutil.RemoveFinalizer(secret, replicaTokenFinalizer) {
+			continue
+		}
+		if err := kube.Update(ctx, secret); kclient.IgnoreNotFound(err) != nil {
+			logger.Error(err, "cannot release vault token shared between replicas", "namespace", secret.Namespace, "secret", secret.Name)
+		}
+	}
+}
+
+// cleanReplicaTokens deletes the Secrets of shared tokens that expired. The
+// tokens of stores that were torn down, i.e. deleted Secrets held by their
+// finalizer and Secrets of stores that don't exist anymore, are revoked
+// before their Secret is deleted. They are revoked with the client that
+// shared or used them last in this replica, and left to expire if there is
+// none.
+func cleanReplicaTokens(ctx context.Context, kube kclient.Client) {
+	var secrets corev1.SecretList
+	if err := kube.List(ctx, &secrets, kclient.InNamespace(replicaTokenNamespace), kclient.MatchingLabels{replicaTokenLabel: "true"}); err != nil {
+		logger.Error(err, "cannot list vault tokens shared between replicas")
+		return
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		tornDown := secret.DeletionTimestamp != nil
+		if !tornDown && !replicaTokenExpired(secret) {
+			exists, err := replicaTokenStoreExists(ctx, kube, secret)
+			if err != nil {
+				logger.Error(err, "cannot get store of vault token shared between replicas", "secret", secret.Name)
+				continue
+			}
+			if exists {
+				// a previous leader released the Secret on shutdown.
+				if controllerutil.AddFinalizer(secret, replicaTokenFinalizer) {
+					if err := kube.Update(ctx, secret); kclient.IgnoreNotFound(err) != nil {
+						logger.Error(err, "cannot update vault token shared between replicas", "secret", secret.Name)
+					}
+				}
+				continue
+			}
+			tornDown = true
+		}
+		if tornDown {
+			revokeReplicaToken(ctx, secret)
+		}
+		if err := deleteReplicaToken(ctx, kube, secret); err != nil {
+			logger.Error(err, "cannot delete vault token shared between replicas", "secret", secret.Name)
+		}
+	}
+}
+
+func replicaTokenExpired(secret *corev1.Secret) bool {
+	expiry, err := time.Parse(time.RFC3339, secret.Annotations[replicaTokenExpiryAnnotation])
+	return err == nil && time.Now().After(expiry)
+}
+
+// replicaTokenStoreExists reports whether the store that shared the token
+// still exists. Secrets without a store are left to expire.
+func replicaTokenStoreExists(ctx context.Context, kube kclient.Client, secret *corev1.Secret) (bool, error) {
+	ref := strings.SplitN(secret.Annotations[replicaTokenStoreAnnotation], "/", 3)
+	if len(ref) != 3 {
+		return true, nil
+	}
+	var store kclient.Object = &esv1.SecretStore{}
+	if ref[0] == esv1.ClusterSecretStoreKind {
+		store = &esv1.ClusterSecretStore{}
+	}
+	err := kube.Get(ctx, types.NamespacedName{Namespace: ref[1], Name: ref[2]}, store)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func revokeReplicaToken(ctx context.Context, secret *corev1.Secret) {
+	token := string(secret.Data[replicaTokenKey])
+	replicaTokensMu.Lock()
+	client, ok := replicaTokenClients[secret.Name]
+	replicaTokensMu.Unlock()
+	if !ok || token == "" {
+		logger.V(1).Info("leaving vault token shared between replicas to expire", "secret", secret.Name)
+		return
+	}
+	// shared tokens are never revoked otherwise, see revokeToken.
+	unmarkReplicaToken(token)
+	client = client.WithNamespace(client.Namespace())
+	client.SetToken(token)
+	if err := revokeTokenIfValid(ctx, client, esv1.VaultTokenRevokeScopeSelf); err != nil {
+		logger.Error(err, "cannot revoke vault token shared between replicas", "secret", secret.Name)
+	}
+}
+
+// deleteReplicaToken removes the finalizer of the Secret and deletes it,
+// unless the Secret changed since it was listed.
+func deleteReplicaToken(ctx context.Context, kube kclient.Client, secret *corev1.Secret) error {
+	replicaTokensMu.Lock()
+	delete(replicaTokenClients, secret.Name)
+	replicaTokensMu.Unlock()
+	if controllerutil.RemoveFinalizer(secret, replicaTokenFinalizer) {
+		if err := kube.Update(ctx, secret); err != nil {
+			return kclient.IgnoreNotFound(err)
+		}
+	}
+	if secret.DeletionTimestamp != nil {
+		return nil
+	}
+	return kclient.IgnoreNotFound(kube.Delete(ctx, secret, kclient.Preconditions{ResourceVersion: &secret.ResourceVersion}))
+}
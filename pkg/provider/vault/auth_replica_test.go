@@ -0,0 +1,495 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	kclient "sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+// replica is a controller replica logging in to Vault.
+type replica struct {
+	c     *client
+	token string
+	// beforeLogin runs while the login of the replica is in flight.
+	beforeLogin func()
+}
+
+func TestReplicaSharedToken(t *testing.T) {
+	defer func(namespace string) { replicaTokenNamespace = namespace }(replicaTokenNamespace)
+
+	cases := map[string]struct {
+		namespace string
+		// sharedToken is stored in the Secret before the replicas log in.
+		sharedToken string
+		// race lets replica a log in while the login of replica b is in
+		// flight, otherwise b logs in after a.
+		race bool
+		// tokenNamespace is the Vault namespace the tokens of replica b are
+		// issued in. Tokens revoked in it are recorded as namespace/token.
+		tokenNamespace string
+		wantTokens     []string
+		wantLogins     int
+		wantRevoked    []string
+		wantShared     string
+	}{
+		"Race": {
+			namespace:   "external-secrets",
+			race:        true,
+			wantTokens:  []string{"token-a", "token-a"},
+			wantLogins:  2,
+			wantRevoked: []string{"token-b"},
+			wantShared:  "token-a",
+		},
+		// the token that lost the race is revoked in the namespace it was
+		// issued in.
+		"RaceTokenInOtherNamespace": {
+			namespace:      "external-secrets",
+			race:           true,
+			tokenNamespace: "login-ns",
+			wantTokens:     []string{"token-a", "token-a"},
+			wantLogins:     2,
+			wantRevoked:    []string{"login-ns/token-b"},
+			wantShared:     "token-a",
+		},
+		"SharedTokenUsed": {
+			namespace:  "external-secrets",
+			wantTokens: []string{"token-a", "token-a"},
+			wantLogins: 1,
+			wantShared: "token-a",
+		},
+		// the first replica replaces the expired token, the second uses it.
+		"ExpiredSharedToken": {
+			namespace:   "external-secrets",
+			sharedToken: "expired-token",
+			wantTokens:  []string{"token-a", "token-a"},
+			wantLogins:  1,
+			wantShared:  "token-a",
+		},
+		"Disabled": {
+			wantTokens:  []string{"token-a", "token-b"},
+			wantLogins:  2,
+			wantRevoked: []string{"token-a", "token-b"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			replicaTokenNamespace = tc.namespace
+			replicaTokens = map[string]struct{}{}
+			valid := map[string]bool{"token-a": true, "token-b": true}
+			var revoked []string
+			logins := 0
+
+			replicas := []*replica{{}, {}}
+			for i, r := range replicas {
+				r.c = makeKubernetesAuthClient(t, makeServiceAccountJWT(t, nil), &esv1.VaultKubernetesAuth{
+					Path: "kubernetes",
+					Role: "kubernetes-auth-role",
+				}, &logins)
+				if i > 0 {
+					r.c.kube = replicas[0].c.kube
+				}
+				newToken := []string{"token-a", "token-b"}[i]
+				if i > 0 && tc.tokenNamespace != "" {
+					r.c.tokenNamespace = &tc.tokenNamespace
+				}
+				r.c.auth = fake.Auth{
+					LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+						logins++
+						if r.beforeLogin != nil {
+							r.beforeLogin()
+						}
+						r.token = newToken
+						return &vault.Secret{}, nil
+					},
+				}
+				var vaultClient func(namespace string) *util.VaultClient
+				vaultClient = func(namespace string) *util.VaultClient {
+					return &util.VaultClient{
+						TokenFunc:        func() string { return r.token },
+						SetTokenFunc:     func(v string) { r.token = v },
+						ClearTokenFunc:   func() { r.token = "" },
+						NamespaceFunc:    func() string { return namespace },
+						SetNamespaceFunc: func(string) {},
+						WithNamespaceFunc: func(namespace string) util.Client {
+							return vaultClient(namespace)
+						},
+						AuthField: r.c.auth,
+						AuthTokenField: fake.Token{
+							LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+								if !valid[r.token] {
+									return nil, errors.New("permission denied")
+								}
+								return makeTokenLookup(time.Hour, false), nil
+							},
+							RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+								if namespace != "" {
+									v = namespace + "/" + v
+								}
+								revoked = append(revoked, v)
+								return nil
+							},
+						},
+					}
+				}
+				r.c.client = vaultClient("")
+				r.c.token = r.c.client.AuthToken()
+			}
+			kube := replicas[0].c.kube
+			if tc.sharedToken != "" {
+				name, _ := replicas[0].c.replicaTokenSecret()
+				if err := kube.Create(context.Background(), &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: tc.namespace},
+					Data:       map[string][]byte{replicaTokenKey: []byte(tc.sharedToken)},
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			a, b := replicas[0], replicas[1]
+			if tc.race {
+				b.beforeLogin = func() {
+					if err := a.c.setAuth(context.Background(), nil); err != nil {
+						t.Errorf("unexpected error: %v", err)
+					}
+				}
+			} else if err := a.c.setAuth(context.Background(), nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := b.c.setAuth(context.Background(), nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantTokens, []string{a.token, b.token}); diff != "" {
+				t.Errorf("unexpected tokens (-want, +got):\n%s", diff)
+			}
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, logins)
+			}
+
+			// shared tokens are left to expire when the clients are closed.
+			for _, r := range replicas {
+				if err := r.c.Close(context.Background()); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if diff := cmp.Diff(tc.wantRevoked, revoked); diff != "" {
+				t.Errorf("unexpected revoked tokens (-want, +got):\n%s", diff)
+			}
+
+			if tc.wantShared == "" {
+				return
+			}
+			name, _ := a.c.replicaTokenSecret()
+			secret := &corev1.Secret{}
+			if err := kube.Get(context.Background(), types.NamespacedName{Namespace: tc.namespace, Name: name}, secret); err != nil {
+				t.Fatal(err)
+			}
+			if shared := string(secret.Data[replicaTokenKey]); shared != tc.wantShared {
+				t.Errorf("expected shared token %q, got %q", tc.wantShared, shared)
+			}
+			if _, ok := secret.Annotations[replicaTokenExpiryAnnotation]; !ok {
+				t.Error("expected the expiry of the shared token")
+			}
+			if diff := cmp.Diff([]string{replicaTokenFinalizer}, secret.Finalizers); diff != "" {
+				t.Errorf("unexpected finalizers (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReplicaTokenOwner(t *testing.T) {
+	defer func(namespace string) { replicaTokenNamespace = namespace }(replicaTokenNamespace)
+	replicaTokenNamespace = "external-secrets"
+
+	cases := map[string]struct {
+		storeKind      string
+		namespace      string
+		wantStore      string
+		wantOwnerCount int
+	}{
+		"ClusterSecretStore": {
+			storeKind:      esv1.ClusterSecretStoreKind,
+			namespace:      "team-a",
+			wantStore:      "ClusterSecretStore//vault-store",
+			wantOwnerCount: 1,
+		},
+		"SecretStoreInNamespace": {
+			storeKind:      esv1.SecretStoreKind,
+			namespace:      "external-secrets",
+			wantStore:      "SecretStore/external-secrets/vault-store",
+			wantOwnerCount: 1,
+		},
+		// owners in other namespaces aren't allowed.
+		"SecretStoreInOtherNamespace": {
+			storeKind: esv1.SecretStoreKind,
+			namespace: "team-a",
+			wantStore: "SecretStore/team-a/vault-store",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := &client{
+				namespace: tc.namespace,
+				storeKind: tc.storeKind,
+				storeName: "vault-store",
+				storeUID:  "1234",
+				store:     &esv1.VaultProvider{},
+				token: fake.Token{
+					LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+						return makeTokenLookup(time.Hour, false), nil
+					},
+				},
+			}
+			secret := &corev1.Secret{}
+			c.setReplicaToken(context.Background(), secret, "token-a")
+			if got := secret.Annotations[replicaTokenStoreAnnotation]; got != tc.wantStore {
+				t.Errorf("expected store %q, got %q", tc.wantStore, got)
+			}
+			if len(secret.OwnerReferences) != tc.wantOwnerCount {
+				t.Fatalf("expected %d owners, got %v", tc.wantOwnerCount, secret.OwnerReferences)
+			}
+			if tc.wantOwnerCount > 0 && (secret.OwnerReferences[0].Kind != tc.storeKind || secret.OwnerReferences[0].UID != "1234") {
+				t.Errorf("unexpected owner %v", secret.OwnerReferences[0])
+			}
+		})
+	}
+}
+
+func TestCleanReplicaTokens(t *testing.T) {
+	defer func(namespace string) { replicaTokenNamespace = namespace }(replicaTokenNamespace)
+	replicaTokenNamespace = "external-secrets"
+
+	cases := map[string]struct {
+		store    string
+		expiry   time.Time
+		deleting bool
+		// noClient leaves no client to revoke the token with.
+		noClient bool
+		// released leaves the Secret without a finalizer, as on shutdown.
+		released    bool
+		wantRevoked []string
+		wantDeleted bool
+	}{
+		"StoreExists": {
+			store:  "SecretStore/default/vault-store",
+			expiry: time.Now().Add(time.Hour),
+		},
+		"Released": {
+			store:    "SecretStore/default/vault-store",
+			expiry:   time.Now().Add(time.Hour),
+			released: true,
+		},
+		"Expired": {
+			store:       "SecretStore/default/vault-store",
+			expiry:      time.Now().Add(-time.Minute),
+			wantDeleted: true,
+		},
+		"StoreDeleted": {
+			store:       "SecretStore/default/other-store",
+			expiry:      time.Now().Add(time.Hour),
+			wantRevoked: []string{"token-a"},
+			wantDeleted: true,
+		},
+		"ClusterStoreDeleted": {
+			store:       "ClusterSecretStore//vault-store",
+			wantRevoked: []string{"token-a"},
+			wantDeleted: true,
+		},
+		// the garbage collector deleted the Secret along with its owner.
+		"OwnerDeleted": {
+			store:       "SecretStore/default/vault-store",
+			deleting:    true,
+			wantRevoked: []string{"token-a"},
+			wantDeleted: true,
+		},
+		"NoClient": {
+			store:       "SecretStore/default/other-store",
+			noClient:    true,
+			wantDeleted: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := esv1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "vault-token-1234",
+					Namespace:   replicaTokenNamespace,
+					Labels:      map[string]string{replicaTokenLabel: "true"},
+					Annotations: map[string]string{replicaTokenStoreAnnotation: tc.store},
+					Finalizers:  []string{replicaTokenFinalizer},
+				},
+				Data: map[string][]byte{replicaTokenKey: []byte("token-a")},
+			}
+			if !tc.expiry.IsZero() {
+				secret.Annotations[replicaTokenExpiryAnnotation] = tc.expiry.UTC().Format(time.RFC3339)
+			}
+			if tc.deleting {
+				secret.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			}
+			if tc.released {
+				secret.Finalizers = nil
+			}
+			kube := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, &esv1.SecretStore{
+				ObjectMeta: metav1.ObjectMeta{Name: "vault-store", Namespace: "default"},
+			}).Build()
+
+			var revoked []string
+			authToken := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					return makeTokenLookup(time.Hour, false), nil
+				},
+				RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+					revoked = append(revoked, v)
+					return nil
+				},
+			}
+			var vaultClient *util.VaultClient
+			vaultClient = &util.VaultClient{
+				TokenFunc:         func() string { return "token-a" },
+				SetTokenFunc:      func(string) {},
+				ClearTokenFunc:    func() {},
+				NamespaceFunc:     func() string { return "" },
+				WithNamespaceFunc: func(string) util.Client { return vaultClient },
+				AuthTokenField:    authToken,
+			}
+			replicaTokenClients = map[string]util.Client{}
+			if !tc.noClient {
+				replicaTokenClients[secret.Name] = vaultClient
+			}
+			markReplicaToken("token-a")
+			defer unmarkReplicaToken("token-a")
+
+			cleanReplicaTokens(context.Background(), kube)
+
+			if diff := cmp.Diff(tc.wantRevoked, revoked); diff != "" {
+				t.Errorf("unexpected revoked tokens (-want, +got):\n%s", diff)
+			}
+			got := &corev1.Secret{}
+			err := kube.Get(context.Background(), types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}, got)
+			if deleted := apierrors.IsNotFound(err); deleted != tc.wantDeleted {
+				t.Errorf("expected deleted %t, got %v", tc.wantDeleted, err)
+			}
+			// the Secrets of existing stores keep their finalizer.
+			if diff := cmp.Diff([]string{replicaTokenFinalizer}, got.Finalizers); err == nil && diff != "" {
+				t.Errorf("unexpected finalizers (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReleaseReplicaTokens(t *testing.T) {
+	defer func(namespace string) { replicaTokenNamespace = namespace }(replicaTokenNamespace)
+	defer elected.Store(false)
+
+	cases := map[string]struct {
+		namespace string
+		// run runs the leader until it is done.
+		run          func(ctx context.Context, kube kclient.Client)
+		wantReleased []string
+	}{
+		// the leader releases the Secrets of its namespace on shutdown.
+		"Shutdown": {
+			namespace: "external-secrets",
+			run: func(ctx context.Context, kube kclient.Client) {
+				cleanupReplicaTokens(ctx, kube)
+			},
+			wantReleased: []string{"external-secrets"},
+		},
+		// without sharing, the Secrets of an earlier run are released in
+		// all namespaces.
+		"Disabled": {
+			run: func(ctx context.Context, kube kclient.Client) {
+				(&Provider{}).onElected(ctx, kube, nil)
+			},
+			wantReleased: []string{"default", "external-secrets"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			replicaTokenNamespace = tc.namespace
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := esv1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			builder := clientfake.NewClientBuilder().WithScheme(scheme).WithObjects(&esv1.SecretStore{
+				ObjectMeta: metav1.ObjectMeta{Name: "vault-store", Namespace: "default"},
+			})
+			namespaces := []string{"default", "external-secrets"}
+			for _, namespace := range namespaces {
+				builder = builder.WithObjects(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "vault-token-1234",
+						Namespace:   namespace,
+						Labels:      map[string]string{replicaTokenLabel: "true"},
+						Annotations: map[string]string{replicaTokenStoreAnnotation: "SecretStore/default/vault-store"},
+						Finalizers:  []string{replicaTokenFinalizer},
+					},
+					Data: map[string][]byte{replicaTokenKey: []byte("token-a")},
+				})
+			}
+			kube := builder.Build()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			tc.run(ctx, kube)
+
+			var released []string
+			for _, namespace := range namespaces {
+				secret := &corev1.Secret{}
+				if err := kube.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "vault-token-1234"}, secret); err != nil {
+					t.Fatal(err)
+				}
+				if len(secret.Finalizers) == 0 {
+					released = append(released, namespace)
+				}
+			}
+			if diff := cmp.Diff(tc.wantReleased, released); diff != "" {
+				t.Errorf("unexpected released Secrets (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+const (
+	defaultAuthRetryInterval  = time.Second
+	defaultMaxLoginRetryAfter = time.Minute
+	maxLoginRetryInterval     = time.Minute
+)
+
+// maxLoginRetryAfter bounds the wait honored when Vault rate limits a login
+// with a Retry-After header. The header is ignored if zero.
+var maxLoginRetryAfter = defaultMaxLoginRetryAfter
+
+// retryPolicy retries a single step of a login with a fixed interval, or
+// with an interval doubling on each retry up to maxLoginRetryInterval.
+type retryPolicy struct {
+	maxRetries  int
+	interval    time.Duration
+	exponential bool
+}
+
+func newRetryPolicy(settings *esv1.SecretStoreRetrySettings) (retryPolicy, error) {
+	policy := retryPolicy{interval: defaultAuthRetryInterval}
+	if settings == nil {
+		return policy, nil
+	}
+	if settings.MaxRetries != nil {
+		if *settings.MaxRetries < 0 {
+			return policy, fmt.Errorf("maxRetries must not be negative, got %d", *settings.MaxRetries)
+		}
+		policy.maxRetries = int(*settings.MaxRetries)
+	}
+	if settings.RetryInterval != nil {
+		interval, err := time.ParseDuration(*settings.RetryInterval)
+		if err != nil {
+			return policy, err
+		}
+		policy.interval = interval
+	}
+	return policy, nil
+}
+
+// newLoginRetryPolicy returns the policy retrying login requests with an
+// exponential backoff. It doesn't retry if settings is nil.
+func newLoginRetryPolicy(settings *esv1.VaultLoginRetry) (retryPolicy, error) {
+	policy := retryPolicy{interval: defaultAuthRetryInterval, exponential: true}
+	if settings == nil {
+		return policy, nil
+	}
+	if settings.MaxAttempts < 1 {
+		return policy, fmt.Errorf("maxAttempts must be at least 1, got %d", settings.MaxAttempts)
+	}
+	policy.maxRetries = int(settings.MaxAttempts) - 1
+	if settings.InitialInterval != nil {
+		interval, err := time.ParseDuration(*settings.InitialInterval)
+		if err != nil {
+			return policy, err
+		}
+		policy.interval = interval
+	}
+	return policy, nil
+}
+
+// do runs fn until it succeeds, fails with an error that isn't retryable or
+// the retries are exhausted.
+func (p retryPolicy) do(ctx context.Context, retryable func(error) bool, fn func() error) error {
+	err := fn()
+	interval := p.interval
+	for attempt := 0; attempt < p.maxRetries && err != nil && retryable(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(interval):
+		}
+		if p.exponential {
+			interval = min(2*interval, maxLoginRetryInterval)
+		}
+		err = fn()
+	}
+	return err
+}
+
+// retryLogin runs the login request of the named auth method, retrying it
+// as configured by Auth.LoginRetry while it fails with a transient error.
+func (c *client) retryLogin(ctx context.Context, method string, login func() error) error {
+	policy, err := newLoginRetryPolicy(c.store.Auth.LoginRetry)
+	if err != nil {
+		return err
+	}
+	return policy.do(ctx, c.isRetryableLoginError(method), login)
+}
+
+// isRetryableTokenRequestError reports whether a failed TokenRequest may
+// succeed when repeated. Missing permissions or service accounts won't.
+func isRetryableTokenRequestError(err error) bool {
+	return !apierrors.IsForbidden(err) && !apierrors.IsNotFound(err) && !apierrors.IsUnauthorized(err)
+}
+
+// loginBackoff returns a backoff for the retries of the Vault client that
+// waits as long as Vault asks for when it rate limits a login, and uses
+// the given backoff for all other retries.
+func loginBackoff(backoff retryablehttp.Backoff) retryablehttp.Backoff {
+	return func(minWait, maxWait time.Duration, attempt int, resp *http.Response) time.Duration {
+		if wait, ok := loginRetryAfter(resp); ok {
+			return wait
+		}
+		return backoff(minWait, maxWait, attempt, resp)
+	}
+}
+
+// loginRetryAfter returns the wait requested by the Retry-After header of a
+// rate limited login, bounded by maxLoginRetryAfter. Retries are aborted
+// once the context of the request is done, even while waiting.
+func loginRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if maxLoginRetryAfter <= 0 || resp == nil || resp.StatusCode != http.StatusTooManyRequests || !isLoginRequest(resp.Request) {
+		return 0, false
+	}
+	// https://httpwg.org/specs/rfc9110.html#field.retry-after
+	header := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds)*time.Second, maxLoginRetryAfter), true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return min(max(time.Until(at), 0), maxLoginRetryAfter), true
+	}
+	return 0, false
+}
+
+// isLoginRequest reports whether the request logs in with an auth method,
+// e.g. auth/kubernetes/login or auth/userpass/login/<username>.
+func isLoginRequest(req *http.Request) bool {
+	if req == nil {
+		return false
+	}
+	path := req.URL.Path
+	return strings.Contains(path, "/auth/") && (strings.HasSuffix(path, "/login") || strings.Contains(path, "/login/"))
+}
@@ -0,0 +1,558 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	vault "github.com/hashicorp/vault/api"
+	authv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+// flakyTokenRequests fails the first token requests with err.
+type flakyTokenRequests struct {
+	typedcorev1.CoreV1Interface
+	typedcorev1.ServiceAccountInterface
+
+	token    string
+	failures int
+	err      error
+	requests int
+}
+
+func (f *flakyTokenRequests) ServiceAccounts(string) typedcorev1.ServiceAccountInterface {
+	return f
+}
+
+func (f *flakyTokenRequests) CreateToken(context.Context, string, *authv1.TokenRequest, metav1.CreateOptions) (*authv1.TokenRequest, error) {
+	f.requests++
+	if f.requests <= f.failures {
+		return nil, f.err
+	}
+	return &authv1.TokenRequest{Status: authv1.TokenRequestStatus{Token: f.token}}, nil
+}
+
+func TestKubernetesAuthRetries(t *testing.T) {
+	unavailable := &vault.ResponseError{StatusCode: http.StatusServiceUnavailable}
+	denied := &vault.ResponseError{StatusCode: http.StatusForbidden}
+	teapot := &vault.ResponseError{StatusCode: http.StatusTeapot}
+	timeout := apierrors.NewTimeoutError("request timed out", 1)
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "serviceaccounts"}, "vault-sa", errors.New("cannot create token"))
+
+	retries := func(n int32) *esv1.SecretStoreRetrySettings {
+		return &esv1.SecretStoreRetrySettings{MaxRetries: ptr.To(n), RetryInterval: ptr.To("1ms")}
+	}
+
+	cases := map[string]struct {
+		tokenRetry    *esv1.SecretStoreRetrySettings
+		loginRetry    *esv1.SecretStoreRetrySettings
+		statusMapping []esv1.VaultAuthStatusMapping
+		tokenFailures int
+		tokenErr      error
+		loginFailures int
+		loginErr      error
+		wantErr       bool
+		wantRequests  int
+		wantLogins    int
+	}{
+		"NoRetries": {
+			loginFailures: 1,
+			loginErr:      unavailable,
+			wantErr:       true,
+			wantRequests:  1,
+			wantLogins:    1,
+		},
+		"TokenRequestRetried": {
+			tokenRetry:    retries(2),
+			tokenFailures: 2,
+			tokenErr:      timeout,
+			wantRequests:  3,
+			wantLogins:    1,
+		},
+		"TokenRequestRetriesExhausted": {
+			tokenRetry:    retries(1),
+			tokenFailures: 3,
+			tokenErr:      timeout,
+			wantErr:       true,
+			wantRequests:  2,
+		},
+		"TokenRequestForbiddenNotRetried": {
+			tokenRetry:    retries(3),
+			tokenFailures: 1,
+			tokenErr:      forbidden,
+			wantErr:       true,
+			wantRequests:  1,
+		},
+		"LoginRetriedWithoutNewToken": {
+			loginRetry:    retries(2),
+			loginFailures: 2,
+			loginErr:      unavailable,
+			wantRequests:  1,
+			wantLogins:    3,
+		},
+		"TokenRequestPolicyDoesNotRetryLogin": {
+			tokenRetry:    retries(3),
+			loginFailures: 1,
+			loginErr:      unavailable,
+			wantErr:       true,
+			wantRequests:  1,
+			wantLogins:    1,
+		},
+		"LoginDeniedNotRetried": {
+			loginRetry:    retries(3),
+			loginFailures: 1,
+			loginErr:      denied,
+			wantErr:       true,
+			wantRequests:  1,
+			wantLogins:    1,
+		},
+		"UnknownStatusNotRetried": {
+			loginRetry:    retries(3),
+			loginFailures: 1,
+			loginErr:      teapot,
+			wantErr:       true,
+			wantRequests:  1,
+			wantLogins:    1,
+		},
+		"StatusMappedToTransientRetried": {
+			loginRetry: retries(2),
+			statusMapping: []esv1.VaultAuthStatusMapping{
+				{StatusCode: http.StatusTeapot, Class: esv1.VaultAuthErrorClassTransient},
+			},
+			loginFailures: 2,
+			loginErr:      teapot,
+			wantRequests:  1,
+			wantLogins:    3,
+		},
+		"StatusMappedForOtherMethodNotRetried": {
+			loginRetry: retries(2),
+			statusMapping: []esv1.VaultAuthStatusMapping{
+				{Method: esv1.VaultAuthMethodAppRole, StatusCode: http.StatusTeapot, Class: esv1.VaultAuthErrorClassTransient},
+			},
+			loginFailures: 1,
+			loginErr:      teapot,
+			wantErr:       true,
+			wantRequests:  1,
+			wantLogins:    1,
+		},
+		"ServerErrorMappedToAuthRejectedNotRetried": {
+			loginRetry: retries(2),
+			statusMapping: []esv1.VaultAuthStatusMapping{
+				{Method: esv1.VaultAuthMethodKubernetes, StatusCode: http.StatusServiceUnavailable, Class: esv1.VaultAuthErrorClassAuthRejected},
+			},
+			loginFailures: 1,
+			loginErr:      unavailable,
+			wantErr:       true,
+			wantRequests:  1,
+			wantLogins:    1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tokens := &flakyTokenRequests{
+				token:    makeServiceAccountJWT(t, jwt.MapClaims{}),
+				failures: tc.tokenFailures,
+				err:      tc.tokenErr,
+			}
+			logins := 0
+			kubernetesAuth := &esv1.VaultKubernetesAuth{
+				Path:                      "kubernetes",
+				Role:                      "kubernetes-auth-role",
+				ServiceAccountRef:         &esmeta.ServiceAccountSelector{Name: "vault-sa"},
+				TokenRequestRetrySettings: tc.tokenRetry,
+				LoginRetrySettings:        tc.loginRetry,
+			}
+			c := &client{
+				kube:      clientfake.NewClientBuilder().Build(),
+				log:       logger,
+				corev1:    tokens,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store: &esv1.VaultProvider{
+					Auth: &esv1.VaultAuth{Kubernetes: kubernetesAuth, StatusMapping: tc.statusMapping},
+				},
+				client: &util.VaultClient{
+					SetTokenFunc: func(string) {},
+				},
+				auth: fake.Auth{
+					LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+						logins++
+						if logins <= tc.loginFailures {
+							return nil, tc.loginErr
+						}
+						return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "kubernetes-token"}}, nil
+					},
+				},
+				logical: fake.Logical{
+					ReadWithDataWithContextFn: fake.NewReadWithContextFn(nil, denied),
+				},
+			}
+
+			err := c.requestTokenWithKubernetesAuth(context.Background(), kubernetesAuth)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if tokens.requests != tc.wantRequests {
+				t.Errorf("expected %d token requests, got %d", tc.wantRequests, tokens.requests)
+			}
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, logins)
+			}
+		})
+	}
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	if _, err := newRetryPolicy(&esv1.SecretStoreRetrySettings{RetryInterval: ptr.To("soon")}); err == nil {
+		t.Error("expected an error for an invalid retry interval")
+	}
+	if _, err := newRetryPolicy(&esv1.SecretStoreRetrySettings{MaxRetries: ptr.To(int32(-1))}); err == nil {
+		t.Error("expected an error for negative retries")
+	}
+	policy, err := newRetryPolicy(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy.maxRetries != 0 {
+		t.Errorf("expected no retries by default, got %d", policy.maxRetries)
+	}
+}
+
+func TestLoginRetry(t *testing.T) {
+	unavailable := &vault.ResponseError{StatusCode: http.StatusInternalServerError}
+	badRequest := &vault.ResponseError{StatusCode: http.StatusBadRequest}
+	denied := &vault.ResponseError{StatusCode: http.StatusForbidden}
+	network := &url.Error{Op: "Put", URL: "https://vault.example.com", Err: errors.New("connection reset by peer")}
+
+	cases := map[string]struct {
+		loginRetry    *esv1.VaultLoginRetry
+		loginFailures int
+		loginErr      error
+		timeout       time.Duration
+		wantErr       bool
+		wantLogins    int
+	}{
+		"NoRetries": {
+			loginFailures: 1,
+			loginErr:      unavailable,
+			wantErr:       true,
+			wantLogins:    1,
+		},
+		"ServerErrorRetried": {
+			loginRetry:    &esv1.VaultLoginRetry{MaxAttempts: 3, InitialInterval: ptr.To("1ms")},
+			loginFailures: 2,
+			loginErr:      unavailable,
+			wantLogins:    3,
+		},
+		"NetworkErrorRetried": {
+			loginRetry:    &esv1.VaultLoginRetry{MaxAttempts: 2, InitialInterval: ptr.To("1ms")},
+			loginFailures: 1,
+			loginErr:      network,
+			wantLogins:    2,
+		},
+		"AttemptsExhausted": {
+			loginRetry:    &esv1.VaultLoginRetry{MaxAttempts: 2, InitialInterval: ptr.To("1ms")},
+			loginFailures: 3,
+			loginErr:      unavailable,
+			wantErr:       true,
+			wantLogins:    2,
+		},
+		"BadRequestNotRetried": {
+			loginRetry:    &esv1.VaultLoginRetry{MaxAttempts: 3, InitialInterval: ptr.To("1ms")},
+			loginFailures: 1,
+			loginErr:      badRequest,
+			wantErr:       true,
+			wantLogins:    1,
+		},
+		"DeniedNotRetried": {
+			loginRetry:    &esv1.VaultLoginRetry{MaxAttempts: 3, InitialInterval: ptr.To("1ms")},
+			loginFailures: 1,
+			loginErr:      denied,
+			wantErr:       true,
+			wantLogins:    1,
+		},
+		// the backoff is aborted once the context is done.
+		"Cancelled": {
+			loginRetry:    &esv1.VaultLoginRetry{MaxAttempts: 3, InitialInterval: ptr.To("1m")},
+			loginFailures: 1,
+			loginErr:      unavailable,
+			timeout:       50 * time.Millisecond,
+			wantErr:       true,
+			wantLogins:    1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			c := &client{
+				log: logger,
+				store: &esv1.VaultProvider{
+					Auth: &esv1.VaultAuth{
+						LoginRetry: tc.loginRetry,
+						UserPass: &esv1.VaultUserPassAuth{
+							Path:     "userpass",
+							Username: "eso",
+						},
+					},
+				},
+				auth: fake.Auth{
+					LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+						logins++
+						if logins <= tc.loginFailures {
+							return nil, tc.loginErr
+						}
+						return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "userpass-token"}}, nil
+					},
+				},
+				client: &util.VaultClient{SetTokenFunc: func(string) {}},
+			}
+
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			start := time.Now()
+			var resp *vault.Secret
+			err := c.retryLogin(ctx, authMethodUserPass, func() error {
+				var loginErr error
+				resp, loginErr = c.login(ctx, nil)
+				return loginErr
+			})
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if !tc.wantErr && resp.Auth.ClientToken != "userpass-token" {
+				t.Errorf("unexpected login response %v", resp)
+			}
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, logins)
+			}
+			if elapsed := time.Since(start); tc.timeout > 0 && elapsed >= time.Second {
+				t.Errorf("expected the retries to end with the context, took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestNewLoginRetryPolicy(t *testing.T) {
+	if _, err := newLoginRetryPolicy(&esv1.VaultLoginRetry{MaxAttempts: 0}); err == nil {
+		t.Error("expected an error for no attempts")
+	}
+	if _, err := newLoginRetryPolicy(&esv1.VaultLoginRetry{MaxAttempts: 2, InitialInterval: ptr.To("soon")}); err == nil {
+		t.Error("expected an error for an invalid initial interval")
+	}
+	policy, err := newLoginRetryPolicy(&esv1.VaultLoginRetry{MaxAttempts: 4, InitialInterval: ptr.To("10ms")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the interval doubles with each retry.
+	var waits []time.Duration
+	last := time.Now()
+	_ = policy.do(context.Background(), func(error) bool { return true }, func() error {
+		now := time.Now()
+		waits = append(waits, now.Sub(last))
+		last = now
+		return errors.New("unavailable")
+	})
+	if len(waits) != 4 {
+		t.Fatalf("expected 4 attempts, got %d", len(waits))
+	}
+	for i, want := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond} {
+		if waits[i+1] < want {
+			t.Errorf("expected retry %d after at least %v, got %v", i+1, want, waits[i+1])
+		}
+	}
+}
+
+func TestLoginRetryAfter(t *testing.T) {
+	defer func(d time.Duration) { maxLoginRetryAfter = d }(maxLoginRetryAfter)
+
+	cases := map[string]struct {
+		status     int
+		path       string
+		retryAfter string
+		disabled   bool
+		want       time.Duration
+		wantOK     bool
+	}{
+		"Seconds": {
+			status:     http.StatusTooManyRequests,
+			path:       "/v1/auth/kubernetes/login",
+			retryAfter: "3",
+			want:       3 * time.Second,
+			wantOK:     true,
+		},
+		"HTTPDate": {
+			status:     http.StatusTooManyRequests,
+			path:       "/v1/auth/kubernetes/login",
+			retryAfter: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat),
+			want:       defaultMaxLoginRetryAfter,
+			wantOK:     true,
+		},
+		"PastHTTPDate": {
+			status:     http.StatusTooManyRequests,
+			path:       "/v1/auth/kubernetes/login",
+			retryAfter: "Fri, 31 Dec 1999 23:59:59 GMT",
+			wantOK:     true,
+		},
+		"Capped": {
+			status:     http.StatusTooManyRequests,
+			path:       "/v1/auth/kubernetes/login",
+			retryAfter: "120",
+			want:       defaultMaxLoginRetryAfter,
+			wantOK:     true,
+		},
+		"LoginWithUsername": {
+			status:     http.StatusTooManyRequests,
+			path:       "/v1/auth/userpass/login/eso",
+			retryAfter: "3",
+			want:       3 * time.Second,
+			wantOK:     true,
+		},
+		"Disabled": {
+			status:     http.StatusTooManyRequests,
+			path:       "/v1/auth/kubernetes/login",
+			retryAfter: "3",
+			disabled:   true,
+		},
+		"NotLogin": {
+			status:     http.StatusTooManyRequests,
+			path:       "/v1/secret/data/foo",
+			retryAfter: "3",
+		},
+		"NotRateLimited": {
+			status:     http.StatusServiceUnavailable,
+			path:       "/v1/auth/kubernetes/login",
+			retryAfter: "3",
+		},
+		"Invalid": {
+			status:     http.StatusTooManyRequests,
+			path:       "/v1/auth/kubernetes/login",
+			retryAfter: "soon",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			maxLoginRetryAfter = defaultMaxLoginRetryAfter
+			if tc.disabled {
+				maxLoginRetryAfter = 0
+			}
+			resp := &http.Response{
+				StatusCode: tc.status,
+				Header:     http.Header{"Retry-After": []string{tc.retryAfter}},
+				Request:    &http.Request{URL: &url.URL{Path: tc.path}},
+			}
+			got, ok := loginRetryAfter(resp)
+			if ok != tc.wantOK || got != tc.want {
+				t.Errorf("expected wait %v (%t), got %v (%t)", tc.want, tc.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestLoginRetryAfterWait(t *testing.T) {
+	cases := map[string]struct {
+		retryAfter string
+		timeout    time.Duration
+		wantWait   time.Duration
+		wantErr    bool
+	}{
+		"HonorsRetryAfter": {
+			retryAfter: "1",
+			timeout:    time.Minute,
+			wantWait:   time.Second,
+		},
+		// the wait is aborted once the context is done.
+		"BoundedByContext": {
+			retryAfter: "30",
+			timeout:    200 * time.Millisecond,
+			wantErr:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) == 1 {
+					w.Header().Set("Retry-After", tc.retryAfter)
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token"}}`))
+			}))
+			defer server.Close()
+
+			c := &client{log: logger, store: &esv1.VaultProvider{Server: server.URL}}
+			cfg, err := c.newConfig(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the Vault client would retry after at most 10ms otherwise.
+			cfg.MinRetryWait = 10 * time.Millisecond
+			cfg.MaxRetryWait = 10 * time.Millisecond
+			vaultClient, err := vault.NewClient(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			start := time.Now()
+			_, err = vaultClient.Logical().WriteWithContext(ctx, "auth/approle/login", map[string]any{"role_id": "eso"})
+			elapsed := time.Since(start)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr {
+				if elapsed >= time.Second {
+					t.Errorf("expected the retry to be aborted with the context, waited %v", elapsed)
+				}
+				return
+			}
+			if elapsed < tc.wantWait {
+				t.Errorf("expected to wait at least %v before retrying, waited %v", tc.wantWait, elapsed)
+			}
+			if got := requests.Load(); got != 2 {
+				t.Errorf("expected 2 login requests, got %d", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	authv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/external-secrets/external-secrets/pkg/cache"
+)
+
+const (
+	// mintedTokenMinValidity is how long a service account token must still
+	// be valid to be reused, so that it doesn't expire during the login.
+	mintedTokenMinValidity = time.Minute
+	// with reuseMintedTokens, a service account token is reused while it
+	// is valid for more than 1/mintedTokenReuseFraction of its lifetime.
+	mintedTokenReuseFraction = 5
+	defaultMintedTokensSize  = 2 << 10
+	mintedTokenKind          = "ServiceAccountToken"
+)
+
+// mintedToken is a service account token issued by the TokenRequest API.
+type mintedToken struct {
+	token  string
+	issued time.Time
+	expiry time.Time
+}
+
+var (
+	// reuseMintedTokens logs in with the last service account token issued
+	// for a token request while it is valid, instead of requesting a new
+	// one for every login.
+	reuseMintedTokens bool
+	// mintedTokens holds the last service account token issued for each
+	// token request, so that it can be reused across logins and while the
+	// Kubernetes API is unavailable. Least recently used tokens are evicted.
+	mintedTokens = cache.Must[mintedToken](defaultMintedTokensSize, nil)
+)
+
+func initMintedTokens(size int) {
+	mintedTokens = cache.Must[mintedToken](size, nil)
+}
+
+// mintedTokenKey identifies the token requests that issue interchangeable
+// tokens.
+func mintedTokenKey(namespace, name string, spec authv1.TokenRequestSpec) string {
+	audiences := slices.Sorted(slices.Values(spec.Audiences))
+	parts := []string{namespace, name, strings.Join(audiences, ",")}
+	if spec.ExpirationSeconds != nil {
+		parts = append(parts, strconv.FormatInt(*spec.ExpirationSeconds, 10))
+	}
+	if ref := spec.BoundObjectRef; ref != nil {
+		parts = append(parts, ref.APIVersion, ref.Kind, ref.Name, string(ref.UID))
+	}
+	return strings.Join(parts, "/")
+}
+
+// storeMintedToken remembers a newly issued service account token. Tokens
+// without a known expiry are never reused.
+func storeMintedToken(key, token string, expiry time.Time) {
+	if expiry.IsZero() {
+		return
+	}
+	mintedTokens.Add("", cache.Key{Name: key, Kind: mintedTokenKind}, mintedToken{
+		token:  token,
+		issued: time.Now(),
+		expiry: expiry,
+	})
+}
+
+// cachedMintedToken returns the last token issued for the token request if
+// it is still valid for long enough to log in with it.
+func cachedMintedToken(key string) (string, bool) {
+	t, ok := mintedTokens.Get("", cache.Key{Name: key, Kind: mintedTokenKind})
+	if !ok || time.Until(t.expiry) < mintedTokenMinValidity {
+		return "", false
+	}
+	return t.token, true
+}
+
+// reusableMintedToken returns the last token issued for the token request
+// if it is still valid for a fifth of its lifetime, so that a token is
+// requested again well before it expires.
+func reusableMintedToken(key string) (string, bool) {
+	t, ok := mintedTokens.Get("", cache.Key{Name: key, Kind: mintedTokenKind})
+	if !ok {
+		return "", false
+	}
+	margin := max(t.expiry.Sub(t.issued)/mintedTokenReuseFraction, mintedTokenMinValidity)
+	if time.Until(t.expiry) < margin {
+		return "", false
+	}
+	return t.token, true
+}
+
+// forgetMintedTokens drops all remembered service account tokens.
+func forgetMintedTokens() {
+	mintedTokens.Purge()
+}
+
+// isKubeAPIUnavailable reports whether a failed TokenRequest is due to the
+// Kubernetes API being unreachable or overloaded, rather than the request
+// being rejected.
+func isKubeAPIUnavailable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsInternalError(err)
+}
@@ -0,0 +1,268 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	authv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/cache"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+// outageTokenRequests issues tokens valid for ttl until the outage begins,
+// and fails all token requests with err afterwards.
+type outageTokenRequests struct {
+	typedcorev1.CoreV1Interface
+	typedcorev1.ServiceAccountInterface
+
+	ttl      time.Duration
+	outage   bool
+	err      error
+	requests int
+}
+
+func (f *outageTokenRequests) ServiceAccounts(string) typedcorev1.ServiceAccountInterface {
+	return f
+}
+
+func (f *outageTokenRequests) CreateToken(context.Context, string, *authv1.TokenRequest, metav1.CreateOptions) (*authv1.TokenRequest, error) {
+	f.requests++
+	if f.outage {
+		return nil, f.err
+	}
+	return &authv1.TokenRequest{Status: authv1.TokenRequestStatus{
+		Token:               "minted-jwt",
+		ExpirationTimestamp: metav1.NewTime(time.Now().Add(f.ttl)),
+	}}, nil
+}
+
+func TestServiceAccountTokenOutage(t *testing.T) {
+	unavailable := apierrors.NewServiceUnavailable("apiserver is shutting down")
+	timeout := apierrors.NewTimeoutError("request timed out", 1)
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "serviceaccounts"}, "vault-sa", errors.New("cannot create token"))
+
+	cases := map[string]struct {
+		ttl       time.Duration
+		minted    bool
+		outageErr error
+		wantErr   bool
+		wantJwt   string
+	}{
+		"OutageWithValidToken": {
+			ttl:       10 * time.Minute,
+			minted:    true,
+			outageErr: unavailable,
+			wantJwt:   "minted-jwt",
+		},
+		"TimeoutWithValidToken": {
+			ttl:       10 * time.Minute,
+			minted:    true,
+			outageErr: timeout,
+			wantJwt:   "minted-jwt",
+		},
+		"OutageWithExpiringToken": {
+			ttl:       30 * time.Second,
+			minted:    true,
+			outageErr: unavailable,
+			wantErr:   true,
+		},
+		"OutageWithoutToken": {
+			outageErr: unavailable,
+			wantErr:   true,
+		},
+		"RejectedWithValidToken": {
+			ttl:       10 * time.Minute,
+			minted:    true,
+			outageErr: forbidden,
+			wantErr:   true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Cleanup(forgetMintedTokens)
+			corev1Client := &outageTokenRequests{ttl: tc.ttl, err: tc.outageErr}
+			loginJwt := ""
+			c := &client{
+				log:       logger,
+				corev1:    corev1Client,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store:     &esv1.VaultProvider{Auth: &esv1.VaultAuth{}},
+				client: &util.VaultClient{
+					SetTokenFunc: func(string) {},
+				},
+				logical: fake.Logical{
+					WriteWithContextFn: func(ctx context.Context, path string, data map[string]any) (*vault.Secret, error) {
+						loginJwt, _ = data["jwt"].(string)
+						return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "jwt-token"}}, nil
+					},
+				},
+			}
+			jwtAuth := &esv1.VaultJwtAuth{
+				Path: "jwt",
+				Role: "role",
+				KubernetesServiceAccountToken: &esv1.VaultKubernetesServiceAccountTokenAuth{
+					ServiceAccountRef: esmeta.ServiceAccountSelector{Name: "vault-sa"},
+				},
+			}
+
+			if tc.minted {
+				if err := c.requestTokenWithJwtAuth(context.Background(), jwtAuth); err != nil {
+					t.Fatalf("unexpected error before the outage: %v", err)
+				}
+			}
+			corev1Client.outage = true
+			loginJwt = ""
+
+			err := c.requestTokenWithJwtAuth(context.Background(), jwtAuth)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if loginJwt != tc.wantJwt {
+				t.Errorf("expected login with jwt %q, got %q", tc.wantJwt, loginJwt)
+			}
+		})
+	}
+}
+
+func TestMintedTokenKey(t *testing.T) {
+	spec := func(audiences []string, expirationSeconds int64, bound *authv1.BoundObjectReference) authv1.TokenRequestSpec {
+		return authv1.TokenRequestSpec{Audiences: audiences, ExpirationSeconds: &expirationSeconds, BoundObjectRef: bound}
+	}
+	pod := &authv1.BoundObjectReference{Kind: "Pod", APIVersion: "v1", Name: "app"}
+	// the order of the audiences doesn't change the token.
+	if mintedTokenKey("default", "vault-sa", spec([]string{"a", "b"}, 600, nil)) != mintedTokenKey("default", "vault-sa", spec([]string{"b", "a"}, 600, nil)) {
+		t.Errorf("expected the same key regardless of the order of the audiences")
+	}
+	for name, key := range map[string]string{
+		"Namespace":      mintedTokenKey("other", "vault-sa", spec([]string{"a"}, 600, nil)),
+		"Name":           mintedTokenKey("default", "other-sa", spec([]string{"a"}, 600, nil)),
+		"Audiences":      mintedTokenKey("default", "vault-sa", spec([]string{"b"}, 600, nil)),
+		"Expiration":     mintedTokenKey("default", "vault-sa", spec([]string{"a"}, 3600, nil)),
+		"BoundObjectRef": mintedTokenKey("default", "vault-sa", spec([]string{"a"}, 600, pod)),
+	} {
+		if key == mintedTokenKey("default", "vault-sa", spec([]string{"a"}, 600, nil)) {
+			t.Errorf("expected a different key for another %s", name)
+		}
+	}
+}
+
+func TestServiceAccountTokenReuse(t *testing.T) {
+	defer func(reuse bool) { reuseMintedTokens = reuse }(reuseMintedTokens)
+	defer initMintedTokens(defaultMintedTokensSize)
+
+	cases := map[string]struct {
+		reuse bool
+		ttl   time.Duration
+		// age is how long ago the first token was issued.
+		age time.Duration
+		// size bounds the number of cached tokens.
+		size int
+		// otherAccount requests a token for another service account in
+		// between.
+		otherAccount bool
+		wantRequests int
+	}{
+		"Disabled": {
+			ttl:          10 * time.Minute,
+			wantRequests: 2,
+		},
+		"ValidToken": {
+			reuse:        true,
+			ttl:          10 * time.Minute,
+			wantRequests: 1,
+		},
+		// tokens are requested again well before they expire.
+		"TokenPastReuseMargin": {
+			reuse:        true,
+			ttl:          10 * time.Minute,
+			age:          9 * time.Minute,
+			wantRequests: 2,
+		},
+		"ExpiringToken": {
+			reuse:        true,
+			ttl:          30 * time.Second,
+			wantRequests: 2,
+		},
+		"OtherAccount": {
+			reuse:        true,
+			ttl:          10 * time.Minute,
+			otherAccount: true,
+			wantRequests: 2,
+		},
+		"EvictedToken": {
+			reuse:        true,
+			ttl:          10 * time.Minute,
+			size:         1,
+			otherAccount: true,
+			wantRequests: 3,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reuseMintedTokens = tc.reuse
+			size := defaultMintedTokensSize
+			if tc.size > 0 {
+				size = tc.size
+			}
+			initMintedTokens(size)
+			corev1Client := &outageTokenRequests{ttl: tc.ttl - tc.age}
+			ref := esmeta.ServiceAccountSelector{Name: "vault-sa", Audiences: []string{"vault"}}
+			request := func(ref esmeta.ServiceAccountSelector) {
+				t.Helper()
+				jwt, err := createServiceAccountToken(context.Background(), corev1Client, esv1.SecretStoreKind, "default", ref, nil)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if jwt != "minted-jwt" {
+					t.Errorf("expected jwt %q, got %q", "minted-jwt", jwt)
+				}
+			}
+
+			request(ref)
+			if tc.age > 0 {
+				key := mintedTokenKey("default", ref.Name, tokenRequestSpec(ref, nil))
+				mintedTokens.Add("", cache.Key{Name: key, Kind: mintedTokenKind}, mintedToken{
+					token:  "minted-jwt",
+					issued: time.Now().Add(-tc.age),
+					expiry: time.Now().Add(tc.ttl - tc.age),
+				})
+			}
+			if tc.otherAccount {
+				request(esmeta.ServiceAccountSelector{Name: "other-sa", Audiences: []string{"vault"}})
+			}
+			request(ref)
+
+			if corev1Client.requests != tc.wantRequests {
+				t.Errorf("expected %d token requests, got %d", tc.wantRequests, corev1Client.requests)
+			}
+		})
+	}
+}
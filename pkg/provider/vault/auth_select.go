@@ -0,0 +1,135 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"errors"
+	"os"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+const (
+	errAuthSelectionNoMatch = "no auth selection rule matches the controller environment"
+)
+
+// selectionMethods maps the method names used in selection rules to the
+// auth methods tried by setAuth.
+var selectionMethods = map[esv1.VaultAuthMethodName]string{
+	esv1.VaultAuthMethodTokenSecretRef: authMethodToken,
+	esv1.VaultAuthMethodTokenFile:      authMethodTokenFile,
+	esv1.VaultAuthMethodAppRole:        authMethodAppRole,
+	esv1.VaultAuthMethodKubernetes:     authMethodKubernetes,
+	esv1.VaultAuthMethodLdap:           authMethodLdap,
+	esv1.VaultAuthMethodUserPass:       authMethodUserPass,
+	esv1.VaultAuthMethodJwt:            authMethodJwt,
+	esv1.VaultAuthMethodCert:           authMethodCert,
+	esv1.VaultAuthMethodIam:            authMethodIam,
+	esv1.VaultAuthMethodGcp:            authMethodGcp,
+	esv1.VaultAuthMethodAzure:          authMethodAzure,
+	esv1.VaultAuthMethodPlugin:         authMethodPlugin,
+}
+
+// selectAuthMethods narrows down the auth methods to the one chosen by the
+// first selection rule matching the controller environment. Without
+// selection rules all methods are returned.
+func (c *client) selectAuthMethods(methods []authMethod) ([]authMethod, error) {
+	if len(c.store.Auth.Selection) == 0 {
+		return methods, nil
+	}
+	for i, rule := range c.store.Auth.Selection {
+		if !matchesEnvironment(rule) {
+			continue
+		}
+		c.log.V(1).Info("selected auth method", "method", rule.Method, "rule", i)
+		name := selectionMethods[rule.Method]
+		for _, method := range methods {
+			if method.name == name {
+				return []authMethod{method}, nil
+			}
+		}
+		return nil, errors.New(errAuthFormat)
+	}
+	return nil, errors.New(errAuthSelectionNoMatch)
+}
+
+// prioritizeAuthMethods orders the auth methods as listed in the auth method
+// priority, leaving out those that aren't listed. Without a priority the
+// methods are returned in the order setAuth tries them by default.
+func (c *client) prioritizeAuthMethods(methods []authMethod) []authMethod {
+	priority := c.store.Auth.AuthMethodPriority
+	if len(priority) == 0 {
+		return methods
+	}
+	prioritized := make([]authMethod, 0, len(priority))
+	for _, name := range priority {
+		for _, method := range methods {
+			if method.name == selectionMethods[name] {
+				prioritized = append(prioritized, method)
+			}
+		}
+	}
+	return prioritized
+}
+
+// matchesEnvironment reports whether all conditions of the rule hold.
+func matchesEnvironment(rule esv1.VaultAuthSelectionRule) bool {
+	if rule.EnvVar != "" {
+		value := os.Getenv(rule.EnvVar)
+		if value == "" {
+			return false
+		}
+		if rule.EnvValue != nil && value != *rule.EnvValue {
+			return false
+		}
+	}
+	if rule.FileExists != "" {
+		if _, err := os.Stat(rule.FileExists); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// isAuthMethodConfigured reports whether the named method is configured.
+func isAuthMethodConfigured(auth *esv1.VaultAuth, method esv1.VaultAuthMethodName) bool {
+	switch method {
+	case esv1.VaultAuthMethodTokenSecretRef:
+		return auth.TokenSecretRef != nil
+	case esv1.VaultAuthMethodTokenFile:
+		return auth.TokenFilePath != ""
+	case esv1.VaultAuthMethodAppRole:
+		return auth.AppRole != nil
+	case esv1.VaultAuthMethodKubernetes:
+		return auth.Kubernetes != nil
+	case esv1.VaultAuthMethodLdap:
+		return auth.Ldap != nil
+	case esv1.VaultAuthMethodUserPass:
+		return auth.UserPass != nil
+	case esv1.VaultAuthMethodJwt:
+		return auth.Jwt != nil
+	case esv1.VaultAuthMethodCert:
+		return auth.Cert != nil
+	case esv1.VaultAuthMethodIam:
+		return auth.Iam != nil
+	case esv1.VaultAuthMethodGcp:
+		return auth.Gcp != nil
+	case esv1.VaultAuthMethodAzure:
+		return auth.Azure != nil
+	case esv1.VaultAuthMethodPlugin:
+		return auth.Plugin != nil
+	}
+	return false
+}
@@ -0,0 +1,329 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/api/auth/approle"
+	"k8s.io/utils/ptr"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+const (
+	cloudEnvVar = "ESO_TEST_CLOUD_PROVIDER"
+)
+
+func TestSelectAuthMethods(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	hybridSelection := []esv1.VaultAuthSelectionRule{
+		{Method: esv1.VaultAuthMethodIam, EnvVar: cloudEnvVar, EnvValue: ptr.To("aws")},
+		{Method: esv1.VaultAuthMethodJwt, EnvVar: cloudEnvVar},
+		{Method: esv1.VaultAuthMethodKubernetes},
+	}
+
+	cases := map[string]struct {
+		selection []esv1.VaultAuthSelectionRule
+		env       map[string]string
+		want      string
+		wantErr   error
+	}{
+		"OnPrem": {
+			selection: hybridSelection,
+			want:      authMethodKubernetes,
+		},
+		"CloudAWS": {
+			selection: hybridSelection,
+			env:       map[string]string{cloudEnvVar: "aws"},
+			want:      authMethodIam,
+		},
+		"OtherCloud": {
+			selection: hybridSelection,
+			env:       map[string]string{cloudEnvVar: "gcp"},
+			want:      authMethodJwt,
+		},
+		"FileExists": {
+			selection: []esv1.VaultAuthSelectionRule{
+				{Method: esv1.VaultAuthMethodIam, FileExists: tokenFile},
+				{Method: esv1.VaultAuthMethodKubernetes},
+			},
+			want: authMethodIam,
+		},
+		"FileMissing": {
+			selection: []esv1.VaultAuthSelectionRule{
+				{Method: esv1.VaultAuthMethodIam, FileExists: tokenFile + ".missing"},
+				{Method: esv1.VaultAuthMethodKubernetes},
+			},
+			want: authMethodKubernetes,
+		},
+		"NoMatch": {
+			selection: []esv1.VaultAuthSelectionRule{
+				{Method: esv1.VaultAuthMethodIam, EnvVar: cloudEnvVar},
+			},
+			wantErr: errors.New(errAuthSelectionNoMatch),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(cloudEnvVar, "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			c := &client{
+				log: logger,
+				store: &esv1.VaultProvider{
+					Auth: &esv1.VaultAuth{Selection: tc.selection},
+				},
+			}
+
+			methods, err := c.selectAuthMethods(c.authMethods(nil))
+			if tc.wantErr != nil {
+				if err == nil || err.Error() != tc.wantErr.Error() {
+					t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(methods) != 1 || methods[0].name != tc.want {
+				t.Errorf("expected method %q, got %+v", tc.want, methods)
+			}
+		})
+	}
+}
+
+func TestSelectAuthMethodsWithoutSelection(t *testing.T) {
+	c := &client{
+		log:   logger,
+		store: &esv1.VaultProvider{Auth: &esv1.VaultAuth{}},
+	}
+	all := c.authMethods(nil)
+	methods, err := c.selectAuthMethods(all)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(methods) != len(all) {
+		t.Errorf("expected all %d methods, got %d", len(all), len(methods))
+	}
+}
+
+func TestSetAuthOnPremSelectsKubernetes(t *testing.T) {
+	t.Setenv(cloudEnvVar, "")
+	logins := 0
+	kubernetesAuth := &esv1.VaultKubernetesAuth{
+		Path: "kubernetes",
+		Role: "kubernetes-auth-role",
+	}
+	c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), kubernetesAuth, &logins)
+	// IAM auth is configured as well, but only selected in the cloud.
+	c.store.Auth.Iam = &esv1.VaultIamAuth{}
+	c.store.Auth.Selection = []esv1.VaultAuthSelectionRule{
+		{Method: esv1.VaultAuthMethodIam, EnvVar: cloudEnvVar},
+		{Method: esv1.VaultAuthMethodKubernetes},
+	}
+	c.client = &util.VaultClient{
+		TokenFunc:        func() string { return "" },
+		NamespaceFunc:    func() string { return "" },
+		SetNamespaceFunc: func(string) {},
+	}
+
+	if err := c.setAuth(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if logins != 1 {
+		t.Errorf("expected 1 Kubernetes login, got %d", logins)
+	}
+}
+
+func TestSetAuthReportsAuthMethod(t *testing.T) {
+	cases := map[string]struct {
+		cloud      string
+		token      string
+		want       string
+		wantLogins int
+	}{
+		"SelectedToken": {
+			cloud: "aws",
+			want:  authMethodToken,
+		},
+		// the token rule doesn't match outside the cloud, so the
+		// Kubernetes rule takes over.
+		"FallbackToKubernetes": {
+			want:       authMethodKubernetes,
+			wantLogins: 1,
+		},
+		"ReusedToken": {
+			token: "existing-token",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(cloudEnvVar, tc.cloud)
+			logins := 0
+			kubernetesAuth := &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), kubernetesAuth, &logins)
+			c.store.Auth.TokenSecretRef = &esmeta.SecretKeySelector{
+				Name: "vault-sa-token",
+				Key:  "token",
+			}
+			c.store.Auth.Selection = []esv1.VaultAuthSelectionRule{
+				{Method: esv1.VaultAuthMethodTokenSecretRef, EnvVar: cloudEnvVar},
+				{Method: esv1.VaultAuthMethodKubernetes},
+			}
+			token := tc.token
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+			}
+			c.token = fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					return makeTokenLookup(time.Hour, false), nil
+				},
+			}
+
+			if err := c.setAuth(context.Background(), nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := c.AuthMethod(); got != tc.want {
+				t.Errorf("expected auth method %q, got %q", tc.want, got)
+			}
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d Kubernetes logins, got %d", tc.wantLogins, logins)
+			}
+		})
+	}
+}
+
+func TestSetAuthMethodPriority(t *testing.T) {
+	cases := map[string]struct {
+		priority []esv1.VaultAuthMethodName
+		// failing are the auth methods whose login fails.
+		failing    []string
+		wantLogins []string
+		wantMethod string
+		wantErr    []string
+	}{
+		"PrimarySucceeds": {
+			priority:   []esv1.VaultAuthMethodName{esv1.VaultAuthMethodKubernetes, esv1.VaultAuthMethodAppRole},
+			wantLogins: []string{authMethodKubernetes},
+			wantMethod: authMethodKubernetes,
+		},
+		"FallbackOnFailure": {
+			priority:   []esv1.VaultAuthMethodName{esv1.VaultAuthMethodKubernetes, esv1.VaultAuthMethodAppRole},
+			failing:    []string{authMethodKubernetes},
+			wantLogins: []string{authMethodKubernetes, authMethodAppRole},
+			wantMethod: authMethodAppRole,
+		},
+		"AllFail": {
+			priority:   []esv1.VaultAuthMethodName{esv1.VaultAuthMethodKubernetes, esv1.VaultAuthMethodAppRole},
+			failing:    []string{authMethodKubernetes, authMethodAppRole},
+			wantLogins: []string{authMethodKubernetes, authMethodAppRole},
+			wantErr:    []string{"kubernetes auth matched but login failed", "approle auth matched but login failed"},
+		},
+		// methods that aren't listed aren't tried.
+		"Restricted": {
+			priority:   []esv1.VaultAuthMethodName{esv1.VaultAuthMethodKubernetes},
+			failing:    []string{authMethodKubernetes},
+			wantLogins: []string{authMethodKubernetes},
+			wantErr:    []string{"kubernetes auth matched but login failed"},
+		},
+		// without a priority, the first configured method is used without
+		// falling back.
+		"NoPriority": {
+			failing:    []string{authMethodAppRole},
+			wantLogins: []string{authMethodAppRole},
+			wantErr:    []string{"approle auth matched but login failed"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var logins []string
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, new(int))
+			c.store.Auth.AppRole = &esv1.VaultAppRole{
+				Path:      "approle",
+				RoleID:    "approle-role",
+				SecretRef: esmeta.SecretKeySelector{Name: "vault-sa-token", Key: "token"},
+			}
+			c.store.Auth.AuthMethodPriority = tc.priority
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					method := authMethodKubernetes
+					if _, ok := authMethod.(*approle.AppRoleAuth); ok {
+						method = authMethodAppRole
+					}
+					logins = append(logins, method)
+					for _, failing := range tc.failing {
+						if failing == method {
+							return nil, errors.New("permission denied")
+						}
+					}
+					return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: method + "-token"}}, nil
+				},
+			}
+			token := ""
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+			}
+
+			err := c.setAuth(context.Background(), nil)
+			if len(tc.wantErr) == 0 && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error containing %q, got %v", want, err)
+				}
+			}
+			if diff := cmp.Diff(tc.wantLogins, logins); diff != "" {
+				t.Errorf("unexpected logins (-want, +got):\n%s", diff)
+			}
+			if c.AuthMethod() != tc.wantMethod {
+				t.Errorf("expected auth method %q, got %q", tc.wantMethod, c.AuthMethod())
+			}
+		})
+	}
+}
@@ -18,7 +18,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -27,10 +36,14 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
 	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
 	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
 )
 
 // Test Vault Namespace logic.
@@ -90,7 +103,7 @@ func TestSetAuthNamespace(t *testing.T) {
 			},
 		},
 		"StoreWithAuthNamespace": {
-			reason: "switch to the auth namespace during login then revert",
+			reason: "use the auth namespace during login only",
 			args: args{
 				store: func(store *esv1.SecretStore) *esv1.SecretStore {
 					s := store.DeepCopy()
@@ -113,7 +126,7 @@ func TestSetAuthNamespace(t *testing.T) {
 			},
 		},
 		"StoreWithDistinctNamespace": {
-			reason: "switch from team namespace, to admin, then back",
+			reason: "use the admin namespace during login only, the team namespace otherwise",
 			args: args{
 				store: func(store *esv1.SecretStore) *esv1.SecretStore {
 					s := store.DeepCopy()
@@ -124,6 +137,29 @@ func TestSetAuthNamespace(t *testing.T) {
 				expected: result{Before: teamNS, During: adminNS, After: teamNS},
 			},
 		},
+		"StoreWithRootAuthNamespace": {
+			reason: "use the root namespace during login only, the team namespace otherwise",
+			args: args{
+				store: func(store *esv1.SecretStore) *esv1.SecretStore {
+					s := store.DeepCopy()
+					s.Spec.Provider.Vault.Namespace = ptr.To(teamNS)
+					s.Spec.Provider.Vault.Auth.RootNamespace = true
+					return s
+				}(store),
+				expected: result{Before: teamNS, During: "", After: teamNS},
+			},
+		},
+		"StoreWithoutNamespaceWithRootAuthNamespace": {
+			reason: "no namespace should ever be set",
+			args: args{
+				store: func(store *esv1.SecretStore) *esv1.SecretStore {
+					s := store.DeepCopy()
+					s.Spec.Provider.Vault.Auth.RootNamespace = true
+					return s
+				}(store),
+				expected: result{Before: "", During: "", After: ""},
+			},
+		},
 	}
 
 	for name, tc := range cases {
@@ -139,12 +175,12 @@ func TestSetAuthNamespace(t *testing.T) {
 
 			client, err := getVaultClient(prov, tc.args.store, cfg, "default")
 			if err != nil {
-				t.Errorf("vault.useAuthNamespace: failed to create client: %s", err.Error())
+				t.Errorf("vault.withAuthNamespace: failed to create client: %s", err.Error())
 			}
 
 			_, err = prov.initClient(context.Background(), c, client, cfg, tc.args.store.Spec.Provider.Vault)
 			if err != nil {
-				t.Errorf("vault.useAuthNamespace: failed to init client: %s", err.Error())
+				t.Errorf("vault.withAuthNamespace: failed to init client: %s", err.Error())
 			}
 
 			c.client = client
@@ -155,20 +191,418 @@ func TestSetAuthNamespace(t *testing.T) {
 			}
 
 			// during authentication (getting a token)
-			resetNS := c.useAuthNamespace(context.Background())
-			actual.During = c.client.Namespace()
-			resetNS()
+			actual.During = c.withAuthNamespace().client.Namespace()
 
 			// after getting the token
 			actual.After = c.client.Namespace()
 
 			if diff := cmp.Diff(tc.args.expected, actual, cmpopts.EquateComparable()); diff != "" {
-				t.Errorf("\n%s\nvault.useAuthNamepsace(...): -want namespace, +got namespace:\n%s", tc.reason, diff)
+				t.Errorf("\n%s\nvault.withAuthNamespace(...): -want namespace, +got namespace:\n%s", tc.reason, diff)
 			}
 		})
 	}
 }
 
+// Logins in the auth namespace must not leak into concurrent operations
+// of the same Vault client, and vice versa.
+func TestAuthNamespaceConcurrency(t *testing.T) {
+	adminNS := "admin"
+	teamNS := "admin/team-a"
+
+	var crossTalk atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ns := r.Header.Get("X-Vault-Namespace")
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			if ns != adminNS {
+				crossTalk.Add(1)
+			}
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token", "lease_duration": 3600}}`))
+		case "/v1/secret/data/foo":
+			if ns != teamNS {
+				crossTalk.Add(1)
+			}
+			_, _ = w.Write([]byte(`{"data": {"data": {"foo": "bar"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := vault.DefaultConfig()
+	cfg.Address = server.URL
+	vaultClient, err := NewVaultClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "approle-secret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"secret-id": []byte("secret-id"),
+		},
+	}).Build()
+	c := &client{
+		kube:      kube,
+		log:       logger,
+		namespace: "default",
+		storeKind: esv1.SecretStoreKind,
+		store: &esv1.VaultProvider{
+			Namespace: ptr.To(teamNS),
+			Auth: &esv1.VaultAuth{
+				Namespace: ptr.To(adminNS),
+				AppRole: &esv1.VaultAppRole{
+					Path:   "approle",
+					RoleID: "role-id",
+					SecretRef: esmeta.SecretKeySelector{
+						Name: "approle-secret",
+						Key:  "secret-id",
+					},
+				},
+			},
+		},
+		client:  vaultClient,
+		auth:    vaultClient.Auth(),
+		logical: vaultClient.Logical(),
+		token:   vaultClient.AuthToken(),
+	}
+	c.client.SetNamespace(teamNS)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				// a fresh login for every attempt.
+				if _, err := c.withAuthNamespace().authenticate(context.Background(), cfg); err != nil {
+					t.Errorf("unexpected login error: %v", err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				if _, err := c.logical.ReadWithDataWithContext(context.Background(), "secret/data/foo", nil); err != nil {
+					t.Errorf("unexpected read error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := crossTalk.Load(); n != 0 {
+		t.Errorf("expected no requests in the wrong namespace, got %d", n)
+	}
+	if ns := c.client.Namespace(); ns != teamNS {
+		t.Errorf("expected client namespace %q, got %q", teamNS, ns)
+	}
+}
+
+// A login in the auth namespace that fails or panics leaves the namespace
+// of the shared Vault client as it was, as the login runs on a copy.
+func TestAuthNamespaceLoginFailure(t *testing.T) {
+	cases := map[string]struct {
+		login   func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error)
+		wantErr string
+	}{
+		"Error": {
+			login: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+				return nil, errors.New("permission denied")
+			},
+			wantErr: "permission denied",
+		},
+		"Panic": {
+			login: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+				panic("login panicked")
+			},
+			wantErr: "login panicked",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "approle-secret", Namespace: "default"},
+				Data:       map[string][]byte{"secret-id": []byte("secret-id")},
+			}).Build()
+			auth := makeAppRoleAuth("role-id")
+			auth.Namespace = ptr.To("admin")
+			namespace := ""
+			var switched []string
+			c := &client{
+				kube:      kube,
+				log:       logger,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store: &esv1.VaultProvider{
+					Namespace: ptr.To("admin/team-a"),
+					Auth:      &auth,
+				},
+			}
+			c.client = &util.VaultClient{
+				TokenFunc:      func() string { return "" },
+				SetTokenFunc:   func(string) {},
+				ClearTokenFunc: func() {},
+				NamespaceFunc:  func() string { return namespace },
+				SetNamespaceFunc: func(ns string) {
+					namespace = ns
+					switched = append(switched, ns)
+				},
+				WithNamespaceFunc: func(ns string) util.Client {
+					return &util.VaultClient{
+						TokenFunc:     func() string { return "" },
+						SetTokenFunc:  func(string) {},
+						NamespaceFunc: func() string { return ns },
+						AuthField:     fake.Auth{LoginFn: tc.login},
+					}
+				},
+			}
+
+			err := func() (err error) {
+				defer func() {
+					if r := recover(); r != nil {
+						err = fmt.Errorf("%v", r)
+					}
+				}()
+				return c.setAuth(context.Background(), nil)
+			}()
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff([]string{"admin/team-a"}, switched); diff != "" {
+				t.Errorf("unexpected namespaces set on the shared client (-want, +got):\n%s", diff)
+			}
+			if namespace != "admin/team-a" {
+				t.Errorf("expected client namespace %q, got %q", "admin/team-a", namespace)
+			}
+		})
+	}
+}
+
+func TestDiscoverNamespace(t *testing.T) {
+	cases := map[string]struct {
+		discover bool
+		metadata map[string]string
+		// relogin authenticates a second time, re-using the token.
+		relogin       bool
+		wantNamespace string
+		wantScoped    []string
+	}{
+		"Disabled": {
+			metadata:      map[string]string{"namespace_path": "admin/team-a/"},
+			wantNamespace: "admin",
+		},
+		"Discovered": {
+			discover:      true,
+			metadata:      map[string]string{"namespace_path": "admin/team-a/"},
+			wantNamespace: "admin/team-a",
+			wantScoped:    []string{"admin/team-a"},
+		},
+		"SameNamespace": {
+			discover:      true,
+			metadata:      map[string]string{"namespace_path": "admin/"},
+			wantNamespace: "admin",
+		},
+		"NoNamespacePath": {
+			discover:      true,
+			metadata:      map[string]string{"role": "eso"},
+			wantNamespace: "admin",
+		},
+		// the token is still used in the discovered namespace once the
+		// store namespace was set again, and looked up there.
+		"TokenReused": {
+			discover:      true,
+			metadata:      map[string]string{"namespace_path": "admin/team-a/"},
+			relogin:       true,
+			wantNamespace: "admin/team-a",
+			wantScoped:    []string{"admin/team-a", "admin/team-a", "admin/team-a"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "approle-secret", Namespace: "default"},
+				Data:       map[string][]byte{"secret-id": []byte("secret-id")},
+			}).Build()
+			auth := makeAppRoleAuth("role-id")
+			auth.DiscoverNamespace = tc.discover
+			logins := 0
+			token := ""
+			var scoped, lookups []string
+			var newClient func(namespace string) *util.VaultClient
+			newClient = func(namespace string) *util.VaultClient {
+				return &util.VaultClient{
+					TokenFunc:        func() string { return token },
+					SetTokenFunc:     func(v string) { token = v },
+					ClearTokenFunc:   func() { token = "" },
+					NamespaceFunc:    func() string { return namespace },
+					SetNamespaceFunc: func(ns string) { namespace = ns },
+					WithNamespaceFunc: func(ns string) util.Client {
+						scoped = append(scoped, ns)
+						return newClient(ns)
+					},
+					AuthField: fake.Auth{
+						LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+							logins++
+							token = "approle-token"
+							return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: token, Metadata: tc.metadata}}, nil
+						},
+					},
+					AuthTokenField: fake.Token{
+						LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+							lookups = append(lookups, namespace)
+							return makeTokenLookup(time.Hour, true), nil
+						},
+					},
+				}
+			}
+			vaultClient := newClient("")
+			c := &client{
+				kube:      kube,
+				log:       logger,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store: &esv1.VaultProvider{
+					Namespace: ptr.To("admin"),
+					Auth:      &auth,
+				},
+				client: vaultClient,
+				auth:   vaultClient.Auth(),
+				token:  vaultClient.AuthToken(),
+			}
+
+			if err := c.setAuth(context.Background(), nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.relogin {
+				if err := c.setAuth(context.Background(), nil); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				// the token is looked up in the namespace it was issued in.
+				if diff := cmp.Diff([]string{tc.wantNamespace}, lookups); diff != "" {
+					t.Errorf("unexpected namespaces of the token lookups (-want, +got):\n%s", diff)
+				}
+			}
+			if logins != 1 {
+				t.Errorf("expected 1 login, got %d", logins)
+			}
+			if got := c.client.Namespace(); got != tc.wantNamespace {
+				t.Errorf("expected client namespace %q, got %q", tc.wantNamespace, got)
+			}
+			if diff := cmp.Diff(tc.wantScoped, scoped); diff != "" {
+				t.Errorf("unexpected clients scoped to a namespace (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// Requests about the token must run in the namespace it was issued in,
+// even if the store reads secrets in another namespace.
+func TestTokenRequestsUseIssuingNamespace(t *testing.T) {
+	adminNS := "admin"
+	teamNS := "admin/team-a"
+
+	var mu sync.Mutex
+	namespaces := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		namespaces[r.URL.Path] = append(namespaces[r.URL.Path], r.Header.Get("X-Vault-Namespace"))
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token", "lease_duration": 3600}}`))
+		case "/v1/auth/token/lookup-self":
+			_, _ = w.Write([]byte(`{"data": {"type": "service", "ttl": 3600, "expire_time": "2100-01-01T00:00:00Z"}}`))
+		case "/v1/auth/token/revoke-self":
+			w.WriteHeader(http.StatusNoContent)
+		case "/v1/sys/health":
+			_, _ = w.Write([]byte(`{"initialized": true, "sealed": false}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := vault.DefaultConfig()
+	cfg.Address = server.URL
+	vaultClient, err := NewVaultClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "approle-secret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"secret-id": []byte("secret-id"),
+		},
+	}).Build()
+	c := &client{
+		kube:      kube,
+		log:       logger,
+		namespace: "default",
+		storeKind: esv1.SecretStoreKind,
+		store: &esv1.VaultProvider{
+			Namespace: ptr.To(teamNS),
+			Auth: &esv1.VaultAuth{
+				Namespace: ptr.To(adminNS),
+				AppRole: &esv1.VaultAppRole{
+					Path:   "approle",
+					RoleID: "role-id",
+					SecretRef: esmeta.SecretKeySelector{
+						Name: "approle-secret",
+						Key:  "secret-id",
+					},
+				},
+			},
+		},
+		client:  vaultClient,
+		auth:    vaultClient.Auth(),
+		logical: vaultClient.Logical(),
+		token:   vaultClient.AuthToken(),
+	}
+
+	ctx := context.Background()
+	if err := c.setAuth(ctx, cfg); err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+	// the token is re-used after being looked up.
+	if err := c.setAuth(ctx, cfg); err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+	if _, err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	if err := c.ExpectActivity(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Close(ctx); err != nil {
+		t.Fatalf("unexpected error on close: %v", err)
+	}
+
+	want := map[string][]string{
+		"/v1/auth/approle/login":     {adminNS},
+		"/v1/auth/token/lookup-self": {adminNS, adminNS, adminNS, adminNS},
+		"/v1/auth/token/revoke-self": {adminNS},
+		// the server is pinged by the validation, without a namespace.
+		"/v1/sys/health": {""},
+	}
+	if diff := cmp.Diff(want, namespaces); diff != "" {
+		t.Errorf("unexpected namespaces of requests (-want, +got):\n%s", diff)
+	}
+	if ns := c.client.Namespace(); ns != teamNS {
+		t.Errorf("expected client namespace %q, got %q", teamNS, ns)
+	}
+	if token := c.client.Token(); token != "" {
+		t.Errorf("expected the revoked token to be cleared, got %q", token)
+	}
+}
+
 func TestCheckTokenErrors(t *testing.T) {
 	cases := map[string]struct {
 		message string
@@ -202,8 +636,8 @@ func TestCheckTokenErrors(t *testing.T) {
 				},
 			}
 
-			cached, _ := checkToken(context.Background(), token)
-			if cached {
+			state, _ := checkToken(context.Background(), token, tokenExpiryThreshold)
+			if state == tokenValid {
 				t.Errorf("%v", tc.message)
 			}
 		})
@@ -214,13 +648,15 @@ func TestCheckTokenTtl(t *testing.T) {
 	cases := map[string]struct {
 		message string
 		secret  *vault.Secret
-		cache   bool
+		// leeway is the TokenExpirationLeewaySeconds of the store.
+		leeway int
+		cache  bool
 	}{
 		"LongTTLExpirable": {
 			message: "should cache if expirable token expires far into the future",
 			secret: &vault.Secret{
 				Data: map[string]interface{}{
-					"expire_time": "2024-01-01T00:00:00.000000000Z",
+					"expire_time": expireIn(time.Hour),
 					"ttl":         json.Number("3600"),
 					"type":        "service",
 				},
@@ -231,7 +667,7 @@ func TestCheckTokenTtl(t *testing.T) {
 			message: "should not cache if expirable token is about to expire",
 			secret: &vault.Secret{
 				Data: map[string]interface{}{
-					"expire_time": "2024-01-01T00:00:00.000000000Z",
+					"expire_time": expireIn(5 * time.Second),
 					"ttl":         json.Number("5"),
 					"type":        "service",
 				},
@@ -242,13 +678,46 @@ func TestCheckTokenTtl(t *testing.T) {
 			message: "should not cache if expirable token has TTL of 0",
 			secret: &vault.Secret{
 				Data: map[string]interface{}{
-					"expire_time": "2024-01-01T00:00:00.000000000Z",
+					"expire_time": expireIn(0),
 					"ttl":         json.Number("0"),
 					"type":        "service",
 				},
 			},
 			cache: false,
 		},
+		"ExpireTimeSoonerThanTTL": {
+			message: "should not cache if expire_time is about to pass even though ttl is long",
+			secret: &vault.Secret{
+				Data: map[string]interface{}{
+					"expire_time": expireIn(10 * time.Second),
+					"ttl":         json.Number("3600"),
+					"type":        "service",
+				},
+			},
+			cache: false,
+		},
+		"TTLSoonerThanExpireTime": {
+			message: "should not cache if ttl is about to run out even though expire_time is far away",
+			secret: &vault.Secret{
+				Data: map[string]interface{}{
+					"expire_time": expireIn(time.Hour),
+					"ttl":         json.Number("10"),
+					"type":        "service",
+				},
+			},
+			cache: false,
+		},
+		"DisagreementWithinTolerance": {
+			message: "should cache if ttl and expire_time only differ by less than the tolerance",
+			secret: &vault.Secret{
+				Data: map[string]interface{}{
+					"expire_time": expireIn(time.Hour - 5*time.Second),
+					"ttl":         json.Number("3600"),
+					"type":        "service",
+				},
+			},
+			cache: true,
+		},
 		"NonExpirable": {
 			message: "should cache if token is non-expirable",
 			secret: &vault.Secret{
@@ -260,6 +729,41 @@ func TestCheckTokenTtl(t *testing.T) {
 			},
 			cache: true,
 		},
+		"TTLOutsideDefaultThreshold": {
+			message: "should cache if expirable token expires after the default threshold",
+			secret: &vault.Secret{
+				Data: map[string]interface{}{
+					"expire_time": expireIn(90 * time.Second),
+					"ttl":         json.Number("90"),
+					"type":        "service",
+				},
+			},
+			cache: true,
+		},
+		"TTLWithinLeeway": {
+			message: "should not cache if expirable token expires within the leeway of the store",
+			secret: &vault.Secret{
+				Data: map[string]interface{}{
+					"expire_time": expireIn(90 * time.Second),
+					"ttl":         json.Number("90"),
+					"type":        "service",
+				},
+			},
+			leeway: 120,
+			cache:  false,
+		},
+		"TTLOutsideLeeway": {
+			message: "should cache if expirable token expires after a leeway shorter than the default",
+			secret: &vault.Secret{
+				Data: map[string]interface{}{
+					"expire_time": expireIn(30 * time.Second),
+					"ttl":         json.Number("30"),
+					"type":        "service",
+				},
+			},
+			leeway: 10,
+			cache:  true,
+		},
 	}
 
 	for name, tc := range cases {
@@ -269,11 +773,693 @@ func TestCheckTokenTtl(t *testing.T) {
 					return tc.secret, nil
 				},
 			}
+			c := &client{store: &esv1.VaultProvider{Auth: &esv1.VaultAuth{TokenExpirationLeewaySeconds: tc.leeway}}}
 
-			cached, err := checkToken(context.Background(), token)
-			if cached != tc.cache || err != nil {
+			state, err := checkToken(context.Background(), token, c.expiryThreshold())
+			if (state == tokenValid) != tc.cache || err != nil {
 				t.Errorf("%v: err = %v", tc.message, err)
 			}
 		})
 	}
 }
+
+func TestCheckTokenTTLRepresentations(t *testing.T) {
+	representations := map[string]func(seconds int64) any{
+		"JSONNumber":     func(seconds int64) any { return json.Number(strconv.FormatInt(seconds, 10)) },
+		"Float":          func(seconds int64) any { return float64(seconds) },
+		"SecondsString":  func(seconds int64) any { return strconv.FormatInt(seconds, 10) },
+		"DurationString": func(seconds int64) any { return (time.Duration(seconds) * time.Second).String() },
+	}
+
+	cases := map[string]struct {
+		ttl        int64
+		expireTime any
+		cache      bool
+	}{
+		"LongTTLExpirable": {
+			ttl:        900,
+			expireTime: expireIn(15 * time.Minute),
+			cache:      true,
+		},
+		"ShortTTLExpirable": {
+			ttl:        5,
+			expireTime: expireIn(5 * time.Second),
+		},
+		"NonExpirable": {
+			cache: true,
+		},
+	}
+
+	for name, tc := range cases {
+		for repr, encode := range representations {
+			t.Run(name+"/"+repr, func(t *testing.T) {
+				token := fake.Token{
+					LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+						return &vault.Secret{
+							Data: map[string]any{
+								"expire_time": tc.expireTime,
+								"ttl":         encode(tc.ttl),
+								"type":        "service",
+							},
+						}, nil
+					},
+				}
+
+				state, err := checkToken(context.Background(), token, tokenExpiryThreshold)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if cached := state == tokenValid; cached != tc.cache {
+					t.Errorf("expected cache %t for ttl %v, got %t", tc.cache, encode(tc.ttl), cached)
+				}
+			})
+		}
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	cases := map[string]struct {
+		ttl     any
+		want    int64
+		wantErr bool
+	}{
+		"JSONNumber":     {ttl: json.Number("900"), want: 900},
+		"SecondsString":  {ttl: "900", want: 900},
+		"DurationString": {ttl: "15m", want: 900},
+		"CompoundString": {ttl: "1h0m30s", want: 3630},
+		"Float":          {ttl: float64(900), want: 900},
+		"InvalidString":  {ttl: "soon", wantErr: true},
+		"InvalidNumber":  {ttl: json.Number("9.5e"), wantErr: true},
+		"UnexpectedType": {ttl: true, wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseTTL(tc.ttl)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("parseTTL(%v) = %d, want %d", tc.ttl, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRenewable(t *testing.T) {
+	cases := map[string]struct {
+		renewable any
+		want      bool
+		wantErr   bool
+	}{
+		"Missing":        {renewable: nil, want: false},
+		"True":           {renewable: true, want: true},
+		"False":          {renewable: false, want: false},
+		"TrueString":     {renewable: "true", want: true},
+		"FalseString":    {renewable: "false", want: false},
+		"PaddedString":   {renewable: " True ", want: true},
+		"JSONNumber":     {renewable: json.Number("1"), want: true},
+		"JSONNumberZero": {renewable: json.Number("0"), want: false},
+		"InvalidString":  {renewable: "maybe", wantErr: true},
+		"UnexpectedType": {renewable: []string{"true"}, wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseRenewable(tc.renewable)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("parseRenewable(%v) = %t, want %t", tc.renewable, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConservativeTTL(t *testing.T) {
+	defer func(tolerance time.Duration) { tokenExpiryTolerance = tolerance }(tokenExpiryTolerance)
+	tokenExpiryTolerance = time.Minute
+
+	cases := map[string]struct {
+		ttl        int64
+		expireTime any
+		max        int64
+	}{
+		"WithinTolerance": {
+			ttl:        3600,
+			expireTime: expireIn(time.Hour - 30*time.Second),
+			max:        3600,
+		},
+		"ExpireTimeSooner": {
+			ttl:        3600,
+			expireTime: expireIn(10 * time.Minute),
+			max:        600,
+		},
+		"TTLSooner": {
+			ttl:        600,
+			expireTime: expireIn(time.Hour),
+			max:        600,
+		},
+		"UnparsableExpireTime": {
+			ttl:        3600,
+			expireTime: "not-a-time",
+			max:        3600,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := conservativeTTL(tc.ttl, tc.expireTime)
+			// expire_time is relative to now, so allow a second of slack.
+			if got > tc.max || got < tc.max-1 {
+				t.Errorf("conservativeTTL(%d, %v) = %d, want %d", tc.ttl, tc.expireTime, got, tc.max)
+			}
+		})
+	}
+}
+
+func TestCloseStaticToken(t *testing.T) {
+	cases := map[string]struct {
+		auth       esv1.VaultAuth
+		wantRevoke bool
+	}{
+		"StaticTokenNotRevokedByDefault": {
+			auth: esv1.VaultAuth{
+				TokenSecretRef: &esmeta.SecretKeySelector{Name: tokenSecretName, Key: "token"},
+			},
+		},
+		"StaticTokenRevokedOnRequest": {
+			auth: esv1.VaultAuth{
+				TokenSecretRef:    &esmeta.SecretKeySelector{Name: tokenSecretName, Key: "token"},
+				RevokeStaticToken: true,
+			},
+			wantRevoke: true,
+		},
+		"MintedTokenRevoked": {
+			auth: esv1.VaultAuth{
+				Kubernetes: &esv1.VaultKubernetesAuth{},
+			},
+			wantRevoke: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			revoked := 0
+			c := &client{
+				log:   logger,
+				store: &esv1.VaultProvider{Auth: &tc.auth},
+				// as set when the token is read from the TokenSecretRef.
+				suppliedToken: tc.auth.TokenSecretRef != nil,
+				client: &util.VaultClient{
+					TokenFunc:      func() string { return "current-token" },
+					ClearTokenFunc: func() {},
+					AuthTokenField: fake.Token{
+						LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+							return makeTokenLookup(time.Hour, true), nil
+						},
+						RevokeSelfWithContextFn: func(ctx context.Context, token string) error {
+							revoked++
+							return nil
+						},
+					},
+				},
+			}
+
+			if err := c.Close(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantRevoke != (revoked == 1) {
+				t.Errorf("expected revoked: %t, got %d revocations", tc.wantRevoke, revoked)
+			}
+		})
+	}
+}
+
+func expireIn(d time.Duration) string {
+	return time.Now().Add(d).Format(time.RFC3339Nano)
+}
+
+func TestCloseRevokeScope(t *testing.T) {
+	cases := map[string]struct {
+		scope esv1.VaultTokenRevokeScope
+		want  string
+	}{
+		"Default": {
+			want: constants.CallHCVaultRevokeSelf,
+		},
+		"Self": {
+			scope: esv1.VaultTokenRevokeScopeSelf,
+			want:  constants.CallHCVaultRevokeSelf,
+		},
+		"Tree": {
+			scope: esv1.VaultTokenRevokeScopeTree,
+			want:  constants.CallHCVaultRevokeTree,
+		},
+		"Orphan": {
+			scope: esv1.VaultTokenRevokeScopeOrphan,
+			want:  constants.CallHCVaultRevokeOrphan,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			token := "current-token"
+			var called []string
+			revoke := func(call string) func(ctx context.Context, v string) error {
+				return func(ctx context.Context, v string) error {
+					if v != "current-token" {
+						t.Errorf("expected token %q to be revoked, got %q", "current-token", v)
+					}
+					called = append(called, call)
+					return nil
+				}
+			}
+			c := &client{
+				log: logger,
+				store: &esv1.VaultProvider{
+					Auth: &esv1.VaultAuth{
+						Kubernetes:  &esv1.VaultKubernetesAuth{},
+						RevokeScope: tc.scope,
+					},
+				},
+				client: &util.VaultClient{
+					TokenFunc:      func() string { return token },
+					ClearTokenFunc: func() { token = "" },
+					AuthTokenField: fake.Token{
+						LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+							return makeTokenLookup(time.Hour, true), nil
+						},
+						RevokeSelfWithContextFn:   revoke(constants.CallHCVaultRevokeSelf),
+						RevokeTreeWithContextFn:   revoke(constants.CallHCVaultRevokeTree),
+						RevokeOrphanWithContextFn: revoke(constants.CallHCVaultRevokeOrphan),
+					},
+				},
+			}
+
+			if err := c.Close(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff([]string{tc.want}, called); diff != "" {
+				t.Errorf("unexpected revoke calls: -want +got:\n%s", diff)
+			}
+			if token != "" {
+				t.Errorf("expected token to be cleared, got %q", token)
+			}
+		})
+	}
+}
+
+func TestRevokeTokenIfValid(t *testing.T) {
+	cases := map[string]struct {
+		// tokenSecretRef sets the token from a Secret before revoking it.
+		tokenSecretRef    bool
+		revokeStaticToken bool
+		policies          []any
+		identityPolicies  []any
+		wantLookups       int
+		wantRevoked       int
+	}{
+		"LoginToken": {
+			policies:    []any{"default"},
+			wantLookups: 1,
+			wantRevoked: 1,
+		},
+		// tokens read from a Secret aren't even looked up.
+		"SecretKeyToken": {
+			tokenSecretRef: true,
+		},
+		"SecretKeyTokenRevokedOnRequest": {
+			tokenSecretRef:    true,
+			revokeStaticToken: true,
+			policies:          []any{"default"},
+			wantLookups:       1,
+			wantRevoked:       1,
+		},
+		// root tokens are never revoked, also if issued by a login.
+		"RootLoginToken": {
+			policies:    []any{"root"},
+			wantLookups: 1,
+		},
+		"RootIdentityPolicyLoginToken": {
+			policies:         []any{"default"},
+			identityPolicies: []any{"root"},
+			wantLookups:      1,
+		},
+		"RootSecretKeyTokenRevokedOnRequest": {
+			tokenSecretRef:    true,
+			revokeStaticToken: true,
+			policies:          []any{"root"},
+			wantLookups:       1,
+		},
+		"RootIdentityPolicy": {
+			tokenSecretRef:    true,
+			revokeStaticToken: true,
+			policies:          []any{"default"},
+			identityPolicies:  []any{"root"},
+			wantLookups:       1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			token := "login-token"
+			lookups := 0
+			revoked := 0
+			auth := &esv1.VaultAuth{RevokeStaticToken: tc.revokeStaticToken}
+			if tc.tokenSecretRef {
+				auth.TokenSecretRef = &esmeta.SecretKeySelector{Name: tokenSecretName, Key: "token"}
+			}
+			c := &client{
+				kube: clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: tokenSecretName, Namespace: "default"},
+					Data:       map[string][]byte{"token": []byte("secret-token")},
+				}).Build(),
+				log:       logger,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store:     &esv1.VaultProvider{Auth: auth},
+				client: &util.VaultClient{
+					TokenFunc:      func() string { return token },
+					SetTokenFunc:   func(v string) { token = v },
+					ClearTokenFunc: func() { token = "" },
+					AuthTokenField: fake.Token{
+						LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+							lookups++
+							lookup := makeTokenLookup(time.Hour, true)
+							lookup.Data["policies"] = tc.policies
+							lookup.Data["identity_policies"] = tc.identityPolicies
+							return lookup, nil
+						},
+						RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+							revoked++
+							return nil
+						},
+					},
+				},
+			}
+			if tc.tokenSecretRef {
+				if _, err := setSecretKeyToken(context.Background(), c); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if token != "secret-token" {
+					t.Fatalf("expected the token of the secret, got %q", token)
+				}
+			}
+
+			if err := c.revokeLoginToken(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if lookups != tc.wantLookups {
+				t.Errorf("expected %d lookups, got %d", tc.wantLookups, lookups)
+			}
+			if revoked != tc.wantRevoked {
+				t.Errorf("expected %d revocations, got %d", tc.wantRevoked, revoked)
+			}
+			if wantCleared := tc.wantRevoked > 0; wantCleared != (token == "") {
+				t.Errorf("expected token cleared: %t, got %q", wantCleared, token)
+			}
+		})
+	}
+}
+
+func TestRevokeOnRelogin(t *testing.T) {
+	cases := map[string]struct {
+		revokeOnRelogin bool
+		lookup          *vault.Secret
+		revokeErr       error
+		wantRevoked     int
+		wantCleared     int
+		wantLogins      int
+	}{
+		"Disabled": {
+			lookup:     makeTokenLookup(30*time.Second, false),
+			wantLogins: 1,
+		},
+		"ExpiringToken": {
+			revokeOnRelogin: true,
+			lookup:          makeTokenLookup(30*time.Second, false),
+			wantRevoked:     1,
+			wantCleared:     1,
+			wantLogins:      1,
+		},
+		// the new token is still acquired.
+		"RevokeFails": {
+			revokeOnRelogin: true,
+			lookup:          makeTokenLookup(30*time.Second, false),
+			revokeErr:       errors.New("permission denied"),
+			wantRevoked:     1,
+			wantLogins:      1,
+		},
+		"ValidToken": {
+			revokeOnRelogin: true,
+			lookup:          makeTokenLookup(time.Hour, false),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			revoked := 0
+			cleared := 0
+			token := "stale-token"
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, nil), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, &logins)
+			c.store.Auth.RevokeOnRelogin = tc.revokeOnRelogin
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					logins++
+					token = "kubernetes-token"
+					return &vault.Secret{}, nil
+				},
+			}
+			authToken := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					return tc.lookup, nil
+				},
+				RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+					if v != "stale-token" {
+						t.Errorf("expected token %q to be revoked, got %q", "stale-token", v)
+					}
+					revoked++
+					return tc.revokeErr
+				},
+			}
+			c.token = authToken
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				ClearTokenFunc:   func() { cleared++; token = "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+				AuthTokenField:   authToken,
+			}
+
+			before := apiCallCount(t, constants.CallHCVaultRevokeSelf)
+			if err := c.setAuth(context.Background(), nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if revoked != tc.wantRevoked {
+				t.Errorf("expected %d revocations, got %d", tc.wantRevoked, revoked)
+			}
+			if got := apiCallCount(t, constants.CallHCVaultRevokeSelf) - before; got != float64(tc.wantRevoked) {
+				t.Errorf("expected %d recorded revocations, got %v", tc.wantRevoked, got)
+			}
+			if cleared != tc.wantCleared {
+				t.Errorf("expected the token to be cleared %d times, got %d", tc.wantCleared, cleared)
+			}
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, logins)
+			}
+			wantToken := "stale-token"
+			if tc.wantLogins > 0 {
+				wantToken = "kubernetes-token"
+			}
+			if token != wantToken {
+				t.Errorf("expected token %q, got %q", wantToken, token)
+			}
+		})
+	}
+}
+
+// apiCallCount returns the number of recorded Vault API calls of the given
+// kind, whatever their status.
+func apiCallCount(t *testing.T, call string) float64 {
+	t.Helper()
+	families, err := ctrlmetrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count float64
+	for _, f := range families {
+		if f.GetName() != "externalsecret_provider_api_calls_count" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["provider"] == constants.ProviderHCVault && labels["call"] == call {
+				count += m.GetCounter().GetValue()
+			}
+		}
+	}
+	return count
+}
+
+func TestSetAuthRecordsAuthMethod(t *testing.T) {
+	authMetricsOnce.Do(func() { metrics.SetUpAuthMetrics("") })
+
+	cases := map[string]struct {
+		storeKind     string
+		token         string
+		wantMethod    string
+		wantNamespace string
+	}{
+		"Login": {
+			storeKind:     esv1.SecretStoreKind,
+			wantMethod:    authMethodKubernetes,
+			wantNamespace: "default",
+		},
+		"ReusedToken": {
+			storeKind:     esv1.SecretStoreKind,
+			token:         "valid-token",
+			wantMethod:    authMethodReused,
+			wantNamespace: "default",
+		},
+		// cluster stores aren't namespaced.
+		"ClusterStore": {
+			storeKind:  esv1.ClusterSecretStoreKind,
+			token:      "valid-token",
+			wantMethod: authMethodReused,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			logins := 0
+			token := tc.token
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, nil), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, &logins)
+			c.storeKind = tc.storeKind
+			c.storeName = "auth-method-" + name
+			authToken := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					return makeTokenLookup(time.Hour, false), nil
+				},
+			}
+			c.token = authToken
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+				AuthTokenField:   authToken,
+			}
+
+			if err := c.setAuth(context.Background(), nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := map[string]float64{tc.wantMethod + "/" + tc.wantNamespace: 1}
+			if diff := cmp.Diff(want, authMethodCount(t, c.storeName)); diff != "" {
+				t.Errorf("unexpected auth methods (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// authMethodCount returns the number of recorded Vault auths of the store
+// by method and namespace, joined with a slash.
+func authMethodCount(t *testing.T, storeName string) map[string]float64 {
+	t.Helper()
+	families, err := ctrlmetrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]float64{}
+	for _, f := range families {
+		if f.GetName() != "externalsecret_provider_auth_method_count" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["provider"] == constants.ProviderHCVault && labels["store_name"] == storeName {
+				counts[labels["method"]+"/"+labels["store_namespace"]] = m.GetCounter().GetValue()
+			}
+		}
+	}
+	return counts
+}
+
+func TestAuthenticateErrorDetails(t *testing.T) {
+	defer func(dirs []string) { tokenFileDirs = dirs }(tokenFileDirs)
+
+	cases := map[string]struct {
+		// tokenFile configures a token file that doesn't exist.
+		tokenFile  bool
+		kubernetes bool
+		loginErr   error
+		wantErr    string
+	}{
+		"MethodFailed": {
+			kubernetes: true,
+			loginErr:   errors.New("permission denied"),
+			wantErr:    "kubernetes auth matched but login failed: permission denied",
+		},
+		"MethodSkipped": {
+			tokenFile: true,
+			wantErr:   `cannot initialize Vault client: no auth method could log in: tokenfile auth skipped: token file "`,
+		},
+		// the skipped methods aren't reported once another one logs in.
+		"SkippedMethodFailed": {
+			tokenFile:  true,
+			kubernetes: true,
+			loginErr:   errors.New("permission denied"),
+			wantErr:    "kubernetes auth matched but login failed: permission denied",
+		},
+		"NoMethod": {
+			wantErr: errAuthFormat,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			tokenFileDirs = []string{dir}
+			logins := 0
+			c := makeFileTokenClient(t, filepath.Join(dir, "token"), &logins)
+			if !tc.tokenFile {
+				c.store.Auth.TokenFilePath = ""
+			}
+			if !tc.kubernetes {
+				c.store.Auth.Kubernetes = nil
+			}
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					return nil, tc.loginErr
+				},
+			}
+
+			_, err := c.authenticate(context.Background(), nil)
+			if err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error starting with %q, got %v", tc.wantErr, err)
+			}
+			if tc.loginErr != nil && !errors.Is(err, tc.loginErr) {
+				t.Errorf("expected the login error to be wrapped, got %v", err)
+			}
+			// credentials never end up in the error.
+			if jwt := makeServiceAccountJWT(t, nil); strings.Contains(err.Error(), jwt) {
+				t.Errorf("expected no credentials in the error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+const (
+	errAuthMethodTimeout = "%s login timed out after %s: %w"
+)
+
+var (
+	// authTimeout bounds the login of every auth method. The timeout of the
+	// Vault client applies if zero.
+	authTimeout time.Duration
+	// authMethodTimeouts bound the logins of specific auth methods,
+	// overriding authTimeout.
+	authMethodTimeouts = methodTimeouts{}
+)
+
+// methodTimeouts is a flag value mapping auth method names to the timeouts
+// of their logins, e.g. "iam=30s,approle=5s".
+type methodTimeouts map[string]time.Duration
+
+func (m methodTimeouts) String() string {
+	pairs := make([]string, 0, len(m))
+	for _, method := range slices.Sorted(maps.Keys(m)) {
+		pairs = append(pairs, method+"="+m[method].String())
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m methodTimeouts) Set(value string) error {
+	for pair := range strings.SplitSeq(value, ",") {
+		method, timeout, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return fmt.Errorf("%q is not a method=timeout pair", pair)
+		}
+		if !slices.Contains(slices.Collect(maps.Values(selectionMethods)), method) {
+			return fmt.Errorf("unknown auth method %q", method)
+		}
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout of auth method %q: %w", method, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("negative timeout of auth method %q", method)
+		}
+		m[method] = d
+	}
+	return nil
+}
+
+func (m methodTimeouts) Type() string {
+	return "methodToDuration"
+}
+
+// authMethodTimeout returns the timeout of a login with the auth method
+// configured with the flags, and whether there is one.
+func authMethodTimeout(method string) (time.Duration, bool) {
+	if timeout, ok := authMethodTimeouts[method]; ok {
+		return timeout, true
+	}
+	return authTimeout, authTimeout != 0
+}
+
+// loginTimeout returns the timeout of a login with the auth method, or zero
+// if it isn't bounded. The LoginTimeout of the store takes precedence over
+// the flags, which take precedence over the timeout of the Vault client.
+func (c *client) loginTimeout(cfg *vault.Config, method string) time.Duration {
+	if c.store.Auth != nil && c.store.Auth.LoginTimeout != nil {
+		return c.store.Auth.LoginTimeout.Duration
+	}
+	if timeout, ok := authMethodTimeout(method); ok {
+		return timeout
+	}
+	if cfg != nil {
+		return cfg.Timeout
+	}
+	return 0
+}
+
+// loginTimeoutError is returned if a login ran out of time, as opposed to
+// being rejected by Vault.
+type loginTimeoutError struct {
+	method  string
+	timeout time.Duration
+	err     error
+}
+
+func (e *loginTimeoutError) Error() string {
+	return fmt.Errorf(errAuthMethodTimeout, e.method, e.timeout, e.err).Error()
+}
+
+func (e *loginTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// IsLoginTimeout reports whether err is caused by a Vault login running out
+// of time, e.g. against a hung Vault endpoint.
+func IsLoginTimeout(err error) bool {
+	var timeoutErr *loginTimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
+// loginWithTimeout logs in with the auth method within the timeout, unless
+// it is zero. A login running out of time fails with a loginTimeoutError,
+// unless the context of the request ran out first.
+func loginWithTimeout(ctx context.Context, method authMethod, timeout time.Duration) (bool, error) {
+	if timeout == 0 {
+		return method.login(ctx)
+	}
+	loginCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	loggedIn, err := method.login(loginCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(loginCtx.Err(), context.DeadlineExceeded) {
+		err = &loginTimeoutError{method: method.name, timeout: timeout, err: err}
+	}
+	return loggedIn, err
+}
@@ -0,0 +1,251 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestLoginWithTimeout(t *testing.T) {
+	defer func(global time.Duration, perMethod methodTimeouts) {
+		authTimeout = global
+		authMethodTimeouts = perMethod
+	}(authTimeout, authMethodTimeouts)
+
+	cases := map[string]struct {
+		global       time.Duration
+		perMethod    methodTimeouts
+		loginTimeout *metav1.Duration
+		// clientTimeout is the timeout of the Vault client's config.
+		clientTimeout time.Duration
+		method        string
+		loginTime     time.Duration
+		wantDeadline  time.Duration
+		wantErr       string
+	}{
+		"NoTimeout": {
+			method:    authMethodAppRole,
+			loginTime: 10 * time.Millisecond,
+		},
+		"GlobalTimeout": {
+			global:       20 * time.Millisecond,
+			method:       authMethodAppRole,
+			loginTime:    time.Second,
+			wantDeadline: 20 * time.Millisecond,
+			wantErr:      "approle login timed out after 20ms: context deadline exceeded",
+		},
+		"MethodTimeout": {
+			global:       time.Minute,
+			perMethod:    methodTimeouts{authMethodIam: 20 * time.Millisecond},
+			method:       authMethodIam,
+			loginTime:    time.Second,
+			wantDeadline: 20 * time.Millisecond,
+			wantErr:      "iam login timed out after 20ms: context deadline exceeded",
+		},
+		"MethodTimeoutOverridesGlobal": {
+			global:       20 * time.Millisecond,
+			perMethod:    methodTimeouts{authMethodIam: time.Minute},
+			method:       authMethodIam,
+			loginTime:    50 * time.Millisecond,
+			wantDeadline: time.Minute,
+		},
+		"OtherMethodKeepsGlobal": {
+			global:       time.Minute,
+			perMethod:    methodTimeouts{authMethodIam: 20 * time.Millisecond},
+			method:       authMethodAppRole,
+			loginTime:    50 * time.Millisecond,
+			wantDeadline: time.Minute,
+		},
+		"MethodTimeoutDisabled": {
+			global:    20 * time.Millisecond,
+			perMethod: methodTimeouts{authMethodIam: 0},
+			method:    authMethodIam,
+			loginTime: 50 * time.Millisecond,
+		},
+		"StoreTimeoutOverridesFlags": {
+			global:       time.Minute,
+			perMethod:    methodTimeouts{authMethodIam: time.Minute},
+			loginTimeout: &metav1.Duration{Duration: 20 * time.Millisecond},
+			method:       authMethodIam,
+			loginTime:    time.Second,
+			wantDeadline: 20 * time.Millisecond,
+			wantErr:      "iam login timed out after 20ms: context deadline exceeded",
+		},
+		"ClientTimeoutByDefault": {
+			clientTimeout: 20 * time.Millisecond,
+			method:        authMethodKubernetes,
+			loginTime:     time.Second,
+			wantDeadline:  20 * time.Millisecond,
+			wantErr:       "kubernetes login timed out after 20ms: context deadline exceeded",
+		},
+		"FlagsOverrideClientTimeout": {
+			global:        time.Minute,
+			clientTimeout: 20 * time.Millisecond,
+			method:        authMethodKubernetes,
+			loginTime:     50 * time.Millisecond,
+			wantDeadline:  time.Minute,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			authTimeout = tc.global
+			authMethodTimeouts = tc.perMethod
+			var deadline time.Duration
+			method := authMethod{
+				name: tc.method,
+				login: func(ctx context.Context) (bool, error) {
+					if d, ok := ctx.Deadline(); ok {
+						deadline = time.Until(d).Round(10 * time.Millisecond)
+					}
+					select {
+					case <-ctx.Done():
+						return true, ctx.Err()
+					case <-time.After(tc.loginTime):
+						return true, nil
+					}
+				},
+			}
+
+			c := &client{store: &esv1.VaultProvider{Auth: &esv1.VaultAuth{LoginTimeout: tc.loginTimeout}}}
+			var cfg *vault.Config
+			if tc.clientTimeout != 0 {
+				cfg = &vault.Config{Timeout: tc.clientTimeout}
+			}
+
+			loggedIn, err := loginWithTimeout(context.Background(), method, c.loginTimeout(cfg, tc.method))
+			if !loggedIn {
+				t.Errorf("expected the method to be used")
+			}
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || err.Error() != tc.wantErr) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr != "" && !IsLoginTimeout(fmt.Errorf(errAuthMethodFailed, tc.method, err)) {
+				t.Errorf("expected a login timeout error, got %T", err)
+			}
+			if deadline != tc.wantDeadline {
+				t.Errorf("expected a deadline in %s, got %s", tc.wantDeadline, deadline)
+			}
+		})
+	}
+}
+
+func TestSetAuthMethodTimeout(t *testing.T) {
+	defer func(global time.Duration, perMethod methodTimeouts) {
+		authTimeout = global
+		authMethodTimeouts = perMethod
+	}(authTimeout, authMethodTimeouts)
+	authTimeout = time.Minute
+	authMethodTimeouts = methodTimeouts{authMethodKubernetes: 20 * time.Millisecond}
+
+	logins := 0
+	token := ""
+	c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), &esv1.VaultKubernetesAuth{
+		Path: "kubernetes",
+		Role: "kubernetes-auth-role",
+	}, &logins)
+	c.auth = fake.Auth{
+		// a login hanging until its context is done.
+		LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	c.client = &util.VaultClient{
+		TokenFunc:        func() string { return token },
+		SetTokenFunc:     func(v string) { token = v },
+		ClearTokenFunc:   func() { token = "" },
+		NamespaceFunc:    func() string { return "" },
+		SetNamespaceFunc: func(string) {},
+	}
+
+	start := time.Now()
+	err := c.setAuth(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "kubernetes login timed out after 20ms") {
+		t.Fatalf("expected the kubernetes login to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the login to time out with the timeout of its method, took %s", elapsed)
+	}
+}
+
+func TestLoginWithTimeoutRequestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := loginWithTimeout(ctx, authMethod{
+		name: authMethodAppRole,
+		login: func(ctx context.Context) (bool, error) {
+			<-ctx.Done()
+			return true, ctx.Err()
+		},
+	}, time.Minute)
+	// the login is bounded by the request, not by the method's timeout.
+	if !errors.Is(err, context.Canceled) || IsLoginTimeout(err) {
+		t.Errorf("expected the cancellation of the request, got %v", err)
+	}
+}
+
+func TestMethodTimeoutsFlag(t *testing.T) {
+	cases := map[string]struct {
+		value   string
+		want    methodTimeouts
+		wantErr bool
+	}{
+		"Single":        {value: "iam=30s", want: methodTimeouts{"iam": 30 * time.Second}},
+		"Multiple":      {value: "iam=30s, approle=5s", want: methodTimeouts{"iam": 30 * time.Second, "approle": 5 * time.Second}},
+		"Zero":          {value: "kubernetes=0s", want: methodTimeouts{"kubernetes": 0}},
+		"UnknownMethod": {value: "github=5s", wantErr: true},
+		"NoTimeout":     {value: "iam", wantErr: true},
+		"InvalidValue":  {value: "iam=soon", wantErr: true},
+		"Negative":      {value: "iam=-5s", wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := methodTimeouts{}
+			err := got.Set(tc.value)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected timeouts (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	if got := (methodTimeouts{"iam": 30 * time.Second, "approle": 5 * time.Second}).String(); got != "approle=5s,iam=30s" {
+		t.Errorf("unexpected flag string %q", got)
+	}
+}
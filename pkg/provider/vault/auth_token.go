@@ -16,10 +16,33 @@ package vault
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 
+	vault "github.com/hashicorp/vault/api"
+
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
 	"github.com/external-secrets/external-secrets/pkg/utils/resolvers"
 )
 
+const (
+	errTokenFileNotAllowed = "cannot read Vault token from %q: not within a directory allowed with --vault-token-file-dirs"
+	errTokenFileRead       = "cannot read Vault token from %q: %w"
+	errTokenFileMissing    = "token file %q does not exist"
+	errTokenFileEmpty      = "token file %q is empty"
+	errTokenUnwrap         = "cannot unwrap Vault token: %w"
+	errTokenUnwrapEmpty    = "wrapped response contains no token"
+)
+
+// tokenFileDirs are the directories of the controller filesystem within
+// which stores may read their token from a file. No files are read if empty.
+var tokenFileDirs []string
+
 func setSecretKeyToken(ctx context.Context, v *client) (bool, error) {
 	tokenRef := v.store.Auth.TokenSecretRef
 	if tokenRef != nil {
@@ -27,8 +50,90 @@ func setSecretKeyToken(ctx context.Context, v *client) (bool, error) {
 		if err != nil {
 			return true, err
 		}
+		if v.store.Auth.WrappedToken {
+			token, err = v.unwrapToken(ctx, tokenRef, strings.TrimSpace(token))
+			if err != nil {
+				return true, err
+			}
+		}
 		v.client.SetToken(token)
+		v.suppliedToken = true
+		v.renewStaticToken(ctx)
 		return true, nil
 	}
 	return false, nil
 }
+
+// unwrapToken returns the Vault token wrapped by the token read from the
+// Secret, e.g. one created with `vault token create -wrap-ttl`.
+func (c *client) unwrapToken(ctx context.Context, ref *esmeta.SecretKeySelector, wrappingToken string) (string, error) {
+	token, err := c.unwrapSecretValue(ctx, ref, wrappingToken, constants.CallHCVaultUnwrapToken, func(resp *vault.Secret) (string, error) {
+		if resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+			return "", errors.New(errTokenUnwrapEmpty)
+		}
+		return resp.Auth.ClientToken, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf(errTokenUnwrap, err)
+	}
+	return token, nil
+}
+
+// setFileToken sets the token read from the token file of the store. The
+// file is read on each login, so that a rotated token is picked up. The
+// next auth method is tried while the file is missing or empty, e.g. until
+// a Vault Agent wrote it, and the reason is reported if none logs in.
+func setFileToken(_ context.Context, v *client) (bool, error) {
+	path := v.store.Auth.TokenFilePath
+	if path == "" {
+		return false, nil
+	}
+	// the path is checked before and after resolving symlinks, so that
+	// stores can neither read nor probe the existence of other files.
+	if !inTokenFileDirs(path, false) {
+		return true, fmt.Errorf(errTokenFileNotAllowed, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf(errTokenFileMissing, path)
+	}
+	if err != nil {
+		return true, fmt.Errorf(errTokenFileRead, path, err)
+	}
+	if !inTokenFileDirs(resolved, true) {
+		return true, fmt.Errorf(errTokenFileNotAllowed, path)
+	}
+	raw, err := os.ReadFile(resolved)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf(errTokenFileMissing, path)
+	}
+	if err != nil {
+		return true, fmt.Errorf(errTokenFileRead, path, err)
+	}
+	token := strings.TrimSpace(string(raw))
+	if token == "" {
+		return false, fmt.Errorf(errTokenFileEmpty, path)
+	}
+	v.client.SetToken(token)
+	v.suppliedToken = true
+	v.log.V(1).Info("loaded token from file", "path", path)
+	return true, nil
+}
+
+// inTokenFileDirs reports whether the path is within one of the allowed
+// token file directories, whose symlinks are resolved if requested.
+func inTokenFileDirs(path string, resolve bool) bool {
+	path = filepath.Clean(path)
+	for _, dir := range tokenFileDirs {
+		if resolve {
+			if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+				dir = resolved
+			}
+		}
+		rel, err := filepath.Rel(filepath.Clean(dir), path)
+		if err == nil && rel != "." && filepath.IsLocal(rel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const errTokenInfo = "cannot look up the vault token: %w"
+
+// TokenInfo is the lease and identity of the Vault token a client uses, as
+// returned by a token lookup.
+type TokenInfo struct {
+	// Type is "service" or "batch".
+	Type string
+	// TTL is the time left until the token expires, zero for non-expirable
+	// tokens.
+	TTL time.Duration
+	// ExpireTime is zero for non-expirable tokens.
+	ExpireTime time.Time
+	Renewable  bool
+	Accessor   string
+	// Policies are the token and identity policies of the token.
+	Policies []string
+}
+
+// TokenInspector is implemented by clients that can report on the Vault
+// token they use, e.g. for debugging.
+type TokenInspector interface {
+	TokenInfo(ctx context.Context) (*TokenInfo, error)
+}
+
+var _ TokenInspector = &client{}
+
+// TokenInfo looks up the token the client currently uses. The lookup is
+// parsed like the lookups checking whether the token is still valid, so the
+// TTL is the one validity is decided on. Looking up a limited-use token
+// consumes one of its uses.
+func (c *client) TokenInfo(ctx context.Context) (*TokenInfo, error) {
+	if c.client.Token() == "" {
+		return nil, fmt.Errorf(errTokenInfo, errors.New("client has no token"))
+	}
+	// https://developer.hashicorp.com/vault/api-docs/auth/token#lookup-a-token-self
+	resp, err := c.tokenAPI().LookupSelfWithContext(ctx)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLookupSelf, err)
+	if err != nil {
+		return nil, fmt.Errorf(errTokenInfo, err)
+	}
+	info, err := parseTokenLookup(resp)
+	if err != nil {
+		return nil, fmt.Errorf(errTokenInfo, err)
+	}
+	// the policies of a lookup include those of the identity.
+	info.Policies, err = resp.TokenPolicies()
+	if err != nil {
+		return nil, fmt.Errorf(errTokenInfo, err)
+	}
+	return info, nil
+}
@@ -0,0 +1,161 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	vault "github.com/hashicorp/vault/api"
+
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestTokenInfo(t *testing.T) {
+	cases := map[string]struct {
+		token     string
+		lookup    *vault.Secret
+		lookupErr error
+		// the TTL of want is compared with two seconds of tolerance, as TTLs
+		// are whole seconds, and is expected to be left until ExpireTime.
+		want    *TokenInfo
+		wantErr string
+	}{
+		"Valid": {
+			token: "current-token",
+			lookup: &vault.Secret{Data: map[string]any{
+				"type":              "service",
+				"ttl":               json.Number("3600"),
+				"expire_time":       expireIn(time.Hour),
+				"renewable":         true,
+				"accessor":          "8609694a-cdbc-db9b-d345-e782dbb562ed",
+				"policies":          []any{"default", "app"},
+				"identity_policies": []any{"team"},
+			}},
+			want: &TokenInfo{
+				Type:      "service",
+				TTL:       time.Hour,
+				Renewable: true,
+				Accessor:  "8609694a-cdbc-db9b-d345-e782dbb562ed",
+				Policies:  []string{"default", "app", "team"},
+			},
+		},
+		// the TTL is the sooner expiry, like when checking the token.
+		"ExpireTimeSooner": {
+			token: "current-token",
+			lookup: &vault.Secret{Data: map[string]any{
+				"type":        "service",
+				"ttl":         json.Number("3600"),
+				"expire_time": expireIn(10 * time.Minute),
+			}},
+			want: &TokenInfo{
+				Type: "service",
+				TTL:  10 * time.Minute,
+			},
+		},
+		"NonExpirable": {
+			token: "root-token",
+			lookup: &vault.Secret{Data: map[string]any{
+				"type":        "service",
+				"ttl":         json.Number("0"),
+				"expire_time": nil,
+				"policies":    []any{"root"},
+			}},
+			want: &TokenInfo{
+				Type:     "service",
+				Policies: []string{"root"},
+			},
+		},
+		"Batch": {
+			token: "batch-token",
+			lookup: &vault.Secret{Data: map[string]any{
+				"type":        "batch",
+				"ttl":         "15m",
+				"expire_time": expireIn(15 * time.Minute),
+				"renewable":   "false",
+			}},
+			want: &TokenInfo{
+				Type: "batch",
+				TTL:  15 * time.Minute,
+			},
+		},
+		"NoToken": {
+			wantErr: "client has no token",
+		},
+		"LookupFails": {
+			token:     "current-token",
+			lookupErr: errors.New("permission denied"),
+			wantErr:   "cannot look up the vault token: permission denied",
+		},
+		"InvalidTTL": {
+			token: "current-token",
+			lookup: &vault.Secret{Data: map[string]any{
+				"type": "service",
+				"ttl":  "forever",
+			}},
+			wantErr: "invalid token TTL",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			lookups := 0
+			c := &client{
+				client: &util.VaultClient{
+					TokenFunc: func() string { return tc.token },
+				},
+				token: fake.Token{
+					LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+						lookups++
+						return tc.lookup, tc.lookupErr
+					},
+				},
+			}
+
+			info, err := c.TokenInfo(context.Background())
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+			if tc.token == "" && lookups != 0 {
+				t.Errorf("expected no lookup without a token, got %d", lookups)
+			}
+			if diff := cmp.Diff(tc.want, info, cmpopts.IgnoreFields(TokenInfo{}, "TTL", "ExpireTime")); diff != "" {
+				t.Errorf("unexpected token info (-want, +got):\n%s", diff)
+			}
+			if info == nil || tc.want == nil {
+				return
+			}
+			if (info.TTL - tc.want.TTL).Abs() > 2*time.Second {
+				t.Errorf("expected TTL %s, got %s", tc.want.TTL, info.TTL)
+			}
+			if tc.want.TTL == 0 && !info.ExpireTime.IsZero() {
+				t.Errorf("expected no expiry, got %s", info.ExpireTime)
+			}
+			if tc.want.TTL > 0 && (time.Until(info.ExpireTime)-tc.want.TTL).Abs() > 2*time.Second {
+				t.Errorf("expected the token to expire in %s, got %s", tc.want.TTL, time.Until(info.ExpireTime))
+			}
+		})
+	}
+}
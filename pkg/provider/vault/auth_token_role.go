@@ -0,0 +1,67 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	vault "github.com/hashicorp/vault/api"
+
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errTokenRoleCreate = "cannot create token with token role %q: %w"
+)
+
+// createRoleToken replaces the token obtained by the login with a child
+// token created with the token role of the store, if it has one. The parent
+// token is left to expire, and takes the child token with it unless the
+// role creates orphan tokens.
+func (c *client) createRoleToken(ctx context.Context) error {
+	role := c.store.Auth.TokenRole
+	if role == nil {
+		return nil
+	}
+	req := &vault.TokenCreateRequest{Policies: role.Policies}
+	if role.TTL != nil {
+		req.TTL = role.TTL.Duration.String()
+	}
+	// https://developer.hashicorp.com/vault/api-docs/auth/token#create-token
+	resp, err := c.token.CreateWithRoleWithContext(ctx, req, role.Name)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultCreateRoleToken, err)
+	if err != nil {
+		return fmt.Errorf(errTokenRoleCreate, role.Name, err)
+	}
+	if err := c.setLoginToken(ctx, resp); err != nil {
+		return fmt.Errorf(errTokenRoleCreate, role.Name, err)
+	}
+	c.log.V(1).Info("Created token with token role", "role", role.Name)
+	return nil
+}
+
+// providedToken reports whether the token of the client was supplied as is
+// by the token file or TokenSecretRef, unless it is revoked on request.
+// Such tokens are managed outside of ESO, while tokens created with a token
+// role are always issued for the client.
+func (c *client) providedToken() bool {
+	if c.store.Auth.TokenRole != nil {
+		return false
+	}
+	return c.authMethod == authMethodTokenFile ||
+		(c.suppliedToken && !c.store.Auth.RevokeStaticToken)
+}
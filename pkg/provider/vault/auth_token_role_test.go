@@ -0,0 +1,176 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+)
+
+func TestTokenRole(t *testing.T) {
+	cases := map[string]struct {
+		createStatus      int
+		revokeStaticToken bool
+		wantRequest       map[string]any
+		// wantRequests are the requests by path and token.
+		wantRequests []string
+		wantErr      string
+	}{
+		"Created": {
+			createStatus: http.StatusOK,
+			wantRequest: map[string]any{
+				"policies": []any{"read-secrets"},
+				"ttl":      "30m0s",
+			},
+			wantRequests: []string{
+				"/v1/auth/token/create/eso parent-token",
+				"/v1/secret/data/foo child-token",
+				"/v1/auth/token/lookup-self child-token",
+				"/v1/auth/token/revoke-self child-token",
+			},
+		},
+		// the parent is left to expire, also if static tokens are revoked.
+		"ParentNotRevoked": {
+			createStatus:      http.StatusOK,
+			revokeStaticToken: true,
+			wantRequests: []string{
+				"/v1/auth/token/create/eso parent-token",
+				"/v1/secret/data/foo child-token",
+				"/v1/auth/token/lookup-self child-token",
+				"/v1/auth/token/revoke-self child-token",
+			},
+		},
+		"Denied": {
+			createStatus: http.StatusForbidden,
+			wantRequests: []string{
+				"/v1/auth/token/create/eso parent-token",
+			},
+			wantErr: `cannot create token with token role "eso"`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var requests []string
+			var request map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				requests = append(requests, r.URL.Path+" "+r.Header.Get("X-Vault-Token"))
+				switch r.URL.Path {
+				case "/v1/auth/token/create/eso":
+					if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+						t.Errorf("cannot decode request: %v", err)
+					}
+					w.WriteHeader(tc.createStatus)
+					if tc.createStatus != http.StatusOK {
+						_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+						return
+					}
+					_, _ = w.Write([]byte(`{"auth": {"client_token": "child-token", "lease_duration": 1800, "renewable": true}}`))
+				case "/v1/auth/token/lookup-self":
+					_, _ = w.Write([]byte(`{"data": {"type": "service", "ttl": 1800, "expire_time": "2030-01-01T00:00:00Z"}}`))
+				case "/v1/auth/token/revoke-self":
+					w.WriteHeader(http.StatusNoContent)
+				case "/v1/secret/data/foo":
+					_, _ = w.Write([]byte(`{"data": {"data": {"foo": "bar"}}}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vault-token",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"token": []byte("parent-token"),
+				},
+			}).Build()
+			store := &esv1.SecretStore{
+				TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vault-store",
+					Namespace: "default",
+				},
+				Spec: esv1.SecretStoreSpec{
+					Provider: &esv1.SecretStoreProvider{
+						Vault: &esv1.VaultProvider{
+							Server:  server.URL,
+							Version: esv1.VaultKVStoreV2,
+							Auth: &esv1.VaultAuth{
+								TokenSecretRef:    &esmeta.SecretKeySelector{Name: "vault-token", Key: "token"},
+								RevokeStaticToken: tc.revokeStaticToken,
+								TokenRole: &esv1.VaultTokenRole{
+									Name:     "eso",
+									Policies: []string{"read-secrets"},
+									TTL:      &metav1.Duration{Duration: 30 * time.Minute},
+								},
+							},
+						},
+					},
+				},
+			}
+			prov := &Provider{NewVaultClient: NewVaultClient}
+			c, err := prov.newClient(context.Background(), store, kube, nil, "default")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if _, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "secret/foo"}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				// the created token is revoked, unlike the static parent.
+				if err := c.Close(context.Background()); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if tc.wantRequest != nil {
+				for key, want := range tc.wantRequest {
+					if diff := cmp.Diff(want, request[key]); diff != "" {
+						t.Errorf("unexpected %s in create request (-want, +got):\n%s", key, diff)
+					}
+				}
+			}
+			if diff := cmp.Diff(tc.wantRequests, requests); diff != "" {
+				t.Errorf("unexpected requests (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestSetFileToken(t *testing.T) {
+	defer func(dirs []string) { tokenFileDirs = dirs }(tokenFileDirs)
+
+	cases := map[string]struct {
+		// content is written to the token file, which is missing if nil.
+		content *string
+		// outside places the token file outside of the allowed directory.
+		outside bool
+		// symlink links the token file from the allowed directory to one
+		// outside of it.
+		symlink    bool
+		wantToken  string
+		wantMethod string
+		wantErr    string
+	}{
+		"Loaded": {
+			content:    ptr.To(" file-token\n"),
+			wantToken:  "file-token",
+			wantMethod: authMethodTokenFile,
+		},
+		// the next auth method logs in until the file is written.
+		"Missing": {
+			wantToken:  "kubernetes-token",
+			wantMethod: authMethodKubernetes,
+		},
+		"Empty": {
+			content:    ptr.To("\n"),
+			wantToken:  "kubernetes-token",
+			wantMethod: authMethodKubernetes,
+		},
+		"NotAllowed": {
+			content: ptr.To("file-token"),
+			outside: true,
+			wantErr: "not within a directory allowed",
+		},
+		// files outside of the allowed directory aren't probed either.
+		"MissingNotAllowed": {
+			outside: true,
+			wantErr: "not within a directory allowed",
+		},
+		"SymlinkNotAllowed": {
+			content: ptr.To("file-token"),
+			symlink: true,
+			wantErr: "not within a directory allowed",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			allowed, other := t.TempDir(), t.TempDir()
+			tokenFileDirs = []string{allowed}
+			path := filepath.Join(allowed, "token")
+			if tc.outside {
+				path = filepath.Join(other, "token")
+			}
+			if tc.content != nil {
+				target := path
+				if tc.symlink {
+					target = filepath.Join(other, "token")
+					if err := os.Symlink(target, path); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := os.WriteFile(target, []byte(*tc.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			logins := 0
+			c := makeFileTokenClient(t, path, &logins)
+			err := c.setAuth(context.Background(), nil)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if token := c.client.Token(); token != tc.wantToken {
+				t.Errorf("expected token %q, got %q", tc.wantToken, token)
+			}
+			if c.authMethod != tc.wantMethod {
+				t.Errorf("expected auth method %q, got %q", tc.wantMethod, c.authMethod)
+			}
+		})
+	}
+}
+
+func TestFileTokenRotation(t *testing.T) {
+	defer func(dirs []string) { tokenFileDirs = dirs }(tokenFileDirs)
+	dir := t.TempDir()
+	tokenFileDirs = []string{dir}
+	path := filepath.Join(dir, "token")
+
+	logins := 0
+	c := makeFileTokenClient(t, path, &logins)
+	for _, token := range []string{"first-token", "rotated-token"} {
+		if err := os.WriteFile(path, []byte(token), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// the file is read again on the next login.
+		c.client.ClearToken()
+		if err := c.setAuth(context.Background(), nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := c.client.Token(); got != token {
+			t.Errorf("expected token %q, got %q", token, got)
+		}
+	}
+
+	// tokens read from a file are left to whoever wrote them.
+	if err := c.revokeLoginToken(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if logins != 0 {
+		t.Errorf("expected no Kubernetes login, got %d", logins)
+	}
+}
+
+// makeFileTokenClient returns a client reading its token from the file at
+// path, falling back to Kubernetes auth. Its tokens are valid for an hour
+// and revoking them fails the test.
+func makeFileTokenClient(t *testing.T, path string, logins *int) *client {
+	t.Helper()
+	c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, nil), &esv1.VaultKubernetesAuth{
+		Path: "kubernetes",
+		Role: "kubernetes-auth-role",
+	}, logins)
+	c.store.Auth.TokenFilePath = path
+	token := ""
+	c.auth = fake.Auth{
+		LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+			*logins++
+			token = "kubernetes-token"
+			return &vault.Secret{}, nil
+		},
+	}
+	authToken := fake.Token{
+		LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+			return makeTokenLookup(time.Hour, false), nil
+		},
+		RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+			t.Errorf("unexpected revocation of %q", token)
+			return nil
+		},
+	}
+	c.token = authToken
+	c.client = &util.VaultClient{
+		TokenFunc:        func() string { return token },
+		SetTokenFunc:     func(v string) { token = v },
+		ClearTokenFunc:   func() { token = "" },
+		NamespaceFunc:    func() string { return "" },
+		SetNamespaceFunc: func(string) {},
+		AuthTokenField:   authToken,
+	}
+	return c
+}
+
+func TestSetWrappedToken(t *testing.T) {
+	defer func() { unwrapped = map[string]unwrappedValue{} }()
+
+	cases := map[string]struct {
+		// values are the values of the Secret on each login.
+		values      []string
+		unwrapErr   error
+		wantUnwraps int
+		wantToken   string
+		wantErr     string
+	}{
+		"Unwrapped": {
+			values:      []string{" wrapping-token\n"},
+			wantUnwraps: 1,
+			wantToken:   "vault-token",
+		},
+		// the token can be unwrapped only once, so it is reused.
+		"Reused": {
+			values:      []string{"wrapping-token", "wrapping-token"},
+			wantUnwraps: 1,
+			wantToken:   "vault-token",
+		},
+		"Replaced": {
+			values:      []string{"wrapping-token", "other-wrapping-token"},
+			wantUnwraps: 2,
+			wantToken:   "vault-token",
+		},
+		"AlreadyUnwrapped": {
+			values: []string{"wrapping-token"},
+			unwrapErr: &vault.ResponseError{
+				StatusCode: http.StatusBadRequest,
+				Errors:     []string{"wrapping token is not valid or does not exist"},
+			},
+			wantUnwraps: 1,
+			wantErr:     `cannot unwrap Vault token: wrapping token in secret "vault-token" was already unwrapped or has expired`,
+		},
+		"NoToken": {
+			values:      []string{"wrapping-token"},
+			wantUnwraps: 1,
+			wantErr:     "cannot unwrap Vault token: wrapped response contains no token",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			unwrapped = map[string]unwrappedValue{}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "vault-token", Namespace: "default"},
+			}
+			kube := clientfake.NewClientBuilder().WithObjects(secret).Build()
+			unwraps := 0
+			token := ""
+			c := &client{
+				kube:      kube,
+				log:       logger,
+				namespace: "default",
+				storeKind: esv1.SecretStoreKind,
+				store: &esv1.VaultProvider{
+					Auth: &esv1.VaultAuth{
+						TokenSecretRef: &esmeta.SecretKeySelector{Name: "vault-token", Key: "token"},
+						WrappedToken:   true,
+					},
+				},
+				logical: fake.Logical{
+					UnwrapWithContextFn: func(ctx context.Context, wrappingToken string) (*vault.Secret, error) {
+						unwraps++
+						if !strings.HasSuffix(wrappingToken, "wrapping-token") {
+							t.Errorf("unexpected wrapping token %q", wrappingToken)
+						}
+						if tc.unwrapErr != nil {
+							return nil, tc.unwrapErr
+						}
+						if name == "NoToken" {
+							return &vault.Secret{}, nil
+						}
+						return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "vault-token"}}, nil
+					},
+				},
+				client: &util.VaultClient{
+					TokenFunc:    func() string { return token },
+					SetTokenFunc: func(v string) { token = v },
+				},
+			}
+
+			var err error
+			for _, value := range tc.values {
+				secret.Data = map[string][]byte{"token": []byte(value)}
+				if err := kube.Update(context.Background(), secret); err != nil {
+					t.Fatal(err)
+				}
+				if _, err = setSecretKeyToken(context.Background(), c); err != nil {
+					break
+				}
+			}
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+			if unwraps != tc.wantUnwraps {
+				t.Errorf("expected %d unwraps, got %d", tc.wantUnwraps, unwraps)
+			}
+			if token != tc.wantToken {
+				t.Errorf("expected token %q, got %q", tc.wantToken, token)
+			}
+		})
+	}
+}
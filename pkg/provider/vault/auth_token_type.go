@@ -0,0 +1,67 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errTokenTypeLookup   = "cannot look up the type of the vault token: %w"
+	errTokenTypeMismatch = "login issued a %s token, but the store requires a %s token: configure the role of the auth method with token_type=%s"
+)
+
+// tokenTypeError is returned by logins issuing a token of another type than
+// the store requires.
+type tokenTypeError struct {
+	issued   string
+	required esv1.VaultTokenType
+}
+
+func (e *tokenTypeError) Error() string {
+	return fmt.Sprintf(errTokenTypeMismatch, e.issued, e.required, e.required)
+}
+
+// checkTokenType checks the type of the token of a fresh login against the
+// type the store requires. A token of another type is dropped, and the
+// login is rejected rather than failing transiently, so that a role issuing
+// batch tokens isn't retried and is remembered by the negative auth cache.
+func (c *client) checkTokenType(ctx context.Context) error {
+	required := c.store.Auth.TokenType
+	if required == "" {
+		return nil
+	}
+	resp, err := c.tokenAPI().LookupSelfWithContext(ctx)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLookupSelf, err)
+	if err == nil && resp == nil {
+		err = errors.New("no response nor error for token lookup")
+	}
+	if err != nil {
+		c.dropLoginToken(ctx, "failed token type check")
+		return fmt.Errorf(errTokenTypeLookup, err)
+	}
+	tokenType, _ := resp.Data["type"].(string)
+	if tokenType == string(required) {
+		return nil
+	}
+	c.dropLoginToken(ctx, "token type mismatch")
+	return &tokenTypeError{issued: tokenType, required: required}
+}
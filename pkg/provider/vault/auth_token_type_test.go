@@ -0,0 +1,119 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestTokenType(t *testing.T) {
+	defer func(ttl time.Duration) { negativeAuthCacheTTL = ttl }(negativeAuthCacheTTL)
+	negativeAuthCacheTTL = time.Minute
+	defer func() { authFailures = map[string]authFailure{} }()
+
+	cases := map[string]struct {
+		required esv1.VaultTokenType
+		issued   string
+		wantErr  string
+		// wantLogins are the logins of two reconciles.
+		wantLogins int
+		wantToken  string
+	}{
+		// batch tokens are replaced on every reuse.
+		"Unset": {
+			issued:     "batch",
+			wantLogins: 2,
+			wantToken:  "kubernetes-token",
+		},
+		"Service": {
+			required:   esv1.VaultTokenTypeService,
+			issued:     "service",
+			wantLogins: 1,
+			wantToken:  "kubernetes-token",
+		},
+		// the rejected login isn't repeated by the second reconcile.
+		"Batch": {
+			required:   esv1.VaultTokenTypeService,
+			issued:     "batch",
+			wantErr:    "login issued a batch token, but the store requires a service token: configure the role of the auth method with token_type=service",
+			wantLogins: 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			authFailures = map[string]authFailure{}
+			logins := 0
+			token := ""
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, &logins)
+			c.store.Auth.TokenType = tc.required
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					logins++
+					token = "kubernetes-token"
+					return &vault.Secret{}, nil
+				},
+			}
+			authToken := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					lookup := makeTokenLookup(time.Hour, tc.issued == "service")
+					lookup.Data["type"] = tc.issued
+					return lookup, nil
+				},
+				RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+					return nil
+				},
+			}
+			c.token = authToken
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				ClearTokenFunc:   func() { token = "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+				AuthTokenField:   authToken,
+			}
+
+			for range 2 {
+				err := c.setAuth(context.Background(), nil)
+				if tc.wantErr == "" && err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+			}
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, logins)
+			}
+			if token != tc.wantToken {
+				t.Errorf("expected token %q, got %q", tc.wantToken, token)
+			}
+		})
+	}
+}
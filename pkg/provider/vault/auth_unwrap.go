@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	vault "github.com/hashicorp/vault/api"
+
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	// vaultWrappingTokenInvalidMessage is returned by Vault when unwrapping
+	// a token that was already unwrapped or has expired.
+	vaultWrappingTokenInvalidMessage = "wrapping token is not valid or does not exist"
+
+	errWrappingTokenInvalid = "wrapping token in secret %q was already unwrapped or has expired, it has to be replaced: %w"
+)
+
+var (
+	// unwrappedMu is held while unwrapping, so that concurrent logins don't
+	// unwrap the same token twice.
+	unwrappedMu sync.Mutex
+	// unwrapped holds the values unwrapped from the wrapping tokens in the
+	// referenced Secrets, by the namespace, name and key of the Secret.
+	unwrapped = map[string]unwrappedValue{}
+)
+
+// unwrappedValue is a value unwrapped from a wrapping token.
+type unwrappedValue struct {
+	wrappingToken string
+	value         string
+}
+
+// unwrapSecretValue returns the value wrapped by the token read from the
+// Secret, which extract gets from the unwrapped response. As wrapping tokens
+// can be unwrapped only once, the value is reused until the Secret holds
+// another token. The unwrap is recorded as call in the API metrics.
+func (c *client) unwrapSecretValue(ctx context.Context, ref *esmeta.SecretKeySelector, wrappingToken, call string, extract func(*vault.Secret) (string, error)) (string, error) {
+	key := c.secretRefNamespace(ref) + "/" + ref.Name + "/" + ref.Key
+	unwrappedMu.Lock()
+	defer unwrappedMu.Unlock()
+	if u, ok := unwrapped[key]; ok && u.wrappingToken == wrappingToken {
+		return u.value, nil
+	}
+
+	// https://developer.hashicorp.com/vault/api-docs/system/wrapping-unwrap
+	resp, err := c.logical.UnwrapWithContext(ctx, wrappingToken)
+	metrics.ObserveAPICall(constants.ProviderHCVault, call, err)
+	var respErr *vault.ResponseError
+	if errors.As(err, &respErr) && responseContains(respErr, vaultWrappingTokenInvalidMessage) {
+		return "", fmt.Errorf(errWrappingTokenInvalid, ref.Name, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	value, err := extract(resp)
+	if err != nil {
+		return "", err
+	}
+	unwrapped[key] = unwrappedValue{wrappingToken: wrappingToken, value: value}
+	return value, nil
+}
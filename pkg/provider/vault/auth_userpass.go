@@ -16,8 +16,12 @@ package vault
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"strings"
 
+	"github.com/PaesslerAG/jsonpath"
+	vault "github.com/hashicorp/vault/api"
 	authuserpass "github.com/hashicorp/vault/api/auth/userpass"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
@@ -26,6 +30,10 @@ import (
 	"github.com/external-secrets/external-secrets/pkg/utils/resolvers"
 )
 
+const (
+	errUserPassCredentials = "cannot read the userpass credentials from key %q of secret %q: %w"
+)
+
 func setUserPassAuthToken(ctx context.Context, v *client) (bool, error) {
 	userPassAuth := v.store.Auth.UserPass
 	if userPassAuth != nil {
@@ -39,20 +47,65 @@ func setUserPassAuthToken(ctx context.Context, v *client) (bool, error) {
 }
 
 func (c *client) requestTokenWithUserPassAuth(ctx context.Context, userPassAuth *esv1.VaultUserPassAuth) error {
+	if err := c.checkLoginSecrets(ctx, &userPassAuth.SecretRef); err != nil {
+		return err
+	}
 	username := strings.TrimSpace(userPassAuth.Username)
 	password, err := resolvers.SecretKeyRef(ctx, c.kube, c.storeKind, c.namespace, &userPassAuth.SecretRef)
 	if err != nil {
 		return err
 	}
+	if paths := userPassAuth.JSONPaths; paths != nil {
+		username, password, err = userPassCredentialsFromJSON(paths, username, password)
+		if err != nil {
+			return fmt.Errorf(errUserPassCredentials, userPassAuth.SecretRef.Key, userPassAuth.SecretRef.Name, err)
+		}
+	}
 	pass := authuserpass.Password{FromString: password}
 	l, err := authuserpass.NewUserpassAuth(username, &pass, authuserpass.WithMountPath(userPassAuth.Path))
 	if err != nil {
 		return err
 	}
-	_, err = c.auth.Login(ctx, l)
-	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, err)
+	var resp *vault.Secret
+	err = c.retryLogin(ctx, authMethodUserPass, func() error {
+		var loginErr error
+		resp, loginErr = c.login(ctx, l)
+		metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLogin, loginErr)
+		return loginErr
+	})
+	return c.checkLogin(ctx, resp, err)
+}
+
+// userPassCredentialsFromJSON returns the username and password at the
+// JSON paths of the Secret value. The username is kept if it has no path.
+func userPassCredentialsFromJSON(paths *esv1.VaultUserPassJSONPaths, username, value string) (string, string, error) {
+	var data any
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return "", "", fmt.Errorf("value is not valid JSON: %w", err)
+	}
+	if paths.Username != "" {
+		u, err := userPassJSONValue(paths.Username, data)
+		if err != nil {
+			return "", "", err
+		}
+		username = strings.TrimSpace(u)
+	}
+	password, err := userPassJSONValue(paths.Password, data)
 	if err != nil {
-		return err
+		return "", "", err
+	}
+	return username, password, nil
+}
+
+// userPassJSONValue returns the string at the JSON path of data.
+func userPassJSONValue(path string, data any) (string, error) {
+	v, err := jsonpath.Get(path, data)
+	if err != nil {
+		return "", fmt.Errorf("cannot get %s: %w", path, err)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s is not a string, got %T", path, v)
 	}
-	return nil
+	return s, nil
 }
@@ -0,0 +1,153 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+)
+
+func TestUserPassJSONPaths(t *testing.T) {
+	cases := map[string]struct {
+		username  string
+		value     string
+		jsonPaths *esv1.VaultUserPassJSONPaths
+		// wantLogins are the usernames and passwords of the logins.
+		wantLogins []string
+		wantErr    string
+	}{
+		"DirectKey": {
+			username:   "eso",
+			value:      `{"password":"not-parsed"}`,
+			wantLogins: []string{`eso:{"password":"not-parsed"}`},
+		},
+		"JSON": {
+			value:      `{"username":"eso","password":"s3cr3t"}`,
+			jsonPaths:  &esv1.VaultUserPassJSONPaths{Username: "$.username", Password: "$.password"},
+			wantLogins: []string{"eso:s3cr3t"},
+		},
+		"Nested": {
+			value:      `{"vault":{"user":" eso ","credentials":[{"password":"s3cr3t"}]}}`,
+			jsonPaths:  &esv1.VaultUserPassJSONPaths{Username: "$.vault.user", Password: "$.vault.credentials[0].password"},
+			wantLogins: []string{"eso:s3cr3t"},
+		},
+		// the username of the store is used unless it has a path.
+		"PasswordOnly": {
+			username:   "eso",
+			value:      `{"username":"other","password":"s3cr3t"}`,
+			jsonPaths:  &esv1.VaultUserPassJSONPaths{Password: "$.password"},
+			wantLogins: []string{"eso:s3cr3t"},
+		},
+		"NotJSON": {
+			username:  "eso",
+			value:     "s3cr3t",
+			jsonPaths: &esv1.VaultUserPassJSONPaths{Password: "$.password"},
+			wantErr:   `cannot read the userpass credentials from key "credentials" of secret "userpass-secret": value is not valid JSON`,
+		},
+		"MissingKey": {
+			value:     `{"user":"eso","password":"s3cr3t"}`,
+			jsonPaths: &esv1.VaultUserPassJSONPaths{Username: "$.username", Password: "$.password"},
+			wantErr:   "cannot get $.username: unknown key username",
+		},
+		"NotAString": {
+			username:  "eso",
+			value:     `{"password":1234}`,
+			jsonPaths: &esv1.VaultUserPassJSONPaths{Password: "$.password"},
+			wantErr:   "$.password is not a string, got float64",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var logins []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				username, ok := strings.CutPrefix(r.URL.Path, "/v1/auth/userpass/login/")
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				var body map[string]string
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				logins = append(logins, username+":"+body["password"])
+				_, _ = w.Write([]byte(`{"auth": {"client_token": "userpass-token"}}`))
+			}))
+			defer server.Close()
+
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "userpass-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{
+					"credentials": []byte(tc.value),
+				},
+			}).Build()
+			store := &esv1.SecretStore{
+				TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vault-store",
+					Namespace: "default",
+				},
+				Spec: esv1.SecretStoreSpec{
+					RetrySettings: &esv1.SecretStoreRetrySettings{MaxRetries: ptr.To(int32(0))},
+					Provider: &esv1.SecretStoreProvider{
+						Vault: &esv1.VaultProvider{
+							Server:  server.URL,
+							Version: esv1.VaultKVStoreV2,
+							Auth: &esv1.VaultAuth{
+								UserPass: &esv1.VaultUserPassAuth{
+									Path:      "userpass",
+									Username:  tc.username,
+									SecretRef: esmeta.SecretKeySelector{Name: "userpass-secret", Key: "credentials"},
+									JSONPaths: tc.jsonPaths,
+								},
+							},
+						},
+					},
+				},
+			}
+			prov := &Provider{NewVaultClient: NewVaultClient}
+			c, err := prov.newClient(context.Background(), store, kube, nil, "default")
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.wantLogins, logins); diff != "" {
+				t.Errorf("unexpected logins: -want, +got:\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+			if token := c.(*client).client.Token(); token != "userpass-token" {
+				t.Errorf("expected token %q, got %q", "userpass-token", token)
+			}
+		})
+	}
+}
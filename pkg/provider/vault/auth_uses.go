@@ -0,0 +1,120 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/find"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errTokenUsesInsufficient = "vault token has %d uses left, but the batch needs %d"
+	errTokenUsesLookup       = "cannot look up the uses left on the vault token: %w"
+)
+
+// findUses estimates the number of requests reading the candidates of a
+// find takes: one read for each matching name, or a metadata read and a
+// read for each candidate of a find by tags.
+func findUses(candidates []string, ref esv1.ExternalSecretFind) int {
+	if ref.Name == nil {
+		return 2 * len(candidates)
+	}
+	matcher, err := find.New(*ref.Name)
+	if err != nil {
+		// the find fails before reading anything.
+		return 0
+	}
+	uses := 0
+	for _, name := range candidates {
+		if matcher.MatchName(name) {
+			uses++
+		}
+	}
+	return uses
+}
+
+// checkBatchUses checks that the token reading the secrets below the path
+// has the uses a batch of reads needs, if the store asks for it. A token
+// with too few uses left is replaced by a new login, or fails the batch.
+func (c *client) checkBatchUses(ctx context.Context, path string, needed int) error {
+	url, err := c.buildMetadataPath(path)
+	if err != nil {
+		return err
+	}
+	scoped, err := c.clientFor(ctx, url)
+	if err != nil {
+		return err
+	}
+	return scoped.checkTokenUses(ctx, needed)
+}
+
+func (c *client) checkTokenUses(ctx context.Context, needed int) error {
+	// override and external tokens are provided as is and can't be replaced.
+	if c.store.Auth == nil || c.store.Auth.TokenUsesCheck == "" || c.authOverride != nil || c.externalToken != nil || needed == 0 {
+		return nil
+	}
+	remaining, limited, err := c.remainingTokenUses(ctx)
+	if err != nil {
+		return err
+	}
+	if !limited || remaining >= needed {
+		return nil
+	}
+	if c.store.Auth.TokenUsesCheck == esv1.VaultTokenUsesCheckFail {
+		return fmt.Errorf(errTokenUsesInsufficient, remaining, needed)
+	}
+
+	c.log.V(1).Info("re-authenticating before a batch the token has too few uses left for", "remaining", remaining, "needed", needed)
+	forgetLease(c.client.Token())
+	c.client.ClearToken()
+	if err := c.setAuth(ctx, c.config); err != nil {
+		return err
+	}
+	// the uses of a new token are known if the store sets them, which saves
+	// a lookup.
+	if numUses := c.store.Auth.TokenNumUses; numUses != nil && *numUses > 0 {
+		remaining, limited = *numUses, true
+	} else if remaining, limited, err = c.remainingTokenUses(ctx); err != nil {
+		return err
+	}
+	if limited && remaining < needed {
+		return fmt.Errorf(errTokenUsesInsufficient, remaining, needed)
+	}
+	return nil
+}
+
+// remainingTokenUses returns the number of uses left on the token, and
+// false if its uses are unlimited. Tokens with few enough uses that a
+// lookup would burn one of them are assumed to be used up.
+func (c *client) remainingTokenUses(ctx context.Context) (int, bool, error) {
+	if c.limitedUseToken() {
+		return 0, true, nil
+	}
+	resp, err := c.tokenAPI().LookupSelfWithContext(ctx)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultLookupSelf, err)
+	if err != nil {
+		return 0, false, fmt.Errorf(errTokenUsesLookup, err)
+	}
+	uses, err := resp.TokenRemainingUses()
+	if err != nil {
+		return 0, false, fmt.Errorf(errTokenUsesLookup, err)
+	}
+	return uses, uses > 0, nil
+}
@@ -0,0 +1,168 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	"k8s.io/utils/ptr"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestGetAllSecretsTokenUses(t *testing.T) {
+	cases := map[string]struct {
+		check   esv1.VaultTokenUsesCheck
+		numUses *int
+		// uses are the uses left on the tokens according to their lookups.
+		uses        map[string]int
+		wantLookups int
+		wantLogins  int
+		// wantReads are the tokens the secrets are read with.
+		wantReads []string
+		wantErr   string
+	}{
+		"Disabled": {
+			uses:      map[string]int{"current-token": 1},
+			wantReads: []string{"current-token", "current-token"},
+		},
+		"EnoughUses": {
+			check:       esv1.VaultTokenUsesCheckRelogin,
+			uses:        map[string]int{"current-token": 2},
+			wantLookups: 1,
+			wantReads:   []string{"current-token", "current-token"},
+		},
+		"UnlimitedUses": {
+			check:       esv1.VaultTokenUsesCheckFail,
+			uses:        map[string]int{"current-token": 0},
+			wantLookups: 1,
+			wantReads:   []string{"current-token", "current-token"},
+		},
+		// the token is replaced before the first read.
+		"Relogin": {
+			check:       esv1.VaultTokenUsesCheckRelogin,
+			uses:        map[string]int{"current-token": 1, "new-token": 10},
+			wantLookups: 2,
+			wantLogins:  1,
+			wantReads:   []string{"new-token", "new-token"},
+		},
+		"ReloginTooFewUses": {
+			check:       esv1.VaultTokenUsesCheckRelogin,
+			uses:        map[string]int{"current-token": 1, "new-token": 1},
+			wantLookups: 2,
+			wantLogins:  1,
+			wantErr:     "vault token has 1 uses left, but the batch needs 2",
+		},
+		// the uses of new tokens are known without a lookup.
+		"ReloginNumUses": {
+			check:       esv1.VaultTokenUsesCheckRelogin,
+			numUses:     ptr.To(5),
+			uses:        map[string]int{"current-token": 1},
+			wantLookups: 1,
+			wantLogins:  1,
+			wantReads:   []string{"new-token", "new-token"},
+		},
+		// limited-use tokens are assumed to be used up instead of burning
+		// a use on a lookup.
+		"ReloginLimitedUseToken": {
+			check:      esv1.VaultTokenUsesCheckRelogin,
+			numUses:    ptr.To(2),
+			wantLogins: 1,
+			wantReads:  []string{"new-token", "new-token"},
+		},
+		"Fail": {
+			check:       esv1.VaultTokenUsesCheckFail,
+			uses:        map[string]int{"current-token": 1},
+			wantLookups: 1,
+			wantErr:     "vault token has 1 uses left, but the batch needs 2",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			token := "current-token"
+			lookups, logins := 0, 0
+			var reads []string
+			c := makeKubernetesAuthClient(t, makeServiceAccountJWT(t, jwt.MapClaims{}), &esv1.VaultKubernetesAuth{
+				Path: "kubernetes",
+				Role: "kubernetes-auth-role",
+			}, &logins)
+			c.store.Path = ptr.To("secret")
+			c.store.Version = esv1.VaultKVStoreV2
+			c.store.Auth.TokenUsesCheck = tc.check
+			c.store.Auth.TokenNumUses = tc.numUses
+			c.auth = fake.Auth{
+				LoginFn: func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+					logins++
+					token = "new-token"
+					return &vault.Secret{}, nil
+				},
+			}
+			c.token = fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					lookups++
+					lookup := makeTokenLookup(time.Hour, false)
+					lookup.Data["num_uses"] = json.Number(strconv.Itoa(tc.uses[token]))
+					return lookup, nil
+				},
+			}
+			c.client = &util.VaultClient{
+				TokenFunc:        func() string { return token },
+				SetTokenFunc:     func(v string) { token = v },
+				ClearTokenFunc:   func() { token = "" },
+				NamespaceFunc:    func() string { return "" },
+				SetNamespaceFunc: func(string) {},
+			}
+			c.logical = &fake.Logical{
+				ListWithContextFn: func(ctx context.Context, path string) (*vault.Secret, error) {
+					return &vault.Secret{Data: map[string]any{"keys": []any{"secret1", "secret2", "other"}}}, nil
+				},
+				ReadWithDataWithContextFn: func(ctx context.Context, path string, data map[string][]string) (*vault.Secret, error) {
+					reads = append(reads, token)
+					return &vault.Secret{Data: map[string]any{"data": map[string]any{"key": "value"}}}, nil
+				},
+			}
+
+			_, err := c.GetAllSecrets(context.Background(), esv1.ExternalSecretFind{
+				Name: &esv1.FindName{RegExp: "secret.*"},
+			})
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+			if lookups != tc.wantLookups {
+				t.Errorf("expected %d lookups, got %d", tc.wantLookups, lookups)
+			}
+			if logins != tc.wantLogins {
+				t.Errorf("expected %d logins, got %d", tc.wantLogins, logins)
+			}
+			if diff := cmp.Diff(tc.wantReads, reads); diff != "" {
+				t.Errorf("unexpected reads (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
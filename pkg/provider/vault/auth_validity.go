@@ -0,0 +1,92 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+// tokenValidity is the result of a lookup of a valid token.
+type tokenValidity struct {
+	tokenLease
+	checked time.Time
+}
+
+var (
+	// tokenValidityCacheTTL is how long the result of a token lookup is
+	// shared between clients. Disabled if zero.
+	tokenValidityCacheTTL time.Duration
+
+	tokenValiditiesMu sync.Mutex
+	tokenValidities   = map[string]tokenValidity{}
+)
+
+// checkTokenShared checks whether the current token is valid, reusing the
+// result of a lookup done by any client within tokenValidityCacheTTL.
+// For expirable tokens, a reconcile is requested for when the token has to
+// be replaced, so that the re-auth happens then rather than inline in a
+// request that could still use the token.
+func (c *client) checkTokenShared(ctx context.Context) (tokenState, error) {
+	token := c.client.Token()
+	tokenValiditiesMu.Lock()
+	validity, ok := tokenValidities[tokenKey(token)]
+	tokenValiditiesMu.Unlock()
+
+	if ok && time.Since(validity.checked) < tokenValidityCacheTTL {
+		if validity.expiresWithin(c.expiryThreshold()) {
+			forgetValidity(token)
+			if validity.renewable {
+				return tokenExpiring, nil
+			}
+			return tokenInvalid, nil
+		}
+		c.log.V(1).Info("Using cached token lookup result")
+	} else {
+		state, lease, err := lookupToken(ctx, c.tokenAPI(), c.expiryThreshold())
+		if err != nil || state != tokenValid {
+			forgetValidity(token)
+			return state, err
+		}
+		validity = tokenValidity{tokenLease: lease, checked: time.Now()}
+		storeValidity(token, validity)
+	}
+
+	if !validity.expiry.IsZero() {
+		esv1.RequestRequeue(ctx, time.Until(validity.expiry)-c.expiryThreshold())
+	}
+	return tokenValid, nil
+}
+
+func storeValidity(token string, validity tokenValidity) {
+	tokenValiditiesMu.Lock()
+	defer tokenValiditiesMu.Unlock()
+	for key, v := range tokenValidities {
+		if v.expiresWithin(0) || time.Since(v.checked) >= tokenValidityCacheTTL {
+			delete(tokenValidities, key)
+		}
+	}
+	tokenValidities[tokenKey(token)] = validity
+}
+
+// forgetValidity drops the lookup result of a token that is no longer used.
+func forgetValidity(token string) {
+	tokenValiditiesMu.Lock()
+	defer tokenValiditiesMu.Unlock()
+	delete(tokenValidities, tokenKey(token))
+}
@@ -0,0 +1,109 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestCheckTokenShared(t *testing.T) {
+	defer func(ttl time.Duration) { tokenValidityCacheTTL = ttl }(tokenValidityCacheTTL)
+
+	cases := map[string]struct {
+		cacheTTL time.Duration
+		lookup   *vault.Secret
+		cached   *tokenValidity
+		want     renewCounters
+		// wantRequeue is the expected requeue, with a second of tolerance.
+		wantRequeue time.Duration
+	}{
+		"Disabled": {
+			lookup: makeTokenLookup(time.Hour, true),
+			want:   renewCounters{lookups: 2},
+		},
+		"SharedBetweenClients": {
+			cacheTTL:    time.Minute,
+			lookup:      makeTokenLookup(time.Hour, true),
+			want:        renewCounters{lookups: 1},
+			wantRequeue: time.Hour - tokenExpiryThreshold,
+		},
+		"NonExpirableToken": {
+			cacheTTL: time.Minute,
+			lookup: &vault.Secret{Data: map[string]any{
+				"expire_time": nil,
+				"ttl":         json.Number("0"),
+				"type":        "service",
+			}},
+			want: renewCounters{lookups: 1},
+		},
+		"StaleResult": {
+			cacheTTL:    time.Minute,
+			lookup:      makeTokenLookup(time.Hour, true),
+			cached:      &tokenValidity{checked: time.Now().Add(-2 * time.Minute)},
+			want:        renewCounters{lookups: 1},
+			wantRequeue: time.Hour - tokenExpiryThreshold,
+		},
+		"ExpiringToken": {
+			cacheTTL: time.Minute,
+			lookup:   makeTokenLookup(time.Hour, true),
+			cached: &tokenValidity{
+				tokenLease: tokenLease{expiry: time.Now().Add(30 * time.Second)},
+				checked:    time.Now(),
+			},
+			// the first client re-authenticates in the reconcile scheduled for
+			// the expiry, the second one no longer finds the cached result.
+			want:        renewCounters{lookups: 1, logins: 1},
+			wantRequeue: time.Hour - tokenExpiryThreshold,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tokenValidityCacheTTL = tc.cacheTTL
+			t.Cleanup(func() { tokenValidities = map[string]tokenValidity{} })
+			counters := renewCounters{}
+			if tc.cached != nil {
+				tokenValidities[tokenKey("current-token")] = *tc.cached
+			}
+
+			ctx, hint := esv1.ContextWithRequeueHint(context.Background())
+			wantRequeue := tc.wantRequeue
+			for i := range 2 {
+				c := makeRenewClient(t, tc.lookup, nil, &counters)
+				if i == 0 && wantRequeue > 0 {
+					// the reconcile is scheduled ahead by the jitter of the token.
+					wantRequeue -= c.expiryJitter()
+				}
+				if err := c.setAuth(ctx, nil); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if counters != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, counters)
+			}
+			after, ok := hint.After()
+			if ok != (wantRequeue > 0) || (after-wantRequeue).Abs() > time.Second {
+				t.Errorf("expected requeue after %s, got %s", wantRequeue, after)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errVaultServerVersion    = "cannot determine Vault server version: %w"
+	errVaultServerVersionOld = "vault server version %s is below version %s required by %s"
+)
+
+const (
+	serverVersionCheckError = "error"
+)
+
+var (
+	// serverVersionCheck is empty if the check is disabled. Outdated
+	// servers are only logged unless it is set to "error".
+	serverVersionCheck string
+	minServerVersion   string
+
+	// serverVersions caches the version per server address, so that it is
+	// only queried on the first login against a server.
+	serverVersions sync.Map
+)
+
+// versionedFeature is a feature of the store that requires a minimum
+// Vault server version.
+type versionedFeature struct {
+	name       string
+	minVersion string
+	inUse      func(store *esv1.VaultProvider) bool
+}
+
+var versionedFeatures = []versionedFeature{
+	{
+		name:       "readYourWrites",
+		minVersion: "1.7.0",
+		inUse:      func(store *esv1.VaultProvider) bool { return store.ReadYourWrites },
+	},
+	{
+		name:       "forwardInconsistent",
+		minVersion: "1.7.0",
+		inUse:      func(store *esv1.VaultProvider) bool { return store.ForwardInconsistent },
+	},
+}
+
+// checkServerVersion compares the Vault server version with the minimum
+// versions required by the features in use. Depending on
+// serverVersionCheck, an outdated server is logged or returned as error.
+func (c *client) checkServerVersion(ctx context.Context) error {
+	if serverVersionCheck == "" {
+		return nil
+	}
+	version, err := c.serverVersion(ctx)
+	if err != nil {
+		// not being able to check the version shouldn't block the login.
+		c.log.Error(err, "unable to check Vault server version")
+		return nil
+	}
+
+	required := make([]versionedFeature, 0, len(versionedFeatures)+1)
+	if minServerVersion != "" {
+		required = append(required, versionedFeature{name: "--vault-min-server-version", minVersion: minServerVersion})
+	}
+	for _, feature := range versionedFeatures {
+		if feature.inUse(c.store) {
+			required = append(required, feature)
+		}
+	}
+	for _, feature := range required {
+		minVersion, err := semver.NewVersion(feature.minVersion)
+		if err != nil {
+			return fmt.Errorf("invalid minimum Vault server version %q: %w", feature.minVersion, err)
+		}
+		if !version.LessThan(minVersion) {
+			continue
+		}
+		err = fmt.Errorf(errVaultServerVersionOld, version, minVersion, feature.name)
+		if serverVersionCheck == serverVersionCheckError {
+			return err
+		}
+		c.log.Info("Vault server version is below the required version", "error", err.Error())
+	}
+	return nil
+}
+
+// serverVersion returns the version reported by the sys/health endpoint.
+func (c *client) serverVersion(ctx context.Context) (*semver.Version, error) {
+	if v, ok := serverVersions.Load(c.store.Server + c.store.BasePath); ok {
+		return v.(*semver.Version), nil
+	}
+
+	// sys/health is only served from the root namespace.
+	// https://developer.hashicorp.com/vault/api-docs/system/health
+	resp, err := c.client.WithNamespace("").Sys().HealthWithContext(ctx)
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultHealth, err)
+	if err != nil {
+		return nil, fmt.Errorf(errVaultServerVersion, err)
+	}
+	if resp == nil || resp.Version == "" {
+		return nil, fmt.Errorf(errVaultServerVersion, errors.New("no version in health response"))
+	}
+	version, err := semver.NewVersion(resp.Version)
+	if err != nil {
+		return nil, fmt.Errorf(errVaultServerVersion, err)
+	}
+	serverVersions.Store(c.store.Server+c.store.BasePath, version)
+	return version, nil
+}
@@ -0,0 +1,133 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestCheckServerVersion(t *testing.T) {
+	defer func(check, minVersion string) {
+		serverVersionCheck = check
+		minServerVersion = minVersion
+	}(serverVersionCheck, minServerVersion)
+
+	cases := map[string]struct {
+		check          string
+		minVersion     string
+		serverVersion  string
+		healthErr      error
+		readYourWrites bool
+		wantErr        error
+		wantHealth     int
+	}{
+		"Disabled": {
+			serverVersion:  "1.6.0",
+			readYourWrites: true,
+		},
+		"AboveFeatureMinimum": {
+			check:          serverVersionCheckError,
+			serverVersion:  "1.15.2+ent",
+			readYourWrites: true,
+			wantHealth:     1,
+		},
+		"BelowFeatureMinimum": {
+			check:          serverVersionCheckError,
+			serverVersion:  "1.6.3",
+			readYourWrites: true,
+			wantErr:        fmt.Errorf(errVaultServerVersionOld, "1.6.3", "1.7.0", "readYourWrites"),
+			wantHealth:     1,
+		},
+		"BelowFeatureMinimumWarnOnly": {
+			check:          "warn",
+			serverVersion:  "1.6.3",
+			readYourWrites: true,
+			wantHealth:     1,
+		},
+		"BelowMinimumOfUnusedFeature": {
+			check:         serverVersionCheckError,
+			serverVersion: "1.6.3",
+			wantHealth:    1,
+		},
+		"BelowConfiguredMinimum": {
+			check:         serverVersionCheckError,
+			minVersion:    "1.14.0",
+			serverVersion: "1.13.9",
+			wantErr:       fmt.Errorf(errVaultServerVersionOld, "1.13.9", "1.14.0", "--vault-min-server-version"),
+			wantHealth:    1,
+		},
+		"HealthCheckFails": {
+			check:          serverVersionCheckError,
+			healthErr:      errors.New("connection refused"),
+			readYourWrites: true,
+			wantHealth:     2,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			serverVersionCheck = tc.check
+			minServerVersion = tc.minVersion
+			health := 0
+			sys := fake.Sys{
+				HealthWithContextFn: func(ctx context.Context) (*vault.HealthResponse, error) {
+					health++
+					if tc.healthErr != nil {
+						return nil, tc.healthErr
+					}
+					return &vault.HealthResponse{Version: tc.serverVersion}, nil
+				},
+			}
+			c := &client{
+				log: logger,
+				store: &esv1.VaultProvider{
+					Server:         "https://" + name + ".vault.example.com",
+					ReadYourWrites: tc.readYourWrites,
+				},
+				client: &util.VaultClient{
+					WithNamespaceFunc: func(namespace string) util.Client {
+						if namespace != "" {
+							t.Errorf("expected health check in the root namespace, got %q", namespace)
+						}
+						return &util.VaultClient{SysField: sys}
+					},
+				},
+			}
+
+			// the version is only queried once per server, unless the query fails.
+			for range 2 {
+				err := c.checkServerVersion(context.Background())
+				if tc.wantErr == nil && err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tc.wantErr != nil && (err == nil || err.Error() != tc.wantErr.Error()) {
+					t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+				}
+			}
+			if health != tc.wantHealth {
+				t.Errorf("expected %d health calls, got %d", tc.wantHealth, health)
+			}
+		})
+	}
+}
@@ -18,57 +18,88 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/go-logr/logr"
+	"github.com/hashicorp/go-retryablehttp"
 	vault "github.com/hashicorp/vault/api"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	kclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	vaultiamauth "github.com/external-secrets/external-secrets/pkg/provider/vault/iamauth"
 	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
-	"github.com/external-secrets/external-secrets/pkg/utils"
 	"github.com/external-secrets/external-secrets/pkg/utils/resolvers"
 )
 
 var _ esv1.SecretsClient = &client{}
 
 type client struct {
-	kube      kclient.Client
-	store     *esv1.VaultProvider
-	log       logr.Logger
-	corev1    typedcorev1.CoreV1Interface
-	client    util.Client
-	auth      util.Auth
-	logical   util.Logical
-	token     util.Token
-	namespace string
-	storeKind string
+	kube    kclient.Client
+	store   *esv1.VaultProvider
+	log     logr.Logger
+	corev1  typedcorev1.CoreV1Interface
+	client  util.Client
+	auth    util.Auth
+	logical util.Logical
+	token   util.Token
+	config  *vault.Config
+	// authOverride references a token used instead of the store's auth.
+	authOverride *esmeta.SecretKeySelector
+	// externalToken is a token managed outside of external-secrets that is
+	// used instead of the store's auth.
+	externalToken *ExternalToken
+	namespace     string
+	storeKind     string
+	storeName     string
+	storeUID      types.UID
+	// loginWarnings are the warnings returned by the last login.
+	loginWarnings []string
+	// authMethod is the auth method of the last login.
+	authMethod string
+	// loginAuth is the auth data of the token issued by the last login.
+	loginAuth *vault.SecretAuth
+	// suppliedToken is set if the token was supplied by the user through
+	// the TokenSecretRef or the token file rather than issued by a login.
+	suppliedToken bool
+	// tokenNamespace is the namespace the token was issued in, if it was
+	// obtained in an auth namespace. Nil if it is the client's namespace.
+	tokenNamespace *string
+	// discoveredNamespace is the namespace reported by the last login, see
+	// loginNamespace.
+	discoveredNamespace *string
+	// heldToken is the token the client holds a reference to, see
+	// acquireToken.
+	heldToken string
+	// mounts holds the clients of the store's MountAuth and WriteAuth, it
+	// is nil for clients logged in for a mount or for writes.
+	mounts *mountClients
+	// jwtProvider and stsProvider obtain the AWS credentials of IAM auth,
+	// see WithJWTProvider and WithSTSProvider.
+	jwtProvider util.JwtProviderFactory
+	stsProvider vaultiamauth.STSProvider
 }
 
 func (c *client) newConfig(ctx context.Context) (*vault.Config, error) {
 	cfg := vault.DefaultConfig()
 	cfg.Address = c.store.Server
 
+	reloaded := c.reloadedCA()
 	if len(c.store.CABundle) != 0 || c.store.CAProvider != nil {
-		caCertPool := x509.NewCertPool()
-		ca, err := utils.FetchCACertFromSource(ctx, utils.CreateCertOpts{
-			CABundle:   c.store.CABundle,
-			CAProvider: c.store.CAProvider,
-			StoreKind:  c.storeKind,
-			Namespace:  c.namespace,
-			Client:     c.kube,
-		})
+		var caCertPool *x509.CertPool
+		var err error
+		if reloaded != nil {
+			caCertPool, err = reloaded.load(ctx, c)
+		} else {
+			caCertPool, err = c.fetchCACertPool(ctx)
+		}
 		if err != nil {
 			return nil, err
 		}
-		ok := caCertPool.AppendCertsFromPEM(ca)
-		if !ok {
-			return nil, fmt.Errorf(errVaultCert, errors.New("failed to parse certificates from CertPool"))
-		}
 
 		if transport, ok := cfg.HttpClient.Transport.(*http.Transport); ok {
 			transport.TLSClientConfig.RootCAs = caCertPool
@@ -79,10 +110,29 @@ func (c *client) newConfig(ctx context.Context) (*vault.Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	c.configureRevocationCheck(cfg)
+	if reloaded != nil {
+		reloaded.verifyConnections(cfg)
+	}
+
+	if err := c.configureDialAddress(cfg); err != nil {
+		return nil, err
+	}
+	if err := configureUnixSocket(cfg); err != nil {
+		return nil, err
+	}
+	if err := configureBasePath(cfg, c.store.BasePath); err != nil {
+		return nil, err
+	}
+	if err := configureHTTPVersion(cfg, c.store.HTTPVersion); err != nil {
+		return nil, err
+	}
 
 	// If either read-after-write consistency feature is enabled, enable ReadYourWrites
 	cfg.ReadYourWrites = c.store.ReadYourWrites || c.store.ForwardInconsistent
 
+	cfg.Backoff = loginBackoff(retryablehttp.LinearJitterBackoff)
+
 	return cfg, nil
 }
 
@@ -118,13 +168,43 @@ func (c *client) configureClientTLS(ctx context.Context, cfg *vault.Config) erro
 }
 
 func (c *client) Close(ctx context.Context) error {
-	// Revoke the token if we have one set, it wasn't sourced from a TokenSecretRef,
-	// and token caching isn't enabled
-	if !enableCache && c.client.Token() != "" && c.store.Auth != nil && c.store.Auth.TokenSecretRef == nil {
-		err := revokeTokenIfValid(ctx, c.client)
+	// external tokens are managed by the embedding application.
+	if c.externalToken != nil {
+		c.externalToken.release(c)
+		return nil
+	}
+	if err := c.closeMounts(ctx); err != nil {
+		return err
+	}
+	inUse := c.releaseToken(ctx)
+	// cached tokens are reused by later clients.
+	if enableCache || inUse {
+		return nil
+	}
+	return c.revokeLoginToken(ctx)
+}
+
+// revokeLoginToken revokes the token if we have one set and it wasn't
+// sourced from a TokenSecretRef (unless requested), a token file or an auth
+// override, see providedToken.
+func (c *client) revokeLoginToken(ctx context.Context) error {
+	if c.client.Token() != "" && c.store.Auth != nil && c.authOverride == nil && !c.providedToken() {
+		// Limited-use tokens are revoked by Vault once their last use is
+		// consumed, and checking them before revoking would burn a use.
+		if c.limitedUseToken() {
+			forgetLease(c.client.Token())
+			return nil
+		}
+		forgetValidity(c.client.Token())
+		tokenClient := c.tokenClient()
+		err := revokeTokenIfValid(ctx, tokenClient, c.store.Auth.RevokeScope)
 		if err != nil {
 			return err
 		}
+		// the token is cleared on the client of the token's namespace.
+		if tokenClient.Token() == "" {
+			c.client.ClearToken()
+		}
 	}
 	return nil
 }
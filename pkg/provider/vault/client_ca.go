@@ -0,0 +1,155 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/utils"
+)
+
+const (
+	errVaultServerNoCertificate = "Vault server presented no certificate"
+)
+
+var (
+	// caReloadInterval is the minimum time between reads of the CA
+	// certificates of a CAProvider. Once set, transports verify the server
+	// against the certificates read last instead of those read when they
+	// were set up, so that long-lived clients follow a rotated CA. The CA
+	// is fixed per transport if zero.
+	caReloadInterval time.Duration
+
+	// reloadedCAs holds a *reloadedCA per CAProvider.
+	reloadedCAs sync.Map
+)
+
+// reloadedCA holds the CA certificates last read from a CAProvider, which
+// all transports verifying against the CAProvider share.
+type reloadedCA struct {
+	mu   sync.Mutex
+	read time.Time
+	pool atomic.Pointer[x509.CertPool]
+}
+
+// fetchCACertPool reads the CA certificates of the store.
+func (c *client) fetchCACertPool(ctx context.Context) (*x509.CertPool, error) {
+	caCertPool := x509.NewCertPool()
+	ca, err := utils.FetchCACertFromSource(ctx, utils.CreateCertOpts{
+		CABundle:   c.store.CABundle,
+		CAProvider: c.store.CAProvider,
+		StoreKind:  c.storeKind,
+		Namespace:  c.namespace,
+		Client:     c.kube,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ok := caCertPool.AppendCertsFromPEM(ca)
+	if !ok {
+		return nil, fmt.Errorf(errVaultCert, errors.New("failed to parse certificates from CertPool"))
+	}
+	return caCertPool, nil
+}
+
+// reloadedCA returns the reloaded CA certificates of the CAProvider of the
+// store, or nil if they aren't reloaded. A CABundle is part of the store
+// and never reloaded.
+func (c *client) reloadedCA() *reloadedCA {
+	provider := c.store.CAProvider
+	if caReloadInterval == 0 || len(c.store.CABundle) != 0 || provider == nil {
+		return nil
+	}
+	namespace := c.namespace
+	if c.storeKind == esv1.ClusterSecretStoreKind && provider.Namespace != nil {
+		namespace = *provider.Namespace
+	}
+	key := strings.Join([]string{c.storeKind, namespace, string(provider.Type), provider.Name, provider.Key}, "/")
+	ca, _ := reloadedCAs.LoadOrStore(key, &reloadedCA{})
+	return ca.(*reloadedCA)
+}
+
+// load returns the CA certificates, which are read again once the reload
+// interval passed since they were read last. The new certificates replace
+// the previous ones for all transports sharing them at once.
+func (r *reloadedCA) load(ctx context.Context, c *client) (*x509.CertPool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous := r.pool.Load()
+	if previous != nil && time.Since(r.read) < caReloadInterval {
+		return previous, nil
+	}
+	pool, err := c.fetchCACertPool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil && !previous.Equal(pool) {
+		c.log.Info("reloaded rotated Vault CA certificates", "caProvider", c.store.CAProvider.Name)
+	}
+	r.pool.Store(pool)
+	r.read = time.Now()
+	return pool, nil
+}
+
+// verifyConnections makes the transport verify the server certificate of
+// new connections against the reloaded CA certificates. Established
+// connections are left alone, they were verified when they were set up.
+// Further checks of the connection, like the revocation check, run on the
+// verified chains afterwards.
+func (r *reloadedCA) verifyConnections(cfg *vault.Config) {
+	transport, ok := cfg.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return
+	}
+	tlsConfig := transport.TLSClientConfig
+	next := tlsConfig.VerifyConnection
+	// the RootCAs are fixed for the transport, so the certificate is
+	// verified with the current CA certificates in VerifyConnection instead.
+	tlsConfig.InsecureSkipVerify = true //nolint:gosec // verified below
+	tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New(errVaultServerNoCertificate)
+		}
+		opts := x509.VerifyOptions{
+			Roots:         r.pool.Load(),
+			DNSName:       cs.ServerName,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		chains, err := cs.PeerCertificates[0].Verify(opts)
+		if err != nil {
+			return err
+		}
+		cs.VerifiedChains = chains
+		if next != nil {
+			return next(cs)
+		}
+		return nil
+	}
+}
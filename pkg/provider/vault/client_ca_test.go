@@ -0,0 +1,147 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestCAReload(t *testing.T) {
+	defer func(interval time.Duration) { caReloadInterval = interval }(caReloadInterval)
+	caReloadInterval = time.Hour
+
+	oldCA, oldKey := makeRevocationCA(t)
+	newCA, newKey := makeRevocationCA(t)
+	leaf := func(ca *x509.Certificate, key crypto.Signer) *tls.Certificate {
+		cert := &tls.Certificate{}
+		cert.Certificate, cert.PrivateKey = makeRevocationLeaf(t, ca, key, big.NewInt(2), "http://127.0.0.1")
+		return cert
+	}
+	var current atomic.Pointer[tls.Certificate]
+	current.Store(leaf(oldCA, oldKey))
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "ca-reload-token", "lease_duration": 3600}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	// the certificate is picked per handshake, so that the server can
+	// rotate it while connections are established.
+	server.Listener = tls.NewListener(server.Listener, &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return current.Load(), nil
+		},
+	})
+	server.Start()
+	defer server.Close()
+
+	caSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vault-ca", Namespace: "default"},
+		Data:       map[string][]byte{"ca.crt": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: oldCA.Raw})},
+	}
+	kube := clientfake.NewClientBuilder().WithObjects(caSecret, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "approle-secret", Namespace: "default"},
+		Data:       map[string][]byte{"secret-id": []byte("secret-id")},
+	}).Build()
+	auth := makeAppRoleAuth("ca-reload-role-id")
+	c := &client{
+		kube:      kube,
+		log:       logger,
+		namespace: "default",
+		storeKind: esv1.SecretStoreKind,
+		store: &esv1.VaultProvider{
+			Server: "https://" + server.Listener.Addr().String(),
+			CAProvider: &esv1.CAProvider{
+				Type: esv1.CAProviderTypeSecret,
+				Name: "vault-ca",
+				Key:  "ca.crt",
+			},
+			Auth: &auth,
+		},
+	}
+	ctx := context.Background()
+	cfg, err := c.newConfig(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// rejected certificates aren't retried.
+	cfg.MaxRetries = 0
+	// the client is kept like one of the token cache.
+	vaultClient, err := NewVaultClient(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	login := func() error {
+		vaultClient.ClearToken()
+		login := *c
+		login.client = vaultClient
+		login.auth = vaultClient.Auth()
+		login.logical = vaultClient.Logical()
+		login.token = vaultClient.AuthToken()
+		return login.setAuth(ctx, cfg)
+	}
+	if err := login(); err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+
+	// the server rotates its certificate to one of the new CA.
+	current.Store(leaf(newCA, newKey))
+	cfg.HttpClient.CloseIdleConnections()
+	if err := login(); err == nil || !strings.Contains(err.Error(), "certificate signed by unknown authority") {
+		t.Fatalf("expected the old CA to be rejected, got %v", err)
+	}
+
+	caSecret.Data["ca.crt"] = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: newCA.Raw})
+	if err := kube.Update(ctx, caSecret); err != nil {
+		t.Fatal(err)
+	}
+	// the CA is not read again within the reload interval.
+	if _, err := c.newConfig(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := login(); err == nil {
+		t.Fatal("expected the CA not to be reloaded within the interval")
+	}
+
+	caReloadInterval = time.Nanosecond
+	if _, err := c.newConfig(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the transport of the kept client now verifies against the new CA.
+	if err := login(); err != nil {
+		t.Fatalf("expected a login with the reloaded CA, got %v", err)
+	}
+}
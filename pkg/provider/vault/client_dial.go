@@ -0,0 +1,198 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+const (
+	errVaultDialAddress = "cannot use dial address for Vault server %q: %w"
+	errVaultUnixSocket  = "cannot connect to Vault server %q: %w"
+	errVaultBasePath    = "cannot use base path %q for Vault server %q: %w"
+	errVaultHTTPVersion = "cannot use HTTP version %q: %w"
+)
+
+// http2Proto is the ALPN protocol of HTTP/2 over TLS.
+const http2Proto = "h2"
+
+// unixSocketScheme is the scheme of Vault server addresses that are unix
+// domain sockets, e.g. of a Vault Agent sidecar.
+const unixSocketScheme = "unix://"
+
+// unixSocketHost is the address requests to a unix domain socket are sent to.
+// It only fills the Host header, the transport always dials the socket.
+const unixSocketHost = "http://localhost"
+
+// isUnixSocket returns whether the Vault server address is a unix domain
+// socket.
+func isUnixSocket(address string) bool {
+	return strings.HasPrefix(address, unixSocketScheme)
+}
+
+// configureUnixSocket makes the transport dial the unix domain socket of a
+// unix:// server address for all requests. Requests are sent over plain HTTP
+// to localhost, the socket is expected to be protected by its file
+// permissions instead.
+func configureUnixSocket(cfg *vault.Config) error {
+	if !isUnixSocket(cfg.Address) {
+		return nil
+	}
+	socket := strings.TrimPrefix(cfg.Address, unixSocketScheme)
+	if socket == "" {
+		return fmt.Errorf(errVaultUnixSocket, cfg.Address, errors.New("no socket path"))
+	}
+	transport, ok := cfg.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf(errVaultUnixSocket, cfg.Address, errors.New("unsupported transport"))
+	}
+	dialer := &net.Dialer{}
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", socket)
+	}
+	// the Vault client would replace the dialer with one of its own that
+	// ignores the context, if it sees the unix:// address.
+	cfg.Address = unixSocketHost
+	return nil
+}
+
+// configureDialAddress makes the transport connect to the dial address of
+// the store whenever it dials the Vault server. Requests still go to the
+// server URL, so the Host header and the TLS server name are unaffected.
+func (c *client) configureDialAddress(cfg *vault.Config) error {
+	if c.store.DialAddress == "" {
+		return nil
+	}
+	server, err := serverHostPort(cfg.Address)
+	if err != nil {
+		return fmt.Errorf(errVaultDialAddress, cfg.Address, err)
+	}
+	transport, ok := cfg.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf(errVaultDialAddress, cfg.Address, errors.New("unsupported transport"))
+	}
+
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	dialAddress := c.store.DialAddress
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// connections to anything else, like a proxy, are left alone.
+		if addr == server {
+			addr = dialAddress
+		}
+		return dial(ctx, network, addr)
+	}
+	return nil
+}
+
+// serverHostPort returns the host and port the transport dials for the
+// server address.
+func serverHostPort(address string) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", errors.New("server address has no host")
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	port := "443"
+	if u.Scheme == "http" {
+		port = "80"
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// configureBasePath prepends the base path to the path of the server
+// address, which the Vault client prefixes the path of every request with.
+// It has to be applied after configureUnixSocket, which would otherwise
+// take the base path for a part of the socket path.
+func configureBasePath(cfg *vault.Config, basePath string) error {
+	if basePath == "" {
+		return nil
+	}
+	u, err := url.Parse(cfg.Address)
+	if err != nil {
+		return fmt.Errorf(errVaultBasePath, basePath, cfg.Address, err)
+	}
+	u.Path = path.Join("/", u.Path, basePath)
+	cfg.Address = u.String()
+	return nil
+}
+
+// validateBasePath checks that the base path is a plain path, without the
+// /v1 prefix the Vault client adds to every request path on its own.
+func validateBasePath(basePath string) error {
+	if basePath == "" {
+		return nil
+	}
+	if strings.ContainsAny(basePath, "?#") {
+		return fmt.Errorf(errInvalidBasePath, basePath, errors.New("must not contain a query or fragment"))
+	}
+	if cleaned := path.Clean("/" + basePath); cleaned == "/v1" || strings.HasSuffix(cleaned, "/v1") {
+		return fmt.Errorf(errInvalidBasePath, basePath, errors.New("must not end with the /v1 API prefix"))
+	}
+	return nil
+}
+
+// configureHTTPVersion restricts the HTTP versions the transport negotiates
+// with the server over TLS, which supports both HTTP/1.1 and HTTP/2 by
+// default. Plain HTTP always uses HTTP/1.1.
+func configureHTTPVersion(cfg *vault.Config, version esv1.VaultHTTPVersion) error {
+	if version == "" {
+		return nil
+	}
+	transport, ok := cfg.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf(errVaultHTTPVersion, version, errors.New("unsupported transport"))
+	}
+	tlsConfig := transport.TLSClientConfig
+	switch version {
+	case esv1.VaultHTTPVersion1:
+		// a non-nil map without "h2" disables HTTP/2, while the server
+		// mustn't select it in the handshake either.
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		tlsConfig.NextProtos = slices.DeleteFunc(slices.Clone(tlsConfig.NextProtos), func(proto string) bool {
+			return proto == http2Proto
+		})
+	case esv1.VaultHTTPVersion2:
+		if _, ok := transport.TLSNextProto[http2Proto]; !ok {
+			return fmt.Errorf(errVaultHTTPVersion, version, errors.New("HTTP/2 is not configured on the transport"))
+		}
+		// the handshake fails with servers that don't support HTTP/2.
+		tlsConfig.NextProtos = []string{http2Proto}
+	default:
+		return fmt.Errorf(errVaultHTTPVersion, version, errors.New("unknown version"))
+	}
+	return nil
+}
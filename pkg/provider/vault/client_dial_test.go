@@ -0,0 +1,418 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+func TestDialAddress(t *testing.T) {
+	var host, serverName string
+	tunnel := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+		serverName = r.TLS.ServerName
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"initialized": true, "sealed": false, "version": "1.15.0"}`))
+	}))
+	defer tunnel.Close()
+
+	c := &client{
+		kube: clientfake.NewClientBuilder().Build(),
+		log:  logger,
+		store: &esv1.VaultProvider{
+			// the test certificate is valid for *.example.com.
+			Server:      "https://vault.example.com:8200",
+			DialAddress: tunnel.Listener.Addr().String(),
+			CABundle:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tunnel.Certificate().Raw}),
+		},
+		storeKind: esv1.SecretStoreKind,
+		namespace: "default",
+	}
+	cfg, err := c.newConfig(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vaultClient, err := vault.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := vaultClient.Sys().HealthWithContext(context.Background()); err != nil {
+		t.Fatalf("expected the request to go through the dial address: %v", err)
+	}
+	if host != "vault.example.com:8200" {
+		t.Errorf("expected Host header %q, got %q", "vault.example.com:8200", host)
+	}
+	if serverName != "vault.example.com" {
+		t.Errorf("expected TLS server name %q, got %q", "vault.example.com", serverName)
+	}
+}
+
+func TestServerHostPort(t *testing.T) {
+	cases := map[string]struct {
+		address string
+		want    string
+		wantErr bool
+	}{
+		"ExplicitPort": {address: "https://vault.example.com:8200", want: "vault.example.com:8200"},
+		"HTTPS":        {address: "https://vault.example.com", want: "vault.example.com:443"},
+		"HTTP":         {address: "http://vault.example.com", want: "vault.example.com:80"},
+		"IPv6":         {address: "https://[::1]:8200", want: "[::1]:8200"},
+		"NoScheme":     {address: "vault.example.com", wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := serverHostPort(tc.address)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("serverHostPort(%q) = %q, want %q", tc.address, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	// socket paths are limited to about 100 characters, so the socket
+	// isn't created in the longer t.TempDir().
+	dir, err := os.MkdirTemp("", "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	socket := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loginRoleID, readToken string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("cannot decode login request: %v", err)
+			}
+			loginRoleID = body["role_id"]
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "socket-token", "lease_duration": 3600}}`))
+		case "/v1/auth/token/lookup-self":
+			_, _ = w.Write([]byte(`{"data": {"type": "service", "ttl": 3600, "expire_time": null}}`))
+		case "/v1/secret/data/foo":
+			readToken = r.Header.Get("X-Vault-Token")
+			_, _ = w.Write([]byte(`{"data": {"data": {"value": "secret"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "approle-secret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"secret-id": []byte("secret-id"),
+		},
+	}).Build()
+	store := &esv1.SecretStore{
+		TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vault-store",
+			Namespace: "default",
+		},
+		Spec: esv1.SecretStoreSpec{
+			Provider: &esv1.SecretStoreProvider{
+				Vault: &esv1.VaultProvider{
+					Server:  "unix://" + socket,
+					Version: esv1.VaultKVStoreV2,
+					Auth:    ptr.To(makeAppRoleAuth("agent")),
+				},
+			},
+		},
+	}
+	prov := &Provider{NewVaultClient: NewVaultClient}
+	c, err := prov.newClient(context.Background(), store, kube, nil, "default")
+	if err != nil {
+		t.Fatalf("expected the login to go through the socket: %v", err)
+	}
+	secret, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "secret/foo", Property: "value"})
+	if err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	}
+
+	if loginRoleID != "agent" {
+		t.Errorf("expected login with role id %q, got %q", "agent", loginRoleID)
+	}
+	if readToken != "socket-token" {
+		t.Errorf("expected read with the token of the login, got %q", readToken)
+	}
+	if string(secret) != "secret" {
+		t.Errorf("expected secret %q, got %q", "secret", secret)
+	}
+}
+
+func TestConfigureUnixSocket(t *testing.T) {
+	cases := map[string]struct {
+		address     string
+		wantAddress string
+		wantErr     bool
+	}{
+		"Socket":   {address: "unix:///var/run/vault/agent.sock", wantAddress: unixSocketHost},
+		"NoSocket": {address: "unix://", wantErr: true},
+		"HTTPS":    {address: "https://vault.example.com:8200", wantAddress: "https://vault.example.com:8200"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := vault.DefaultConfig()
+			cfg.Address = tc.address
+			err := configureUnixSocket(cfg)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if !tc.wantErr && cfg.Address != tc.wantAddress {
+				t.Errorf("expected address %q, got %q", tc.wantAddress, cfg.Address)
+			}
+		})
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/vault/v1/auth/approle/login":
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "base-path-token", "lease_duration": 3600}}`))
+		case "/vault/v1/auth/token/lookup-self":
+			_, _ = w.Write([]byte(`{"data": {"type": "service", "ttl": 3600, "expire_time": null}}`))
+		case "/vault/v1/secret/data/foo":
+			_, _ = w.Write([]byte(`{"data": {"data": {"foo": "bar"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "approle-secret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"secret-id": []byte("secret-id"),
+		},
+	}).Build()
+	auth := makeAppRoleAuth("base-path-role-id")
+	store := &esv1.SecretStore{
+		TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vault-store",
+			Namespace: "default",
+		},
+		Spec: esv1.SecretStoreSpec{
+			Provider: &esv1.SecretStoreProvider{
+				Vault: &esv1.VaultProvider{
+					Server:   server.URL,
+					BasePath: "/vault/",
+					Version:  esv1.VaultKVStoreV2,
+					Auth:     &auth,
+				},
+			},
+		},
+	}
+	prov := &Provider{NewVaultClient: NewVaultClient}
+	sc, err := prov.newClient(context.Background(), store, kube, nil, "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := sc.(*client)
+	if _, err := c.GetSecret(context.Background(), esv1.ExternalSecretDataRemoteRef{Key: "secret/foo"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := checkToken(context.Background(), c.tokenAPI(), c.expiryThreshold()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"/vault/v1/auth/approle/login",
+		"/vault/v1/secret/data/foo",
+		"/vault/v1/auth/token/lookup-self",
+	}
+	if diff := cmp.Diff(want, paths); diff != "" {
+		t.Errorf("unexpected request paths (-want, +got):\n%s", diff)
+	}
+}
+
+func TestConfigureBasePath(t *testing.T) {
+	cases := map[string]struct {
+		address     string
+		basePath    string
+		wantAddress string
+	}{
+		"Unset":          {address: "https://vault.example.com:8200", wantAddress: "https://vault.example.com:8200"},
+		"Prefix":         {address: "https://proxy.example.com", basePath: "/vault/", wantAddress: "https://proxy.example.com/vault"},
+		"NoLeadingSlash": {address: "https://proxy.example.com", basePath: "vault", wantAddress: "https://proxy.example.com/vault"},
+		"ServerPath":     {address: "https://proxy.example.com/team-a/", basePath: "vault", wantAddress: "https://proxy.example.com/team-a/vault"},
+		"UnixSocket":     {address: unixSocketHost, basePath: "/vault", wantAddress: unixSocketHost + "/vault"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := vault.DefaultConfig()
+			cfg.Address = tc.address
+			if err := configureBasePath(cfg, tc.basePath); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Address != tc.wantAddress {
+				t.Errorf("expected address %q, got %q", tc.wantAddress, cfg.Address)
+			}
+		})
+	}
+}
+
+func TestConfigureHTTPVersion(t *testing.T) {
+	cases := map[string]struct {
+		version        esv1.VaultHTTPVersion
+		wantNextProtos []string
+		wantHTTP2      bool
+		wantErr        bool
+	}{
+		"Default": {
+			wantNextProtos: []string{"h2", "http/1.1"},
+			wantHTTP2:      true,
+		},
+		"HTTP1": {
+			version:        esv1.VaultHTTPVersion1,
+			wantNextProtos: []string{"http/1.1"},
+		},
+		"HTTP2": {
+			version:        esv1.VaultHTTPVersion2,
+			wantNextProtos: []string{"h2"},
+			wantHTTP2:      true,
+		},
+		"Unknown": {
+			version: "HTTP3",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := vault.DefaultConfig()
+			err := configureHTTPVersion(cfg, tc.version)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr {
+				return
+			}
+			transport := cfg.HttpClient.Transport.(*http.Transport)
+			if diff := cmp.Diff(tc.wantNextProtos, transport.TLSClientConfig.NextProtos); diff != "" {
+				t.Errorf("unexpected ALPN protocols (-want, +got):\n%s", diff)
+			}
+			if _, ok := transport.TLSNextProto["h2"]; ok != tc.wantHTTP2 {
+				t.Errorf("expected HTTP/2 on the transport: %t, got %t", tc.wantHTTP2, ok)
+			}
+		})
+	}
+}
+
+func TestHTTPVersion(t *testing.T) {
+	cases := map[string]struct {
+		version     esv1.VaultHTTPVersion
+		serverHTTP2 bool
+		wantProto   int
+		wantErr     bool
+	}{
+		"Default":            {serverHTTP2: true, wantProto: 2},
+		"DefaultWithoutH2":   {wantProto: 1},
+		"HTTP1":              {version: esv1.VaultHTTPVersion1, serverHTTP2: true, wantProto: 1},
+		"HTTP2":              {version: esv1.VaultHTTPVersion2, serverHTTP2: true, wantProto: 2},
+		"HTTP2WithoutServer": {version: esv1.VaultHTTPVersion2, wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			proto := 0
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proto = r.ProtoMajor
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"initialized": true, "sealed": false, "version": "1.15.0"}`))
+			}))
+			server.EnableHTTP2 = tc.serverHTTP2
+			server.StartTLS()
+			defer server.Close()
+
+			c := &client{
+				kube: clientfake.NewClientBuilder().Build(),
+				log:  logger,
+				store: &esv1.VaultProvider{
+					Server:      server.URL,
+					CABundle:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+					HTTPVersion: tc.version,
+				},
+				storeKind: esv1.SecretStoreKind,
+				namespace: "default",
+			}
+			cfg, err := c.newConfig(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			cfg.MaxRetries = 0
+			vaultClient, err := vault.NewClient(cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = vaultClient.Sys().HealthWithContext(context.Background())
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.wantErr, err)
+			}
+			if proto != tc.wantProto {
+				t.Errorf("expected HTTP/%d, got HTTP/%d", tc.wantProto, proto)
+			}
+		})
+	}
+}
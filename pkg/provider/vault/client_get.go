@@ -128,7 +128,11 @@ func (c *client) readSecret(ctx context.Context, path, version string) (map[stri
 		params = make(map[string][]string)
 		params["version"] = []string{version}
 	}
-	vaultSecret, err := c.logical.ReadWithDataWithContext(ctx, dataPath, params)
+	logical, err := c.logicalFor(ctx, dataPath)
+	if err != nil {
+		return nil, err
+	}
+	vaultSecret, err := logical.ReadWithDataWithContext(ctx, dataPath, params)
 	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultReadSecretData, err)
 	if err != nil {
 		return nil, fmt.Errorf(errReadSecret, err)
@@ -190,7 +194,11 @@ func (c *client) readSecretMetadata(ctx context.Context, path string) (map[strin
 	if err != nil {
 		return nil, err
 	}
-	secret, err := c.logical.ReadWithDataWithContext(ctx, url, nil)
+	logical, err := c.logicalFor(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := logical.ReadWithDataWithContext(ctx, url, nil)
 	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultReadSecretData, err)
 	if err != nil {
 		return nil, fmt.Errorf(errReadSecret, err)
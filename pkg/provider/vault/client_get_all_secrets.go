@@ -45,6 +45,9 @@ func (c *client) GetAllSecrets(ctx context.Context, ref esv1.ExternalSecretFind)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.checkBatchUses(ctx, searchPath, findUses(potentialSecrets, ref)); err != nil {
+		return nil, err
+	}
 	if ref.Name != nil {
 		return c.findSecretsFromName(ctx, potentialSecrets, *ref.Name)
 	}
@@ -112,7 +115,11 @@ func (c *client) listSecrets(ctx context.Context, path string) ([]string, error)
 	if err != nil {
 		return nil, err
 	}
-	secret, err := c.logical.ListWithContext(ctx, url)
+	logical, err := c.logicalFor(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := logical.ListWithContext(ctx, url)
 	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultListSecrets, err)
 	if err != nil {
 		return nil, fmt.Errorf(errReadSecret, err)
@@ -0,0 +1,60 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	vaultiamauth "github.com/external-secrets/external-secrets/pkg/provider/vault/iamauth"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+// ClientOption customizes the clients of a Provider, see
+// Provider.ClientOptions.
+type ClientOption func(*client)
+
+// WithJWTProvider obtains the AWS credentials of IAM auth for a service
+// account token with jwtProvider, instead of assuming the role of the
+// service account with AssumeRoleWithWebIdentity. It applies to IAM auth with
+// jwt and to IAM auth with the service account of the controller.
+func WithJWTProvider(jwtProvider util.JwtProviderFactory) ClientOption {
+	return func(c *client) {
+		c.jwtProvider = jwtProvider
+	}
+}
+
+// WithSTSProvider creates the STS client IAM auth assumes the role of
+// the store with, instead of one using the AWS session of the login.
+func WithSTSProvider(stsProvider vaultiamauth.STSProvider) ClientOption {
+	return func(c *client) {
+		c.stsProvider = stsProvider
+	}
+}
+
+// iamJWTProvider returns the JWT provider of IAM auth, which defaults to
+// vaultiamauth.DefaultJWTProvider.
+func (c *client) iamJWTProvider() util.JwtProviderFactory {
+	if c.jwtProvider == nil {
+		return vaultiamauth.DefaultJWTProvider
+	}
+	return c.jwtProvider
+}
+
+// iamSTSProvider returns the STS provider of IAM auth, which defaults to
+// vaultiamauth.DefaultSTSProvider.
+func (c *client) iamSTSProvider() vaultiamauth.STSProvider {
+	if c.stsProvider == nil {
+		return vaultiamauth.DefaultSTSProvider
+	}
+	return c.stsProvider
+}
@@ -0,0 +1,75 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errVaultUnreachable    = "cannot reach Vault server %q: %w"
+	errVaultUninitialized  = "Vault server %q is not initialized"
+	errVaultSealed         = "Vault server %q is sealed"
+	errVaultNoHealthStatus = "no health status in response"
+)
+
+// pingBeforeLogin checks that the Vault server is reachable and unsealed
+// before each login, so that a misconfigured address or a sealed server
+// isn't reported as a failed login.
+var pingBeforeLogin bool
+
+// sealedError is returned by Ping if the Vault server is sealed.
+type sealedError struct {
+	server string
+}
+
+func (e *sealedError) Error() string {
+	return fmt.Sprintf(errVaultSealed, e.server)
+}
+
+// IsSealed reports whether err is caused by a sealed Vault server.
+func IsSealed(err error) bool {
+	var sealedErr *sealedError
+	return errors.As(err, &sealedErr)
+}
+
+// Ping checks that the Vault server can be reached and serves requests,
+// without authenticating. Standby nodes pass, as they forward requests to
+// the active node, while sealed servers fail with an error IsSealed reports.
+func (c *client) Ping(ctx context.Context) error {
+	// sys/health is unauthenticated and only served from the root namespace.
+	// https://developer.hashicorp.com/vault/api-docs/system/health
+	resp, err := c.client.WithNamespace("").Sys().HealthWithContext(ctx)
+	if err == nil && resp == nil {
+		err = errors.New(errVaultNoHealthStatus)
+	}
+	switch {
+	case err != nil:
+		err = fmt.Errorf(errVaultUnreachable, c.store.Server, err)
+	case !resp.Initialized:
+		err = fmt.Errorf(errVaultUninitialized, c.store.Server)
+	case resp.Sealed:
+		err = &sealedError{server: c.store.Server}
+	case resp.Standby || resp.PerformanceStandby:
+		c.log.V(1).Info("Vault server is a standby node", "performanceStandby", resp.PerformanceStandby)
+	}
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultPing, err)
+	return err
+}
@@ -0,0 +1,251 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestPing(t *testing.T) {
+	cases := map[string]struct {
+		health     *vault.HealthResponse
+		healthErr  error
+		wantErr    string
+		wantSealed bool
+	}{
+		"Active": {
+			health: &vault.HealthResponse{Initialized: true},
+		},
+		"Standby": {
+			health: &vault.HealthResponse{Initialized: true, Standby: true},
+		},
+		"PerformanceStandby": {
+			health: &vault.HealthResponse{Initialized: true, PerformanceStandby: true},
+		},
+		"Sealed": {
+			health:     &vault.HealthResponse{Initialized: true, Sealed: true},
+			wantErr:    `Vault server "https://vault.example.com" is sealed`,
+			wantSealed: true,
+		},
+		"Uninitialized": {
+			health:  &vault.HealthResponse{Sealed: true},
+			wantErr: `Vault server "https://vault.example.com" is not initialized`,
+		},
+		"Unreachable": {
+			healthErr: errors.New("connection refused"),
+			wantErr:   `cannot reach Vault server "https://vault.example.com": connection refused`,
+		},
+		"NoResponse": {
+			wantErr: `cannot reach Vault server "https://vault.example.com": no health status in response`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			sys := fake.Sys{
+				HealthWithContextFn: func(ctx context.Context) (*vault.HealthResponse, error) {
+					return tc.health, tc.healthErr
+				},
+			}
+			c := &client{
+				log:   logger,
+				store: &esv1.VaultProvider{Server: "https://vault.example.com"},
+				client: &util.VaultClient{
+					WithNamespaceFunc: func(namespace string) util.Client {
+						if namespace != "" {
+							t.Errorf("expected ping in the root namespace, got %q", namespace)
+						}
+						return &util.VaultClient{SysField: sys}
+					},
+				},
+			}
+
+			err := c.Ping(context.Background())
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || err.Error() != tc.wantErr) {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+			if IsSealed(err) != tc.wantSealed {
+				t.Errorf("expected IsSealed %t, got %t", tc.wantSealed, IsSealed(err))
+			}
+
+			// the validation of the store fails the same way, before the
+			// token is looked up.
+			if tc.wantErr != "" {
+				result, err := c.Validate()
+				if result != esv1.ValidationResultError || err == nil || err.Error() != tc.wantErr {
+					t.Errorf("expected validation error %q, got %v: %v", tc.wantErr, result, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePing(t *testing.T) {
+	defer func(ping bool) { pingBeforeLogin = ping }(pingBeforeLogin)
+
+	cases := map[string]struct {
+		pingBeforeLogin bool
+		storeKind       string
+		store           *esv1.VaultProvider
+		wantPings       int
+		wantLookups     int
+		wantResult      esv1.ValidationResult
+	}{
+		"Pinged": {
+			wantPings:   1,
+			wantLookups: 1,
+			wantResult:  esv1.ValidationResultReady,
+		},
+		// the flag only adds the ping before logins.
+		"PingBeforeLogin": {
+			pingBeforeLogin: true,
+			wantPings:       1,
+			wantLookups:     1,
+			wantResult:      esv1.ValidationResultReady,
+		},
+		// the token of a referent store isn't validated, nor is the server.
+		"Referent": {
+			pingBeforeLogin: true,
+			storeKind:       esv1.ClusterSecretStoreKind,
+			store: &esv1.VaultProvider{Auth: &esv1.VaultAuth{Azure: &esv1.VaultAzureAuth{
+				ServiceAccountRef: &esmeta.ServiceAccountSelector{Name: "vault"},
+			}}},
+			wantResult: esv1.ValidationResultUnknown,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			pingBeforeLogin = tc.pingBeforeLogin
+			pings, lookups := 0, 0
+			store := tc.store
+			if store == nil {
+				store = &esv1.VaultProvider{Auth: &esv1.VaultAuth{}}
+			}
+			store.Server = "https://vault.example.com"
+			token := fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					lookups++
+					return makeTokenLookup(time.Hour, true), nil
+				},
+			}
+			c := &client{
+				log:       logger,
+				store:     store,
+				storeKind: tc.storeKind,
+				token:     token,
+				client: &util.VaultClient{
+					TokenFunc:      func() string { return "vault-token" },
+					AuthTokenField: token,
+					WithNamespaceFunc: func(namespace string) util.Client {
+						return &util.VaultClient{SysField: fake.Sys{
+							HealthWithContextFn: func(ctx context.Context) (*vault.HealthResponse, error) {
+								pings++
+								return &vault.HealthResponse{Initialized: true}, nil
+							},
+						}}
+					},
+				},
+			}
+
+			result, err := c.Validate()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tc.wantResult {
+				t.Errorf("expected result %v, got %v", tc.wantResult, result)
+			}
+			if pings != tc.wantPings {
+				t.Errorf("expected %d pings, got %d", tc.wantPings, pings)
+			}
+			if lookups != tc.wantLookups {
+				t.Errorf("expected %d lookups, got %d", tc.wantLookups, lookups)
+			}
+		})
+	}
+}
+
+func TestPingBeforeLogin(t *testing.T) {
+	defer func(ping bool) { pingBeforeLogin = ping }(pingBeforeLogin)
+	pingBeforeLogin = true
+
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/health":
+			// sealed servers answer with the sealedcode the client asks for.
+			_, _ = w.Write([]byte(`{"initialized": true, "sealed": true}`))
+		case "/v1/auth/approle/login":
+			logins++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "approle-secret",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"secret-id": []byte("secret-id"),
+		},
+	}).Build()
+	auth := makeAppRoleAuth("ping-role-id")
+	store := &esv1.SecretStore{
+		TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vault-store",
+			Namespace: "default",
+		},
+		Spec: esv1.SecretStoreSpec{
+			Provider: &esv1.SecretStoreProvider{
+				Vault: &esv1.VaultProvider{
+					Server: server.URL,
+					Auth:   &auth,
+				},
+			},
+		},
+	}
+	prov := &Provider{NewVaultClient: NewVaultClient}
+	_, err := prov.newClient(context.Background(), store, kube, nil, "default")
+	if !IsSealed(err) || !strings.Contains(err.Error(), "is sealed") {
+		t.Fatalf("expected a sealed error, got %v", err)
+	}
+	if logins != 0 {
+		t.Errorf("expected no login against a sealed server, got %d", logins)
+	}
+}
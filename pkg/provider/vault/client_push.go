@@ -31,6 +31,14 @@ import (
 )
 
 func (c *client) PushSecret(ctx context.Context, secret *corev1.Secret, data esv1.PushSecretData) error {
+	writer, err := c.writeClient(ctx)
+	if err != nil {
+		return err
+	}
+	return writer.pushSecret(ctx, secret, data)
+}
+
+func (c *client) pushSecret(ctx context.Context, secret *corev1.Secret, data esv1.PushSecretData) error {
 	var (
 		value []byte
 		err   error
@@ -156,6 +164,14 @@ func (c *client) PushSecret(ctx context.Context, secret *corev1.Secret, data esv
 }
 
 func (c *client) DeleteSecret(ctx context.Context, remoteRef esv1.PushSecretRemoteRef) error {
+	writer, err := c.writeClient(ctx)
+	if err != nil {
+		return err
+	}
+	return writer.deleteSecret(ctx, remoteRef)
+}
+
+func (c *client) deleteSecret(ctx context.Context, remoteRef esv1.PushSecretRemoteRef) error {
 	path := c.buildPath(remoteRef.GetRemoteKey())
 	metaPath, err := c.buildMetadataPath(remoteRef.GetRemoteKey())
 	if err != nil {
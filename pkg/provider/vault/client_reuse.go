@@ -0,0 +1,136 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	vault "github.com/hashicorp/vault/api"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/cache"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+const (
+	defaultReusedClientsSize = 2 << 10
+)
+
+var (
+	// reuseClients keeps the Vault client constructed for a store, so that
+	// later reconciles don't have to set up a new transport.
+	reuseClients  bool
+	reusedClients *cache.Cache[reusedClient]
+)
+
+// reusedClient is a Vault client along with the config it was constructed
+// from, to detect when it has to be rebuilt. The dial address, the Secrets
+// of the cert auth certificate and the checks of the server certificate are
+// kept as well, since they are only part of the config as a dial function,
+// a certificate callback and a VerifyConnection callback.
+type reusedClient struct {
+	cfg         *vault.Config
+	dialAddress string
+	certAuth    string
+	checks      string
+	client      util.Client
+}
+
+func initReusedClients(size int) {
+	reusedClients = cache.Must[reusedClient](size, nil)
+}
+
+// getReusedClient returns a client based on the one constructed for the key
+// by an earlier reconcile, unless the config changed since. The returned
+// client is a clone of the client constructed first, which never logs in
+// itself: like a newly constructed client it starts with the token and
+// headers read from the environment, if any, and has to log in, but it
+// shares connections with the other clones.
+func getReusedClient(p *Provider, key cache.Key, cfg *vault.Config, dialAddress, certAuth, checks string) (util.Client, error) {
+	if reused, ok := reusedClients.Get("", key); ok && reused.dialAddress == dialAddress && reused.certAuth == certAuth && reused.checks == checks && sameConfig(reused.cfg, cfg) {
+		return reused.client.WithNamespace(""), nil
+	}
+
+	client, err := p.NewVaultClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf(errVaultClient, err)
+	}
+	reusedClients.Add("", key, reusedClient{cfg: cfg, dialAddress: dialAddress, certAuth: certAuth, checks: checks, client: client})
+	return client.WithNamespace(""), nil
+}
+
+// connectionChecks identifies the checks of the server certificate the
+// config of the store sets up in VerifyConnection.
+func connectionChecks(prov *esv1.VaultProvider) string {
+	var checks []string
+	if check := prov.RevocationCheck; check != nil {
+		checks = append(checks, fmt.Sprintf("revocation=%s,softFail=%t", check.Method, check.SoftFail))
+	}
+	if caReloadInterval > 0 && len(prov.CABundle) == 0 && prov.CAProvider != nil {
+		checks = append(checks, "reloadedCA")
+	}
+	return strings.Join(checks, ";")
+}
+
+// sameConfig reports whether a client constructed from a can be used in
+// place of one constructed from b.
+func sameConfig(a, b *vault.Config) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Address != b.Address ||
+		a.MaxRetries != b.MaxRetries ||
+		a.MinRetryWait != b.MinRetryWait ||
+		a.MaxRetryWait != b.MaxRetryWait ||
+		a.Timeout != b.Timeout ||
+		a.ReadYourWrites != b.ReadYourWrites {
+		return false
+	}
+	return sameTLSConfig(transportTLSConfig(a), transportTLSConfig(b))
+}
+
+func transportTLSConfig(cfg *vault.Config) *tls.Config {
+	if cfg.HttpClient == nil {
+		return nil
+	}
+	if transport, ok := cfg.HttpClient.Transport.(*http.Transport); ok {
+		return transport.TLSClientConfig
+	}
+	return nil
+}
+
+// sameTLSConfig compares the CA certificates, client certificates, HTTP
+// versions and verification of the server that are set from the store. A
+// VerifyConnection callback can only be compared by whether it is set, the
+// checks it runs are compared with connectionChecks.
+func sameTLSConfig(a, b *tls.Config) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if !a.RootCAs.Equal(b.RootCAs) || !slices.Equal(a.NextProtos, b.NextProtos) {
+		return false
+	}
+	if a.InsecureSkipVerify != b.InsecureSkipVerify || (a.VerifyConnection == nil) != (b.VerifyConnection == nil) {
+		return false
+	}
+	return slices.EqualFunc(a.Certificates, b.Certificates, func(x, y tls.Certificate) bool {
+		return slices.EqualFunc(x.Certificate, y.Certificate, bytes.Equal)
+	})
+}
@@ -0,0 +1,58 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/cache"
+)
+
+// sharedCacheKind is the cache key kind of clients shared between stores.
+const sharedCacheKind = "Shared"
+
+// shareTokens shares cached tokens between stores of any kind whose
+// connection and auth configuration is identical.
+var shareTokens bool
+
+// sharedCacheKey returns the key and version of the cached client shared by
+// all stores with the same connection and auth configuration, reading their
+// credentials from the same namespace. The namespace keeps tokens obtained
+// with the credentials of one namespace from being used by stores of
+// another, even if their configuration is the same.
+func sharedCacheKey(prov *esv1.VaultProvider, namespace string) (cache.Key, string, error) {
+	// settings that don't affect the token or the client are left out.
+	shared := prov.DeepCopy()
+	shared.Path = nil
+	shared.Version = ""
+	shared.CheckAndSet = nil
+	shared.MountAuth = nil
+	shared.WriteAuth = nil
+	data, err := json.Marshal(shared)
+	if err != nil {
+		return cache.Key{}, "", fmt.Errorf("cannot hash vault provider: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	return cache.Key{
+		Name:      hash,
+		Namespace: namespace,
+		Kind:      sharedCacheKind,
+	}, hash, nil
+}
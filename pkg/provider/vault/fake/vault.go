@@ -40,11 +40,13 @@ type ReadWithDataWithContextFn func(ctx context.Context, path string, data map[s
 type ListWithContextFn func(ctx context.Context, path string) (*vault.Secret, error)
 type WriteWithContextFn func(ctx context.Context, path string, data map[string]any) (*vault.Secret, error)
 type DeleteWithContextFn func(ctx context.Context, path string) (*vault.Secret, error)
+type UnwrapWithContextFn func(ctx context.Context, wrappingToken string) (*vault.Secret, error)
 type Logical struct {
 	ReadWithDataWithContextFn ReadWithDataWithContextFn
 	ListWithContextFn         ListWithContextFn
 	WriteWithContextFn        WriteWithContextFn
 	DeleteWithContextFn       DeleteWithContextFn
+	UnwrapWithContextFn       UnwrapWithContextFn
 }
 
 func (f Logical) DeleteWithContext(ctx context.Context, path string) (*vault.Secret, error) {
@@ -151,24 +153,66 @@ func (f Logical) ReadWithDataWithContext(ctx context.Context, path string, data
 func (f Logical) ListWithContext(ctx context.Context, path string) (*vault.Secret, error) {
 	return f.ListWithContextFn(ctx, path)
 }
+func (f Logical) UnwrapWithContext(ctx context.Context, wrappingToken string) (*vault.Secret, error) {
+	return f.UnwrapWithContextFn(ctx, wrappingToken)
+}
+
 func (f Logical) WriteWithContext(ctx context.Context, path string, data map[string]any) (*vault.Secret, error) {
 	return f.WriteWithContextFn(ctx, path, data)
 }
 
 type RevokeSelfWithContextFn func(ctx context.Context, token string) error
+type RevokeTreeWithContextFn func(ctx context.Context, token string) error
+type RevokeOrphanWithContextFn func(ctx context.Context, token string) error
 type LookupSelfWithContextFn func(ctx context.Context) (*vault.Secret, error)
+type RenewSelfWithContextFn func(ctx context.Context, increment int) (*vault.Secret, error)
+type CreateWithRoleWithContextFn func(ctx context.Context, opts *vault.TokenCreateRequest, roleName string) (*vault.Secret, error)
 
 type Token struct {
-	RevokeSelfWithContextFn RevokeSelfWithContextFn
-	LookupSelfWithContextFn LookupSelfWithContextFn
+	RevokeSelfWithContextFn   RevokeSelfWithContextFn
+	RevokeTreeWithContextFn   RevokeTreeWithContextFn
+	RevokeOrphanWithContextFn RevokeOrphanWithContextFn
+	LookupSelfWithContextFn   LookupSelfWithContextFn
+	RenewSelfWithContextFn    RenewSelfWithContextFn
+
+	CreateWithRoleWithContextFn CreateWithRoleWithContextFn
 }
 
 func (f Token) RevokeSelfWithContext(ctx context.Context, token string) error {
 	return f.RevokeSelfWithContextFn(ctx, token)
 }
+func (f Token) RevokeTreeWithContext(ctx context.Context, token string) error {
+	return f.RevokeTreeWithContextFn(ctx, token)
+}
+func (f Token) RevokeOrphanWithContext(ctx context.Context, token string) error {
+	return f.RevokeOrphanWithContextFn(ctx, token)
+}
 func (f Token) LookupSelfWithContext(ctx context.Context) (*vault.Secret, error) {
 	return f.LookupSelfWithContextFn(ctx)
 }
+func (f Token) RenewSelfWithContext(ctx context.Context, increment int) (*vault.Secret, error) {
+	return f.RenewSelfWithContextFn(ctx, increment)
+}
+func (f Token) CreateWithRoleWithContext(ctx context.Context, opts *vault.TokenCreateRequest, roleName string) (*vault.Secret, error) {
+	return f.CreateWithRoleWithContextFn(ctx, opts, roleName)
+}
+
+type HealthWithContextFn func(ctx context.Context) (*vault.HealthResponse, error)
+
+type CapabilitiesSelfWithContextFn func(ctx context.Context, path string) ([]string, error)
+
+type Sys struct {
+	HealthWithContextFn           HealthWithContextFn
+	CapabilitiesSelfWithContextFn CapabilitiesSelfWithContextFn
+}
+
+func (f Sys) HealthWithContext(ctx context.Context) (*vault.HealthResponse, error) {
+	return f.HealthWithContextFn(ctx)
+}
+
+func (f Sys) CapabilitiesSelfWithContext(ctx context.Context, path string) ([]string, error) {
+	return f.CapabilitiesSelfWithContextFn(ctx, path)
+}
 
 type MockSetTokenFn func(v string)
 
@@ -188,7 +232,7 @@ type VaultListResponse struct {
 }
 
 func NewAuthTokenFn() Token {
-	return Token{nil, func(ctx context.Context) (*vault.Secret, error) {
+	return Token{LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
 		return &(vault.Secret{}), nil
 	}}
 }
@@ -223,6 +267,7 @@ type VaultClient struct {
 	MockLogical      Logical
 	MockAuth         Auth
 	MockAuthToken    Token
+	MockSys          Sys
 	MockSetToken     MockSetTokenFn
 	MockToken        MockTokenFn
 	MockClearToken   MockClearTokenFn
@@ -268,6 +313,10 @@ func (c *VaultClient) AuthToken() Token {
 	return c.MockAuthToken
 }
 
+func (c *VaultClient) Sys() Sys {
+	return c.MockSys
+}
+
 func (c *VaultClient) SetToken(v string) {
 	c.MockSetToken(v)
 }
@@ -293,6 +342,25 @@ func (c *VaultClient) SetNamespace(namespace string) {
 	c.namespace = namespace
 }
 
+// WithNamespace returns a copy of the client using the given namespace.
+// The copy shares the mocks of this client.
+func (c *VaultClient) WithNamespace(namespace string) util.Client {
+	clone := &VaultClient{
+		MockLogical:      c.MockLogical,
+		MockAuth:         c.MockAuth,
+		MockAuthToken:    c.MockAuthToken,
+		MockSys:          c.MockSys,
+		MockSetToken:     c.MockSetToken,
+		MockToken:        c.MockToken,
+		MockClearToken:   c.MockClearToken,
+		MockNamespace:    c.MockNamespace,
+		MockSetNamespace: c.MockSetNamespace,
+		MockAddHeader:    c.MockAddHeader,
+		namespace:        namespace,
+	}
+	return clone.client()
+}
+
 func (c *VaultClient) AddHeader(key, value string) {
 	c.MockAddHeader(key, value)
 }
@@ -320,15 +388,21 @@ func clientWithLoginMockOptions(_ *vault.Config, opts ...func(cl *VaultClient))
 		opt(cl)
 	}
 
+	return cl.client(), nil
+}
+
+func (c *VaultClient) client() util.Client {
 	return &util.VaultClient{
-		SetTokenFunc:     cl.SetToken,
-		TokenFunc:        cl.Token,
-		ClearTokenFunc:   cl.ClearToken,
-		AuthField:        cl.Auth(),
-		AuthTokenField:   cl.AuthToken(),
-		LogicalField:     cl.Logical(),
-		NamespaceFunc:    cl.Namespace,
-		SetNamespaceFunc: cl.SetNamespace,
-		AddHeaderFunc:    cl.AddHeader,
-	}, nil
+		SetTokenFunc:      c.SetToken,
+		TokenFunc:         c.Token,
+		ClearTokenFunc:    c.ClearToken,
+		AuthField:         c.Auth(),
+		AuthTokenField:    c.AuthToken(),
+		SysField:          c.Sys(),
+		LogicalField:      c.Logical(),
+		NamespaceFunc:     c.Namespace,
+		SetNamespaceFunc:  c.SetNamespace,
+		WithNamespaceFunc: c.WithNamespace,
+		AddHeaderFunc:     c.AddHeader,
+	}
 }
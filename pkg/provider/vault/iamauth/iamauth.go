@@ -18,7 +18,10 @@ package iamauth
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -56,6 +59,8 @@ const (
 
 	STSEndpointEnv                = "AWS_STS_ENDPOINT"
 	AWSWebIdentityTokenFileEnvVar = "AWS_WEB_IDENTITY_TOKEN_FILE"
+
+	errSTSUnreachable = "AWS STS endpoint %s is not reachable, check that egress to it is allowed or set %s to a reachable endpoint: %w"
 )
 
 // DefaultJWTProvider returns a credentials.Provider that calls the AssumeRoleWithWebidentity
@@ -117,6 +122,53 @@ func ResolveEndpointWithServiceMap(customEndpoints map[string]string) endpoints.
 	}
 }
 
+// dialContext is replaced in tests to simulate unreachable endpoints.
+var dialContext = (&net.Dialer{}).DialContext
+
+// CheckSTSReachable connects to the STS endpoint of the region, so that
+// blocked egress fails fast with a clear error instead of waiting for the
+// credentials request to run into the context deadline.
+func CheckSTSReachable(ctx context.Context, region string, timeout time.Duration) error {
+	ep, err := ResolveEndpoint().EndpointFor("sts", region)
+	if err != nil {
+		return err
+	}
+	address, err := endpointAddress(ep.URL)
+	if err != nil {
+		return fmt.Errorf(errSTSUnreachable, ep.URL, STSEndpointEnv, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := dialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf(errSTSUnreachable, ep.URL, STSEndpointEnv, err)
+	}
+	return conn.Close()
+}
+
+// endpointAddress returns the host:port to connect to for an endpoint URL.
+func endpointAddress(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		// endpoints may be configured without a scheme.
+		u, err = url.Parse("https://" + endpoint)
+		if err != nil {
+			return "", err
+		}
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
 // mostly taken from:
 // https://github.com/aws/secrets-store-csi-driver-provider-aws/blob/main/auth/auth.go#L140-L145
 
@@ -259,8 +311,11 @@ func CredsFromSecretRef(ctx context.Context, auth esv1.VaultIamAuth, storeKind s
 	return credentials.NewStaticCredentials(akid, sak, sessionToken), err
 }
 
+// STSProvider returns the STS client used to assume a role with the
+// session. It is used by IAM auth, see vault.WithSTSProvider.
 type STSProvider func(*session.Session) stsiface.STSAPI
 
+// DefaultSTSProvider returns an STS client of the session.
 func DefaultSTSProvider(sess *session.Session) stsiface.STSAPI {
 	return sts.New(sess)
 }
@@ -16,7 +16,10 @@ package iamauth
 
 import (
 	"context"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -59,3 +62,90 @@ func TestResolver(t *testing.T) {
 		assert.Equal(t, item.url, ep.URL)
 	}
 }
+
+func TestCheckSTSReachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	// dropped connections never complete, only the timeout ends the dial.
+	blackhole := func(ctx context.Context, network, address string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	tbl := []struct {
+		name     string
+		endpoint string
+		dial     func(ctx context.Context, network, address string) (net.Conn, error)
+		wantErr  bool
+	}{
+		{
+			name:     "reachable",
+			endpoint: "http://" + listener.Addr().String(),
+		},
+		{
+			name:     "refused",
+			endpoint: "http://" + closedAddr,
+			wantErr:  true,
+		},
+		{
+			name:     "blackholed",
+			endpoint: "https://sts.us-east-1.amazonaws.com",
+			dial:     blackhole,
+			wantErr:  true,
+		},
+	}
+
+	for _, item := range tbl {
+		t.Run(item.name, func(t *testing.T) {
+			t.Setenv(STSEndpointEnv, item.endpoint)
+			if item.dial != nil {
+				defer func(dial func(ctx context.Context, network, address string) (net.Conn, error)) { dialContext = dial }(dialContext)
+				dialContext = item.dial
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			start := time.Now()
+			err := CheckSTSReachable(ctx, "us-east-1", 200*time.Millisecond)
+			elapsed := time.Since(start)
+			if !item.wantErr {
+				assert.Nil(t, err)
+				return
+			}
+			if !assert.NotNil(t, err) {
+				return
+			}
+			assert.True(t, strings.Contains(err.Error(), item.endpoint), "error should name the endpoint: %v", err)
+			assert.True(t, strings.Contains(err.Error(), STSEndpointEnv), "error should mention %s: %v", STSEndpointEnv, err)
+			assert.Less(t, elapsed, 2*time.Second, "check should fail fast instead of waiting for the context deadline")
+		})
+	}
+}
+
+func TestEndpointAddress(t *testing.T) {
+	tbl := []struct {
+		endpoint string
+		address  string
+	}{
+		{endpoint: "https://sts.us-east-1.amazonaws.com", address: "sts.us-east-1.amazonaws.com:443"},
+		{endpoint: "http://sts.foo", address: "sts.foo:80"},
+		{endpoint: "http://sts.foo:8080", address: "sts.foo:8080"},
+		{endpoint: "sts.foo", address: "sts.foo:443"},
+	}
+
+	for _, item := range tbl {
+		address, err := endpointAddress(item.endpoint)
+		assert.Nil(t, err)
+		assert.Equal(t, item.address, address)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+)
+
+const (
+	warnNamespaceTrimmed  = "%s %q has surrounding slashes or spaces, it is used as %q"
+	warnNamespaceSegments = "%s %q has empty or space-padded path segments, check it for typos"
+	warnNamespaceCase     = "%s %q differs from namespace %q only in case, check it for typos or set lowercaseNamespaces"
+)
+
+// normalizeNamespace trims the surrounding spaces and slashes of a Vault
+// namespace, so that e.g. "ns1/" and "ns1" are the same namespace, and
+// lowercases it if configured.
+func normalizeNamespace(namespace string, lowercase bool) string {
+	namespace = strings.Trim(strings.TrimSpace(namespace), "/")
+	if lowercase {
+		namespace = strings.ToLower(namespace)
+	}
+	return namespace
+}
+
+// normalizeNamespace normalizes the namespace as configured for the store.
+func (c *client) normalizeNamespace(namespace string) string {
+	return normalizeNamespace(namespace, c.store.LowercaseNamespaces)
+}
+
+// namespaceWarnings returns warnings about the namespaces of the store that
+// are likely mistyped, which are accepted as they may still be intended.
+func namespaceWarnings(vaultProvider *esv1.VaultProvider) admission.Warnings {
+	var warnings admission.Warnings
+	lowercase := vaultProvider.LowercaseNamespaces
+	check := func(field string, namespace *string) {
+		if namespace == nil {
+			return
+		}
+		ns := *namespace
+		trimmed := normalizeNamespace(ns, false)
+		if trimmed != ns {
+			warnings = append(warnings, fmt.Sprintf(warnNamespaceTrimmed, field, ns, normalizeNamespace(ns, lowercase)))
+		}
+		for _, segment := range strings.Split(trimmed, "/") {
+			if trimmed != "" && (segment == "" || strings.TrimSpace(segment) != segment) {
+				warnings = append(warnings, fmt.Sprintf(warnNamespaceSegments, field, ns))
+				break
+			}
+		}
+		if vaultProvider.Namespace == nil || namespace == vaultProvider.Namespace || lowercase {
+			return
+		}
+		store := normalizeNamespace(*vaultProvider.Namespace, false)
+		if trimmed != store && strings.EqualFold(trimmed, store) {
+			warnings = append(warnings, fmt.Sprintf(warnNamespaceCase, field, ns, *vaultProvider.Namespace))
+		}
+	}
+	check("namespace", vaultProvider.Namespace)
+	if vaultProvider.Auth != nil {
+		check("auth.namespace", vaultProvider.Auth.Namespace)
+	}
+	for i := range vaultProvider.MountAuth {
+		check(fmt.Sprintf("mountAuth[%d].auth.namespace", i), vaultProvider.MountAuth[i].Auth.Namespace)
+	}
+	if vaultProvider.WriteAuth != nil {
+		check("writeAuth.namespace", vaultProvider.WriteAuth.Namespace)
+	}
+	return warnings
+}
+
+// loginNamespace returns the namespace the last login reported in the auth
+// metadata of its response, if the store discovers namespaces. Vault
+// Enterprise reports it as namespace_path, e.g. "admin/team-a/".
+func (c *client) loginNamespace() *string {
+	if !c.store.Auth.DiscoverNamespace || c.loginAuth == nil {
+		return nil
+	}
+	path, ok := c.loginAuth.Metadata["namespace_path"]
+	if !ok {
+		return nil
+	}
+	ns := c.normalizeNamespace(path)
+	return &ns
+}
+
+// useDiscoveredNamespace scopes the client to the namespace discovered at
+// login, in which the token was issued, so that the following requests of
+// the store target it. The Vault client is cloned with WithNamespace, like
+// for logins in the auth namespace, as it may be shared.
+func (c *client) useDiscoveredNamespace() {
+	if c.discoveredNamespace == nil {
+		return
+	}
+	ns := *c.discoveredNamespace
+	c.tokenNamespace = &ns
+	if c.client.Namespace() == ns {
+		return
+	}
+	c.log.V(1).Info("using the namespace of the login response", "namespace", ns, "requested", c.client.Namespace())
+	scoped := c.withNamespace(ns)
+	c.client, c.auth, c.logical, c.token = scoped.client, scoped.auth, scoped.logical, scoped.token
+}
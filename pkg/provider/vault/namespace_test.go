@@ -0,0 +1,161 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/util"
+)
+
+func TestAuthNamespaceNormalization(t *testing.T) {
+	cases := map[string]struct {
+		namespace     string
+		authNamespace string
+		lowercase     bool
+		// wantNamespace is the namespace set on the client.
+		wantNamespace string
+		// wantAuthNamespace is the namespace of the login, if it differs.
+		wantAuthNamespace string
+	}{
+		"Same": {
+			namespace:     "team-a",
+			authNamespace: "team-a",
+			wantNamespace: "team-a",
+		},
+		"TrailingSlash": {
+			namespace:     "team-a/",
+			authNamespace: "team-a",
+			wantNamespace: "team-a",
+		},
+		"NestedWithSlashes": {
+			namespace:     "/org/team-a/",
+			authNamespace: "org/team-a/",
+			wantNamespace: "org/team-a",
+		},
+		"MixedCase": {
+			namespace:         "Team-A",
+			authNamespace:     "team-a",
+			wantNamespace:     "Team-A",
+			wantAuthNamespace: "team-a",
+		},
+		"MixedCaseLowercased": {
+			namespace:     "Team-A/",
+			authNamespace: "team-a",
+			lowercase:     true,
+			wantNamespace: "team-a",
+		},
+		"OtherAuthNamespace": {
+			namespace:         "org/team-a/",
+			authNamespace:     "/org/",
+			wantNamespace:     "org/team-a",
+			wantAuthNamespace: "org",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			namespace := ""
+			c := &client{
+				log: logger,
+				store: &esv1.VaultProvider{
+					Namespace:           ptr.To(tc.namespace),
+					LowercaseNamespaces: tc.lowercase,
+					Auth:                &esv1.VaultAuth{Namespace: ptr.To(tc.authNamespace)},
+				},
+			}
+			c.client = &util.VaultClient{
+				NamespaceFunc:    func() string { return namespace },
+				SetNamespaceFunc: func(ns string) { namespace = ns },
+				WithNamespaceFunc: func(ns string) util.Client {
+					return &util.VaultClient{NamespaceFunc: func() string { return ns }}
+				},
+			}
+			c.client.SetNamespace(c.normalizeNamespace(*c.store.Namespace))
+			if namespace != tc.wantNamespace {
+				t.Errorf("expected namespace %q, got %q", tc.wantNamespace, namespace)
+			}
+
+			login := c.withAuthNamespace()
+			if tc.wantAuthNamespace == "" {
+				if login != c {
+					t.Errorf("expected the login in namespace %q, got %q", namespace, login.client.Namespace())
+				}
+				return
+			}
+			if login == c || login.client.Namespace() != tc.wantAuthNamespace {
+				t.Errorf("expected the login in namespace %q, got %q", tc.wantAuthNamespace, login.client.Namespace())
+			}
+		})
+	}
+}
+
+func TestNamespaceWarnings(t *testing.T) {
+	cases := map[string]struct {
+		provider esv1.VaultProvider
+		want     admission.Warnings
+	}{
+		"NoNamespace": {},
+		"Valid": {
+			provider: esv1.VaultProvider{
+				Namespace: ptr.To("org/team-a"),
+				Auth:      &esv1.VaultAuth{Namespace: ptr.To("org")},
+			},
+		},
+		"TrailingSlash": {
+			provider: esv1.VaultProvider{Namespace: ptr.To("team-a/")},
+			want:     admission.Warnings{`namespace "team-a/" has surrounding slashes or spaces, it is used as "team-a"`},
+		},
+		"TrailingSlashLowercased": {
+			provider: esv1.VaultProvider{Namespace: ptr.To("Team-A/"), LowercaseNamespaces: true},
+			want:     admission.Warnings{`namespace "Team-A/" has surrounding slashes or spaces, it is used as "team-a"`},
+		},
+		"EmptySegment": {
+			provider: esv1.VaultProvider{Namespace: ptr.To("org//team-a")},
+			want:     admission.Warnings{`namespace "org//team-a" has empty or space-padded path segments, check it for typos`},
+		},
+		"MixedCaseAuthNamespace": {
+			provider: esv1.VaultProvider{
+				Namespace: ptr.To("team-a"),
+				Auth:      &esv1.VaultAuth{Namespace: ptr.To("Team-A")},
+				MountAuth: []esv1.VaultMountAuth{{Path: "kv", Auth: esv1.VaultAuth{Namespace: ptr.To("team-a/")}}},
+			},
+			want: admission.Warnings{
+				`auth.namespace "Team-A" differs from namespace "team-a" only in case, check it for typos or set lowercaseNamespaces`,
+				`mountAuth[0].auth.namespace "team-a/" has surrounding slashes or spaces, it is used as "team-a"`,
+			},
+		},
+		"MixedCaseLowercased": {
+			provider: esv1.VaultProvider{
+				Namespace:           ptr.To("team-a"),
+				LowercaseNamespaces: true,
+				Auth:                &esv1.VaultAuth{Namespace: ptr.To("Team-A")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, namespaceWarnings(&tc.provider)); diff != "" {
+				t.Errorf("unexpected warnings (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
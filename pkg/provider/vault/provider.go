@@ -36,10 +36,12 @@ import (
 )
 
 var (
-	_           esv1.Provider = &Provider{}
-	enableCache bool
-	logger      = ctrl.Log.WithName("provider").WithName("vault")
-	clientCache *cache.Cache[util.Client]
+	_                    esv1.Provider = &Provider{}
+	enableCache          bool
+	tokenExpiryTolerance = defaultTokenExpiryTolerance
+	tokenWarmupWindow    = defaultTokenWarmupWindow
+	logger               = ctrl.Log.WithName("provider").WithName("vault")
+	clientCache          *cache.Cache[util.Client]
 )
 
 const (
@@ -51,13 +53,22 @@ const (
 )
 
 const (
-	defaultCacheSize = 2 << 17
+	defaultCacheSize            = 2 << 17
+	defaultTokenExpiryTolerance = 10 * time.Second
+	defaultTokenWarmupWindow    = 5 * time.Minute
 )
 
 type Provider struct {
 	// NewVaultClient is a function that returns a new Vault client.
 	// This is used for testing to inject a fake client.
 	NewVaultClient func(config *vault.Config) (util.Client, error)
+	// ExternalToken is used by all clients instead of the store's auth if
+	// set. It allows applications embedding external-secrets to manage the
+	// Vault token themselves.
+	ExternalToken *ExternalToken
+	// ClientOptions are applied to every client of the provider, e.g. to
+	// obtain the AWS credentials of IAM auth with WithJWTProvider.
+	ClientOptions []ClientOption
 }
 
 // NewVaultClient returns a new Vault client.
@@ -66,17 +77,25 @@ func NewVaultClient(config *vault.Config) (util.Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newVaultClient(vaultClient), nil
+}
+
+func newVaultClient(vaultClient *vault.Client) util.Client {
 	return &util.VaultClient{
 		SetTokenFunc:     vaultClient.SetToken,
 		TokenFunc:        vaultClient.Token,
 		ClearTokenFunc:   vaultClient.ClearToken,
 		AuthField:        vaultClient.Auth(),
 		AuthTokenField:   vaultClient.Auth().Token(),
+		SysField:         vaultClient.Sys(),
 		LogicalField:     vaultClient.Logical(),
 		NamespaceFunc:    vaultClient.Namespace,
 		SetNamespaceFunc: vaultClient.SetNamespace,
-		AddHeaderFunc:    vaultClient.AddHeader,
-	}, nil
+		WithNamespaceFunc: func(namespace string) util.Client {
+			return newVaultClient(vaultClient.WithNamespace(namespace))
+		},
+		AddHeaderFunc: vaultClient.AddHeader,
+	}
 }
 
 // Capabilities return the provider supported capabilities (ReadOnly, WriteOnly, ReadWrite).
@@ -137,18 +156,38 @@ func (p *Provider) newClient(ctx context.Context, store esv1.GenericStore, kube
 	if err != nil {
 		return nil, err
 	}
+	vStore.storeName = store.GetName()
+	vStore.storeUID = store.GetUID()
+
+	if ref, ok := esv1.AuthOverrideFromContext(ctx); ok {
+		if err := authOverrideAllowed(store, vaultSpec.Auth, namespace); err != nil {
+			return nil, fmt.Errorf(errAuthOverride, err)
+		}
+		// clients using an override token must never be cached, so that
+		// the token is only used for this request.
+		vStore.authOverride = &ref
+		client, err := p.NewVaultClient(cfg)
+		if err != nil {
+			return nil, fmt.Errorf(errVaultClient, err)
+		}
+		return p.initClient(ctx, vStore, client, cfg, vaultSpec)
+	}
 
 	client, err := getVaultClient(p, store, cfg, namespace)
 	if err != nil {
 		return nil, fmt.Errorf(errVaultClient, err)
 	}
 
-	return p.initClient(ctx, vStore, client, cfg, vaultSpec)
+	secretsClient, err := p.initClient(ctx, vStore, client, cfg, vaultSpec)
+	if err != nil {
+		return nil, pendingCredentials(store, vaultSpec.Auth, err)
+	}
+	return secretsClient, nil
 }
 
 func (p *Provider) initClient(ctx context.Context, c *client, client util.Client, cfg *vault.Config, vaultSpec *esv1.VaultProvider) (esv1.SecretsClient, error) {
 	if vaultSpec.Namespace != nil {
-		client.SetNamespace(*vaultSpec.Namespace)
+		client.SetNamespace(normalizeNamespace(*vaultSpec.Namespace, vaultSpec.LowercaseNamespaces))
 	}
 
 	if vaultSpec.Headers != nil {
@@ -165,12 +204,19 @@ func (p *Provider) initClient(ctx context.Context, c *client, client util.Client
 	c.auth = client.Auth()
 	c.logical = client.Logical()
 	c.token = client.AuthToken()
+	c.config = cfg
 
 	// allow SecretStore controller validation to pass
 	// when using referent namespace.
 	if c.storeKind == esv1.ClusterSecretStoreKind && c.namespace == "" && isReferentSpec(vaultSpec) {
 		return c, nil
 	}
+	if c.authOverride != nil {
+		if err := c.useAuthOverride(ctx); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
 	if err := c.setAuth(ctx, cfg); err != nil {
 		return nil, err
 	}
@@ -180,12 +226,17 @@ func (p *Provider) initClient(ctx context.Context, c *client, client util.Client
 
 func (p *Provider) prepareConfig(ctx context.Context, kube kclient.Client, corev1 typedcorev1.CoreV1Interface, vaultSpec *esv1.VaultProvider, retrySettings *esv1.SecretStoreRetrySettings, namespace, storeKind string) (*client, *vault.Config, error) {
 	c := &client{
-		kube:      kube,
-		corev1:    corev1,
-		store:     vaultSpec,
-		log:       logger,
-		namespace: namespace,
-		storeKind: storeKind,
+		kube:          kube,
+		corev1:        corev1,
+		store:         vaultSpec,
+		log:           logger,
+		namespace:     namespace,
+		storeKind:     storeKind,
+		mounts:        newMountClients(),
+		externalToken: p.ExternalToken,
+	}
+	for _, opt := range p.ClientOptions {
+		opt(c)
 	}
 
 	cfg, err := c.newConfig(ctx)
@@ -218,7 +269,9 @@ func (p *Provider) prepareConfig(ctx context.Context, kube kclient.Client, corev
 func getVaultClient(p *Provider, store esv1.GenericStore, cfg *vault.Config, namespace string) (util.Client, error) {
 	vaultProvider := store.GetSpec().Provider.Vault
 	auth := vaultProvider.Auth
-	isStaticToken := auth != nil && auth.TokenSecretRef != nil
+	// tokens created with a token role are issued for the client, even if
+	// their parent is static.
+	isStaticToken := auth != nil && auth.TokenRole == nil && (auth.TokenSecretRef != nil || auth.TokenFilePath != "")
 	useCache := enableCache && !isStaticToken
 
 	keyNamespace := store.GetObjectMeta().Namespace
@@ -232,8 +285,23 @@ func getVaultClient(p *Provider, store esv1.GenericStore, cfg *vault.Config, nam
 		Namespace: keyNamespace,
 		Kind:      store.GetTypeMeta().Kind,
 	}
+	if !useCache && reuseClients {
+		var certAuth string
+		if auth != nil {
+			certAuth = certAuthKey(store.GetTypeMeta().Kind, namespace, auth.Cert)
+		}
+		return getReusedClient(p, key, cfg, vaultProvider.DialAddress, certAuth, connectionChecks(vaultProvider))
+	}
+	version := store.GetObjectMeta().ResourceVersion
+	if useCache && shareTokens {
+		var err error
+		key, version, err = sharedCacheKey(vaultProvider, keyNamespace)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if useCache {
-		client, ok := clientCache.Get(store.GetObjectMeta().ResourceVersion, key)
+		client, ok := clientCache.Get(version, key)
 		if ok {
 			return client, nil
 		}
@@ -245,7 +313,7 @@ func getVaultClient(p *Provider, store esv1.GenericStore, cfg *vault.Config, nam
 	}
 
 	if useCache && !clientCache.Contains(key) {
-		clientCache.Add(store.GetObjectMeta().ResourceVersion, key, client)
+		clientCache.Add(version, key, client)
 	}
 	return client, nil
 }
@@ -258,9 +326,19 @@ func isReferentSpec(prov *esv1.VaultProvider) bool {
 	if prov.Auth.TokenSecretRef != nil && prov.Auth.TokenSecretRef.Namespace == nil {
 		return true
 	}
+	if prov.Auth.FallbackTokenRef != nil && prov.Auth.FallbackTokenRef.Namespace == nil {
+		return true
+	}
 	if prov.Auth.AppRole != nil && prov.Auth.AppRole.SecretRef.Namespace == nil {
 		return true
 	}
+	if prov.Auth.AppRole != nil {
+		for _, creds := range prov.Auth.AppRole.FallbackCredentials {
+			if creds.SecretRef.Namespace == nil {
+				return true
+			}
+		}
+	}
 	if prov.Auth.Kubernetes != nil && prov.Auth.Kubernetes.SecretRef != nil && prov.Auth.Kubernetes.SecretRef.Namespace == nil {
 		return true
 	}
@@ -273,6 +351,10 @@ func isReferentSpec(prov *esv1.VaultProvider) bool {
 	if prov.Auth.UserPass != nil && prov.Auth.UserPass.SecretRef.Namespace == nil {
 		return true
 	}
+	// a templated mount path may differ per namespace.
+	if prov.Auth.Jwt != nil && isTemplatedPath(prov.Auth.Jwt.Path) {
+		return true
+	}
 	if prov.Auth.Jwt != nil && prov.Auth.Jwt.SecretRef != nil && prov.Auth.Jwt.SecretRef.Namespace == nil {
 		return true
 	}
@@ -291,33 +373,91 @@ func isReferentSpec(prov *esv1.VaultProvider) bool {
 			(prov.Auth.Iam.SecretRef.SessionToken != nil && prov.Auth.Iam.SecretRef.SessionToken.Namespace == nil)) {
 		return true
 	}
+	if prov.Auth.Gcp != nil && prov.Auth.Gcp.ServiceAccountRef != nil && prov.Auth.Gcp.ServiceAccountRef.Namespace == nil {
+		return true
+	}
+	if prov.Auth.Azure != nil && prov.Auth.Azure.ServiceAccountRef != nil && prov.Auth.Azure.ServiceAccountRef.Namespace == nil {
+		return true
+	}
+	if prov.Auth.Plugin != nil {
+		for _, ref := range prov.Auth.Plugin.SecretParameters {
+			if ref.Namespace == nil {
+				return true
+			}
+		}
+	}
 	return false
 }
 
 func initCache(size int) {
 	logger.Info("initializing vault cache", "size", size)
 	clientCache = cache.Must(size, func(client util.Client) {
-		err := revokeTokenIfValid(context.Background(), client)
-		if err != nil {
-			logger.Error(err, "unable to revoke cached token on eviction")
+		// tokens supplied by the user are never cached, see isStaticToken.
+		revoke := func(ctx context.Context) {
+			err := revokeTokenIfValid(ctx, client, esv1.VaultTokenRevokeScopeSelf)
+			if err != nil {
+				logger.Error(err, "unable to revoke cached token on eviction")
+			}
 		}
+		if deferRevocation(client.Token(), revoke) {
+			return
+		}
+		revoke(context.Background())
 	})
 }
 
 func init() {
-	var vaultTokenCacheSize int
+	var vaultTokenCacheSize, mintedTokensSize int
 	fs := pflag.NewFlagSet("vault", pflag.ExitOnError)
 	fs.BoolVar(&enableCache, "experimental-enable-vault-token-cache", false, "Enable experimental Vault token cache. External secrets will reuse the Vault token without creating a new one on each request.")
 	// max. 265k vault leases with 30bytes each ~= 7MB
 	fs.IntVar(&vaultTokenCacheSize, "experimental-vault-token-cache-size", defaultCacheSize, "Maximum size of Vault token cache. When more tokens than Only used if --experimental-enable-vault-token-cache is set.")
+	fs.BoolVar(&countTokenReferences, "vault-count-token-references", false, "Only revoke a Vault token once no client uses it anymore, e.g. a cached token shared with --vault-share-cached-tokens that is evicted while in use, or a static token referenced by several stores with revokeStaticToken.")
+	fs.BoolVar(&shareTokens, "vault-share-cached-tokens", false, "Share cached Vault tokens between SecretStores and ClusterSecretStores whose connection and auth configuration is identical, as long as they read their credentials from the same namespace. Only used if --experimental-enable-vault-token-cache is set.")
+	fs.BoolVar(&reuseClients, "vault-reuse-clients", false, "Reuse the Vault client constructed for a store across reconciles instead of setting up a new transport on each request. The client is rebuilt when its configuration changes, authentication still happens on each request. Has no effect on stores whose tokens are cached with --experimental-enable-vault-token-cache.")
+	fs.BoolVar(&reloadOnSIGHUP, "vault-reload-on-sighup", false, "Invalidate all cached Vault tokens and the cached credentials they were obtained with when the controller receives SIGHUP, so that the next auth logs in again, e.g. after file-based credentials were rotated.")
+	fs.BoolVar(&prevalidateLoginSecrets, "vault-prevalidate-login-secrets", false, "Check that all secrets referenced by an AppRole, LDAP, userPass or cert login are readable before logging in, and fail with a single error naming each missing one.")
+	fs.StringToStringVar(&loginAuditFields, "vault-login-audit-fields", nil, "Fields added to each Vault login so that the audit log attributes it to a store, e.g. cluster=prod,store=${storeNamespace}/${storeName}. Values may reference ${storeKind}, ${storeNamespace} and ${storeName}. Sent as login metadata by the jwt and cert auth methods and in the User-Agent of the login request otherwise.")
+	fs.StringVar(&correlationHeader, "vault-login-correlation-header", "", "Header carrying a correlation ID on each Vault login, e.g. X-Correlation-ID, which Vault records in its audit log once the header is audited with sys/config/auditing/request-headers. The ID is the reconcile ID of the controller, unless an application embedding the provider set one with WithCorrelationID. Disabled if empty.")
+	fs.DurationVar(&tokenExpiryJitter, "vault-token-expiry-jitter", defaultTokenExpiryJitter, "Maximum random jitter added to the threshold within which Vault tokens are treated as expired, so that stores whose tokens share a TTL don't all log in again at once. The jitter is derived from each token and stays the same until the next login. Disabled if zero.")
+	fs.DurationVar(&tokenExpiryTolerance, "vault-token-expiry-tolerance", defaultTokenExpiryTolerance, "Maximum allowed difference between a Vault token's ttl and expire_time. Beyond this, the sooner expiry is used to decide whether the token is still valid.")
+	fs.BoolVar(&renewExpiringTokens, "vault-renew-expiring-tokens", false, "Renew a renewable Vault token that is about to expire instead of logging in again. Falls back to a new login if the renewal fails.")
+	fs.BoolVar(&renewInBackground, "vault-renew-tokens-in-background", false, "Renew a renewable Vault token issued by a login in background after two thirds of its lease, until it reaches its max TTL and is replaced by a new login. The renewal stops once the token is revoked, e.g. when the client is closed or its cached token is evicted.")
+	fs.BoolVar(&renewStaticTokens, "vault-renew-static-tokens", false, "Renew a renewable Vault token read from a secret with tokenSecretRef when it expires within --vault-token-warmup-window. Disabled by default, as these tokens are managed outside of the controller.")
+	fs.DurationVar(&tokenWarmupWindow, "vault-token-warmup-window", defaultTokenWarmupWindow, "When activity is expected on a Vault client, a token expiring within this window is renewed or re-acquired ahead of time.")
+	fs.DurationVar(&tokenValidityCacheTTL, "vault-token-validity-cache-ttl", 0, "Share the result of a Vault token lookup between clients for this long instead of looking the token up on every request. A reconcile is scheduled for when the token has to be replaced, so that the re-auth doesn't happen inline. Disabled if zero.")
+	fs.DurationVar(&loginDedupWindow, "vault-login-dedup-window", 0, "Skip checking the Vault token of a store for this long after a successful auth of the store with it, so that rapid successive reconciles against the same store don't each look the token up. Capped at a minute, within which tokens are treated as expired. Disabled if zero.")
+	fs.DurationVar(&negativeAuthCacheTTL, "vault-negative-auth-cache-ttl", 0, "Cache a login rejected by Vault, e.g. because of invalid credentials, for this long and fail further logins of the same store configuration with the cached error instead of contacting Vault. Transient failures are never cached. Disabled if zero.")
+	fs.StringVar(&replicaTokenNamespace, "vault-replica-token-namespace", "", "Share one Vault token per store identity between controller replicas through a Secret in this namespace. After a login, the replica that creates the Secret first wins, the other replicas revoke their own token and use the shared one until it expires. Shared tokens are only revoked once their store is torn down, and their Secrets are deleted once they expire. Disabled if empty.")
+	fs.BoolVar(&reuseMintedTokens, "vault-reuse-service-account-tokens", false, "Log in to Vault with the service account token last issued for the same service account, audiences and expiration instead of requesting a new one on every login, as long as it is valid for more than a fifth of its lifetime.")
+	fs.IntVar(&mintedTokensSize, "vault-service-account-token-cache-size", defaultMintedTokensSize, "Maximum number of service account tokens kept for reuse with --vault-reuse-service-account-tokens and during outages of the Kubernetes API. The least recently used tokens are evicted first.")
+	fs.BoolVar(&authLeaderOnly, "vault-auth-leader-only", false, "Only log in to Vault once the controller has been elected leader, so that standby replicas hold no tokens. With the token cache enabled, the leader logs in to all stores using the Vault provider right after its election.")
+	fs.DurationVar(&maxLoginRetryAfter, "vault-max-login-retry-after", defaultMaxLoginRetryAfter, "Maximum wait before retrying a Vault login that was rate limited with a Retry-After header, e.g. by a rate limit quota. Longer waits are capped at this value. The header is ignored and the retry interval is used if zero.")
+	fs.DurationVar(&authTimeout, "vault-auth-timeout", 0, "Timeout of each Vault login, including the requests for the credentials it's made with. Defaults to the timeout of the Vault client if zero.")
+	fs.Var(authMethodTimeouts, "vault-auth-method-timeouts", "Timeouts of the Vault logins of specific auth methods overriding --vault-auth-timeout, e.g. iam=30s,approle=5s. Methods are token, tokenfile, approle, kubernetes, ldap, userpass, jwt, cert, iam, gcp, azure and plugin. Zero disables the timeout of a method.")
+	fs.StringSliceVar(&tokenFileDirs, "vault-token-file-dirs", nil, "Directories of the controller filesystem within which stores may read their Vault token from a file with tokenFilePath, e.g. the volume a Vault Agent sidecar writes its token to. No token files can be read if empty.")
+	fs.DurationVar(&stsProbeTimeout, "vault-iam-sts-probe-timeout", defaultSTSProbeTimeout, "Timeout of the check that the AWS STS endpoint is reachable before requesting credentials for Vault IAM auth, so that blocked egress fails fast. Disabled if zero.")
+	fs.BoolVar(&pingBeforeLogin, "vault-ping-before-login", false, "Check that the Vault server is reachable, initialized and unsealed with its sys/health endpoint before each login, so that a misconfigured address or a sealed server fails with a specific error instead of a failed login.")
+	fs.DurationVar(&caReloadInterval, "vault-ca-reload-interval", 0, "Read the CA certificates of a store's caProvider again at most this often, and verify new connections of long-lived Vault clients, e.g. of the token cache, against the certificates read last, so that a rotated CA is picked up without restarting the controller. Existing connections are kept. Disabled if zero.")
+	fs.StringVar(&serverVersionCheck, "vault-server-version-check", "", "Check the Vault server version on the first login against the minimum versions required by the store features in use. Set to \"warn\" to log outdated servers or to \"error\" to fail the login. Disabled if empty.")
+	fs.StringVar(&minServerVersion, "vault-min-server-version", "", "Minimum Vault server version required regardless of the store features in use. Only used if --vault-server-version-check is set.")
+	provider := &Provider{
+		NewVaultClient: NewVaultClient,
+	}
 	feature.Register(feature.Feature{
-		Flags:      fs,
-		Initialize: func() { initCache(vaultTokenCacheSize) },
+		Flags: fs,
+		Initialize: func() {
+			initCache(vaultTokenCacheSize)
+			initReusedClients(defaultReusedClientsSize)
+			initMintedTokens(mintedTokensSize)
+			if reloadOnSIGHUP {
+				startReloadWatcher()
+			}
+		},
+		Elected: provider.onElected,
 	})
 
-	esv1.Register(&Provider{
-		NewVaultClient: NewVaultClient,
-	}, &esv1.SecretStoreProvider{
+	esv1.Register(provider, &esv1.SecretStoreProvider{
 		Vault: &esv1.VaultProvider{},
 	}, esv1.MaintenanceStatusMaintained)
 }
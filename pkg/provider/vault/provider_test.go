@@ -15,9 +15,18 @@ package vault
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
 	"fmt"
+	"math/big"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	vault "github.com/hashicorp/vault/api"
@@ -330,7 +339,7 @@ MIIFkTCCA3mgAwIBAgIUBEUg3m/WqAsWHG4Q/II3IePFfuowDQYJKoZIhvcNAQELBQAwWDELMAkGA1UE
 				corev1:        utilfake.NewCreateTokenMock().WithError(errBoom),
 			},
 			want: want{
-				err: fmt.Errorf(errGetKubeSATokenRequest, "example-sa", fmt.Errorf(errGetKubeSA, "example-sa", fmt.Errorf(errServiceAccountNotFound, "example-sa"))),
+				err: fmt.Errorf(errAuthMethodFailed, authMethodKubernetes, fmt.Errorf(errGetKubeSATokenRequest, "example-sa", fmt.Errorf(errGetKubeSA, "example-sa", fmt.Errorf(errServiceAccountNotFound, "example-sa")))),
 			},
 		},
 		"GetKubeSecretError": {
@@ -347,7 +356,7 @@ MIIFkTCCA3mgAwIBAgIUBEUg3m/WqAsWHG4Q/II3IePFfuowDQYJKoZIhvcNAQELBQAwWDELMAkGA1UE
 				kube: clientfake.NewClientBuilder().Build(),
 			},
 			want: want{
-				err: fmt.Errorf(`cannot get Kubernetes secret "vault-secret" from namespace "default": %w`, errors.New(`secrets "vault-secret" not found`)),
+				err: fmt.Errorf(errAuthMethodFailed, authMethodKubernetes, fmt.Errorf(`cannot get Kubernetes secret "vault-secret" from namespace "default": %w`, errors.New(`secrets "vault-secret" not found`))),
 			},
 		},
 		"SuccessfulVaultStoreWithCertAuth": {
@@ -365,7 +374,11 @@ MIIFkTCCA3mgAwIBAgIUBEUg3m/WqAsWHG4Q/II3IePFfuowDQYJKoZIhvcNAQELBQAwWDELMAkGA1UE
 						tlsCrt: clientCrt,
 					},
 				}).Build(),
-				newClientFunc: fake.ClientWithLoginMock,
+				newClientFunc: fake.ModifiableClientWithLoginMock(func(cl *fake.VaultClient) {
+					cl.MockLogical.WriteWithContextFn = func(ctx context.Context, path string, data map[string]any) (*vault.Secret, error) {
+						return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "cert-token"}}, nil
+					}
+				}),
 			},
 			want: want{
 				err: nil,
@@ -512,7 +525,7 @@ MIIFkTCCA3mgAwIBAgIUBEUg3m/WqAsWHG4Q/II3IePFfuowDQYJKoZIhvcNAQELBQAwWDELMAkGA1UE
 				newClientFunc: fake.ClientWithLoginMock,
 			},
 			want: want{
-				err: fmt.Errorf(errClientTLSAuth, "tls: failed to find any PEM data in certificate input"),
+				err: fmt.Errorf(errAuthMethodFailed, authMethodCert, fmt.Errorf(errClientTLSAuth, "tls: failed to find any PEM data in certificate input")),
 			},
 		},
 		"GetKeyFormatError": {
@@ -533,7 +546,7 @@ MIIFkTCCA3mgAwIBAgIUBEUg3m/WqAsWHG4Q/II3IePFfuowDQYJKoZIhvcNAQELBQAwWDELMAkGA1UE
 				newClientFunc: fake.ClientWithLoginMock,
 			},
 			want: want{
-				err: fmt.Errorf(errClientTLSAuth, "tls: failed to find any PEM data in key input"),
+				err: fmt.Errorf(errAuthMethodFailed, authMethodCert, fmt.Errorf(errClientTLSAuth, "tls: failed to find any PEM data in key input")),
 			},
 		},
 		"ClientTlsInvalidCertificatesError": {
@@ -796,7 +809,241 @@ func TestCacheWithReferentSpec(t *testing.T) {
 	}
 }
 
+func TestCacheSharedAcrossStores(t *testing.T) {
+	t.Cleanup(resetCache)
+	t.Cleanup(func() { shareTokens = false })
+	enableCache = true
+	prov := &Provider{
+		NewVaultClient: fake.ClientWithLoginMock,
+	}
+
+	clusterStore := func(tweaks ...secretStoreTweakFn) *esv1.ClusterSecretStore {
+		s := makeClusterSecretStore(tweaks...)
+		s.Namespace = ""
+		s.Name = "vault-cluster-store"
+		s.ResourceVersion = "7"
+		return s
+	}
+	otherRole := func(s *esv1.SecretStore) {
+		s.Spec.Provider.Vault.Auth.Kubernetes.Role = "other-role"
+	}
+
+	cases := map[string]struct {
+		share         bool
+		first         esv1.GenericStore
+		firstNS       string
+		second        esv1.GenericStore
+		secondNS      string
+		expectSharing bool
+	}{
+		"IdenticalAuthSameNamespace": {
+			share:         true,
+			first:         makeValidSecretStore(),
+			firstNS:       "default",
+			second:        clusterStore(),
+			secondNS:      "default",
+			expectSharing: true,
+		},
+		"SharingDisabled": {
+			first:    makeValidSecretStore(),
+			firstNS:  "default",
+			second:   clusterStore(),
+			secondNS: "default",
+		},
+		"IdenticalAuthOtherNamespace": {
+			share:    true,
+			first:    makeValidSecretStore(),
+			firstNS:  "default",
+			second:   clusterStore(),
+			secondNS: "another-namespace",
+		},
+		"DifferentAuth": {
+			share:    true,
+			first:    makeValidSecretStore(),
+			firstNS:  "default",
+			second:   clusterStore(otherRole),
+			secondNS: "default",
+		},
+		"FixedCredentialNamespace": {
+			share:   true,
+			first:   makeValidSecretStore(),
+			firstNS: "default",
+			// credentials read from a fixed namespace aren't shared with
+			// a store reading them from the namespace of the referent.
+			second: clusterStore(func(s *esv1.SecretStore) {
+				s.Spec.Provider.Vault.Auth.Kubernetes.ServiceAccountRef.Namespace = ptr.To("default")
+			}),
+			secondNS: "default",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			shareTokens = tc.share
+			initCache(defaultCacheSize)
+
+			c1, err := getVaultClient(prov, tc.first, nil, tc.firstNS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c2, err := getVaultClient(prov, tc.second, nil, tc.secondNS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if shared := c1 == c2; shared != tc.expectSharing {
+				t.Errorf("expected sharing %t, got %t", tc.expectSharing, shared)
+			}
+		})
+	}
+}
+
 func resetCache() {
 	enableCache = false
 	clientCache = nil
 }
+
+func TestReuseClients(t *testing.T) {
+	t.Cleanup(resetReusedClients)
+	reuseClients = true
+	initReusedClients(defaultReusedClientsSize)
+
+	constructed := 0
+	prov := &Provider{
+		NewVaultClient: func(cfg *vault.Config) (util.Client, error) {
+			constructed++
+			return fake.ClientWithLoginMock(cfg)
+		},
+	}
+	store := makeValidSecretStore()
+
+	certA := makeCertificate(t, "a.vault.example.com")
+	certB := makeCertificate(t, "b.vault.example.com")
+
+	withCA := func(cert *x509.Certificate) func(*tls.Config) {
+		return func(tlsCfg *tls.Config) {
+			pool := x509.NewCertPool()
+			pool.AddCert(cert)
+			tlsCfg.RootCAs = pool
+		}
+	}
+	withClientCert := func(cert *x509.Certificate) func(*tls.Config) {
+		return func(tlsCfg *tls.Config) {
+			tlsCfg.Certificates = []tls.Certificate{{Certificate: [][]byte{cert.Raw}}}
+		}
+	}
+	insecure := func(tlsCfg *tls.Config) { tlsCfg.InsecureSkipVerify = true }
+	verified := func(tlsCfg *tls.Config) {
+		tlsCfg.VerifyConnection = func(tls.ConnectionState) error { return nil }
+	}
+	newConfig := func(address string, opts ...func(*tls.Config)) *vault.Config {
+		cfg := vault.DefaultConfig()
+		cfg.Address = address
+		for _, opt := range opts {
+			opt(cfg.HttpClient.Transport.(*http.Transport).TLSClientConfig)
+		}
+		return cfg
+	}
+
+	// every reconcile builds a new config, only changes to it rebuild the client.
+	reconciles := []struct {
+		name            string
+		cfg             *vault.Config
+		dialAddress     string
+		revocationCheck *esv1.VaultRevocationCheck
+		wantConstructed int
+	}{
+		{name: "FirstReconcile", cfg: newConfig("https://vault.example.com"), wantConstructed: 1},
+		{name: "SameConfig", cfg: newConfig("https://vault.example.com"), wantConstructed: 1},
+		{name: "CAAdded", cfg: newConfig("https://vault.example.com", withCA(certA)), wantConstructed: 2},
+		{name: "SameCA", cfg: newConfig("https://vault.example.com", withCA(certA)), wantConstructed: 2},
+		{name: "CAChanged", cfg: newConfig("https://vault.example.com", withCA(certB)), wantConstructed: 3},
+		{name: "ClientCertAdded", cfg: newConfig("https://vault.example.com", withCA(certB), withClientCert(certA)), wantConstructed: 4},
+		{name: "SameClientCert", cfg: newConfig("https://vault.example.com", withCA(certB), withClientCert(certA)), wantConstructed: 4},
+		{name: "AddressChanged", cfg: newConfig("https://vault-2.example.com", withCA(certB), withClientCert(certA)), wantConstructed: 5},
+		{name: "DialAddressAdded", cfg: newConfig("https://vault-2.example.com", withCA(certB), withClientCert(certA)), dialAddress: "127.0.0.1:8200", wantConstructed: 6},
+		{name: "SameDialAddress", cfg: newConfig("https://vault-2.example.com", withCA(certB), withClientCert(certA)), dialAddress: "127.0.0.1:8200", wantConstructed: 6},
+		{name: "InsecureSkipVerify", cfg: newConfig("https://vault-2.example.com", withCA(certB), withClientCert(certA), insecure), dialAddress: "127.0.0.1:8200", wantConstructed: 7},
+		{name: "VerifyConnectionAdded", cfg: newConfig("https://vault-2.example.com", withCA(certB), withClientCert(certA), verified), dialAddress: "127.0.0.1:8200", revocationCheck: &esv1.VaultRevocationCheck{Method: esv1.VaultRevocationCheckOCSP}, wantConstructed: 8},
+		{name: "SameRevocationCheck", cfg: newConfig("https://vault-2.example.com", withCA(certB), withClientCert(certA), verified), dialAddress: "127.0.0.1:8200", revocationCheck: &esv1.VaultRevocationCheck{Method: esv1.VaultRevocationCheckOCSP}, wantConstructed: 8},
+		{name: "RevocationCheckChanged", cfg: newConfig("https://vault-2.example.com", withCA(certB), withClientCert(certA), verified), dialAddress: "127.0.0.1:8200", revocationCheck: &esv1.VaultRevocationCheck{Method: esv1.VaultRevocationCheckCRL}, wantConstructed: 9},
+	}
+
+	var previous util.Client
+	for _, reconcile := range reconciles {
+		store.Spec.Provider.Vault.DialAddress = reconcile.dialAddress
+		store.Spec.Provider.Vault.RevocationCheck = reconcile.revocationCheck
+		client, err := getVaultClient(prov, store, reconcile.cfg, "default")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", reconcile.name, err)
+		}
+		if constructed != reconcile.wantConstructed {
+			t.Errorf("%s: expected %d constructed clients, got %d", reconcile.name, reconcile.wantConstructed, constructed)
+		}
+		if client == previous {
+			t.Errorf("%s: expected a separate clone for each reconcile", reconcile.name)
+		}
+		previous = client
+	}
+}
+
+func TestReuseClientsLogsInOnEachReconcile(t *testing.T) {
+	t.Cleanup(resetReusedClients)
+	reuseClients = true
+	initReusedClients(defaultReusedClientsSize)
+
+	constructed := 0
+	logins := 0
+	newLoginClient := fake.ModifiableClientWithLoginMock(func(cl *fake.VaultClient) {
+		cl.MockAuth.LoginFn = func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+			logins++
+			return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: "kubernetes-token"}}, nil
+		}
+	})
+	prov := &Provider{
+		NewVaultClient: func(cfg *vault.Config) (util.Client, error) {
+			constructed++
+			return newLoginClient(cfg)
+		},
+	}
+
+	for range 2 {
+		_, err := prov.newClient(context.Background(), makeValidSecretStore(), clientfake.NewClientBuilder().Build(), utilfake.NewCreateTokenMock().WithToken("ok"), "default")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if constructed != 1 {
+		t.Errorf("expected the client to be constructed once, got %d", constructed)
+	}
+	if logins != 2 {
+		t.Errorf("expected a login on each reconcile, got %d", logins)
+	}
+}
+
+func makeCertificate(t *testing.T, name string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func resetReusedClients() {
+	reuseClients = false
+	reusedClients = nil
+}
@@ -0,0 +1,76 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// reloadOnSIGHUP invalidates the cached tokens when the controller receives
+// SIGHUP, e.g. after file-based credentials were rotated out-of-band.
+var reloadOnSIGHUP bool
+
+func startReloadWatcher() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go watchReloadSignals(signals)
+}
+
+func watchReloadSignals(signals <-chan os.Signal) {
+	for range signals {
+		logger.Info("received reload signal, invalidating cached Vault tokens")
+		invalidateTokens()
+	}
+}
+
+// invalidateTokens drops all cached tokens along with every cache of the
+// credentials they were obtained with: lookups, leases, minted service
+// account tokens, JWTs fetched over HTTP, values unwrapped from Secrets,
+// fallback tokens and rejected logins. The next auth of every store then
+// logs in again with the credentials read anew. Cached tokens are revoked
+// like on eviction, before the fallback tokens are forgotten so that those
+// are still left alone.
+func invalidateTokens() {
+	if clientCache != nil {
+		clientCache.Purge()
+	}
+	tokenValiditiesMu.Lock()
+	clear(tokenValidities)
+	tokenValiditiesMu.Unlock()
+	tokenLeasesMu.Lock()
+	clear(tokenLeases)
+	tokenLeasesMu.Unlock()
+	policySnapshotsMu.Lock()
+	clear(policySnapshots)
+	policySnapshotsMu.Unlock()
+	recentAuthsMu.Lock()
+	clear(recentAuths)
+	recentAuthsMu.Unlock()
+	fetchedJwtsMu.Lock()
+	clear(fetchedJwts)
+	fetchedJwtsMu.Unlock()
+	unwrappedMu.Lock()
+	clear(unwrapped)
+	unwrappedMu.Unlock()
+	fallbackTokensMu.Lock()
+	clear(fallbackTokens)
+	fallbackTokensMu.Unlock()
+	authFailuresMu.Lock()
+	clear(authFailures)
+	authFailuresMu.Unlock()
+	forgetMintedTokens()
+}
@@ -0,0 +1,156 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	utilfake "github.com/external-secrets/external-secrets/pkg/provider/util/fake"
+	"github.com/external-secrets/external-secrets/pkg/provider/vault/fake"
+)
+
+func TestReloadInvalidatesCachedTokens(t *testing.T) {
+	t.Cleanup(resetCache)
+	enableCache = true
+	initCache(defaultCacheSize)
+
+	var mu sync.Mutex
+	token := ""
+	logins := 0
+	revoked := 0
+	prov := &Provider{
+		NewVaultClient: fake.ModifiableClientWithLoginMock(func(cl *fake.VaultClient) {
+			cl.MockToken = func() string {
+				mu.Lock()
+				defer mu.Unlock()
+				return token
+			}
+			cl.MockClearToken = func() {
+				mu.Lock()
+				defer mu.Unlock()
+				token = ""
+			}
+			cl.MockAuthToken = fake.Token{
+				LookupSelfWithContextFn: func(ctx context.Context) (*vault.Secret, error) {
+					return makeTokenLookup(time.Hour, true), nil
+				},
+				RevokeSelfWithContextFn: func(ctx context.Context, v string) error {
+					mu.Lock()
+					defer mu.Unlock()
+					revoked++
+					return nil
+				},
+			}
+			cl.MockAuth.LoginFn = func(ctx context.Context, authMethod vault.AuthMethod) (*vault.Secret, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				logins++
+				token = "kubernetes-token"
+				return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: token}}, nil
+			}
+		}),
+	}
+	corev1 := utilfake.NewCreateTokenMock().WithToken("ok")
+	store := makeValidSecretStore()
+	reconcile := func() {
+		t.Helper()
+		if _, err := prov.newClient(context.Background(), store, clientfake.NewClientBuilder().Build(), corev1, "default"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// the cached token is reused until the reload.
+	reconcile()
+	reconcile()
+	if logins != 1 || len(corev1.Requests()) != 1 {
+		t.Fatalf("expected the cached token to be reused, got %d logins and %d token requests", logins, len(corev1.Requests()))
+	}
+
+	signals := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		watchReloadSignals(signals)
+		close(done)
+	}()
+	// the send returns once the watcher received the signal, closing the
+	// channel afterwards waits for the reload to be done.
+	signals <- syscall.SIGHUP
+	close(signals)
+	<-done
+
+	mu.Lock()
+	if revoked != 1 {
+		t.Errorf("expected the cached token to be revoked, got %d revocations", revoked)
+	}
+	mu.Unlock()
+
+	reconcile()
+	if logins != 2 {
+		t.Errorf("expected a new login after the reload, got %d logins", logins)
+	}
+	if len(corev1.Requests()) != 2 {
+		t.Errorf("expected the service account token to be requested again, got %d requests", len(corev1.Requests()))
+	}
+}
+
+func TestInvalidateTokensWithoutCache(t *testing.T) {
+	lease := tokenLease{expiry: time.Now().Add(time.Hour)}
+	tokenValiditiesMu.Lock()
+	tokenValidities[tokenKey("some-token")] = tokenValidity{tokenLease: lease, checked: time.Now()}
+	tokenValiditiesMu.Unlock()
+	tokenLeasesMu.Lock()
+	tokenLeases[tokenKey("some-token")] = lease
+	tokenLeasesMu.Unlock()
+	fetchedJwtsMu.Lock()
+	fetchedJwts["https://jwt.example.com"] = fetchedJwt{jwt: "some-jwt", expiry: lease.expiry}
+	fetchedJwtsMu.Unlock()
+	unwrappedMu.Lock()
+	unwrapped["default/wrapped/token"] = unwrappedValue{wrappingToken: "wrapping-token", value: "some-token"}
+	unwrappedMu.Unlock()
+	(&client{storeKind: "SecretStore", namespace: "default", storeName: "vault"}).markFallbackToken("fallback-token")
+	authFailuresMu.Lock()
+	authFailures["some-store"] = authFailure{}
+	authFailuresMu.Unlock()
+
+	// the token cache is only set up if enabled.
+	invalidateTokens()
+
+	locked := func(mu *sync.Mutex, n func() int) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return n()
+	}
+	caches := map[string]int{
+		"validities": locked(&tokenValiditiesMu, func() int { return len(tokenValidities) }),
+		"leases":     locked(&tokenLeasesMu, func() int { return len(tokenLeases) }),
+		"jwts":       locked(&fetchedJwtsMu, func() int { return len(fetchedJwts) }),
+		"unwrapped":  locked(&unwrappedMu, func() int { return len(unwrapped) }),
+		"fallbacks":  locked(&fallbackTokensMu, func() int { return len(fallbackTokens) }),
+		"failures":   locked(&authFailuresMu, func() int { return len(authFailures) }),
+	}
+	for name, got := range caches {
+		if got != 0 {
+			t.Errorf("expected all %s to be dropped, got %d", name, got)
+		}
+	}
+}
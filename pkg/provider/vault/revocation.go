@@ -0,0 +1,323 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	"golang.org/x/crypto/ocsp"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	"github.com/external-secrets/external-secrets/pkg/constants"
+	"github.com/external-secrets/external-secrets/pkg/metrics"
+)
+
+const (
+	errCertRevoked          = "Vault server certificate %q was revoked at %s"
+	errRevocationUnknown    = "cannot determine the revocation status of Vault server certificate %q: %w"
+	errRevocationNoIssuer   = "certificate has no verified issuer"
+	errOCSPNoResponder      = "certificate has no OCSP responder"
+	errOCSPRequest          = "cannot query OCSP responder %q: %w"
+	errOCSPResponse         = "invalid OCSP response: %w"
+	errOCSPStatusUnknown    = "OCSP responder doesn't know the certificate"
+	errCRLNoDistribution    = "certificate has no CRL distribution point"
+	errCRLFetch             = "cannot fetch CRL %q: %w"
+	errCRLSignature         = "CRL %q is not signed by the issuer of the certificate: %w"
+	errRevocationStale      = "revocation status expired at %s"
+	errRevocationHTTPStatus = "unexpected status %s"
+)
+
+// maxRevocationResponseSize bounds the size of OCSP responses and CRLs.
+const maxRevocationResponseSize = 10 << 20
+
+// defaultRevocationTimeout bounds the revocation check of transports
+// without a TLS handshake timeout.
+const defaultRevocationTimeout = 10 * time.Second
+
+var (
+	revocationCacheMu sync.Mutex
+	// revocationCache holds the time until which certificates are known to
+	// be unrevoked, by method, issuer and serial number.
+	revocationCache = map[string]time.Time{}
+)
+
+// revocationStatus is the revocation status of a certificate.
+type revocationStatus struct {
+	revoked   bool
+	revokedAt time.Time
+	// nextUpdate is when a newer status is available, zero if unknown.
+	nextUpdate time.Time
+}
+
+// configureRevocationCheck checks the revocation status of the server
+// certificate on each TLS handshake, if configured. OCSP responses and CRLs
+// are fetched with a copy of the transport of the store, so that its proxy
+// applies, but without its TLS config, whose check this is. The check is
+// bounded by the TLS handshake timeout of the transport.
+func (c *client) configureRevocationCheck(cfg *vault.Config) {
+	check := c.store.RevocationCheck
+	if check == nil {
+		return
+	}
+	transport, ok := cfg.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return
+	}
+	fetchTransport := transport.Clone()
+	fetchTransport.TLSClientConfig = nil
+	httpClient := &http.Client{Transport: fetchTransport}
+	timeout := transport.TLSHandshakeTimeout
+	if timeout <= 0 {
+		timeout = defaultRevocationTimeout
+	}
+	log := c.log
+	transport.TLSClientConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		err := verifyRevocation(ctx, httpClient, cs, check.Method)
+		var unknown *revocationUnknownError
+		if errors.As(err, &unknown) && check.SoftFail {
+			log.Info("accepting Vault server certificate of unknown revocation status", "error", err)
+			return nil
+		}
+		return err
+	}
+}
+
+// revocationUnknownError is returned if the revocation status of a
+// certificate can't be determined.
+type revocationUnknownError struct {
+	subject string
+	err     error
+}
+
+func (e *revocationUnknownError) Error() string {
+	return fmt.Errorf(errRevocationUnknown, e.subject, e.err).Error()
+}
+
+func (e *revocationUnknownError) Unwrap() error {
+	return e.err
+}
+
+// verifyRevocation returns an error if the verified server certificate of
+// the connection is revoked or its status can't be determined.
+func verifyRevocation(ctx context.Context, httpClient *http.Client, cs tls.ConnectionState, method esv1.VaultRevocationCheckMethod) error {
+	if len(cs.VerifiedChains) == 0 || len(cs.VerifiedChains[0]) < 2 {
+		subject := ""
+		if len(cs.PeerCertificates) > 0 {
+			subject = cs.PeerCertificates[0].Subject.String()
+		}
+		return &revocationUnknownError{subject: subject, err: errors.New(errRevocationNoIssuer)}
+	}
+	leaf, issuer := cs.VerifiedChains[0][0], cs.VerifiedChains[0][1]
+	key := fmt.Sprintf("%s/%x/%s", method, issuer.SubjectKeyId, leaf.SerialNumber)
+	if cachedUnrevoked(key) {
+		return nil
+	}
+
+	var status *revocationStatus
+	var err error
+	switch method {
+	case esv1.VaultRevocationCheckCRL:
+		status, err = crlStatus(ctx, httpClient, leaf, issuer)
+	default:
+		status, err = ocspStatus(ctx, httpClient, cs.OCSPResponse, leaf, issuer)
+	}
+	if err != nil {
+		return &revocationUnknownError{subject: leaf.Subject.String(), err: err}
+	}
+	if status.revoked {
+		return fmt.Errorf(errCertRevoked, leaf.Subject, status.revokedAt.Format(time.RFC3339))
+	}
+	cacheUnrevoked(key, status.nextUpdate)
+	return nil
+}
+
+// cachedUnrevoked reports whether the certificate is known to be unrevoked.
+func cachedUnrevoked(key string) bool {
+	revocationCacheMu.Lock()
+	defer revocationCacheMu.Unlock()
+	return time.Now().Before(revocationCache[key])
+}
+
+// cacheUnrevoked remembers the certificate as unrevoked until the next
+// update of its status, if there is one.
+func cacheUnrevoked(key string, nextUpdate time.Time) {
+	if nextUpdate.IsZero() {
+		return
+	}
+	revocationCacheMu.Lock()
+	defer revocationCacheMu.Unlock()
+	now := time.Now()
+	for k, until := range revocationCache {
+		if !now.Before(until) {
+			delete(revocationCache, k)
+		}
+	}
+	revocationCache[key] = nextUpdate
+}
+
+// ocspStatus returns the status of the certificate in the stapled OCSP
+// response, or in that of the first of its OCSP responders that answers if
+// none was stapled.
+func ocspStatus(ctx context.Context, httpClient *http.Client, stapled []byte, leaf, issuer *x509.Certificate) (*revocationStatus, error) {
+	if len(stapled) != 0 {
+		return parseOCSPStatus(stapled, leaf, issuer)
+	}
+	if len(leaf.OCSPServer) == 0 {
+		return nil, errors.New(errOCSPNoResponder)
+	}
+	var errs []error
+	for _, server := range leaf.OCSPServer {
+		raw, err := queryOCSP(ctx, httpClient, server, leaf, issuer)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		status, err := parseOCSPStatus(raw, leaf, issuer)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return status, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// parseOCSPStatus returns the status of the certificate in the OCSP
+// response.
+func parseOCSPStatus(raw []byte, leaf, issuer *x509.Certificate) (*revocationStatus, error) {
+	resp, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+	if err != nil {
+		return nil, fmt.Errorf(errOCSPResponse, err)
+	}
+	if !resp.NextUpdate.IsZero() && time.Now().After(resp.NextUpdate) {
+		return nil, fmt.Errorf(errRevocationStale, resp.NextUpdate.Format(time.RFC3339))
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		return &revocationStatus{nextUpdate: resp.NextUpdate}, nil
+	case ocsp.Revoked:
+		return &revocationStatus{revoked: true, revokedAt: resp.RevokedAt}, nil
+	default:
+		return nil, errors.New(errOCSPStatusUnknown)
+	}
+}
+
+// queryOCSP requests the status of the certificate from the OCSP responder.
+func queryOCSP(ctx context.Context, httpClient *http.Client, server string, leaf, issuer *x509.Certificate) ([]byte, error) {
+	body, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf(errOCSPRequest, server, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf(errOCSPRequest, server, err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	resp, err := httpClient.Do(req)
+	if err == nil {
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf(errRevocationHTTPStatus, resp.Status)
+		}
+	}
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultQueryOCSP, err)
+	if err != nil {
+		return nil, fmt.Errorf(errOCSPRequest, server, err)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf(errOCSPRequest, server, err)
+	}
+	return raw, nil
+}
+
+// crlStatus looks the certificate up in the CRL of the first of its
+// distribution points that can be fetched.
+func crlStatus(ctx context.Context, httpClient *http.Client, leaf, issuer *x509.Certificate) (*revocationStatus, error) {
+	if len(leaf.CRLDistributionPoints) == 0 {
+		return nil, errors.New(errCRLNoDistribution)
+	}
+	var errs []error
+	for _, url := range leaf.CRLDistributionPoints {
+		crl, err := fetchCRL(ctx, httpClient, url)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := crl.CheckSignatureFrom(issuer); err != nil {
+			errs = append(errs, fmt.Errorf(errCRLSignature, url, err))
+			continue
+		}
+		if !crl.NextUpdate.IsZero() && time.Now().After(crl.NextUpdate) {
+			errs = append(errs, fmt.Errorf(errRevocationStale, crl.NextUpdate.Format(time.RFC3339)))
+			continue
+		}
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+				return &revocationStatus{revoked: true, revokedAt: entry.RevocationTime}, nil
+			}
+		}
+		return &revocationStatus{nextUpdate: crl.NextUpdate}, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// fetchCRL downloads and parses the CRL, which may be DER or PEM encoded.
+func fetchCRL(ctx context.Context, httpClient *http.Client, url string) (*x509.RevocationList, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf(errCRLFetch, url, err)
+	}
+	resp, err := httpClient.Do(req)
+	if err == nil {
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf(errRevocationHTTPStatus, resp.Status)
+		}
+	}
+	metrics.ObserveAPICall(constants.ProviderHCVault, constants.CallHCVaultFetchCRL, err)
+	if err != nil {
+		return nil, fmt.Errorf(errCRLFetch, url, err)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf(errCRLFetch, url, err)
+	}
+	if block, _ := pem.Decode(raw); block != nil {
+		raw = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(raw)
+	if err != nil {
+		return nil, fmt.Errorf(errCRLFetch, url, err)
+	}
+	return crl, nil
+}
@@ -0,0 +1,422 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	"golang.org/x/crypto/ocsp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
+)
+
+func TestRevocationCheck(t *testing.T) {
+	cases := map[string]struct {
+		method esv1.VaultRevocationCheckMethod
+		// status is the OCSP status or whether the CRL lists the certificate.
+		status int
+		// stapled staples the OCSP response instead of serving it.
+		stapled     bool
+		unavailable bool
+		// fallback lists an unavailable responder or CRL first.
+		fallback    bool
+		softFail    bool
+		wantQueries int
+		wantErr     string
+	}{
+		"OCSPGood": {
+			method:      esv1.VaultRevocationCheckOCSP,
+			status:      ocsp.Good,
+			wantQueries: 1,
+		},
+		"OCSPRevoked": {
+			method:      esv1.VaultRevocationCheckOCSP,
+			status:      ocsp.Revoked,
+			wantQueries: 1,
+			wantErr:     "was revoked",
+		},
+		"OCSPUnknown": {
+			method:      esv1.VaultRevocationCheckOCSP,
+			status:      ocsp.Unknown,
+			wantQueries: 1,
+			wantErr:     "OCSP responder doesn't know the certificate",
+		},
+		"OCSPStapledGood": {
+			method:  esv1.VaultRevocationCheckOCSP,
+			status:  ocsp.Good,
+			stapled: true,
+		},
+		"OCSPStapledRevoked": {
+			method:  esv1.VaultRevocationCheckOCSP,
+			status:  ocsp.Revoked,
+			stapled: true,
+			wantErr: "was revoked",
+		},
+		"OCSPUnavailable": {
+			method:      esv1.VaultRevocationCheckOCSP,
+			unavailable: true,
+			wantQueries: 1,
+			wantErr:     "cannot determine the revocation status",
+		},
+		"OCSPUnavailableSoftFail": {
+			method:      esv1.VaultRevocationCheckOCSP,
+			unavailable: true,
+			softFail:    true,
+			wantQueries: 1,
+		},
+		// revoked certificates are rejected regardless.
+		"OCSPRevokedSoftFail": {
+			method:      esv1.VaultRevocationCheckOCSP,
+			status:      ocsp.Revoked,
+			softFail:    true,
+			wantQueries: 1,
+			wantErr:     "was revoked",
+		},
+		"OCSPFallback": {
+			method:      esv1.VaultRevocationCheckOCSP,
+			status:      ocsp.Good,
+			fallback:    true,
+			wantQueries: 2,
+		},
+		"CRLGood": {
+			method:      esv1.VaultRevocationCheckCRL,
+			wantQueries: 1,
+		},
+		"CRLRevoked": {
+			method:      esv1.VaultRevocationCheckCRL,
+			status:      ocsp.Revoked,
+			wantQueries: 1,
+			wantErr:     "was revoked",
+		},
+		"CRLFallback": {
+			method:      esv1.VaultRevocationCheckCRL,
+			fallback:    true,
+			wantQueries: 2,
+		},
+		"CRLUnavailable": {
+			method:      esv1.VaultRevocationCheckCRL,
+			unavailable: true,
+			wantQueries: 1,
+			wantErr:     "cannot fetch CRL",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			caCert, caKey := makeRevocationCA(t)
+			serial := big.NewInt(time.Now().UnixNano())
+
+			queries := 0
+			responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				queries++
+				if tc.unavailable || r.URL.Path == "/down" {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				if tc.method == esv1.VaultRevocationCheckCRL {
+					_, _ = w.Write(makeCRL(t, caCert, caKey, serial, tc.status == ocsp.Revoked))
+					return
+				}
+				_, _ = w.Write(makeOCSPResponse(t, caCert, caKey, serial, tc.status))
+			}))
+			defer responder.Close()
+
+			urls := []string{responder.URL}
+			if tc.fallback {
+				urls = []string{responder.URL + "/down", responder.URL}
+			}
+			leaf := tls.Certificate{}
+			leaf.Certificate, leaf.PrivateKey = makeRevocationLeaf(t, caCert, caKey, serial, urls...)
+			if tc.stapled {
+				leaf.OCSPStaple = makeOCSPResponse(t, caCert, caKey, serial, tc.status)
+			}
+			logins := 0
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/auth/approle/login" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				logins++
+				_, _ = w.Write([]byte(`{"auth": {"client_token": "approle-token"}}`))
+			}))
+			server.TLS = &tls.Config{Certificates: []tls.Certificate{leaf}}
+			server.StartTLS()
+			defer server.Close()
+
+			kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "approle-secret",
+					Namespace: "default",
+				},
+				Data: map[string][]byte{"secret-id": []byte("secret")},
+			}).Build()
+			store := &esv1.SecretStore{
+				TypeMeta: metav1.TypeMeta{Kind: esv1.SecretStoreKind},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vault-store",
+					Namespace: "default",
+				},
+				Spec: esv1.SecretStoreSpec{
+					// failed handshakes aren't retried by the Vault client.
+					RetrySettings: &esv1.SecretStoreRetrySettings{MaxRetries: ptr.To(int32(0))},
+					Provider: &esv1.SecretStoreProvider{
+						Vault: &esv1.VaultProvider{
+							Server:   server.URL,
+							Version:  esv1.VaultKVStoreV2,
+							CABundle: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw}),
+							RevocationCheck: &esv1.VaultRevocationCheck{
+								Method:   tc.method,
+								SoftFail: tc.softFail,
+							},
+							Auth: &esv1.VaultAuth{
+								AppRole: &esv1.VaultAppRole{
+									Path:      "approle",
+									RoleID:    "role",
+									SecretRef: esmeta.SecretKeySelector{Name: "approle-secret", Key: "secret-id"},
+								},
+							},
+						},
+					},
+				},
+			}
+			prov := &Provider{NewVaultClient: NewVaultClient}
+			_, err := prov.newClient(context.Background(), store, kube, nil, "default")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				if logins != 0 {
+					t.Errorf("expected no login, got %d", logins)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if logins != 1 {
+					t.Errorf("expected a login, got %d", logins)
+				}
+			}
+			if queries != tc.wantQueries {
+				t.Errorf("expected %d revocation queries, got %d", tc.wantQueries, queries)
+			}
+		})
+	}
+}
+
+func TestRevocationCheckCache(t *testing.T) {
+	caCert, caKey := makeRevocationCA(t)
+	serial := big.NewInt(time.Now().UnixNano())
+	queries := 0
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		_, _ = w.Write(makeOCSPResponse(t, caCert, caKey, serial, ocsp.Good))
+	}))
+	defer responder.Close()
+	der, _ := makeRevocationLeaf(t, caCert, caKey, serial, responder.URL)
+	leaf, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs := tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf, caCert}}}
+	for range 2 {
+		if err := verifyRevocation(context.Background(), http.DefaultClient, cs, esv1.VaultRevocationCheckOCSP); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if queries != 1 {
+		t.Errorf("expected the status to be cached until its next update, got %d queries", queries)
+	}
+}
+
+func TestRevocationCheckTransport(t *testing.T) {
+	cases := map[string]struct {
+		// hang keeps the responder from answering.
+		hang    bool
+		wantErr string
+	}{
+		// the responder is reached through the proxy of the store.
+		"Proxy": {},
+		// the check gives up with the TLS handshake.
+		"HandshakeTimeout": {
+			hang:    true,
+			wantErr: "context deadline exceeded",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			caCert, caKey := makeRevocationCA(t)
+			serial := big.NewInt(time.Now().UnixNano())
+			done := make(chan struct{})
+			var proxied atomic.Int32
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proxied.Add(1)
+				if r.Host != "ocsp.example.com" {
+					t.Errorf("expected a request for the responder, got host %q", r.Host)
+				}
+				if tc.hang {
+					<-done
+					return
+				}
+				_, _ = w.Write(makeOCSPResponse(t, caCert, caKey, serial, ocsp.Good))
+			}))
+			defer proxy.Close()
+			defer close(done)
+			proxyURL, err := url.Parse(proxy.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			der, _ := makeRevocationLeaf(t, caCert, caKey, serial, "http://ocsp.example.com")
+			leaf, err := x509.ParseCertificate(der[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := vault.DefaultConfig()
+			transport := cfg.HttpClient.Transport.(*http.Transport)
+			transport.Proxy = http.ProxyURL(proxyURL)
+			transport.TLSHandshakeTimeout = 100 * time.Millisecond
+			c := &client{
+				log:   logger,
+				store: &esv1.VaultProvider{RevocationCheck: &esv1.VaultRevocationCheck{Method: esv1.VaultRevocationCheckOCSP}},
+			}
+			c.configureRevocationCheck(cfg)
+
+			err = transport.TLSClientConfig.VerifyConnection(tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf, caCert}}})
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+			if got := proxied.Load(); got != 1 {
+				t.Errorf("expected 1 proxied query, got %d", got)
+			}
+		})
+	}
+}
+
+// makeRevocationCA returns a CA that signs certificates, OCSP responses
+// and CRLs.
+func makeRevocationCA(t *testing.T) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "vault-ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// makeRevocationLeaf returns a server certificate for localhost whose OCSP
+// responders and CRLs are served at urls.
+func makeRevocationLeaf(t *testing.T, ca *x509.Certificate, caKey crypto.Signer, serial *big.Int, urls ...string) ([][]byte, crypto.Signer) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "vault"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		OCSPServer:            urls,
+		CRLDistributionPoints: urls,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return [][]byte{der}, key
+}
+
+func makeOCSPResponse(t *testing.T, ca *x509.Certificate, caKey crypto.Signer, serial *big.Int, status int) []byte {
+	t.Helper()
+	resp, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+		Status:       status,
+		SerialNumber: serial,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+		RevokedAt:    time.Now().Add(-time.Minute),
+	}, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func makeCRL(t *testing.T, ca *x509.Certificate, caKey crypto.Signer, serial *big.Int, revoked bool) []byte {
+	t.Helper()
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+	if revoked {
+		template.RevokedCertificateEntries = []x509.RevocationListEntry{{
+			SerialNumber:   serial,
+			RevocationTime: time.Now().Add(-time.Minute),
+		}}
+	}
+	crl, err := x509.CreateRevocationList(rand.Reader, template, ca, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crl
+}
@@ -21,6 +21,9 @@ import (
 	vault "github.com/hashicorp/vault/api"
 )
 
+// JwtProviderFactory returns the provider of the AWS credentials of the
+// role of a Kubernetes service account, whose token is requested for the
+// audiences. It is used by IAM auth, see vault.WithJWTProvider.
 type JwtProviderFactory func(name, namespace, roleArn string, aud []string, region string) (credentials.Provider, error)
 
 type Auth interface {
@@ -29,7 +32,11 @@ type Auth interface {
 
 type Token interface {
 	RevokeSelfWithContext(ctx context.Context, token string) error
+	RevokeTreeWithContext(ctx context.Context, token string) error
+	RevokeOrphanWithContext(ctx context.Context, token string) error
 	LookupSelfWithContext(ctx context.Context) (*vault.Secret, error)
+	RenewSelfWithContext(ctx context.Context, increment int) (*vault.Secret, error)
+	CreateWithRoleWithContext(ctx context.Context, opts *vault.TokenCreateRequest, roleName string) (*vault.Secret, error)
 }
 
 type Logical interface {
@@ -37,6 +44,12 @@ type Logical interface {
 	ListWithContext(ctx context.Context, path string) (*vault.Secret, error)
 	WriteWithContext(ctx context.Context, path string, data map[string]any) (*vault.Secret, error)
 	DeleteWithContext(ctx context.Context, path string) (*vault.Secret, error)
+	UnwrapWithContext(ctx context.Context, wrappingToken string) (*vault.Secret, error)
+}
+
+type Sys interface {
+	HealthWithContext(ctx context.Context) (*vault.HealthResponse, error)
+	CapabilitiesSelfWithContext(ctx context.Context, path string) ([]string, error)
 }
 
 type Client interface {
@@ -46,21 +59,27 @@ type Client interface {
 	Auth() Auth
 	Logical() Logical
 	AuthToken() Token
+	Sys() Sys
 	Namespace() string
 	SetNamespace(namespace string)
+	// WithNamespace returns a copy of the client using the given namespace,
+	// leaving the namespace of this client unchanged.
+	WithNamespace(namespace string) Client
 	AddHeader(key, value string)
 }
 
 type VaultClient struct {
-	SetTokenFunc     func(v string)
-	TokenFunc        func() string
-	ClearTokenFunc   func()
-	AuthField        Auth
-	LogicalField     Logical
-	AuthTokenField   Token
-	NamespaceFunc    func() string
-	SetNamespaceFunc func(namespace string)
-	AddHeaderFunc    func(key, value string)
+	SetTokenFunc      func(v string)
+	TokenFunc         func() string
+	ClearTokenFunc    func()
+	AuthField         Auth
+	LogicalField      Logical
+	AuthTokenField    Token
+	SysField          Sys
+	NamespaceFunc     func() string
+	SetNamespaceFunc  func(namespace string)
+	WithNamespaceFunc func(namespace string) Client
+	AddHeaderFunc     func(key, value string)
 }
 
 func (v VaultClient) AddHeader(key, value string) {
@@ -75,6 +94,10 @@ func (v VaultClient) SetNamespace(namespace string) {
 	v.SetNamespaceFunc(namespace)
 }
 
+func (v VaultClient) WithNamespace(namespace string) Client {
+	return v.WithNamespaceFunc(namespace)
+}
+
 func (v VaultClient) ClearToken() {
 	v.ClearTokenFunc()
 }
@@ -98,3 +121,7 @@ func (v VaultClient) AuthToken() Token {
 func (v VaultClient) Logical() Logical {
 	return v.LogicalField
 }
+
+func (v VaultClient) Sys() Sys {
+	return v.SysField
+}
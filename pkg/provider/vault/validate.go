@@ -18,34 +18,76 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/PaesslerAG/jsonpath"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
 	"github.com/external-secrets/external-secrets/pkg/utils"
 )
 
 const (
-	errInvalidCredentials     = "invalid vault credentials: %w"
-	errInvalidStore           = "invalid store"
-	errInvalidStoreSpec       = "invalid store spec"
-	errInvalidStoreProv       = "invalid store provider"
-	errInvalidVaultProv       = "invalid vault provider"
-	errInvalidAppRoleRef      = "invalid Auth.AppRole.RoleRef: %w"
-	errInvalidAppRoleSec      = "invalid Auth.AppRole.SecretRef: %w"
-	errInvalidClientCert      = "invalid Auth.Cert.ClientCert: %w"
-	errInvalidCertSec         = "invalid Auth.Cert.SecretRef: %w"
-	errInvalidJwtSec          = "invalid Auth.Jwt.SecretRef: %w"
-	errInvalidJwtK8sSA        = "invalid Auth.Jwt.KubernetesServiceAccountToken.ServiceAccountRef: %w"
-	errInvalidKubeSA          = "invalid Auth.Kubernetes.ServiceAccountRef: %w"
-	errInvalidKubeSec         = "invalid Auth.Kubernetes.SecretRef: %w"
-	errInvalidLdapSec         = "invalid Auth.Ldap.SecretRef: %w"
-	errInvalidTokenRef        = "invalid Auth.TokenSecretRef: %w"
-	errInvalidUserPassSec     = "invalid Auth.UserPass.SecretRef: %w"
-	errInvalidClientTLSCert   = "invalid ClientTLS.ClientCert: %w"
-	errInvalidClientTLSSecret = "invalid ClientTLS.SecretRef: %w"
-	errInvalidClientTLS       = "when provided, both ClientTLS.ClientCert and ClientTLS.SecretRef should be provided"
-	errCASNotSupportedInKVv1  = "checkAndSet is not supported with Vault KV version v1"
+	errInvalidCredentials        = "invalid vault credentials: %w"
+	errInvalidStore              = "invalid store"
+	errInvalidStoreSpec          = "invalid store spec"
+	errInvalidStoreProv          = "invalid store provider"
+	errInvalidVaultProv          = "invalid vault provider"
+	errInvalidAppRoleRef         = "invalid Auth.AppRole.RoleRef: %w"
+	errInvalidAppRoleSec         = "invalid Auth.AppRole.SecretRef: %w"
+	errInvalidAppRoleFallback    = "invalid Auth.AppRole.FallbackCredentials[%d]: %w"
+	errInvalidClientCert         = "invalid Auth.Cert.ClientCert: %w"
+	errInvalidCertSec            = "invalid Auth.Cert.SecretRef: %w"
+	errInvalidJwtSec             = "invalid Auth.Jwt.SecretRef: %w"
+	errInvalidJwtPath            = "invalid Auth.Jwt.Path: %w"
+	errInvalidJwtK8sSA           = "invalid Auth.Jwt.KubernetesServiceAccountToken.ServiceAccountRef: %w"
+	errInvalidJwtExchange        = "invalid Auth.Jwt.TokenExchange.TokenEndpoint: %w"
+	errInvalidJwtHTTPSource      = "invalid Auth.Jwt.HTTPSource: %w"
+	errInvalidKubeSA             = "invalid Auth.Kubernetes.ServiceAccountRef: %w"
+	errInvalidKubeSec            = "invalid Auth.Kubernetes.SecretRef: %w"
+	errInvalidKubeTokenRetry     = "invalid Auth.Kubernetes.TokenRequestRetrySettings: %w"
+	errInvalidKubeLoginRetry     = "invalid Auth.Kubernetes.LoginRetrySettings: %w"
+	errInvalidKubeTokenReq       = "invalid Auth.Kubernetes.KubernetesTokenRequest: %w"
+	errInvalidJwtTokenReq        = "invalid Auth.Jwt.KubernetesServiceAccountToken.KubernetesTokenRequest: %w"
+	errInvalidLdapSec            = "invalid Auth.Ldap.SecretRef: %w"
+	errInvalidGcpSA              = "invalid Auth.Gcp.ServiceAccountRef: %w"
+	errInvalidAzureSA            = "invalid Auth.Azure.ServiceAccountRef: %w"
+	errInvalidPluginSec          = "invalid Auth.Plugin.SecretParameters[%q]: %w"
+	errInvalidTokenRef           = "invalid Auth.TokenSecretRef: %w"
+	errInvalidFallbackToken      = "invalid Auth.FallbackTokenRef: %w"
+	errInvalidTokenFile          = "Auth.TokenFilePath must be an absolute path, got %q"
+	errInvalidWrappedToken       = "Auth.WrappedToken requires Auth.TokenSecretRef"
+	errInvalidTokenRole          = "invalid Auth.TokenRole: %w"
+	errInvalidUserPassSec        = "invalid Auth.UserPass.SecretRef: %w"
+	errInvalidUserPassPath       = "invalid Auth.UserPass.JSONPaths.%s %q: %w"
+	errMissingUserPassPath       = "Auth.UserPass.JSONPaths.Password is required"
+	errInvalidClientTLSCert      = "invalid ClientTLS.ClientCert: %w"
+	errInvalidClientTLSSecret    = "invalid ClientTLS.SecretRef: %w"
+	errInvalidClientTLS          = "when provided, both ClientTLS.ClientCert and ClientTLS.SecretRef should be provided"
+	errCASNotSupportedInKVv1     = "checkAndSet is not supported with Vault KV version v1"
+	errInvalidAuthSelection      = "invalid Auth.Selection[%d]: auth method %q is not configured"
+	errMultipleAuthMethods       = "only one auth method can be configured without Auth.Selection or Auth.AuthMethodPriority, got %s"
+	errInvalidAuthPriority       = "invalid Auth.AuthMethodPriority[%d]: auth method %q is not configured"
+	errDuplicateAuthPriority     = "invalid Auth.AuthMethodPriority[%d]: auth method %q is listed more than once"
+	errAuthPriorityWithSelection = "Auth.AuthMethodPriority and Auth.Selection are mutually exclusive"
+	errInvalidDialAddress        = "invalid DialAddress: %w"
+	errInvalidBasePath           = "invalid BasePath %q: %w"
+	errInvalidHTTPVersion        = "HTTPVersion HTTP2 requires an HTTPS server address"
+	errInvalidAuthNamespace      = "Auth.Namespace and Auth.RootNamespace are mutually exclusive"
+	errInvalidMountAuth          = "invalid MountAuth[%d]: %w"
+	errInvalidWriteAuth          = "invalid WriteAuth: %w"
+	errInvalidLocalMount         = "Auth.LocalMount cannot be used with ForwardInconsistent"
+	errInvalidPolicySource       = "Auth.PolicySource requires exactly one of configMapRef or rolePath"
+	errInvalidPolicyConfigMap    = "invalid Auth.PolicySource.ConfigMapRef: %w"
+	errInvalidLoginRetry         = "invalid Auth.LoginRetry: %w"
+	errInvalidTokenLeeway        = "Auth.TokenExpirationLeewaySeconds must not be negative"
+	errInvalidLoginTimeout       = "Auth.LoginTimeout must be positive"
+	errInvalidRevocation         = "RevocationCheck.Method must be OCSP or CRL, got %q"
 )
 
 func (p *Provider) ValidateStore(store esv1.GenericStore) (admission.Warnings, error) {
@@ -64,114 +106,397 @@ func (p *Provider) ValidateStore(store esv1.GenericStore) (admission.Warnings, e
 		return nil, errors.New(errInvalidVaultProv)
 	}
 	if vaultProvider.Auth != nil {
-		if vaultProvider.Auth.AppRole != nil {
-			// check SecretRef for valid configuration
-			if err := utils.ValidateReferentSecretSelector(store, vaultProvider.Auth.AppRole.SecretRef); err != nil {
-				return nil, fmt.Errorf(errInvalidAppRoleSec, err)
-			}
-
-			// prefer .auth.appRole.roleId, fallback to .auth.appRole.roleRef, give up after that.
-			if vaultProvider.Auth.AppRole.RoleID == "" { // prevents further RoleID tests if .auth.appRole.roleId is given
-				if vaultProvider.Auth.AppRole.RoleRef != nil { // check RoleRef for valid configuration
-					if err := utils.ValidateReferentSecretSelector(store, *vaultProvider.Auth.AppRole.RoleRef); err != nil {
-						return nil, fmt.Errorf(errInvalidAppRoleRef, err)
-					}
-				} else { // we ran out of ways to get RoleID. return an appropriate error
-					return nil, errors.New(errInvalidAppRoleID)
+		if err := validateAuth(store, vaultProvider.Auth); err != nil {
+			return nil, err
+		}
+		if vaultProvider.Auth.LocalMount && vaultProvider.ForwardInconsistent {
+			return nil, errors.New(errInvalidLocalMount)
+		}
+	}
+	paths := make(map[string]bool, len(vaultProvider.MountAuth))
+	for i := range vaultProvider.MountAuth {
+		mount := &vaultProvider.MountAuth[i]
+		path := normalizeMountPath(mount.Path)
+		if path == "" || paths[path] {
+			return nil, fmt.Errorf(errInvalidMountAuth, i, fmt.Errorf("path %q is empty or not unique", mount.Path))
+		}
+		paths[path] = true
+		if err := validateAuth(store, &mount.Auth); err != nil {
+			return nil, fmt.Errorf(errInvalidMountAuth, i, err)
+		}
+		if mount.Auth.LocalMount && vaultProvider.ForwardInconsistent {
+			return nil, fmt.Errorf(errInvalidMountAuth, i, errors.New(errInvalidLocalMount))
+		}
+	}
+	if writeAuth := vaultProvider.WriteAuth; writeAuth != nil {
+		if err := validateAuth(store, writeAuth); err != nil {
+			return nil, fmt.Errorf(errInvalidWriteAuth, err)
+		}
+		if writeAuth.LocalMount && vaultProvider.ForwardInconsistent {
+			return nil, fmt.Errorf(errInvalidWriteAuth, errors.New(errInvalidLocalMount))
+		}
+	}
+	if vaultProvider.ClientTLS.CertSecretRef != nil && vaultProvider.ClientTLS.KeySecretRef != nil {
+		if err := utils.ValidateReferentSecretSelector(store, *vaultProvider.ClientTLS.CertSecretRef); err != nil {
+			return nil, fmt.Errorf(errInvalidClientTLSCert, err)
+		}
+		if err := utils.ValidateReferentSecretSelector(store, *vaultProvider.ClientTLS.KeySecretRef); err != nil {
+			return nil, fmt.Errorf(errInvalidClientTLSSecret, err)
+		}
+	} else if vaultProvider.ClientTLS.CertSecretRef != nil || vaultProvider.ClientTLS.KeySecretRef != nil {
+		return nil, errors.New(errInvalidClientTLS)
+	}
+
+	if vaultProvider.DialAddress != "" {
+		if isUnixSocket(vaultProvider.Server) {
+			return nil, fmt.Errorf(errInvalidDialAddress, errors.New("cannot be used with a unix socket server address"))
+		}
+		if _, _, err := net.SplitHostPort(vaultProvider.DialAddress); err != nil {
+			return nil, fmt.Errorf(errInvalidDialAddress, err)
+		}
+	}
+
+	if err := validateBasePath(vaultProvider.BasePath); err != nil {
+		return nil, err
+	}
+	if vaultProvider.HTTPVersion == esv1.VaultHTTPVersion2 && !strings.HasPrefix(vaultProvider.Server, "https://") {
+		return nil, errors.New(errInvalidHTTPVersion)
+	}
+
+	if check := vaultProvider.RevocationCheck; check != nil {
+		if check.Method != esv1.VaultRevocationCheckOCSP && check.Method != esv1.VaultRevocationCheckCRL {
+			return nil, fmt.Errorf(errInvalidRevocation, check.Method)
+		}
+	}
+
+	// Validate CAS configuration
+	if vaultProvider.CheckAndSet != nil && vaultProvider.CheckAndSet.Required {
+		if vaultProvider.Version == esv1.VaultKVStoreV1 {
+			return nil, errors.New(errCASNotSupportedInKVv1)
+		}
+	}
+
+	return namespaceWarnings(vaultProvider), nil
+}
+
+// validateAuth validates the auth of the store or of one of its mounts.
+func validateAuth(store esv1.GenericStore, auth *esv1.VaultAuth) error {
+	if auth.AppRole != nil {
+		// check SecretRef for valid configuration
+		if err := utils.ValidateReferentSecretSelector(store, auth.AppRole.SecretRef); err != nil {
+			return fmt.Errorf(errInvalidAppRoleSec, err)
+		}
+
+		// prefer .auth.appRole.roleId, fallback to .auth.appRole.roleRef, give up after that.
+		if auth.AppRole.RoleID == "" { // prevents further RoleID tests if .auth.appRole.roleId is given
+			if auth.AppRole.RoleRef != nil { // check RoleRef for valid configuration
+				if err := utils.ValidateReferentSecretSelector(store, *auth.AppRole.RoleRef); err != nil {
+					return fmt.Errorf(errInvalidAppRoleRef, err)
 				}
+			} else { // we ran out of ways to get RoleID. return an appropriate error
+				return errors.New(errInvalidAppRoleID)
 			}
 		}
-		if vaultProvider.Auth.Cert != nil {
-			if err := utils.ValidateReferentSecretSelector(store, vaultProvider.Auth.Cert.ClientCert); err != nil {
-				return nil, fmt.Errorf(errInvalidClientCert, err)
+		for i, creds := range auth.AppRole.FallbackCredentials {
+			if err := utils.ValidateReferentSecretSelector(store, creds.SecretRef); err != nil {
+				return fmt.Errorf(errInvalidAppRoleFallback, i, err)
+			}
+			if creds.RoleID != "" {
+				continue
 			}
-			if err := utils.ValidateReferentSecretSelector(store, vaultProvider.Auth.Cert.SecretRef); err != nil {
-				return nil, fmt.Errorf(errInvalidCertSec, err)
+			if creds.RoleRef == nil {
+				return fmt.Errorf(errInvalidAppRoleFallback, i, errors.New("neither `roleId` nor `roleRef` was supplied"))
 			}
+			if err := utils.ValidateReferentSecretSelector(store, *creds.RoleRef); err != nil {
+				return fmt.Errorf(errInvalidAppRoleFallback, i, err)
+			}
+		}
+	}
+	if auth.Cert != nil {
+		if err := utils.ValidateReferentSecretSelector(store, auth.Cert.ClientCert); err != nil {
+			return fmt.Errorf(errInvalidClientCert, err)
+		}
+		if err := utils.ValidateReferentSecretSelector(store, auth.Cert.SecretRef); err != nil {
+			return fmt.Errorf(errInvalidCertSec, err)
 		}
-		if vaultProvider.Auth.Jwt != nil {
-			if vaultProvider.Auth.Jwt.SecretRef != nil {
-				if err := utils.ValidateReferentSecretSelector(store, *vaultProvider.Auth.Jwt.SecretRef); err != nil {
-					return nil, fmt.Errorf(errInvalidJwtSec, err)
+	}
+	if auth.Jwt != nil {
+		// templated mount paths are rendered with the namespace of the
+		// store, or a sample one, so that they fail here rather than at login.
+		namespace := store.GetObjectMeta().Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		if _, err := renderJwtPath(auth.Jwt.Path, jwtPathData{
+			Namespace: namespace,
+			StoreKind: store.GetTypeMeta().Kind,
+			StoreName: store.GetObjectMeta().Name,
+		}); err != nil {
+			return fmt.Errorf(errInvalidJwtPath, err)
+		}
+		if auth.Jwt.SecretRef != nil {
+			if err := utils.ValidateReferentSecretSelector(store, *auth.Jwt.SecretRef); err != nil {
+				return fmt.Errorf(errInvalidJwtSec, err)
+			}
+		} else if auth.Jwt.KubernetesServiceAccountToken != nil {
+			if err := utils.ValidateReferentServiceAccountSelector(store, auth.Jwt.KubernetesServiceAccountToken.ServiceAccountRef); err != nil {
+				return fmt.Errorf(errInvalidJwtK8sSA, err)
+			}
+			if request := auth.Jwt.KubernetesServiceAccountToken.KubernetesTokenRequest; request != nil {
+				if auth.Jwt.KubernetesServiceAccountToken.Audiences != nil || auth.Jwt.KubernetesServiceAccountToken.ExpirationSeconds != nil {
+					return fmt.Errorf(errInvalidJwtTokenReq, errors.New("cannot be used with audiences or expirationSeconds"))
 				}
-			} else if vaultProvider.Auth.Jwt.KubernetesServiceAccountToken != nil {
-				if err := utils.ValidateReferentServiceAccountSelector(store, vaultProvider.Auth.Jwt.KubernetesServiceAccountToken.ServiceAccountRef); err != nil {
-					return nil, fmt.Errorf(errInvalidJwtK8sSA, err)
+				if err := validateTokenRequest(request); err != nil {
+					return fmt.Errorf(errInvalidJwtTokenReq, err)
 				}
-			} else {
-				return nil, errors.New(errJwtNoTokenSource)
 			}
-		}
-		if vaultProvider.Auth.Kubernetes != nil {
-			if vaultProvider.Auth.Kubernetes.ServiceAccountRef != nil {
-				if err := utils.ValidateReferentServiceAccountSelector(store, *vaultProvider.Auth.Kubernetes.ServiceAccountRef); err != nil {
-					return nil, fmt.Errorf(errInvalidKubeSA, err)
-				}
+		} else if source := auth.Jwt.HTTPSource; source != nil {
+			if err := validateJwtHTTPSource(store, source); err != nil {
+				return fmt.Errorf(errInvalidJwtHTTPSource, err)
 			}
-			if vaultProvider.Auth.Kubernetes.SecretRef != nil {
-				if err := utils.ValidateReferentSecretSelector(store, *vaultProvider.Auth.Kubernetes.SecretRef); err != nil {
-					return nil, fmt.Errorf(errInvalidKubeSec, err)
-				}
+		} else {
+			return errors.New(errJwtNoTokenSource)
+		}
+		if exchange := auth.Jwt.TokenExchange; exchange != nil {
+			if err := validateTokenEndpoint(exchange.TokenEndpoint); err != nil {
+				return fmt.Errorf(errInvalidJwtExchange, err)
 			}
 		}
-		if vaultProvider.Auth.Ldap != nil {
-			if err := utils.ValidateReferentSecretSelector(store, vaultProvider.Auth.Ldap.SecretRef); err != nil {
-				return nil, fmt.Errorf(errInvalidLdapSec, err)
+	}
+	if auth.Kubernetes != nil {
+		if auth.Kubernetes.ServiceAccountRef != nil {
+			if err := utils.ValidateReferentServiceAccountSelector(store, *auth.Kubernetes.ServiceAccountRef); err != nil {
+				return fmt.Errorf(errInvalidKubeSA, err)
 			}
 		}
-		if vaultProvider.Auth.UserPass != nil {
-			if err := utils.ValidateReferentSecretSelector(store, vaultProvider.Auth.UserPass.SecretRef); err != nil {
-				return nil, fmt.Errorf(errInvalidUserPassSec, err)
+		if auth.Kubernetes.SecretRef != nil {
+			if err := utils.ValidateReferentSecretSelector(store, *auth.Kubernetes.SecretRef); err != nil {
+				return fmt.Errorf(errInvalidKubeSec, err)
 			}
 		}
-		if vaultProvider.Auth.TokenSecretRef != nil {
-			if err := utils.ValidateReferentSecretSelector(store, *vaultProvider.Auth.TokenSecretRef); err != nil {
-				return nil, fmt.Errorf(errInvalidTokenRef, err)
+		if request := auth.Kubernetes.KubernetesTokenRequest; request != nil {
+			if auth.Kubernetes.ServiceAccountRef == nil {
+				return fmt.Errorf(errInvalidKubeTokenReq, errors.New("requires serviceAccountRef"))
+			}
+			if len(auth.Kubernetes.Audiences) > 0 {
+				return fmt.Errorf(errInvalidKubeTokenReq, errors.New("cannot be used with audiences"))
 			}
+			if err := validateTokenRequest(request); err != nil {
+				return fmt.Errorf(errInvalidKubeTokenReq, err)
+			}
+		}
+		if _, err := newRetryPolicy(auth.Kubernetes.TokenRequestRetrySettings); err != nil {
+			return fmt.Errorf(errInvalidKubeTokenRetry, err)
+		}
+		if _, err := newRetryPolicy(auth.Kubernetes.LoginRetrySettings); err != nil {
+			return fmt.Errorf(errInvalidKubeLoginRetry, err)
+		}
+	}
+	if auth.Ldap != nil {
+		if err := utils.ValidateReferentSecretSelector(store, auth.Ldap.SecretRef); err != nil {
+			return fmt.Errorf(errInvalidLdapSec, err)
+		}
+	}
+	if auth.UserPass != nil {
+		if err := utils.ValidateReferentSecretSelector(store, auth.UserPass.SecretRef); err != nil {
+			return fmt.Errorf(errInvalidUserPassSec, err)
+		}
+		if err := validateUserPassJSONPaths(auth.UserPass.JSONPaths); err != nil {
+			return err
+		}
+	}
+	if auth.TokenSecretRef != nil {
+		if err := utils.ValidateReferentSecretSelector(store, *auth.TokenSecretRef); err != nil {
+			return fmt.Errorf(errInvalidTokenRef, err)
 		}
-		if vaultProvider.Auth.Iam != nil {
-			if vaultProvider.Auth.Iam.JWTAuth != nil {
-				if vaultProvider.Auth.Iam.JWTAuth.ServiceAccountRef != nil {
-					if err := utils.ValidateReferentServiceAccountSelector(store, *vaultProvider.Auth.Iam.JWTAuth.ServiceAccountRef); err != nil {
-						return nil, fmt.Errorf(errInvalidTokenRef, err)
-					}
+	}
+	if auth.FallbackTokenRef != nil {
+		if err := utils.ValidateReferentSecretSelector(store, *auth.FallbackTokenRef); err != nil {
+			return fmt.Errorf(errInvalidFallbackToken, err)
+		}
+	}
+	if auth.TokenFilePath != "" && !filepath.IsAbs(auth.TokenFilePath) {
+		return fmt.Errorf(errInvalidTokenFile, auth.TokenFilePath)
+	}
+	if auth.WrappedToken && auth.TokenSecretRef == nil {
+		return errors.New(errInvalidWrappedToken)
+	}
+	if role := auth.TokenRole; role != nil {
+		if role.Name == "" || strings.Contains(role.Name, "/") {
+			return fmt.Errorf(errInvalidTokenRole, fmt.Errorf("invalid name %q", role.Name))
+		}
+		if role.TTL != nil && role.TTL.Duration <= 0 {
+			return fmt.Errorf(errInvalidTokenRole, errors.New("TTL must be positive"))
+		}
+	}
+	if auth.Iam != nil {
+		if auth.Iam.JWTAuth != nil {
+			if auth.Iam.JWTAuth.ServiceAccountRef != nil {
+				if err := utils.ValidateReferentServiceAccountSelector(store, *auth.Iam.JWTAuth.ServiceAccountRef); err != nil {
+					return fmt.Errorf(errInvalidTokenRef, err)
 				}
 			}
+		}
 
-			if vaultProvider.Auth.Iam.SecretRef != nil {
-				if err := utils.ValidateReferentSecretSelector(store, vaultProvider.Auth.Iam.SecretRef.AccessKeyID); err != nil {
-					return nil, fmt.Errorf(errInvalidTokenRef, err)
-				}
-				if err := utils.ValidateReferentSecretSelector(store, vaultProvider.Auth.Iam.SecretRef.SecretAccessKey); err != nil {
-					return nil, fmt.Errorf(errInvalidTokenRef, err)
-				}
-				if vaultProvider.Auth.Iam.SecretRef.SessionToken != nil {
-					if err := utils.ValidateReferentSecretSelector(store, *vaultProvider.Auth.Iam.SecretRef.SessionToken); err != nil {
-						return nil, fmt.Errorf(errInvalidTokenRef, err)
-					}
+		if auth.Iam.SecretRef != nil {
+			if err := utils.ValidateReferentSecretSelector(store, auth.Iam.SecretRef.AccessKeyID); err != nil {
+				return fmt.Errorf(errInvalidTokenRef, err)
+			}
+			if err := utils.ValidateReferentSecretSelector(store, auth.Iam.SecretRef.SecretAccessKey); err != nil {
+				return fmt.Errorf(errInvalidTokenRef, err)
+			}
+			if auth.Iam.SecretRef.SessionToken != nil {
+				if err := utils.ValidateReferentSecretSelector(store, *auth.Iam.SecretRef.SessionToken); err != nil {
+					return fmt.Errorf(errInvalidTokenRef, err)
 				}
 			}
 		}
 	}
-	if vaultProvider.ClientTLS.CertSecretRef != nil && vaultProvider.ClientTLS.KeySecretRef != nil {
-		if err := utils.ValidateReferentSecretSelector(store, *vaultProvider.ClientTLS.CertSecretRef); err != nil {
-			return nil, fmt.Errorf(errInvalidClientTLSCert, err)
+	if auth.Gcp != nil && auth.Gcp.ServiceAccountRef != nil {
+		if err := utils.ValidateReferentServiceAccountSelector(store, *auth.Gcp.ServiceAccountRef); err != nil {
+			return fmt.Errorf(errInvalidGcpSA, err)
 		}
-		if err := utils.ValidateReferentSecretSelector(store, *vaultProvider.ClientTLS.KeySecretRef); err != nil {
-			return nil, fmt.Errorf(errInvalidClientTLSSecret, err)
+	}
+	if auth.Azure != nil && auth.Azure.ServiceAccountRef != nil {
+		if err := utils.ValidateReferentServiceAccountSelector(store, *auth.Azure.ServiceAccountRef); err != nil {
+			return fmt.Errorf(errInvalidAzureSA, err)
+		}
+	}
+	if auth.Plugin != nil {
+		for _, name := range slices.Sorted(maps.Keys(auth.Plugin.SecretParameters)) {
+			if err := utils.ValidateReferentSecretSelector(store, auth.Plugin.SecretParameters[name]); err != nil {
+				return fmt.Errorf(errInvalidPluginSec, name, err)
+			}
+		}
+	}
+	if auth.RootNamespace && auth.Namespace != nil {
+		return errors.New(errInvalidAuthNamespace)
+	}
+	if _, err := newLoginRetryPolicy(auth.LoginRetry); err != nil {
+		return fmt.Errorf(errInvalidLoginRetry, err)
+	}
+	if auth.TokenExpirationLeewaySeconds < 0 {
+		return errors.New(errInvalidTokenLeeway)
+	}
+	if auth.LoginTimeout != nil && auth.LoginTimeout.Duration <= 0 {
+		return errors.New(errInvalidLoginTimeout)
+	}
+	if source := auth.PolicySource; source != nil {
+		if (source.ConfigMapRef != nil) == (source.RolePath != "") {
+			return errors.New(errInvalidPolicySource)
+		}
+		if source.ConfigMapRef != nil {
+			if err := utils.ValidateReferentSecretSelector(store, esmeta.SecretKeySelector{Namespace: source.ConfigMapRef.Namespace}); err != nil {
+				return fmt.Errorf(errInvalidPolicyConfigMap, err)
+			}
+		}
+	}
+	for i, rule := range auth.Selection {
+		if !isAuthMethodConfigured(auth, rule.Method) {
+			return fmt.Errorf(errInvalidAuthSelection, i, rule.Method)
 		}
-	} else if vaultProvider.ClientTLS.CertSecretRef != nil || vaultProvider.ClientTLS.KeySecretRef != nil {
-		return nil, errors.New(errInvalidClientTLS)
 	}
+	if err := validateAuthMethodPriority(auth); err != nil {
+		return err
+	}
+	return validateSingleAuthMethod(auth)
+}
 
-	// Validate CAS configuration
-	if vaultProvider.CheckAndSet != nil && vaultProvider.CheckAndSet.Required {
-		if vaultProvider.Version == esv1.VaultKVStoreV1 {
-			return nil, errors.New(errCASNotSupportedInKVv1)
+// validateAuthMethodPriority validates that the auth method priority lists
+// configured methods only, each of them once.
+func validateAuthMethodPriority(auth *esv1.VaultAuth) error {
+	if len(auth.AuthMethodPriority) == 0 {
+		return nil
+	}
+	if len(auth.Selection) > 0 {
+		return errors.New(errAuthPriorityWithSelection)
+	}
+	for i, method := range auth.AuthMethodPriority {
+		if !isAuthMethodConfigured(auth, method) {
+			return fmt.Errorf(errInvalidAuthPriority, i, method)
+		}
+		if slices.Contains(auth.AuthMethodPriority[:i], method) {
+			return fmt.Errorf(errDuplicateAuthPriority, i, method)
+		}
+	}
+	return nil
+}
+
+// exclusiveAuthMethods are the auth methods of which only one can be
+// configured, in the order setAuth tries them. A token file may be set
+// along with one of them, which is used while the file is missing.
+var exclusiveAuthMethods = []esv1.VaultAuthMethodName{
+	esv1.VaultAuthMethodTokenSecretRef,
+	esv1.VaultAuthMethodAppRole,
+	esv1.VaultAuthMethodKubernetes,
+	esv1.VaultAuthMethodLdap,
+	esv1.VaultAuthMethodUserPass,
+	esv1.VaultAuthMethodJwt,
+	esv1.VaultAuthMethodCert,
+	esv1.VaultAuthMethodIam,
+	esv1.VaultAuthMethodGcp,
+	esv1.VaultAuthMethodAzure,
+	esv1.VaultAuthMethodPlugin,
+}
+
+// validateSingleAuthMethod rejects auth blocks configuring several auth
+// methods, of which setAuth would silently use the first. Several methods
+// can only be configured to choose between them with selection rules, or
+// to fall back on each other with an auth method priority.
+func validateSingleAuthMethod(auth *esv1.VaultAuth) error {
+	if len(auth.Selection) > 0 || len(auth.AuthMethodPriority) > 0 {
+		return nil
+	}
+	var configured []string
+	for _, method := range exclusiveAuthMethods {
+		if isAuthMethodConfigured(auth, method) {
+			configured = append(configured, string(method))
+		}
+	}
+	if len(configured) > 1 {
+		return fmt.Errorf(errMultipleAuthMethods, strings.Join(configured, ", "))
+	}
+	return nil
+}
+
+// validateUserPassJSONPaths validates the JSON paths of the userpass
+// credentials, which are otherwise only parsed once logging in.
+func validateUserPassJSONPaths(paths *esv1.VaultUserPassJSONPaths) error {
+	if paths == nil {
+		return nil
+	}
+	if paths.Password == "" {
+		return errors.New(errMissingUserPassPath)
+	}
+	if paths.Username != "" {
+		if _, err := jsonpath.New(paths.Username); err != nil {
+			return fmt.Errorf(errInvalidUserPassPath, "Username", paths.Username, err)
 		}
 	}
+	if _, err := jsonpath.New(paths.Password); err != nil {
+		return fmt.Errorf(errInvalidUserPassPath, "Password", paths.Password, err)
+	}
+	return nil
+}
 
-	return nil, nil
+// validateTokenRequest validates the request of a service account token,
+// the Kubernetes API only rejects it once the token is requested.
+func validateTokenRequest(request *esv1.VaultKubernetesTokenRequest) error {
+	if request.ExpirationSeconds != nil && *request.ExpirationSeconds < defaultTokenExpirationSeconds {
+		return fmt.Errorf("expirationSeconds must be at least %d", defaultTokenExpirationSeconds)
+	}
+	if ref := request.BoundObjectRef; ref != nil {
+		if ref.Kind != "Pod" && ref.Kind != "Secret" {
+			return fmt.Errorf("boundObjectRef has unsupported kind %q", ref.Kind)
+		}
+		if ref.APIVersion != "" && ref.APIVersion != "v1" {
+			return fmt.Errorf("boundObjectRef has unsupported apiVersion %q", ref.APIVersion)
+		}
+		if ref.Name == "" {
+			return errors.New("boundObjectRef requires a name")
+		}
+	}
+	return nil
 }
 
 func (c *client) Validate() (esv1.ValidationResult, error) {
@@ -181,7 +506,20 @@ func (c *client) Validate() (esv1.ValidationResult, error) {
 	if c.storeKind == esv1.ClusterSecretStoreKind && isReferentSpec(c.store) {
 		return esv1.ValidationResultUnknown, nil
 	}
-	_, err := checkToken(context.Background(), c.token)
+	// the server is checked before the token, also without
+	// --vault-ping-before-login, so that a wrong address or a sealed server
+	// isn't reported as invalid credentials.
+	if err := c.Ping(context.Background()); err != nil {
+		return esv1.ValidationResultError, err
+	}
+	// looking up a limited-use token would consume one of its uses.
+	if c.limitedUseToken() {
+		if c.checkLimitedUseToken(c.expiryThreshold()) {
+			return esv1.ValidationResultReady, nil
+		}
+		return esv1.ValidationResultUnknown, nil
+	}
+	_, err := checkToken(context.Background(), c.tokenAPI(), c.expiryThreshold())
 	if err != nil {
 		return esv1.ValidationResultError, fmt.Errorf(errInvalidCredentials, err)
 	}
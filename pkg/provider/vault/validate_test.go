@@ -15,7 +15,9 @@ package vault
 
 import (
 	"testing"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	pointer "k8s.io/utils/ptr"
 
 	esv1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1"
@@ -30,6 +32,14 @@ func TestValidateStore(t *testing.T) {
 		clientTLS   esv1.VaultClientTLS
 		version     esv1.VaultKVStoreVersion
 		checkAndSet *esv1.VaultCheckAndSet
+		server      string
+		dialAddress string
+		basePath    string
+		httpVersion esv1.VaultHTTPVersion
+		mountAuth   []esv1.VaultMountAuth
+		writeAuth   *esv1.VaultAuth
+		forward     bool
+		revocation  *esv1.VaultRevocationCheck
 	}
 
 	tests := []struct {
@@ -130,13 +140,441 @@ func TestValidateStore(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid jwt token exchange",
+			args: args{
+				auth: esv1.VaultAuth{
+					Jwt: &esv1.VaultJwtAuth{
+						SecretRef: &esmeta.SecretKeySelector{
+							Name: fakeValidationValue,
+						},
+						TokenExchange: &esv1.VaultJwtTokenExchange{
+							TokenEndpoint: "https://sts.example.com/oauth2/token",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid jwt token exchange endpoint",
+			args: args{
+				auth: esv1.VaultAuth{
+					Jwt: &esv1.VaultJwtAuth{
+						SecretRef: &esmeta.SecretKeySelector{
+							Name: fakeValidationValue,
+						},
+						TokenExchange: &esv1.VaultJwtTokenExchange{
+							TokenEndpoint: "sts.example.com/oauth2/token",
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid jwt http source",
+			args: args{
+				auth: esv1.VaultAuth{
+					Jwt: &esv1.VaultJwtAuth{
+						HTTPSource: &esv1.VaultJwtHTTPSource{
+							URL: "https://identity.internal/v1/token",
+							Headers: []esv1.VaultJwtHTTPHeader{
+								{Name: "X-Audience", Value: "vault"},
+								{Name: "Authorization", SecretRef: &esmeta.SecretKeySelector{Name: fakeValidationValue}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid jwt http source header",
+			args: args{
+				auth: esv1.VaultAuth{
+					Jwt: &esv1.VaultJwtAuth{
+						HTTPSource: &esv1.VaultJwtHTTPSource{
+							URL: "https://identity.internal/v1/token",
+							Headers: []esv1.VaultJwtHTTPHeader{
+								{Name: "Authorization", Value: "Bearer", SecretRef: &esmeta.SecretKeySelector{Name: fakeValidationValue}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid kubernetes sa",
 			args: args{
 				auth: esv1.VaultAuth{
 					Kubernetes: &esv1.VaultKubernetesAuth{
-						ServiceAccountRef: &esmeta.ServiceAccountSelector{
-							Namespace: pointer.To("invalid"),
+						ServiceAccountRef: &esmeta.ServiceAccountSelector{
+							Namespace: pointer.To("invalid"),
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid kubernetes secret",
+			args: args{
+				auth: esv1.VaultAuth{
+					Kubernetes: &esv1.VaultKubernetesAuth{
+						SecretRef: &esmeta.SecretKeySelector{
+							Namespace: pointer.To("invalid"),
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid kubernetes token request retry interval",
+			args: args{
+				auth: esv1.VaultAuth{
+					Kubernetes: &esv1.VaultKubernetesAuth{
+						TokenRequestRetrySettings: &esv1.SecretStoreRetrySettings{
+							RetryInterval: pointer.To("soon"),
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid kubernetes login max retries",
+			args: args{
+				auth: esv1.VaultAuth{
+					Kubernetes: &esv1.VaultKubernetesAuth{
+						LoginRetrySettings: &esv1.SecretStoreRetrySettings{
+							MaxRetries: pointer.To(int32(-1)),
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid kubernetes retry settings",
+			args: args{
+				auth: esv1.VaultAuth{
+					Kubernetes: &esv1.VaultKubernetesAuth{
+						TokenRequestRetrySettings: &esv1.SecretStoreRetrySettings{
+							MaxRetries:    pointer.To(int32(3)),
+							RetryInterval: pointer.To("2s"),
+						},
+						LoginRetrySettings: &esv1.SecretStoreRetrySettings{
+							MaxRetries: pointer.To(int32(2)),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "root auth namespace",
+			args: args{
+				auth: esv1.VaultAuth{
+					RootNamespace: true,
+				},
+			},
+		},
+		{
+			name: "root auth namespace with auth namespace",
+			args: args{
+				auth: esv1.VaultAuth{
+					Namespace:     pointer.To("admin"),
+					RootNamespace: true,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "token expiration leeway",
+			args: args{
+				auth: esv1.VaultAuth{
+					TokenExpirationLeewaySeconds: 300,
+				},
+			},
+		},
+		{
+			name: "login retry with invalid initial interval",
+			args: args{
+				auth: esv1.VaultAuth{
+					LoginRetry: &esv1.VaultLoginRetry{MaxAttempts: 3, InitialInterval: pointer.To("soon")},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative token expiration leeway",
+			args: args{
+				auth: esv1.VaultAuth{
+					TokenExpirationLeewaySeconds: -1,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrapped token",
+			args: args{
+				auth: esv1.VaultAuth{
+					TokenSecretRef: &esmeta.SecretKeySelector{Name: "vault-token", Key: "token"},
+					WrappedToken:   true,
+				},
+			},
+		},
+		{
+			name: "token role",
+			args: args{
+				auth: esv1.VaultAuth{
+					TokenSecretRef: &esmeta.SecretKeySelector{Name: "vault-token", Key: "token"},
+					TokenRole: &esv1.VaultTokenRole{
+						Name: "eso",
+						TTL:  &metav1.Duration{Duration: time.Hour},
+					},
+				},
+			},
+		},
+		{
+			name: "token role without name",
+			args: args{
+				auth: esv1.VaultAuth{
+					TokenSecretRef: &esmeta.SecretKeySelector{Name: "vault-token", Key: "token"},
+					TokenRole:      &esv1.VaultTokenRole{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "token role with negative ttl",
+			args: args{
+				auth: esv1.VaultAuth{
+					TokenSecretRef: &esmeta.SecretKeySelector{Name: "vault-token", Key: "token"},
+					TokenRole: &esv1.VaultTokenRole{
+						Name: "eso",
+						TTL:  &metav1.Duration{Duration: -time.Hour},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrapped token without token secret",
+			args: args{
+				auth: esv1.VaultAuth{WrappedToken: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "login timeout",
+			args: args{
+				auth: esv1.VaultAuth{
+					LoginTimeout: &metav1.Duration{Duration: 30 * time.Second},
+				},
+			},
+		},
+		{
+			name: "zero login timeout",
+			args: args{
+				auth: esv1.VaultAuth{
+					LoginTimeout: &metav1.Duration{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "approle with fallback credentials",
+			args: args{
+				auth: esv1.VaultAuth{
+					AppRole: &esv1.VaultAppRole{
+						RoleID: fakeValidationValue,
+						FallbackCredentials: []esv1.VaultAppRoleCredentials{
+							{RoleID: fakeValidationValue},
+							{RoleRef: &esmeta.SecretKeySelector{Name: fakeValidationValue}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "approle with fallback credentials without role id",
+			args: args{
+				auth: esv1.VaultAuth{
+					AppRole: &esv1.VaultAppRole{
+						RoleID:              fakeValidationValue,
+						FallbackCredentials: []esv1.VaultAppRoleCredentials{{}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "approle with fallback credentials in other namespace",
+			args: args{
+				auth: esv1.VaultAuth{
+					AppRole: &esv1.VaultAppRole{
+						RoleID: fakeValidationValue,
+						FallbackCredentials: []esv1.VaultAppRoleCredentials{{
+							RoleID:    fakeValidationValue,
+							SecretRef: esmeta.SecretKeySelector{Namespace: pointer.To("invalid")},
+						}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid mount auth",
+			args: args{
+				mountAuth: []esv1.VaultMountAuth{
+					{Path: "team-a", Auth: esv1.VaultAuth{AppRole: &esv1.VaultAppRole{RoleID: fakeValidationValue}}},
+					{Path: "team-b/", Auth: esv1.VaultAuth{AppRole: &esv1.VaultAppRole{RoleID: fakeValidationValue}}},
+				},
+			},
+		},
+		{
+			name: "mount auth with duplicate path",
+			args: args{
+				mountAuth: []esv1.VaultMountAuth{
+					{Path: "team-a", Auth: esv1.VaultAuth{AppRole: &esv1.VaultAppRole{RoleID: fakeValidationValue}}},
+					{Path: "/team-a/", Auth: esv1.VaultAuth{AppRole: &esv1.VaultAppRole{RoleID: fakeValidationValue}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "mount auth with invalid auth",
+			args: args{
+				mountAuth: []esv1.VaultMountAuth{
+					{Path: "team-a", Auth: esv1.VaultAuth{AppRole: &esv1.VaultAppRole{}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "write auth",
+			args: args{
+				writeAuth: &esv1.VaultAuth{AppRole: &esv1.VaultAppRole{RoleID: fakeValidationValue}},
+			},
+		},
+		{
+			name: "write auth with invalid auth",
+			args: args{
+				writeAuth: &esv1.VaultAuth{AppRole: &esv1.VaultAppRole{}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "policy source",
+			args: args{
+				auth: esv1.VaultAuth{PolicySource: &esv1.VaultPolicySource{RolePath: "auth/kubernetes/role/app"}},
+			},
+		},
+		{
+			name: "empty policy source",
+			args: args{
+				auth: esv1.VaultAuth{PolicySource: &esv1.VaultPolicySource{}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "policy source with configmap and role",
+			args: args{
+				auth: esv1.VaultAuth{PolicySource: &esv1.VaultPolicySource{
+					ConfigMapRef: &esv1.VaultConfigMapKeySelector{Name: "vault-policies", Key: "policies"},
+					RolePath:     "auth/kubernetes/role/app",
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "policy source configmap in other namespace",
+			args: args{
+				auth: esv1.VaultAuth{PolicySource: &esv1.VaultPolicySource{
+					ConfigMapRef: &esv1.VaultConfigMapKeySelector{Name: "vault-policies", Namespace: pointer.To("invalid"), Key: "policies"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "local mount",
+			args: args{
+				auth: esv1.VaultAuth{LocalMount: true},
+			},
+		},
+		{
+			name: "local mount with forward inconsistent",
+			args: args{
+				auth:    esv1.VaultAuth{LocalMount: true},
+				forward: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "local mount auth with forward inconsistent",
+			args: args{
+				mountAuth: []esv1.VaultMountAuth{
+					{Path: "team-a", Auth: esv1.VaultAuth{LocalMount: true}},
+				},
+				forward: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid dial address",
+			args: args{
+				dialAddress: "127.0.0.1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "kubernetes token request",
+			args: args{
+				auth: esv1.VaultAuth{
+					Kubernetes: &esv1.VaultKubernetesAuth{
+						ServiceAccountRef: &esmeta.ServiceAccountSelector{Name: "vault-sa"},
+						KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{
+							Audiences:         []string{"vault"},
+							ExpirationSeconds: pointer.To[int64](3600),
+							BoundObjectRef:    &esv1.VaultKubernetesBoundObjectRef{Kind: "Pod", Name: "app"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "kubernetes token request without service account",
+			args: args{
+				auth: esv1.VaultAuth{
+					Kubernetes: &esv1.VaultKubernetesAuth{
+						KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "kubernetes token request with audiences",
+			args: args{
+				auth: esv1.VaultAuth{
+					Kubernetes: &esv1.VaultKubernetesAuth{
+						ServiceAccountRef:      &esmeta.ServiceAccountSelector{Name: "vault-sa"},
+						Audiences:              []string{"vault"},
+						KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "kubernetes token request with short expiration",
+			args: args{
+				auth: esv1.VaultAuth{
+					Kubernetes: &esv1.VaultKubernetesAuth{
+						ServiceAccountRef: &esmeta.ServiceAccountSelector{Name: "vault-sa"},
+						KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{
+							ExpirationSeconds: pointer.To[int64](60),
 						},
 					},
 				},
@@ -144,18 +582,154 @@ func TestValidateStore(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "invalid kubernetes secret",
+			name: "kubernetes token request bound to unsupported kind",
 			args: args{
 				auth: esv1.VaultAuth{
 					Kubernetes: &esv1.VaultKubernetesAuth{
-						SecretRef: &esmeta.SecretKeySelector{
-							Namespace: pointer.To("invalid"),
+						ServiceAccountRef: &esmeta.ServiceAccountSelector{Name: "vault-sa"},
+						KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{
+							BoundObjectRef: &esv1.VaultKubernetesBoundObjectRef{Kind: "Deployment", Name: "app"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "kubernetes token request bound to unnamed object",
+			args: args{
+				auth: esv1.VaultAuth{
+					Kubernetes: &esv1.VaultKubernetesAuth{
+						ServiceAccountRef: &esmeta.ServiceAccountSelector{Name: "vault-sa"},
+						KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{
+							BoundObjectRef: &esv1.VaultKubernetesBoundObjectRef{Kind: "Pod"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "jwt token request",
+			args: args{
+				auth: esv1.VaultAuth{
+					Jwt: &esv1.VaultJwtAuth{
+						KubernetesServiceAccountToken: &esv1.VaultKubernetesServiceAccountTokenAuth{
+							ServiceAccountRef: esmeta.ServiceAccountSelector{Name: "vault-sa"},
+							KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{
+								BoundObjectRef: &esv1.VaultKubernetesBoundObjectRef{Kind: "Secret", APIVersion: "v1", Name: "binding"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "jwt token request with deprecated expiration",
+			args: args{
+				auth: esv1.VaultAuth{
+					Jwt: &esv1.VaultJwtAuth{
+						KubernetesServiceAccountToken: &esv1.VaultKubernetesServiceAccountTokenAuth{
+							ServiceAccountRef:      esmeta.ServiceAccountSelector{Name: "vault-sa"},
+							ExpirationSeconds:      pointer.To[int64](600),
+							KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{},
 						},
 					},
 				},
 			},
 			wantErr: true,
 		},
+		{
+			name: "jwt token request bound to unsupported api version",
+			args: args{
+				auth: esv1.VaultAuth{
+					Jwt: &esv1.VaultJwtAuth{
+						KubernetesServiceAccountToken: &esv1.VaultKubernetesServiceAccountTokenAuth{
+							ServiceAccountRef: esmeta.ServiceAccountSelector{Name: "vault-sa"},
+							KubernetesTokenRequest: &esv1.VaultKubernetesTokenRequest{
+								BoundObjectRef: &esv1.VaultKubernetesBoundObjectRef{Kind: "Pod", APIVersion: "v2", Name: "app"},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid dial address",
+			args: args{
+				dialAddress: "127.0.0.1:8200",
+			},
+		},
+		{
+			name: "dial address with unix socket server",
+			args: args{
+				server:      "unix:///var/run/vault/agent.sock",
+				dialAddress: "127.0.0.1:8200",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid base path",
+			args: args{
+				basePath: "/vault/",
+			},
+		},
+		{
+			name: "http2 with https server",
+			args: args{
+				server:      "https://vault.example.com",
+				httpVersion: esv1.VaultHTTPVersion2,
+			},
+		},
+		{
+			name: "http2 with unix socket server",
+			args: args{
+				server:      "unix:///var/run/vault/agent.sock",
+				httpVersion: esv1.VaultHTTPVersion2,
+			},
+			wantErr: true,
+		},
+		{
+			name: "http1 with unix socket server",
+			args: args{
+				server:      "unix:///var/run/vault/agent.sock",
+				httpVersion: esv1.VaultHTTPVersion1,
+			},
+		},
+		{
+			name: "base path with api prefix",
+			args: args{
+				basePath: "/vault/v1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "base path with query",
+			args: args{
+				basePath: "/vault?x=y",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unix socket server",
+			args: args{
+				server: "unix:///var/run/vault/agent.sock",
+			},
+		},
+		{
+			name: "valid revocation check",
+			args: args{
+				revocation: &esv1.VaultRevocationCheck{Method: esv1.VaultRevocationCheckCRL, SoftFail: true},
+			},
+		},
+		{
+			name: "revocation check with unknown method",
+			args: args{
+				revocation: &esv1.VaultRevocationCheck{Method: "stapling"},
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid ldap secret",
 			args: args{
@@ -182,6 +756,47 @@ func TestValidateStore(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid userpass json paths",
+			args: args{
+				auth: esv1.VaultAuth{
+					UserPass: &esv1.VaultUserPassAuth{
+						JSONPaths: &esv1.VaultUserPassJSONPaths{
+							Username: "$.username",
+							Password: "$.credentials[0].password",
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "malformed userpass json path",
+			args: args{
+				auth: esv1.VaultAuth{
+					UserPass: &esv1.VaultUserPassAuth{
+						Username: "eso",
+						JSONPaths: &esv1.VaultUserPassJSONPaths{
+							Password: "$.password[",
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing userpass password json path",
+			args: args{
+				auth: esv1.VaultAuth{
+					UserPass: &esv1.VaultUserPassAuth{
+						JSONPaths: &esv1.VaultUserPassJSONPaths{
+							Username: "$.username",
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid token secret",
 			args: args{
@@ -193,6 +808,35 @@ func TestValidateStore(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid fallback token secret",
+			args: args{
+				auth: esv1.VaultAuth{
+					FallbackTokenRef: &esmeta.SecretKeySelector{
+						Namespace: pointer.To("invalid"),
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "relative token file path",
+			args: args{
+				auth: esv1.VaultAuth{
+					TokenFilePath: "vault/token",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid token file path",
+			args: args{
+				auth: esv1.VaultAuth{
+					TokenFilePath: "/vault/secrets/token",
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "valid clientTls config",
 			args: args{
@@ -301,6 +945,91 @@ func TestValidateStore(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "auth selection with configured methods",
+			args: args{
+				auth: esv1.VaultAuth{
+					Kubernetes: &esv1.VaultKubernetesAuth{},
+					Iam:        &esv1.VaultIamAuth{},
+					Selection: []esv1.VaultAuthSelectionRule{
+						{Method: esv1.VaultAuthMethodIam, EnvVar: "AWS_WEB_IDENTITY_TOKEN_FILE"},
+						{Method: esv1.VaultAuthMethodKubernetes},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "templated jwt path",
+			args: args{
+				auth: esv1.VaultAuth{
+					Jwt: &esv1.VaultJwtAuth{
+						Path:      "jwt-{{ .Namespace }}",
+						SecretRef: &esmeta.SecretKeySelector{Name: "jwt"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid jwt path template",
+			args: args{
+				auth: esv1.VaultAuth{
+					Jwt: &esv1.VaultJwtAuth{
+						Path:      "jwt-{{ .Namespace",
+						SecretRef: &esmeta.SecretKeySelector{Name: "jwt"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown jwt path template field",
+			args: args{
+				auth: esv1.VaultAuth{
+					Jwt: &esv1.VaultJwtAuth{
+						Path:      "jwt-{{ .Team }}",
+						SecretRef: &esmeta.SecretKeySelector{Name: "jwt"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "multiple auth methods without selection",
+			args: args{
+				auth: esv1.VaultAuth{
+					AppRole: &esv1.VaultAppRole{
+						RoleID: fakeValidationValue,
+					},
+					Kubernetes: &esv1.VaultKubernetesAuth{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "token file with fallback auth method",
+			args: args{
+				auth: esv1.VaultAuth{
+					TokenFilePath: "/vault/secrets/token",
+					Kubernetes:    &esv1.VaultKubernetesAuth{},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "auth selection with unconfigured method",
+			args: args{
+				auth: esv1.VaultAuth{
+					Kubernetes: &esv1.VaultKubernetesAuth{},
+					Selection: []esv1.VaultAuthSelectionRule{
+						{Method: esv1.VaultAuthMethodIam, EnvVar: "AWS_WEB_IDENTITY_TOKEN_FILE"},
+						{Method: esv1.VaultAuthMethodKubernetes},
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -312,10 +1041,21 @@ func TestValidateStore(t *testing.T) {
 				Spec: esv1.SecretStoreSpec{
 					Provider: &esv1.SecretStoreProvider{
 						Vault: &esv1.VaultProvider{
+							Server:      tt.args.server,
 							Auth:        &auth,
 							ClientTLS:   tt.args.clientTLS,
 							Version:     tt.args.version,
 							CheckAndSet: tt.args.checkAndSet,
+							DialAddress: tt.args.dialAddress,
+							BasePath:    tt.args.basePath,
+							HTTPVersion: tt.args.httpVersion,
+							MountAuth:   tt.args.mountAuth,
+							WriteAuth:   tt.args.writeAuth,
+
+							RevocationCheck: tt.args.revocation,
+
+							ReadYourWrites:      tt.args.forward,
+							ForwardInconsistent: tt.args.forward,
 						},
 					},
 				},
@@ -326,3 +1066,106 @@ func TestValidateStore(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSingleAuthMethod(t *testing.T) {
+	cases := map[string]struct {
+		auth    esv1.VaultAuth
+		wantErr string
+	}{
+		"Single": {
+			auth: esv1.VaultAuth{Kubernetes: &esv1.VaultKubernetesAuth{}},
+		},
+		"Conflicting": {
+			auth: esv1.VaultAuth{
+				TokenSecretRef: &esmeta.SecretKeySelector{Name: "token"},
+				AppRole:        &esv1.VaultAppRole{},
+				Kubernetes:     &esv1.VaultKubernetesAuth{},
+			},
+			wantErr: "only one auth method can be configured without Auth.Selection or Auth.AuthMethodPriority, got tokenSecretRef, appRole, kubernetes",
+		},
+		"Selection": {
+			auth: esv1.VaultAuth{
+				AppRole:    &esv1.VaultAppRole{},
+				Kubernetes: &esv1.VaultKubernetesAuth{},
+				Selection:  []esv1.VaultAuthSelectionRule{{Method: esv1.VaultAuthMethodKubernetes}},
+			},
+		},
+		"Priority": {
+			auth: esv1.VaultAuth{
+				AppRole:            &esv1.VaultAppRole{},
+				Kubernetes:         &esv1.VaultKubernetesAuth{},
+				AuthMethodPriority: []esv1.VaultAuthMethodName{esv1.VaultAuthMethodKubernetes, esv1.VaultAuthMethodAppRole},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateSingleAuthMethod(&tc.auth)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateAuthMethodPriority(t *testing.T) {
+	cases := map[string]struct {
+		auth    esv1.VaultAuth
+		wantErr string
+	}{
+		"NoPriority": {
+			auth: esv1.VaultAuth{Kubernetes: &esv1.VaultKubernetesAuth{}},
+		},
+		"Valid": {
+			auth: esv1.VaultAuth{
+				AppRole:            &esv1.VaultAppRole{},
+				Kubernetes:         &esv1.VaultKubernetesAuth{},
+				AuthMethodPriority: []esv1.VaultAuthMethodName{esv1.VaultAuthMethodKubernetes, esv1.VaultAuthMethodAppRole},
+			},
+		},
+		"NotConfigured": {
+			auth: esv1.VaultAuth{
+				Kubernetes:         &esv1.VaultKubernetesAuth{},
+				AuthMethodPriority: []esv1.VaultAuthMethodName{esv1.VaultAuthMethodKubernetes, esv1.VaultAuthMethodAppRole},
+			},
+			wantErr: `invalid Auth.AuthMethodPriority[1]: auth method "appRole" is not configured`,
+		},
+		"Duplicate": {
+			auth: esv1.VaultAuth{
+				Kubernetes:         &esv1.VaultKubernetesAuth{},
+				AuthMethodPriority: []esv1.VaultAuthMethodName{esv1.VaultAuthMethodKubernetes, esv1.VaultAuthMethodKubernetes},
+			},
+			wantErr: `invalid Auth.AuthMethodPriority[1]: auth method "kubernetes" is listed more than once`,
+		},
+		"WithSelection": {
+			auth: esv1.VaultAuth{
+				Kubernetes:         &esv1.VaultKubernetesAuth{},
+				Selection:          []esv1.VaultAuthSelectionRule{{Method: esv1.VaultAuthMethodKubernetes}},
+				AuthMethodPriority: []esv1.VaultAuthMethodName{esv1.VaultAuthMethodKubernetes},
+			},
+			wantErr: "Auth.AuthMethodPriority and Auth.Selection are mutually exclusive",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateAuthMethodPriority(&tc.auth)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}